@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// multipartOverhead — запас на заголовки и границы multipart сверх размера файла
+const multipartOverhead = 64 << 10
+
+// AttachmentHandler обрабатывает HTTP запросы для вложений задач
+type AttachmentHandler struct {
+	service AttachmentServiceInterface
+}
+
+// NewAttachmentHandler создает новый обработчик вложений
+func NewAttachmentHandler(service AttachmentServiceInterface) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+// UploadAttachment обрабатывает POST /tasks/{id}/attachments (multipart/form-data, поле "file")
+func (ah *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID задачи", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAttachmentSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Ожидается multipart/form-data", http.StatusBadRequest)
+		return
+	}
+
+	// Читаем части потоком, не буферизуя файл целиком в памяти
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Поле 'file' обязательно", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeAttachmentError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := ah.service.AddAttachment(r.Context(), taskID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			writeAttachmentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(attachment)
+		return
+	}
+}
+
+// GetAttachments обрабатывает GET /tasks/{id}/attachments
+func (ah *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID задачи", http.StatusBadRequest)
+		return
+	}
+
+	attachments, err := ah.service.GetAttachments(r.Context(), taskID)
+	if err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// DownloadAttachment обрабатывает GET /tasks/{id}/attachments/{attachmentID}
+// с поддержкой Range и условных запросов
+func (ah *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	taskID, attachmentID, ok := parseAttachmentParams(w, r)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := ah.service.OpenAttachment(r.Context(), taskID, attachmentID)
+	if err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("ETag", `"`+attachment.Checksum+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, attachment.Filename, attachment.CreatedAt, content)
+}
+
+// DeleteAttachment обрабатывает DELETE /tasks/{id}/attachments/{attachmentID}
+func (ah *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	taskID, attachmentID, ok := parseAttachmentParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := ah.service.DeleteAttachment(r.Context(), taskID, attachmentID); err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseAttachmentParams извлекает ID задачи и вложения из URL
+func parseAttachmentParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID задачи", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	attachmentID, err := strconv.Atoi(chi.URLParam(r, "attachmentID"))
+	if err != nil {
+		http.Error(w, "Неверный ID вложения", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return taskID, attachmentID, true
+}
+
+// writeAttachmentError сопоставляет ошибки сервиса вложений со статус-кодами
+func writeAttachmentError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrBlobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAttachmentTooLarge), errors.As(err, &maxBytesErr):
+		http.Error(w, ErrAttachmentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrAttachmentTypeNotAllowed):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxAttachmentSize ограничивает размер одного вложения
+const MaxAttachmentSize = 10 << 20
+
+var (
+	// ErrAttachmentTooLarge возвращается, если файл превышает MaxAttachmentSize
+	ErrAttachmentTooLarge = errors.New("размер вложения превышает допустимый")
+	// ErrAttachmentTypeNotAllowed возвращается для недопустимого типа файла
+	ErrAttachmentTypeNotAllowed = errors.New("недопустимый тип вложения")
+)
+
+// allowedAttachmentTypes перечисляет MIME-типы, которые можно прикреплять к задачам
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+	"application/pdf": true,
+	"application/zip": true,
+}
+
+// AttachmentServiceInterface определяет интерфейс для работы с вложениями
+type AttachmentServiceInterface interface {
+	AddAttachment(ctx context.Context, taskID int, filename string, r io.Reader) (*Attachment, error)
+	GetAttachments(ctx context.Context, taskID int) ([]*Attachment, error)
+	GetAttachmentsForTasks(ctx context.Context, taskIDs []int) map[int][]*Attachment
+	OpenAttachment(ctx context.Context, taskID, id int) (*Attachment, io.ReadSeekCloser, error)
+	DeleteAttachment(ctx context.Context, taskID, id int) error
+	DeleteTaskAttachments(ctx context.Context, taskID int)
+}
+
+// AttachmentService хранит метаданные вложений в памяти, а содержимое в BlobStore
+type AttachmentService struct {
+	tasks       TaskServiceInterface
+	store       BlobStore
+	attachments map[int]*Attachment
+	nextID      int
+	mutex       sync.RWMutex
+	// blobMutex не дает удалить объект из хранилища, пока идет загрузка,
+	// которая может сослаться на то же содержимое
+	blobMutex sync.RWMutex
+	// collecting отслеживает фоновое удаление содержимого (collectBlobs)
+	collecting sync.WaitGroup
+}
+
+// NewAttachmentService создает новый сервис вложений
+func NewAttachmentService(tasks TaskServiceInterface, store BlobStore) AttachmentServiceInterface {
+	return &AttachmentService{
+		tasks:       tasks,
+		store:       store,
+		attachments: make(map[int]*Attachment),
+		nextID:      1,
+	}
+}
+
+// AddAttachment сохраняет файл и прикрепляет его к задаче
+func (as *AttachmentService) AddAttachment(ctx context.Context, taskID int, filename string, r io.Reader) (*Attachment, error) {
+	if _, err := as.tasks.GetTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	// Тип определяем по содержимому, а не по заголовкам клиента
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	contentType := http.DetectContentType(head)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !allowedAttachmentTypes[mediaType] {
+		return nil, ErrAttachmentTypeNotAllowed
+	}
+
+	as.blobMutex.RLock()
+	defer as.blobMutex.RUnlock()
+
+	key, size, err := as.store.Put(&limitedReader{r: br, remaining: MaxAttachmentSize})
+	if err != nil {
+		return nil, err
+	}
+
+	as.mutex.Lock()
+	attachment := &Attachment{
+		ID:          as.nextID,
+		TaskID:      taskID,
+		Filename:    sanitizeFilename(filename),
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    key,
+		CreatedAt:   time.Now(),
+	}
+
+	as.attachments[as.nextID] = attachment
+	as.nextID++
+	as.mutex.Unlock()
+
+	// Задачу могли удалить, пока шла загрузка: ее вложения уже удалены без этого
+	if _, err := as.tasks.GetTask(ctx, taskID); err != nil {
+		as.mutex.Lock()
+		delete(as.attachments, attachment.ID)
+		as.mutex.Unlock()
+		as.collectBlobsAsync([]string{key})
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// GetAttachments возвращает все вложения задачи
+func (as *AttachmentService) GetAttachments(ctx context.Context, taskID int) ([]*Attachment, error) {
+	if _, err := as.tasks.GetTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	attachments := make([]*Attachment, 0)
+	for _, attachment := range as.attachments {
+		if attachment.TaskID == taskID {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments, nil
+}
+
+// GetAttachmentsForTasks возвращает вложения нескольких задач за один проход.
+// Существование задач не проверяется: у несуществующей задачи вложений нет.
+func (as *AttachmentService) GetAttachmentsForTasks(_ context.Context, taskIDs []int) map[int][]*Attachment {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	result := make(map[int][]*Attachment, len(taskIDs))
+	for _, taskID := range taskIDs {
+		result[taskID] = make([]*Attachment, 0)
+	}
+	for _, attachment := range as.attachments {
+		if list, ok := result[attachment.TaskID]; ok {
+			result[attachment.TaskID] = append(list, attachment)
+		}
+	}
+	for _, list := range result {
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	}
+	return result
+}
+
+// OpenAttachment возвращает метаданные вложения и открытое содержимое
+func (as *AttachmentService) OpenAttachment(_ context.Context, taskID, id int) (*Attachment, io.ReadSeekCloser, error) {
+	as.mutex.RLock()
+	attachment, err := as.lookup(taskID, id)
+	as.mutex.RUnlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := as.store.Open(attachment.Checksum)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+// DeleteAttachment удаляет вложение; содержимое удаляется, когда на него не осталось ссылок
+func (as *AttachmentService) DeleteAttachment(_ context.Context, taskID, id int) error {
+	as.blobMutex.Lock()
+	defer as.blobMutex.Unlock()
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	attachment, err := as.lookup(taskID, id)
+	if err != nil {
+		return err
+	}
+
+	delete(as.attachments, id)
+
+	for _, other := range as.attachments {
+		if other.Checksum == attachment.Checksum {
+			return nil
+		}
+	}
+
+	if err := as.store.Delete(attachment.Checksum); err != nil && !errors.Is(err, ErrBlobNotFound) {
+		return err
+	}
+	return nil
+}
+
+// DeleteTaskAttachments удаляет вложения удаленной задачи. Содержимое, на которое не
+// осталось ссылок, удаляется из хранилища в фоне: удаление ждет завершения начатых
+// загрузок, а вызывающий — сервис задач — держит при этом свой мьютекс.
+func (as *AttachmentService) DeleteTaskAttachments(_ context.Context, taskID int) {
+	as.mutex.Lock()
+	var checksums []string
+	for id, attachment := range as.attachments {
+		if attachment.TaskID == taskID {
+			delete(as.attachments, id)
+			checksums = append(checksums, attachment.Checksum)
+		}
+	}
+	as.mutex.Unlock()
+
+	if len(checksums) > 0 {
+		as.collectBlobsAsync(checksums)
+	}
+}
+
+// collectBlobsAsync запускает collectBlobs в фоне
+func (as *AttachmentService) collectBlobsAsync(checksums []string) {
+	as.collecting.Add(1)
+	go func() {
+		defer as.collecting.Done()
+		as.collectBlobs(checksums)
+	}()
+}
+
+// collectBlobs удаляет из хранилища содержимое checksums, на которое не ссылается ни одно вложение
+func (as *AttachmentService) collectBlobs(checksums []string) {
+	as.blobMutex.Lock()
+	defer as.blobMutex.Unlock()
+
+	as.mutex.RLock()
+	referenced := make(map[string]bool, len(as.attachments))
+	for _, attachment := range as.attachments {
+		referenced[attachment.Checksum] = true
+	}
+	as.mutex.RUnlock()
+
+	for _, checksum := range checksums {
+		if referenced[checksum] {
+			continue
+		}
+		referenced[checksum] = true // повторы в checksums удаляются один раз
+		if err := as.store.Delete(checksum); err != nil && !errors.Is(err, ErrBlobNotFound) {
+			log.Printf("Не удалось удалить содержимое вложения %s: %v", checksum, err)
+		}
+	}
+}
+
+// lookup ищет вложение задачи; вызывающий должен удерживать мьютекс
+func (as *AttachmentService) lookup(taskID, id int) (*Attachment, error) {
+	attachment, exists := as.attachments[id]
+	if !exists || attachment.TaskID != taskID {
+		return nil, newNotFoundError("вложение с ID %d не найдено", id)
+	}
+	return attachment, nil
+}
+
+// limitedReader возвращает ErrAttachmentTooLarge при превышении лимита, прерывая запись в хранилище
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.remaining -= int64(n)
+	if lr.remaining < 0 {
+		return n, ErrAttachmentTooLarge
+	}
+	return n, err
+}
+
+// sanitizeFilename отбрасывает путь и управляющие символы из имени файла клиента
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newTestAttachmentService(t *testing.T) (*TaskService, AttachmentServiceInterface) {
+	t.Helper()
+
+	store, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Ошибка при создании хранилища: %v", err)
+	}
+
+	tasks := NewTaskService()
+	return tasks, NewAttachmentService(tasks, store)
+}
+
+func TestAttachmentService_AddAttachment(t *testing.T) {
+	tasks, service := newTestAttachmentService(t)
+	task := tasks.CreateTask(t.Context(), "Задача", "Описание")
+
+	attachment, err := service.AddAttachment(t.Context(), task.ID, "../logs/app.log", strings.NewReader("строка лога\n"))
+	if err != nil {
+		t.Fatalf("Ошибка при добавлении вложения: %v", err)
+	}
+
+	if attachment.Filename != "app.log" {
+		t.Errorf("Ожидалось имя 'app.log', получено '%s'", attachment.Filename)
+	}
+
+	if attachment.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("Ожидался тип 'text/plain; charset=utf-8', получен '%s'", attachment.ContentType)
+	}
+
+	if len(attachment.Checksum) != 64 {
+		t.Errorf("Ожидалась контрольная сумма SHA-256, получено '%s'", attachment.Checksum)
+	}
+}
+
+func TestAttachmentService_AddAttachment_TaskNotFound(t *testing.T) {
+	_, service := newTestAttachmentService(t)
+
+	_, err := service.AddAttachment(t.Context(), 999, "app.log", strings.NewReader("лог"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ожидалась ошибка ErrNotFound, получена %v", err)
+	}
+}
+
+func TestAttachmentService_AddAttachment_TypeNotAllowed(t *testing.T) {
+	tasks, service := newTestAttachmentService(t)
+	task := tasks.CreateTask(t.Context(), "Задача", "Описание")
+
+	_, err := service.AddAttachment(t.Context(), task.ID, "page.html", strings.NewReader("<html><body>привет</body></html>"))
+	if !errors.Is(err, ErrAttachmentTypeNotAllowed) {
+		t.Errorf("Ожидалась ошибка ErrAttachmentTypeNotAllowed, получена %v", err)
+	}
+}
+
+func TestAttachmentService_AddAttachment_TooLarge(t *testing.T) {
+	tasks, service := newTestAttachmentService(t)
+	task := tasks.CreateTask(t.Context(), "Задача", "Описание")
+
+	content := strings.Repeat("a", MaxAttachmentSize+1)
+	_, err := service.AddAttachment(t.Context(), task.ID, "big.txt", strings.NewReader(content))
+	if !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("Ожидалась ошибка ErrAttachmentTooLarge, получена %v", err)
+	}
+}
+
+func TestAttachmentService_DeleteAttachment_SharedContent(t *testing.T) {
+	tasks, service := newTestAttachmentService(t)
+	task := tasks.CreateTask(t.Context(), "Задача", "Описание")
+
+	first, _ := service.AddAttachment(t.Context(), task.ID, "a.txt", strings.NewReader("одинаковое содержимое"))
+	second, _ := service.AddAttachment(t.Context(), task.ID, "b.txt", strings.NewReader("одинаковое содержимое"))
+
+	if first.Checksum != second.Checksum {
+		t.Fatal("Одинаковое содержимое должно иметь одинаковую контрольную сумму")
+	}
+
+	if err := service.DeleteAttachment(t.Context(), task.ID, first.ID); err != nil {
+		t.Fatalf("Ошибка при удалении вложения: %v", err)
+	}
+
+	// Второе вложение ссылается на то же содержимое и должно остаться доступным
+	_, content, err := service.OpenAttachment(t.Context(), task.ID, second.ID)
+	if err != nil {
+		t.Fatalf("Ошибка при открытии вложения: %v", err)
+	}
+	defer content.Close()
+
+	data, _ := io.ReadAll(content)
+	if string(data) != "одинаковое содержимое" {
+		t.Errorf("Неожиданное содержимое: '%s'", data)
+	}
+}
+
+func TestAttachmentService_DeleteTaskAttachments(t *testing.T) {
+	store, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Ошибка при создании хранилища: %v", err)
+	}
+	tasks := NewTaskService()
+	service := NewAttachmentService(tasks, store).(*AttachmentService)
+	tasks.OnDelete(service.DeleteTaskAttachments)
+
+	removed := tasks.CreateTask(t.Context(), "Удаляемая", "")
+	kept := tasks.CreateTask(t.Context(), "Остающаяся", "")
+	own, _ := service.AddAttachment(t.Context(), removed.ID, "own.txt", strings.NewReader("только у удаляемой"))
+	service.AddAttachment(t.Context(), removed.ID, "shared.txt", strings.NewReader("общее содержимое"))
+	shared, _ := service.AddAttachment(t.Context(), kept.ID, "shared.txt", strings.NewReader("общее содержимое"))
+
+	// Откаченное удаление не трогает вложения
+	if _, err := tasks.ApplyBatch(t.Context(), []BatchOperation{{Op: BatchOpDelete, ID: removed.ID}, {Op: BatchOpDelete, ID: 999}}, true); err == nil {
+		t.Fatal("Ожидалась ошибка атомарного пакета")
+	}
+	service.collecting.Wait()
+	if list, _ := service.GetAttachments(t.Context(), removed.ID); len(list) != 2 {
+		t.Errorf("После отката вложения должны остаться, получено %d", len(list))
+	}
+
+	if _, err := tasks.ApplyBatch(t.Context(), []BatchOperation{{Op: BatchOpDelete, ID: removed.ID}}, false); err != nil {
+		t.Fatalf("Ошибка при удалении задачи: %v", err)
+	}
+	service.collecting.Wait()
+
+	if list := service.GetAttachmentsForTasks(t.Context(), []int{removed.ID})[removed.ID]; len(list) != 0 {
+		t.Errorf("Вложения удаленной задачи должны быть удалены: %v", list)
+	}
+	if _, err := store.Open(own.Checksum); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Содержимое без ссылок должно быть удалено, получено %v", err)
+	}
+	// Содержимое, на которое ссылается вложение другой задачи, остается
+	_, content, err := service.OpenAttachment(t.Context(), kept.ID, shared.ID)
+	if err != nil {
+		t.Fatalf("Общее содержимое должно остаться доступным: %v", err)
+	}
+	content.Close()
+}
+
+func TestAttachmentHandler_UploadAndDownload(t *testing.T) {
+	tasks, service := newTestAttachmentService(t)
+	handler := NewAttachmentHandler(service)
+	tasks.CreateTask(t.Context(), "Задача", "Описание")
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "app.log")
+	part.Write([]byte("0123456789"))
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/tasks/1/attachments", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.UploadAttachment(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var attachment Attachment
+	if err := json.Unmarshal(w.Body.Bytes(), &attachment); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+
+	req = httptest.NewRequest("GET", "/tasks/1/attachments/1", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rctx = chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	rctx.URLParams.Add("attachmentID", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w = httptest.NewRecorder()
+	handler.DownloadAttachment(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusPartialContent, w.Code)
+	}
+
+	if w.Body.String() != "2345" {
+		t.Errorf("Ожидался диапазон '2345', получен '%s'", w.Body.String())
+	}
+
+	if w.Header().Get("ETag") != `"`+attachment.Checksum+`"` {
+		t.Errorf("Ожидался ETag с контрольной суммой, получен '%s'", w.Header().Get("ETag"))
+	}
+}
+
+func TestAttachmentHandler_DownloadAttachment_NotFound(t *testing.T) {
+	tasks, service := newTestAttachmentService(t)
+	handler := NewAttachmentHandler(service)
+	tasks.CreateTask(t.Context(), "Задача", "Описание")
+
+	req := httptest.NewRequest("GET", "/tasks/1/attachments/999", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	rctx.URLParams.Add("attachmentID", "999")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.DownloadAttachment(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import "context"
+
+// MaxBatchOperations ограничивает число операций в одном пакетном запросе
+const MaxBatchOperations = 1000
+
+// Типы операций пакетного запроса
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// validateBatchOperation проверяет операцию до ее выполнения
+func validateBatchOperation(op *BatchOperation) error {
+	switch op.Op {
+	case BatchOpCreate, BatchOpUpdate:
+		if op.Title == "" {
+			return newInvalidInputError("поле 'title' обязательно")
+		}
+		if !op.Priority.Valid() {
+			return newInvalidInputError("поле 'priority' должно быть 'low', 'medium' или 'high'")
+		}
+	case BatchOpDelete:
+	default:
+		return newInvalidInputError("неизвестная операция '%s': ожидается create, update или delete", op.Op)
+	}
+	return nil
+}
+
+// batchOperationOptions возвращает опции задачи, заданные в операции
+func batchOperationOptions(op *BatchOperation) []TaskOption {
+	return []TaskOption{WithTags(op.Tags), WithPriority(op.Priority), WithProject(op.Project), WithDueDate(op.DueDate)}
+}
+
+// ApplyBatch выполняет операции по порядку под одной блокировкой.
+// В атомарном режиме первая ошибка откатывает все уже выполненные операции:
+// у сбойной операции в результате ее ошибка, у остальных — ErrBatchAborted,
+// и метод возвращает ошибку сбойной операции. Без атомарного режима операции
+// выполняются независимо, и ошибки сообщаются только в результатах.
+func (ts *TaskService) ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchItemResult, error) {
+	ts.lock(ctx)
+	defer ts.mutex.Unlock()
+	defer ts.publishLocked(ctx)
+
+	results := make([]BatchItemResult, len(ops))
+	var undo []func()
+	nextID := ts.nextID
+
+	for i := range ops {
+		op := &ops[i]
+		task, rollback, err := ts.applyLocked(op)
+		if err == nil {
+			if task != nil {
+				// Сохраняем копию: последующие операции пакета не должны менять уже выданный результат
+				snapshot := *task
+				results[i].Task = &snapshot
+			}
+			undo = append(undo, rollback)
+			continue
+		}
+
+		results[i].Err = err
+		if !atomic {
+			continue
+		}
+
+		// Откатываем выполненные операции в обратном порядке
+		for j := len(undo) - 1; j >= 0; j-- {
+			undo[j]()
+		}
+		ts.nextID = nextID
+		ts.pending = nil // об откаченных изменениях подписчики не узнают
+		for j := range results {
+			if j != i {
+				results[j] = BatchItemResult{Err: ErrBatchAborted}
+			}
+		}
+		return results, err
+	}
+
+	return results, nil
+}
+
+// applyLocked выполняет одну операцию и возвращает функцию ее отмены;
+// вызывающий должен удерживать мьютекс на запись
+func (ts *TaskService) applyLocked(op *BatchOperation) (*Task, func(), error) {
+	if err := validateBatchOperation(op); err != nil {
+		return nil, nil, err
+	}
+
+	switch op.Op {
+	case BatchOpCreate:
+		task := ts.createLocked(op.Title, op.Description, batchOperationOptions(op)...)
+		return task, func() {
+			delete(ts.tasks, task.ID)
+			ts.index.Remove(task.ID)
+		}, nil
+
+	case BatchOpUpdate:
+		existing, exists := ts.tasks[op.ID]
+		if !exists {
+			return nil, nil, newNotFoundError("задача с ID %d не найдена", op.ID)
+		}
+		previous := *existing
+		task, err := ts.updateLocked(op.ID, op.Title, op.Description, op.Completed, batchOperationOptions(op)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return task, func() {
+			*task = previous
+			ts.index.Index(task)
+		}, nil
+
+	default: // BatchOpDelete
+		task, exists := ts.tasks[op.ID]
+		if !exists {
+			return nil, nil, newNotFoundError("задача с ID %d не найдена", op.ID)
+		}
+		if err := ts.deleteLocked(op.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, func() {
+			ts.tasks[task.ID] = task
+			ts.index.Index(task)
+		}, nil
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTaskService_ApplyBatch_AtomicRollback(t *testing.T) {
+	service := NewTaskService()
+	kept := service.CreateTask(t.Context(), "Сохранить", "исходное описание")
+	removed := service.CreateTask(t.Context(), "Удалить", "")
+
+	ops := []BatchOperation{
+		{Op: BatchOpCreate, Title: "Новая задача"},
+		{Op: BatchOpUpdate, ID: kept.ID, Title: "Изменено", Completed: true},
+		{Op: BatchOpDelete, ID: removed.ID},
+		{Op: BatchOpDelete, ID: 999},
+	}
+
+	results, err := service.ApplyBatch(t.Context(), ops, true)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Ожидалась ошибка ErrNotFound, получена %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if !errors.Is(results[i].Err, ErrBatchAborted) {
+			t.Errorf("Операция %d: ожидалась ошибка ErrBatchAborted, получена %v", i, results[i].Err)
+		}
+	}
+
+	// Состояние сервиса должно совпадать с исходным
+	tasks := service.GetAllTasks(t.Context())
+	if len(tasks) != 2 {
+		t.Fatalf("Ожидалось 2 задачи после отката, получено %d", len(tasks))
+	}
+
+	task, _ := service.GetTask(t.Context(), kept.ID)
+	if task.Title != "Сохранить" || task.Completed || task.Description != "исходное описание" {
+		t.Errorf("Обновление не откатилось: %+v", task)
+	}
+
+	if _, err := service.GetTask(t.Context(), removed.ID); err != nil {
+		t.Errorf("Удаленная задача не восстановилась: %v", err)
+	}
+
+	if results := service.SearchTasks(t.Context(), "изменено", 10); len(results) != 0 {
+		t.Errorf("Индекс поиска не откатился: найдено %d задач", len(results))
+	}
+
+	// Следующая задача получает ID, как если бы пакета не было
+	if next := service.CreateTask(t.Context(), "Следующая", ""); next.ID != 3 {
+		t.Errorf("Ожидался ID 3, получен %d", next.ID)
+	}
+}
+
+func TestTaskService_ApplyBatch_BestEffort(t *testing.T) {
+	service := NewTaskService()
+
+	ops := []BatchOperation{
+		{Op: BatchOpCreate, Title: "Первая"},
+		{Op: BatchOpCreate, Title: ""},
+		{Op: BatchOpUpdate, ID: 1, Title: "Первая", Priority: PriorityHigh},
+		{Op: "archive", ID: 1},
+	}
+
+	results, err := service.ApplyBatch(t.Context(), ops, false)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if results[0].Err != nil || results[0].Task.Priority != PriorityNone {
+		t.Errorf("Результат создания не должен меняться последующим обновлением: %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, ErrInvalidInput) {
+		t.Errorf("Ожидалась ошибка ErrInvalidInput для пустого заголовка, получена %v", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Task.Priority != PriorityHigh {
+		t.Errorf("Ожидалось успешное обновление, получено %+v", results[2])
+	}
+	if !errors.Is(results[3].Err, ErrInvalidInput) {
+		t.Errorf("Ожидалась ошибка ErrInvalidInput для неизвестной операции, получена %v", results[3].Err)
+	}
+}
+
+func TestTaskHandler_BatchTasks(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	body, _ := json.Marshal(BatchRequest{
+		Atomic: true,
+		Operations: []BatchOperation{
+			{Op: BatchOpCreate, Title: "Первая"},
+			{Op: BatchOpUpdate, ID: 42, Title: "Нет такой"},
+		},
+	})
+
+	req := httptest.NewRequest("POST", "/tasks/batch", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.BatchTasks(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusNotFound, w.Code)
+	}
+
+	var response BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+
+	if response.Results[0].Status != http.StatusFailedDependency || response.Results[1].Status != http.StatusNotFound {
+		t.Errorf("Неверные статусы операций: %+v", response.Results)
+	}
+	if len(service.GetAllTasks(t.Context())) != 0 {
+		t.Errorf("Атомарный пакет с ошибкой не должен создавать задачи")
+	}
+
+	body, _ = json.Marshal(BatchRequest{
+		Operations: []BatchOperation{
+			{Op: BatchOpCreate, Title: "Первая"},
+			{Op: BatchOpDelete, ID: 42},
+		},
+	})
+
+	req = httptest.NewRequest("POST", "/tasks/batch", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.BatchTasks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, w.Code)
+	}
+
+	response = BatchResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Succeeded != 1 || response.Failed != 1 || response.Results[0].Status != http.StatusCreated {
+		t.Errorf("Неверный результат пакета: %+v", response)
+	}
+}
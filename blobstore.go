@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrBlobNotFound возвращается, если объект отсутствует в хранилище
+var ErrBlobNotFound = errors.New("объект не найден в хранилище")
+
+// BlobStore определяет интерфейс хранилища бинарных данных.
+// Ключом объекта служит SHA-256 его содержимого, поэтому одинаковые файлы хранятся один раз.
+type BlobStore interface {
+	Put(r io.Reader) (key string, size int64, err error)
+	Open(key string) (io.ReadSeekCloser, error)
+	Delete(key string) error
+	// HealthCheck проверяет, что хранилище доступно для записи
+	HealthCheck(ctx context.Context) error
+}
+
+// FSBlobStore хранит объекты в локальной файловой системе
+type FSBlobStore struct {
+	root string
+}
+
+// NewFSBlobStore создает хранилище в указанном каталоге
+func NewFSBlobStore(root string) (BlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог хранилища: %w", err)
+	}
+	return &FSBlobStore{root: root}, nil
+}
+
+// Put сохраняет содержимое и возвращает его ключ и размер
+func (s *FSBlobStore) Put(r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(s.root, ".upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err == nil {
+		// Вложение должно оказаться на диске до того, как на него сошлется задача
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	path := s.path(key)
+	if _, err := os.Stat(path); err == nil {
+		// Такое содержимое уже хранится
+		return key, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+
+	return key, size, nil
+}
+
+// Open открывает объект для чтения
+func (s *FSBlobStore) Open(key string) (io.ReadSeekCloser, error) {
+	if !validBlobKey(key) {
+		return nil, ErrBlobNotFound
+	}
+
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+// Delete удаляет объект из хранилища
+func (s *FSBlobStore) Delete(key string) error {
+	if !validBlobKey(key) {
+		return ErrBlobNotFound
+	}
+
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+// HealthCheck проверяет, что в каталоге хранилища можно создавать файлы
+func (s *FSBlobStore) HealthCheck(ctx context.Context) error {
+	return checkWritableDir(s.root)
+}
+
+// path раскладывает объекты по подкаталогам по первым двум символам ключа
+func (s *FSBlobStore) path(key string) string {
+	return filepath.Join(s.root, key[:2], key)
+}
+
+// validBlobKey проверяет, что ключ является hex-представлением SHA-256
+func validBlobKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
@@ -0,0 +1,634 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Подмножество CalDAV (RFC 4791) и синхронизации коллекций (RFC 6578), достаточное
+// для Apple Reminders, Thunderbird и DAVx5. Пользователь один, поэтому принципал и
+// домашний каталог календарей совпадают с корнем /dav/, а все задачи лежат в одной
+// коллекции /dav/tasks/ как ресурсы VTODO.
+
+const (
+	davRootPath       = "/dav/"
+	davCollectionPath = "/dav/tasks/"
+	// maxDAVBodySize ограничивает тело запросов CalDAV
+	maxDAVBodySize = 1 << 20
+	// davSyncHistory — сколько последних состояний коллекции помнит сервер для sync-collection
+	davSyncHistory = 100
+)
+
+func init() {
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+}
+
+// davResource — задача как ресурс коллекции
+type davResource struct {
+	task *Task
+	name string // имя ресурса без экранирования, например task-1@todo-api.ics
+	etag string
+	data []byte // VCALENDAR с одним VTODO
+}
+
+// CalDAVHandler обрабатывает запросы CalDAV поверх сервиса задач
+type CalDAVHandler struct {
+	service TaskServiceInterface
+	sync    *davSyncState
+	// names хранит имена ресурсов, выбранные клиентами при создании через PUT,
+	// если они отличаются от имени по умолчанию (UID задачи + .ics)
+	names      map[int]string
+	ids        map[string]int // обратное отображение names
+	namesMutex sync.RWMutex
+	// writeMutex делает проверку If-Match и последующую запись атомарными для клиентов CalDAV
+	writeMutex sync.Mutex
+}
+
+// NewCalDAVHandler создает обработчик CalDAV
+func NewCalDAVHandler(service TaskServiceInterface) *CalDAVHandler {
+	return &CalDAVHandler{
+		service: service,
+		sync:    newDAVSyncState(),
+		names:   make(map[int]string),
+		ids:     make(map[string]int),
+	}
+}
+
+// WellKnown обрабатывает GET /.well-known/caldav (RFC 6764)
+func (h *CalDAVHandler) WellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, davRootPath, http.StatusMovedPermanently)
+}
+
+// Options сообщает клиентам о поддержке CalDAV
+func (h *CalDAVHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Propfind обрабатывает PROPFIND для корня, коллекции и отдельных задач
+func (h *CalDAVHandler) Propfind(w http.ResponseWriter, r *http.Request) {
+	req, err := parseDAVRequest(http.MaxBytesReader(w, r.Body, maxDAVBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	depth := r.Header.Get("Depth")
+
+	path := strings.TrimSuffix(r.URL.Path, "/") + "/"
+	switch {
+	case path == davRootPath:
+		responses := []davResponse{h.propResponse(davRootPath, req, h.rootProp)}
+		if depth != "0" {
+			resources := h.resources(r.Context())
+			responses = append(responses, h.propResponse(davCollectionPath, req, h.collectionProp(resources)))
+		}
+		writeMultistatus(w, responses, "")
+
+	case path == davCollectionPath:
+		resources := h.resources(r.Context())
+		responses := []davResponse{h.propResponse(davCollectionPath, req, h.collectionProp(resources))}
+		if depth != "0" {
+			for _, res := range resources {
+				responses = append(responses, h.propResponse(davHrefFor(res.name), req, resourceProp(res)))
+			}
+		}
+		writeMultistatus(w, responses, "")
+
+	default:
+		res, ok := h.findResource(r)
+		if !ok {
+			http.Error(w, "Ресурс не найден", http.StatusNotFound)
+			return
+		}
+		writeMultistatus(w, []davResponse{h.propResponse(davHrefFor(res.name), req, resourceProp(res))}, "")
+	}
+}
+
+// Report обрабатывает REPORT calendar-query, calendar-multiget и sync-collection на коллекции
+func (h *CalDAVHandler) Report(w http.ResponseWriter, r *http.Request) {
+	req, err := parseDAVRequest(http.MaxBytesReader(w, r.Body, maxDAVBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resources := h.resources(r.Context())
+	switch req.root {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		var responses []davResponse
+		for _, res := range resources {
+			if req.matches(res.task) {
+				responses = append(responses, h.propResponse(davHrefFor(res.name), req, resourceProp(res)))
+			}
+		}
+		writeMultistatus(w, responses, "")
+
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		byName := make(map[string]davResource, len(resources))
+		for _, res := range resources {
+			byName[res.name] = res
+		}
+		var responses []davResponse
+		for _, href := range req.hrefs {
+			res, ok := byName[davNameFromHref(href)]
+			if !ok {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+			responses = append(responses, h.propResponse(davHrefFor(res.name), req, resourceProp(res)))
+		}
+		writeMultistatus(w, responses, "")
+
+	case xml.Name{Space: nsDAV, Local: "sync-collection"}:
+		h.syncCollection(w, req, resources)
+
+	default:
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "supported-report"})
+	}
+}
+
+// syncCollection возвращает ресурсы, изменившиеся с момента sync-token, и удаленные ресурсы
+func (h *CalDAVHandler) syncCollection(w http.ResponseWriter, req *davRequest, resources []davResource) {
+	current := davETags(resources)
+	token := h.sync.token(current)
+
+	var previous map[string]string
+	if req.syncToken != "" {
+		var ok bool
+		if previous, ok = h.sync.lookup(req.syncToken); !ok {
+			writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "valid-sync-token"})
+			return
+		}
+	}
+
+	var responses []davResponse
+	for _, res := range resources {
+		if previous[res.name] != res.etag {
+			responses = append(responses, h.propResponse(davHrefFor(res.name), req, resourceProp(res)))
+		}
+	}
+	var deleted []string
+	for name := range previous {
+		if _, exists := current[name]; !exists {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+	for _, name := range deleted {
+		responses = append(responses, davResponse{href: davHrefFor(name), status: http.StatusNotFound})
+	}
+
+	writeMultistatus(w, responses, token)
+}
+
+// GetResource обрабатывает GET и HEAD /dav/tasks/{name}
+func (h *CalDAVHandler) GetResource(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.findResource(r)
+	if !ok {
+		http.Error(w, "Ресурс не найден", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", res.etag)
+	w.Header().Set("Last-Modified", res.task.UpdatedAt.UTC().Format(http.TimeFormat))
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Length", strconv.Itoa(len(res.data)))
+		return
+	}
+	w.Write(res.data)
+}
+
+// PutResource обрабатывает PUT /dav/tasks/{name}: создает или заменяет задачу из VTODO
+func (h *CalDAVHandler) PutResource(w http.ResponseWriter, r *http.Request) {
+	name := davNameFromHref(r.URL.EscapedPath())
+	if !strings.HasSuffix(name, ".ics") {
+		http.Error(w, "Имя ресурса должно оканчиваться на .ics", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := decodeICalendar(http.MaxBytesReader(w, r.Body, maxDAVBodySize))
+	if err != nil {
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-calendar-data"})
+		return
+	}
+	if len(rows) != 1 {
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "supported-calendar-component"})
+		return
+	}
+	if rows[0].Err != nil {
+		http.Error(w, rows[0].Err.Error(), http.StatusBadRequest)
+		return
+	}
+	task := rows[0].Task
+
+	h.writeMutex.Lock()
+	defer h.writeMutex.Unlock()
+
+	existing, exists := h.lookupResource(r.Context(), name)
+	if !checkDAVPreconditions(w, r, existing, exists) {
+		return
+	}
+
+	if exists {
+		task.UID = TaskUID(existing.task)
+	} else {
+		if task.UID == "" {
+			task.UID = strings.TrimSuffix(name, ".ics")
+		}
+		// Один UID не может принадлежать двум ресурсам (RFC 4791, 5.3.2.1)
+		if h.taskByUID(r.Context(), task.UID) != nil {
+			writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "no-uid-conflict"})
+			return
+		}
+	}
+
+	importer, err := capability[TaskImporter](h.service, "ImportTasks")
+	if err != nil {
+		http.Error(w, err.Error(), batchErrorStatus(err))
+		return
+	}
+	result := importer.ImportTasks(r.Context(), []*Task{task}, ImportOptions{MatchUID: true})[0]
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stored := result.Task
+	if !exists && name != davDefaultName(stored) {
+		h.namesMutex.Lock()
+		h.names[stored.ID] = name
+		h.ids[name] = stored.ID
+		h.namesMutex.Unlock()
+	}
+
+	w.Header().Set("ETag", davETag(renderDAVResource(stored)))
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// DeleteResource обрабатывает DELETE /dav/tasks/{name}
+func (h *CalDAVHandler) DeleteResource(w http.ResponseWriter, r *http.Request) {
+	h.writeMutex.Lock()
+	defer h.writeMutex.Unlock()
+
+	res, exists := h.findResource(r)
+	if !exists {
+		http.Error(w, "Ресурс не найден", http.StatusNotFound)
+		return
+	}
+	if !checkDAVPreconditions(w, r, res, exists) {
+		return
+	}
+
+	if err := h.service.DeleteTask(r.Context(), res.task.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.namesMutex.Lock()
+	delete(h.names, res.task.ID)
+	delete(h.ids, res.name)
+	h.namesMutex.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkDAVPreconditions проверяет If-Match и If-None-Match; при нарушении отвечает 412
+func checkDAVPreconditions(w http.ResponseWriter, r *http.Request, res davResource, exists bool) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !exists || (ifMatch != "*" && !davETagListContains(ifMatch, res.etag)) {
+			http.Error(w, "Ресурс изменился", http.StatusPreconditionFailed)
+			return false
+		}
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && exists {
+		if ifNoneMatch == "*" || davETagListContains(ifNoneMatch, res.etag) {
+			http.Error(w, "Ресурс уже существует", http.StatusPreconditionFailed)
+			return false
+		}
+	}
+	return true
+}
+
+// davETagListContains проверяет, есть ли etag в списке из заголовка If-Match/If-None-Match
+func davETagListContains(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// resources возвращает все задачи как ресурсы коллекции в порядке ID
+func (h *CalDAVHandler) resources(ctx context.Context) []davResource {
+	tasks := filterTasks(ctx, h.service, func(*Task) bool { return true })
+
+	h.namesMutex.RLock()
+	defer h.namesMutex.RUnlock()
+
+	resources := make([]davResource, 0, len(tasks))
+	for _, task := range tasks {
+		name, ok := h.names[task.ID]
+		if !ok {
+			name = davDefaultName(task)
+		}
+		resources = append(resources, newDAVResource(task, name))
+	}
+	return resources
+}
+
+// findResource ищет ресурс по пути запроса
+func (h *CalDAVHandler) findResource(r *http.Request) (davResource, bool) {
+	return h.lookupResource(r.Context(), davNameFromHref(r.URL.EscapedPath()))
+}
+
+// lookupResource находит ресурс по имени, не сериализуя остальные задачи: имя,
+// выбранное клиентом, берется из h.names, а имя по умолчанию — UID задачи и .ics
+func (h *CalDAVHandler) lookupResource(ctx context.Context, name string) (davResource, bool) {
+	h.namesMutex.RLock()
+	id, custom := h.ids[name]
+	h.namesMutex.RUnlock()
+
+	var task *Task
+	switch {
+	case custom:
+		task, _ = h.service.GetTask(ctx, id)
+	case strings.HasSuffix(name, ".ics"):
+		task = h.taskByUID(ctx, strings.TrimSuffix(name, ".ics"))
+	}
+	if task == nil {
+		return davResource{}, false
+	}
+
+	// Задача с именем, выбранным клиентом, доступна только под этим именем
+	h.namesMutex.RLock()
+	current, renamed := h.names[task.ID]
+	h.namesMutex.RUnlock()
+	if renamed && current != name {
+		return davResource{}, false
+	}
+	return newDAVResource(task, name), true
+}
+
+// taskByUID ищет задачу по UID. UID задачи, созданной через API, содержит ее ID,
+// и задача читается напрямую; остальные UID сравниваются без сериализации задач.
+func (h *CalDAVHandler) taskByUID(ctx context.Context, uid string) *Task {
+	var id int
+	if _, err := fmt.Sscanf(uid, "task-%d@todo-api", &id); err == nil {
+		if task, err := h.service.GetTask(ctx, id); err == nil && TaskUID(task) == uid {
+			return task
+		}
+	}
+	if matches := filterTasks(ctx, h.service, func(task *Task) bool { return TaskUID(task) == uid }); len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
+
+// newDAVResource сериализует задачу в ресурс с именем name
+func newDAVResource(task *Task, name string) davResource {
+	data := renderDAVResource(task)
+	return davResource{task: task, name: name, etag: davETag(data), data: data}
+}
+
+// davDefaultName возвращает имя ресурса по умолчанию — UID задачи с расширением .ics
+func davDefaultName(task *Task) string {
+	return TaskUID(task) + ".ics"
+}
+
+// davHrefFor возвращает путь ресурса с экранированием
+func davHrefFor(name string) string {
+	return davCollectionPath + url.PathEscape(name)
+}
+
+// davNameFromHref извлекает имя ресурса из пути или полного URL
+func davNameFromHref(href string) string {
+	if parsed, err := url.Parse(href); err == nil {
+		href = parsed.EscapedPath()
+	}
+	name := href[strings.LastIndex(href, "/")+1:]
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+// renderDAVResource сериализует задачу в VCALENDAR с одним VTODO
+func renderDAVResource(task *Task) []byte {
+	var buf bytes.Buffer
+	encoder := newICalendarEncoder(&buf)
+	encoder.Encode(task)
+	encoder.Flush()
+	return buf.Bytes()
+}
+
+// davETag вычисляет сильный ETag по содержимому ресурса
+func davETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// davETags возвращает ETag каждого ресурса по имени
+func davETags(resources []davResource) map[string]string {
+	etags := make(map[string]string, len(resources))
+	for _, res := range resources {
+		etags[res.name] = res.etag
+	}
+	return etags
+}
+
+// matches применяет фильтр calendar-query к задаче. Поддерживаются фильтр по типу
+// компонента и prop-filter/is-not-defined для COMPLETED, которым клиенты запрашивают
+// невыполненные задачи; остальные условия не сужают выборку.
+func (req *davRequest) matches(task *Task) bool {
+	for _, comp := range req.compFilters {
+		if comp != "VCALENDAR" && comp != "VTODO" {
+			return false
+		}
+	}
+	for _, prop := range req.notDefined {
+		if prop == "COMPLETED" && task.Completed {
+			return false
+		}
+	}
+	return true
+}
+
+// propResponse собирает ответ с запрошенными свойствами ресурса; lookup возвращает
+// содержимое свойства и признак того, что свойство есть у ресурса
+func (h *CalDAVHandler) propResponse(href string, req *davRequest, lookup func(xml.Name) (string, bool)) davResponse {
+	response := davResponse{href: href}
+	names := req.props
+	if req.allprop {
+		names = davAllProps
+	}
+	for _, name := range names {
+		if inner, ok := lookup(name); ok {
+			response.found = append(response.found, davProp{name: name, inner: inner})
+		} else if !req.allprop {
+			response.missing = append(response.missing, name)
+		}
+	}
+	return response
+}
+
+// davAllProps — свойства, возвращаемые на allprop; calendar-data отдается только по запросу
+var davAllProps = []xml.Name{
+	{Space: nsDAV, Local: "resourcetype"},
+	{Space: nsDAV, Local: "displayname"},
+	{Space: nsDAV, Local: "getetag"},
+	{Space: nsDAV, Local: "getcontenttype"},
+	{Space: nsDAV, Local: "getlastmodified"},
+	{Space: nsDAV, Local: "sync-token"},
+	{Space: nsCalServer, Local: "getctag"},
+	{Space: nsCalDAV, Local: "supported-calendar-component-set"},
+}
+
+// davPrivileges — права текущего пользователя; без write Apple Reminders открывает список только для чтения
+const davPrivileges = "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>" +
+	"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>"
+
+// rootProp возвращает свойства корня, который одновременно принципал и домашний каталог календарей
+func (h *CalDAVHandler) rootProp(name xml.Name) (string, bool) {
+	switch name {
+	case xml.Name{Space: nsDAV, Local: "resourcetype"}:
+		return "<d:collection/><d:principal/>", true
+	case xml.Name{Space: nsDAV, Local: "displayname"}:
+		return "todo-api", true
+	case xml.Name{Space: nsDAV, Local: "current-user-principal"},
+		xml.Name{Space: nsDAV, Local: "principal-URL"},
+		xml.Name{Space: nsDAV, Local: "owner"},
+		xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}:
+		return davHref(davRootPath), true
+	case xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}:
+		return davPrivileges, true
+	}
+	return "", false
+}
+
+// collectionProp возвращает функцию свойств коллекции задач
+func (h *CalDAVHandler) collectionProp(resources []davResource) func(xml.Name) (string, bool) {
+	token := h.sync.token(davETags(resources))
+	return func(name xml.Name) (string, bool) {
+		switch name {
+		case xml.Name{Space: nsDAV, Local: "resourcetype"}:
+			return "<d:collection/><c:calendar/>", true
+		case xml.Name{Space: nsDAV, Local: "displayname"}:
+			return "Задачи", true
+		case xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}:
+			return `<c:comp name="VTODO"/>`, true
+		case xml.Name{Space: nsCalServer, Local: "getctag"}, xml.Name{Space: nsDAV, Local: "sync-token"}:
+			return davText(token), true
+		case xml.Name{Space: nsDAV, Local: "supported-report-set"}:
+			return "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+				"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>" +
+				"<d:supported-report><d:report><d:sync-collection/></d:report></d:supported-report>", true
+		case xml.Name{Space: nsDAV, Local: "current-user-principal"}, xml.Name{Space: nsDAV, Local: "owner"}:
+			return davHref(davRootPath), true
+		case xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}:
+			return davPrivileges, true
+		}
+		return "", false
+	}
+}
+
+// resourceProp возвращает функцию свойств отдельной задачи
+func resourceProp(res davResource) func(xml.Name) (string, bool) {
+	return func(name xml.Name) (string, bool) {
+		switch name {
+		case xml.Name{Space: nsDAV, Local: "resourcetype"}:
+			return "", true
+		case xml.Name{Space: nsDAV, Local: "getetag"}:
+			return davText(res.etag), true
+		case xml.Name{Space: nsDAV, Local: "getcontenttype"}:
+			return "text/calendar; charset=utf-8; component=VTODO", true
+		case xml.Name{Space: nsDAV, Local: "getlastmodified"}:
+			return res.task.UpdatedAt.UTC().Format(http.TimeFormat), true
+		case xml.Name{Space: nsCalDAV, Local: "calendar-data"}:
+			return davText(string(res.data)), true
+		}
+		return "", false
+	}
+}
+
+// davSyncState хранит последние состояния коллекции (ETag по имени ресурса),
+// чтобы отвечать на sync-collection разницей между состояниями
+type davSyncState struct {
+	epoch     string
+	seq       int
+	snapshots []davSnapshot
+	mutex     sync.Mutex
+}
+
+type davSnapshot struct {
+	seq   int
+	etags map[string]string
+}
+
+func newDAVSyncState() *davSyncState {
+	// Эпоха отличает токены разных запусков сервера: после перезапуска старые токены недействительны
+	return &davSyncState{epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// token возвращает sync-token для текущего состояния коллекции, запоминая его,
+// если оно отличается от последнего известного
+func (s *davSyncState) token(etags map[string]string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if n := len(s.snapshots); n == 0 || !sameETags(s.snapshots[n-1].etags, etags) {
+		s.seq++
+		s.snapshots = append(s.snapshots, davSnapshot{seq: s.seq, etags: etags})
+		if len(s.snapshots) > davSyncHistory {
+			s.snapshots = s.snapshots[len(s.snapshots)-davSyncHistory:]
+		}
+	}
+	return s.format(s.snapshots[len(s.snapshots)-1].seq)
+}
+
+// lookup возвращает состояние коллекции для sync-token; false — токен неизвестен или устарел
+func (s *davSyncState) lookup(token string) (map[string]string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, snapshot := range s.snapshots {
+		if s.format(snapshot.seq) == token {
+			return snapshot.etags, true
+		}
+	}
+	return nil, false
+}
+
+func (s *davSyncState) format(seq int) string {
+	return fmt.Sprintf("http://todo-api/ns/sync/%s-%d", s.epoch, seq)
+}
+
+func sameETags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, etag := range a {
+		if b[name] != etag {
+			return false
+		}
+	}
+	return true
+}
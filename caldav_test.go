@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// newCalDAVRouter создает полный маршрутизатор, чтобы проверять и регистрацию методов PROPFIND и REPORT
+func newCalDAVRouter(t *testing.T) (http.Handler, TaskServiceInterface) {
+	t.Helper()
+
+	service := NewTaskService()
+	blobStore, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Ошибка при создании хранилища: %v", err)
+	}
+	views, _ := NewViewService("")
+	attachments := NewAttachmentService(service, blobStore)
+	graphQLHandler, err := NewGraphQLHandler(service, attachments, views, DefaultGraphQLMaxDepth, DefaultGraphQLMaxComplexity)
+	if err != nil {
+		t.Fatalf("Ошибка построения схемы GraphQL: %v", err)
+	}
+	// Все тесты через полный маршрутизатор заодно проверяют ответы по спецификации
+	validator, err := NewOpenAPIValidator(OpenAPIValidationStrict)
+	if err != nil {
+		t.Fatalf("Ошибка разбора спецификации: %v", err)
+	}
+	validator.report = func(r *http.Request, err error) {
+		t.Errorf("%s %s: ответ не соответствует спецификации: %v", r.Method, r.URL.Path, err)
+	}
+
+	router := SetupRoutes(NewTaskHandler(service),
+		WithAttachments(NewAttachmentHandler(attachments)),
+		WithViews(NewViewHandler(views, service)),
+		WithCalDAV(NewCalDAVHandler(service)),
+		WithGraphQL(graphQLHandler),
+		WithJSONRPC(NewJSONRPCServer(service)),
+		WithMCP(NewMCPServer(service)),
+		WithHealth(NewHealthRegistry()),
+		WithMetrics(NewMetrics()),
+		WithCORS(NewCORSPolicy([]string{"*"})),
+		WithIdempotency(NewIdempotencyStore(DefaultIdempotencyTTL)),
+		WithOpenAPIValidator(validator),
+	)
+	return router, service
+}
+
+// davDo выполняет запрос с телом из файла testdata/caldav (записанные запросы реальных клиентов)
+func davDo(t *testing.T, router http.Handler, method, path, fixture string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body := ""
+	if fixture != "" {
+		data, err := os.ReadFile(filepath.Join("testdata", "caldav", fixture))
+		if err != nil {
+			t.Fatalf("Ошибка чтения фикстуры: %v", err)
+		}
+		body = string(data)
+	}
+	for name, value := range headers {
+		body = strings.ReplaceAll(body, "{{"+name+"}}", value)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		if !strings.Contains(name, "-token") {
+			req.Header.Set(name, value)
+		}
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCalDAV_Discovery(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+
+	w := davDo(t, router, "GET", "/.well-known/caldav", "", nil)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != davRootPath {
+		t.Errorf("Ожидалось перенаправление на %s, получено %d %s", davRootPath, w.Code, w.Header().Get("Location"))
+	}
+
+	w = davDo(t, router, "OPTIONS", "/dav/", "", nil)
+	if !strings.Contains(w.Header().Get("DAV"), "calendar-access") {
+		t.Errorf("Ожидался заголовок DAV с calendar-access, получено %q", w.Header().Get("DAV"))
+	}
+
+	w = davDo(t, router, "PROPFIND", "/dav/", "apple-propfind-principal.xml", map[string]string{"Depth": "0"})
+	if w.Code != http.StatusMultiStatus || !strings.Contains(w.Body.String(), "<d:current-user-principal><d:href>/dav/</d:href>") {
+		t.Errorf("Неверный ответ на поиск принципала: %d\n%s", w.Code, w.Body.String())
+	}
+
+	w = davDo(t, router, "PROPFIND", "/dav/", "davx5-propfind-home.xml", map[string]string{"Depth": "0"})
+	body := w.Body.String()
+	if !strings.Contains(body, "<c:calendar-home-set><d:href>/dav/</d:href></c:calendar-home-set>") {
+		t.Errorf("В ответе нет calendar-home-set:\n%s", body)
+	}
+	// Неизвестное свойство возвращается в propstat с 404
+	if !strings.Contains(body, "<c:calendar-user-address-set/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status>") {
+		t.Errorf("Неизвестное свойство должно вернуться с 404:\n%s", body)
+	}
+
+	w = davDo(t, router, "PROPFIND", "/dav/tasks/", "thunderbird-propfind-collection.xml", map[string]string{"Depth": "0"})
+	body = w.Body.String()
+	for _, expected := range []string{"<c:calendar/>", `<c:comp name="VTODO"/>`, "<cs:getctag>", "<d:write/>", "<c:calendar-multiget/>"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("В свойствах коллекции нет %s:\n%s", expected, body)
+		}
+	}
+}
+
+func TestCalDAV_PutGetDelete(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+	path := "/dav/tasks/5B9C6E2A-8F1D-4C3B-9E7A-2D4F6A8B0C1E.ics"
+
+	w := davDo(t, router, "PUT", path, "apple-put-vtodo.ics", map[string]string{"If-None-Match": "*"})
+	if w.Code != http.StatusCreated || w.Header().Get("ETag") == "" {
+		t.Fatalf("Ожидался статус 201 с ETag, получено %d %q: %s", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+
+	tasks := service.GetAllTasks(t.Context())
+	if len(tasks) != 1 || tasks[0].Title != "Позвонить в банк" || tasks[0].Priority != PriorityHigh {
+		t.Fatalf("Задача не создана из VTODO: %+v", tasks)
+	}
+
+	// Повторное создание с If-None-Match: * отклоняется
+	w = davDo(t, router, "PUT", path, "apple-put-vtodo.ics", map[string]string{"If-None-Match": "*"})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusPreconditionFailed, w.Code)
+	}
+
+	w = davDo(t, router, "GET", path, "", nil)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != etag || !strings.Contains(w.Body.String(), "SUMMARY:Позвонить в банк") {
+		t.Errorf("Неверный ответ GET: %d %s\n%s", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+
+	// Изменение через REST API меняет ETag, и устаревший If-Match отклоняется
+	service.UpdateTask(t.Context(), tasks[0].ID, "Позвонить в банк до обеда", "", false, WithPriority(PriorityHigh))
+	w = davDo(t, router, "DELETE", path, "", map[string]string{"If-Match": etag})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Ожидался статус %d для устаревшего ETag, получен %d", http.StatusPreconditionFailed, w.Code)
+	}
+
+	etag = davDo(t, router, "GET", path, "", nil).Header().Get("ETag")
+	w = davDo(t, router, "DELETE", path, "", map[string]string{"If-Match": etag})
+	if w.Code != http.StatusNoContent || len(service.GetAllTasks(t.Context())) != 0 {
+		t.Errorf("Ожидалось удаление задачи, получен статус %d", w.Code)
+	}
+}
+
+func TestCalDAV_ResourceLookup(t *testing.T) {
+	service := &countingTasks{ServerTaskService: NewTaskService()}
+	handler := NewCalDAVHandler(service)
+	for _, title := range []string{"Первая", "Вторая", "Третья"} {
+		service.CreateTask(t.Context(), title, "")
+	}
+	body, err := os.ReadFile(filepath.Join("testdata", "caldav", "apple-put-vtodo.ics"))
+	if err != nil {
+		t.Fatalf("Ошибка чтения фикстуры: %v", err)
+	}
+	w := httptest.NewRecorder()
+	handler.PutResource(w, httptest.NewRequest("PUT", "/dav/tasks/custom-name.ics", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d: %s", w.Code, w.Body.String())
+	}
+
+	// Ресурс находится по имени без перебора задач: по одному GetTask на запрос
+	service.calls.Store(0)
+	for path, summary := range map[string]string{
+		"/dav/tasks/task-2@todo-api.ics": "SUMMARY:Вторая",
+		"/dav/tasks/custom-name.ics":     "SUMMARY:Позвонить в банк",
+	} {
+		w := httptest.NewRecorder()
+		handler.GetResource(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), summary) {
+			t.Errorf("%s: неверный ответ %d\n%s", path, w.Code, w.Body.String())
+		}
+	}
+	if n := service.calls.Load(); n != 2 {
+		t.Errorf("Ожидалось 2 обращения к сервису, получено %d", n)
+	}
+
+	// Задача с именем, выбранным клиентом, не доступна под именем по умолчанию
+	for _, path := range []string{"/dav/tasks/5B9C6E2A-8F1D-4C3B-9E7A-2D4F6A8B0C1E.ics", "/dav/tasks/task-9@todo-api.ics"} {
+		w := httptest.NewRecorder()
+		handler.GetResource(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: ожидался статус 404, получен %d", path, w.Code)
+		}
+	}
+}
+
+func TestCalDAV_Reports(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+	service.CreateTask(t.Context(), "Открытая", "")
+	done := service.CreateTask(t.Context(), "Закрытая", "")
+	service.UpdateTask(t.Context(), done.ID, done.Title, "", true)
+
+	w := davDo(t, router, "REPORT", "/dav/tasks/", "thunderbird-calendar-query.xml", map[string]string{"Depth": "1"})
+	body := w.Body.String()
+	if w.Code != http.StatusMultiStatus || !strings.Contains(body, "task-1@todo-api.ics") || strings.Contains(body, "task-2@todo-api.ics") {
+		t.Errorf("calendar-query должен вернуть только невыполненные задачи:\n%s", body)
+	}
+
+	w = davDo(t, router, "REPORT", "/dav/tasks/", "davx5-calendar-query-events.xml", map[string]string{"Depth": "1"})
+	if strings.Contains(w.Body.String(), "<d:response>") {
+		t.Errorf("Запрос VEVENT не должен возвращать задачи:\n%s", w.Body.String())
+	}
+
+	w = davDo(t, router, "REPORT", "/dav/tasks/", "davx5-calendar-multiget.xml", map[string]string{"Depth": "1"})
+	body = w.Body.String()
+	if !strings.Contains(body, "SUMMARY:Открытая") || !strings.Contains(body, "<d:href>/dav/tasks/missing.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>") {
+		t.Errorf("Неверный ответ calendar-multiget:\n%s", body)
+	}
+}
+
+func TestCalDAV_SyncCollection(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+	first := service.CreateTask(t.Context(), "Первая", "")
+	second := service.CreateTask(t.Context(), "Вторая", "")
+
+	tokenPattern := regexp.MustCompile(`<d:sync-token>([^<]+)</d:sync-token>`)
+	sync := func(token string) (string, string) {
+		w := davDo(t, router, "REPORT", "/dav/tasks/", "davx5-sync-collection.xml", map[string]string{"sync-token": token})
+		if w.Code != http.StatusMultiStatus {
+			t.Fatalf("Ожидался статус 207, получен %d: %s", w.Code, w.Body.String())
+		}
+		match := tokenPattern.FindStringSubmatch(w.Body.String())
+		if match == nil {
+			t.Fatalf("В ответе нет sync-token:\n%s", w.Body.String())
+		}
+		return w.Body.String(), match[1]
+	}
+
+	// Первая синхронизация без токена возвращает все ресурсы
+	body, token := sync("")
+	if strings.Count(body, "<d:getetag>") != 2 {
+		t.Errorf("Ожидались 2 ресурса при начальной синхронизации:\n%s", body)
+	}
+
+	// Без изменений — пустой ответ и тот же токен
+	body, sameToken := sync(token)
+	if strings.Contains(body, "<d:response>") || sameToken != token {
+		t.Errorf("Без изменений ответ должен быть пустым, а токен прежним:\n%s", body)
+	}
+
+	service.UpdateTask(t.Context(), first.ID, "Первая, измененная", "", false)
+	service.DeleteTask(t.Context(), second.ID)
+
+	body, newToken := sync(token)
+	if newToken == token {
+		t.Errorf("После изменений токен должен смениться")
+	}
+	if !strings.Contains(body, "task-1@todo-api.ics</d:href><d:propstat>") {
+		t.Errorf("Измененная задача должна вернуться со свойствами:\n%s", body)
+	}
+	if !strings.Contains(body, "task-2@todo-api.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>") {
+		t.Errorf("Удаленная задача должна вернуться с 404:\n%s", body)
+	}
+
+	w := davDo(t, router, "REPORT", "/dav/tasks/", "davx5-sync-collection.xml", map[string]string{"sync-token": "http://todo-api/ns/sync/unknown"})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "valid-sync-token") {
+		t.Errorf("Неизвестный токен должен отклоняться с valid-sync-token, получено %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Пространства имен WebDAV и CalDAV
+const (
+	nsDAV         = "DAV:"
+	nsCalDAV      = "urn:ietf:params:xml:ns:caldav"
+	nsCalServer   = "http://calendarserver.org/ns/"
+	nsAppleICal   = "http://apple.com/ns/ical/"
+	davXMLHeader  = `<?xml version="1.0" encoding="utf-8"?>` + "\n"
+	davXMLContent = "application/xml; charset=utf-8"
+)
+
+// davPrefixes — префиксы, под которыми пространства имен объявляются в ответах
+var davPrefixes = map[string]string{
+	nsDAV:       "d",
+	nsCalDAV:    "c",
+	nsCalServer: "cs",
+	nsAppleICal: "ical",
+}
+
+// davRequest — разобранное тело PROPFIND или REPORT. Клиенты шлют
+// разные по форме запросы, поэтому собираются только нужные серверу части.
+type davRequest struct {
+	root        xml.Name   // корневой элемент: propfind, calendar-query, calendar-multiget, sync-collection
+	allprop     bool       // запрошены все свойства (allprop, propname или пустое тело)
+	props       []xml.Name // запрошенные свойства
+	hrefs       []string   // ссылки calendar-multiget
+	syncToken   string     // sync-token из sync-collection
+	compFilters []string   // имена comp-filter в порядке вложенности
+	notDefined  []string   // свойства с prop-filter/is-not-defined
+}
+
+// parseDAVRequest разбирает тело запроса; пустое тело означает allprop
+func parseDAVRequest(r io.Reader) (*davRequest, error) {
+	req := &davRequest{}
+	decoder := xml.NewDecoder(r)
+
+	var stack []xml.Name
+	propFilter := ""
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("неверный XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 {
+				req.root = t.Name
+			}
+			parent := xml.Name{}
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			switch {
+			case parent == (xml.Name{Space: nsDAV, Local: "prop"}) && len(stack) <= 2:
+				req.props = append(req.props, t.Name)
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("неверный XML: %w", err)
+				}
+				continue
+			case t.Name.Space == nsDAV && (t.Name.Local == "allprop" || t.Name.Local == "propname"):
+				req.allprop = true
+			case t.Name == xml.Name{Space: nsDAV, Local: "href"}:
+				var href string
+				if err := decoder.DecodeElement(&href, &t); err != nil {
+					return nil, fmt.Errorf("неверный XML: %w", err)
+				}
+				req.hrefs = append(req.hrefs, strings.TrimSpace(href))
+				continue
+			case t.Name == xml.Name{Space: nsDAV, Local: "sync-token"}:
+				if err := decoder.DecodeElement(&req.syncToken, &t); err != nil {
+					return nil, fmt.Errorf("неверный XML: %w", err)
+				}
+				req.syncToken = strings.TrimSpace(req.syncToken)
+				continue
+			case t.Name == xml.Name{Space: nsCalDAV, Local: "comp-filter"}:
+				req.compFilters = append(req.compFilters, strings.ToUpper(davAttr(t, "name")))
+			case t.Name == xml.Name{Space: nsCalDAV, Local: "prop-filter"}:
+				propFilter = strings.ToUpper(davAttr(t, "name"))
+			case t.Name == xml.Name{Space: nsCalDAV, Local: "is-not-defined"} && propFilter != "":
+				req.notDefined = append(req.notDefined, propFilter)
+			}
+			stack = append(stack, t.Name)
+
+		case xml.EndElement:
+			if t.Name == (xml.Name{Space: nsCalDAV, Local: "prop-filter"}) {
+				propFilter = ""
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if req.root.Local == "" || (req.root.Local == "propfind" && req.props == nil) {
+		req.allprop = true
+	}
+	return req, nil
+}
+
+// davAttr возвращает значение атрибута элемента
+func davAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// davProp — свойство ресурса с уже подготовленным XML-содержимым
+type davProp struct {
+	name  xml.Name
+	inner string
+}
+
+// davResponse — элемент response в ответе multistatus
+type davResponse struct {
+	href    string
+	found   []davProp
+	missing []xml.Name
+	status  int // если задан, ответ содержит только статус (например, 404 для удаленного ресурса)
+}
+
+// davText экранирует текст для вставки в XML
+func davText(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+// davHref оборачивает путь в элемент href
+func davHref(path string) string {
+	return "<d:href>" + davText(path) + "</d:href>"
+}
+
+// davElement выводит элемент с префиксом; неизвестное пространство имен объявляется на месте
+func davElement(b *strings.Builder, name xml.Name, inner string) {
+	prefix, known := davPrefixes[name.Space]
+	tag := name.Local
+	declaration := ""
+	switch {
+	case known:
+		tag = prefix + ":" + name.Local
+	case name.Space != "":
+		tag = "x:" + name.Local
+		declaration = ` xmlns:x="` + davText(name.Space) + `"`
+	}
+
+	if inner == "" {
+		b.WriteString("<" + tag + declaration + "/>")
+		return
+	}
+	b.WriteString("<" + tag + declaration + ">" + inner + "</" + tag + ">")
+}
+
+// davStatusLine форматирует строку статуса для multistatus
+func davStatusLine(status int) string {
+	return fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status>", status, http.StatusText(status))
+}
+
+// writeMultistatus отправляет ответ 207 Multi-Status; syncToken добавляется для sync-collection
+func writeMultistatus(w http.ResponseWriter, responses []davResponse, syncToken string) {
+	var b strings.Builder
+	b.WriteString(davXMLHeader)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCalServer + `" xmlns:ical="` + nsAppleICal + `">`)
+
+	for _, response := range responses {
+		b.WriteString("<d:response>")
+		b.WriteString(davHref(response.href))
+		if response.status != 0 {
+			b.WriteString(davStatusLine(response.status))
+		}
+		if len(response.found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, prop := range response.found {
+				davElement(&b, prop.name, prop.inner)
+			}
+			b.WriteString("</d:prop>" + davStatusLine(http.StatusOK) + "</d:propstat>")
+		}
+		if len(response.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range response.missing {
+				davElement(&b, name, "")
+			}
+			b.WriteString("</d:prop>" + davStatusLine(http.StatusNotFound) + "</d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+
+	if syncToken != "" {
+		b.WriteString("<d:sync-token>" + davText(syncToken) + "</d:sync-token>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", davXMLContent)
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+// writeDAVError отправляет ответ с элементом DAV:error и нарушенным предусловием
+func writeDAVError(w http.ResponseWriter, status int, condition xml.Name) {
+	var b strings.Builder
+	b.WriteString(davXMLHeader)
+	b.WriteString(`<d:error xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `">`)
+	davElement(&b, condition, "")
+	b.WriteString("</d:error>")
+
+	w.Header().Set("Content-Type", davXMLContent)
+	w.WriteHeader(status)
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,257 @@
+// Package client реализует models.TaskServiceInterface поверх REST API задач,
+// поэтому код, написанный для сервиса задач в своем процессе, может работать
+// с удаленным сервером без изменений:
+//
+//	var service models.TaskServiceInterface = client.New("https://todo.example.com")
+//	task, err := service.GetTask(ctx, 1)
+//	if errors.Is(err, models.ErrNotFound) {
+//		...
+//	}
+//
+// Методы интерфейса выполняют запрос с переданным контекстом. У методов, которые
+// по интерфейсу не могут вернуть ошибку (CreateTask, GetAllTasks, SearchTasks и др.),
+// есть варианты с суффиксом Context, которые ее возвращают; ошибки самих методов
+// интерфейса передаются обработчику из WithErrorHandler.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-api/models"
+)
+
+// Значения по умолчанию
+const (
+	DefaultTimeout = 30 * time.Second
+	DefaultRetries = 3
+	DefaultBackoff = 100 * time.Millisecond
+)
+
+// Error — ответ сервера с кодом ошибки. Текст совпадает с текстом ошибки сервиса
+// на сервере, а errors.Is сопоставляет код с ошибками models: 404 — ErrNotFound,
+// 400 — ErrInvalidInput, 409 — ErrConflict, 424 — ErrBatchAborted.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return target == models.ErrNotFound
+	case http.StatusBadRequest:
+		return target == models.ErrInvalidInput
+	case http.StatusConflict:
+		return target == models.ErrConflict
+	case http.StatusFailedDependency:
+		return target == models.ErrBatchAborted
+	case http.StatusNotImplemented:
+		return target == models.ErrNotSupported
+	}
+	return false
+}
+
+// Client работает с задачами на сервере ToDo API. Клиент безопасен
+// для использования из нескольких горутин.
+type Client struct {
+	baseURL  string
+	http     *http.Client
+	retries  int
+	backoff  time.Duration
+	auth     func(*http.Request)
+	errorLog func(error)
+}
+
+// Option настраивает клиент
+type Option func(*Client)
+
+// WithHTTPClient задает HTTP клиент, например с другим таймаутом или транспортом
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithRetries задает число повторов идемпотентного запроса и паузу перед первым
+// повтором; каждая следующая пауза вдвое длиннее. 0 отключает повторы.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithBearerToken добавляет в запросы заголовок Authorization: Bearer
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.auth = func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// WithBasicAuth добавляет в запросы логин и пароль (HTTP Basic)
+func WithBasicAuth(user, password string) Option {
+	return func(c *Client) {
+		c.auth = func(req *http.Request) {
+			req.SetBasicAuth(user, password)
+		}
+	}
+}
+
+// WithErrorHandler задает обработчик ошибок методов интерфейса, которые не могут
+// вернуть ошибку (CreateTask, GetAllTasks, SearchTasks и др.). По умолчанию ошибки пишутся в лог.
+func WithErrorHandler(handle func(error)) Option {
+	return func(c *Client) {
+		c.errorLog = handle
+	}
+}
+
+// New создает клиент для сервера с адресом baseURL (например, http://localhost:8080)
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: DefaultTimeout},
+		retries: DefaultRetries,
+		backoff: DefaultBackoff,
+		auth:    func(*http.Request) {},
+		errorLog: func(err error) {
+			log.Printf("todo-api/client: %v", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// retryable сообщает, может ли повтор ответа с таким кодом завершиться иначе
+func retryable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// newIdempotencyKey создает случайный ключ для заголовка Idempotency-Key
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+// request описывает запрос к API; тело уже закодировано, чтобы его можно было отправить повторно
+type request struct {
+	method      string
+	path        string
+	contentType string
+	body        []byte
+}
+
+// do отправляет JSON запрос; ответ декодируется в result, если он задан
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	req := request{method: method, path: path}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req.body = data
+		req.contentType = "application/json"
+	}
+	return c.send(ctx, req, result)
+}
+
+// send выполняет запрос с повторами. Повторяются только идемпотентные запросы:
+// GET, PUT и DELETE, а также POST, для которого клиент передает Idempotency-Key,
+// чтобы сервер не выполнил его дважды. Повтор делается при сетевой ошибке и
+// ответах 502, 503 и 504. Ответ с кодом ошибки возвращается как *Error; если
+// тело такого ответа — JSON, оно тоже декодируется в result (так пакетный
+// запрос сообщает результаты операций вместе с ошибкой).
+func (c *Client) send(ctx context.Context, req request, result any) error {
+	var idempotencyKey string
+	if req.method == http.MethodPost {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	delay := c.backoff
+	uncertain := false // предыдущая попытка могла быть выполнена сервером
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, bytes.NewReader(req.body))
+		if err != nil {
+			return err
+		}
+		if req.contentType != "" {
+			httpReq.Header.Set("Content-Type", req.contentType)
+		}
+		if idempotencyKey != "" {
+			httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		c.auth(httpReq)
+
+		resp, err := c.http.Do(httpReq)
+		if err == nil && (!retryable(resp.StatusCode) || attempt == c.retries) {
+			defer resp.Body.Close()
+			// Удаление, повторенное после сбоя, может не найти уже удаленную задачу
+			if uncertain && req.method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+				return nil
+			}
+			return decodeResponse(resp, result)
+		}
+		if err != nil && (ctx.Err() != nil || attempt == c.retries) {
+			return fmt.Errorf("сервер %s недоступен: %w", c.baseURL, err)
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		uncertain = true
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// decodeResponse разбирает ответ сервера
+func decodeResponse(resp *http.Response, result any) error {
+	isJSON := strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json")
+	if resp.StatusCode < 400 {
+		if result == nil || resp.StatusCode == http.StatusNoContent {
+			return nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("неверный ответ сервера: %w", err)
+		}
+		return nil
+	}
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if isJSON && result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("неверный ответ сервера: %w", err)
+		}
+		apiErr.Message = http.StatusText(resp.StatusCode)
+		return apiErr
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	apiErr.Message = strings.TrimSpace(string(message))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-api/models"
+)
+
+// flakyServer отвечает 503 на первые failures запросов, а затем передает их handler
+type flakyServer struct {
+	mutex    sync.Mutex
+	failures int
+	requests []http.Header
+}
+
+// attempts возвращает заголовки полученных запросов
+func (f *flakyServer) attempts() []http.Header {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.requests
+}
+
+func newFlakyServer(t *testing.T, failures int, handler http.HandlerFunc) (*flakyServer, *Client) {
+	t.Helper()
+
+	flaky := &flakyServer{failures: failures}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flaky.mutex.Lock()
+		flaky.requests = append(flaky.requests, r.Header.Clone())
+		fail := len(flaky.requests) <= flaky.failures
+		flaky.mutex.Unlock()
+		if fail {
+			http.Error(w, "сервер перегружен", http.StatusServiceUnavailable)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return flaky, New(server.URL, WithRetries(3, time.Millisecond), WithBearerToken("secret"))
+}
+
+func TestClient_RetriesIdempotentRequests(t *testing.T) {
+	flaky, c := newFlakyServer(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1, "title": "Купить молоко"}`))
+	})
+
+	task, err := c.CreateTaskContext(context.Background(), "Купить молоко", "")
+	if err != nil || task.ID != 1 {
+		t.Fatalf("Ожидалась задача после повторов, получено %v %+v", err, task)
+	}
+	if len(flaky.attempts()) != 3 {
+		t.Fatalf("Ожидалось 3 попытки, получено %d", len(flaky.attempts()))
+	}
+	// Все попытки POST несут один ключ, чтобы сервер не создал задачу дважды
+	key := flaky.attempts()[0].Get("Idempotency-Key")
+	for _, header := range flaky.attempts() {
+		if key == "" || header.Get("Idempotency-Key") != key || header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Неверные заголовки попытки: %v", header)
+		}
+	}
+}
+
+func TestClient_GivesUpAfterRetries(t *testing.T) {
+	flaky, c := newFlakyServer(t, 10, nil)
+
+	_, err := c.GetTask(context.Background(), 1)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "сервер перегружен" {
+		t.Errorf("Ожидалась ошибка 503, получено %v", err)
+	}
+	if len(flaky.attempts()) != 4 {
+		t.Errorf("Ожидалось 4 попытки, получено %d", len(flaky.attempts()))
+	}
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	flaky, c := newFlakyServer(t, 0, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Поле 'title' обязательно", http.StatusBadRequest)
+	})
+
+	_, err := c.CreateTaskContext(context.Background(), "", "")
+	if !errors.Is(err, models.ErrInvalidInput) || err.Error() != "Поле 'title' обязательно" {
+		t.Errorf("Ожидалась ErrInvalidInput, получено %v", err)
+	}
+	if len(flaky.attempts()) != 1 {
+		t.Errorf("Ошибка 400 не должна повторяться, попыток: %d", len(flaky.attempts()))
+	}
+}
+
+func TestClient_RetriedDeleteIgnoresNotFound(t *testing.T) {
+	_, c := newFlakyServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "задача с ID 1 не найдена", http.StatusNotFound)
+	})
+
+	// Первая попытка могла удалить задачу, поэтому 404 при повторе — успех
+	if err := c.DeleteTask(context.Background(), 1); err != nil {
+		t.Errorf("Ожидалось успешное удаление, получено %v", err)
+	}
+	if err := c.DeleteTask(context.Background(), 1); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Без повтора 404 должен быть ErrNotFound, получено %v", err)
+	}
+}
+
+func TestClient_ContextCancelsRetries(t *testing.T) {
+	flaky, c := newFlakyServer(t, 10, nil)
+	c = New(c.baseURL, WithRetries(10, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetTask(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Ожидалась отмена по контексту, получено %v", err)
+	}
+	if len(flaky.attempts()) != 1 {
+		t.Errorf("После отмены контекста повторов быть не должно, попыток: %d", len(flaky.attempts()))
+	}
+}
+
+func TestClient_ErrorHandler(t *testing.T) {
+	var handled []error
+	c := New("http://127.0.0.1:1", WithRetries(0, 0), WithErrorHandler(func(err error) {
+		handled = append(handled, err)
+	}))
+
+	if task := c.CreateTask(t.Context(), "Купить молоко", ""); task != nil {
+		t.Errorf("При ошибке CreateTask должен вернуть nil")
+	}
+	results := c.ImportTasks(t.Context(), []*models.Task{{Title: "a"}, {Title: "b"}}, models.ImportOptions{})
+	if len(results) != 2 || results[1].Err == nil {
+		t.Errorf("Ошибка запроса должна быть в результате каждой задачи: %+v", results)
+	}
+	events, cancel := c.Subscribe(t.Context())
+	defer cancel()
+	if _, ok := <-events; ok {
+		t.Errorf("Канал неудачной подписки должен быть закрыт")
+	}
+	if len(handled) != 3 {
+		t.Errorf("Ожидалось 3 ошибки в обработчике, получено %d: %v", len(handled), handled)
+	}
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"todo-api/models"
+)
+
+// SubscribeContext подписывается на поток GET /tasks/events и возвращается, когда
+// сервер подтвердил подписку: изменения, сделанные после возврата, не будут пропущены.
+// Канал закрывается при отмене ctx или обрыве потока; как и на сервере, после этого
+// нужно подписаться заново и перечитать задачи. Поток не ограничен таймаутом HTTP клиента.
+func (c *Client) SubscribeContext(ctx context.Context) (<-chan models.TaskEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/tasks/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.auth(req)
+
+	stream := *c.http
+	stream.Timeout = 0
+	resp, err := stream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, decodeResponse(resp, nil)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	if !scanner.Scan() || scanner.Text() != ": subscribed" {
+		resp.Body.Close()
+		return nil, errors.New("сервер не подтвердил подписку на события")
+	}
+
+	events := make(chan models.TaskEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		var data []string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "data:"):
+				data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			case line == "" && len(data) > 0:
+				var event models.TaskEvent
+				err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event)
+				data = nil
+				if err != nil {
+					c.errorLog(err)
+					return
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Subscribe подписывается на события об изменении задач; подписка действует до
+// вызова cancel или отмены ctx. Если подписаться не удалось, ошибка передается
+// обработчику, а канал сразу закрыт.
+func (c *Client) Subscribe(ctx context.Context) (<-chan models.TaskEvent, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	events, err := c.SubscribeContext(ctx)
+	if err != nil {
+		c.errorLog(err)
+		closed := make(chan models.TaskEvent)
+		close(closed)
+		return closed, cancel
+	}
+	return events, cancel
+}
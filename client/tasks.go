@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"todo-api/models"
+)
+
+var (
+	_ models.TaskServiceInterface = (*Client)(nil)
+	_ models.TaskSearcher         = (*Client)(nil)
+	_ models.TaskBatcher          = (*Client)(nil)
+	_ models.TaskImporter         = (*Client)(nil)
+	_ models.TaskSubscriber       = (*Client)(nil)
+)
+
+// taskPath возвращает путь задачи
+func taskPath(id int) string {
+	return "/tasks/" + strconv.Itoa(id)
+}
+
+// withLimit добавляет к запросу параметр limit; 0 означает значение сервера по умолчанию
+func withLimit(query url.Values, limit int) string {
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query.Encode()
+}
+
+// CreateTaskContext создает задачу через POST /tasks
+func (c *Client) CreateTaskContext(ctx context.Context, title, description string, opts ...models.TaskOption) (*models.Task, error) {
+	// Опции меняют поля задачи, поэтому их значения снимаются с пустой задачи
+	fields := &models.Task{}
+	for _, opt := range opts {
+		opt(fields)
+	}
+
+	req := models.CreateTaskRequest{
+		Title:       title,
+		Description: description,
+		Tags:        fields.Tags,
+		Priority:    fields.Priority,
+		Project:     fields.Project,
+		DueDate:     fields.DueDate,
+	}
+	var task models.Task
+	if err := c.do(ctx, http.MethodPost, "/tasks", req, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetTask возвращает задачу по ID
+func (c *Client) GetTask(ctx context.Context, id int) (*models.Task, error) {
+	var task models.Task
+	if err := c.do(ctx, http.MethodGet, taskPath(id), nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetAllTasksContext возвращает все задачи
+func (c *Client) GetAllTasksContext(ctx context.Context) ([]*models.Task, error) {
+	return c.QueryTasksContext(ctx, "")
+}
+
+// QueryTasksContext возвращает задачи, подходящие под запрос на языке фильтров
+// (tag:дом AND due<tomorrow); отбор выполняет сервер
+func (c *Client) QueryTasksContext(ctx context.Context, query string) ([]*models.Task, error) {
+	path := "/tasks"
+	if query != "" {
+		path += "?" + url.Values{"q": {query}}.Encode()
+	}
+	var tasks []*models.Task
+	if err := c.do(ctx, http.MethodGet, path, nil, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// UpdateTask обновляет задачу. PUT заменяет задачу целиком, поэтому клиент
+// сначала читает задачу и применяет опции к ее текущим полям, как сервис на сервере.
+// Изменение, сделанное другим клиентом между чтением и записью, будет перезаписано.
+func (c *Client) UpdateTask(ctx context.Context, id int, title, description string, completed bool, opts ...models.TaskOption) (*models.Task, error) {
+	task, err := c.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(task)
+	}
+
+	req := models.UpdateTaskRequest{
+		Title:       title,
+		Description: description,
+		Completed:   completed,
+		Tags:        task.Tags,
+		Priority:    task.Priority,
+		Project:     task.Project,
+		DueDate:     task.DueDate,
+	}
+	var updated models.Task
+	if err := c.do(ctx, http.MethodPut, taskPath(id), req, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteTask удаляет задачу
+func (c *Client) DeleteTask(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, taskPath(id), nil, nil)
+}
+
+// ApplyBatch выполняет пакет операций через POST /tasks/batch. Ошибки
+// операций возвращаются в результатах как *Error; для атомарного пакета метод
+// также возвращает ошибку операции, из-за которой пакет был отменен.
+func (c *Client) ApplyBatch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]models.BatchItemResult, error) {
+	var response models.BatchResponse
+	err := c.do(ctx, http.MethodPost, "/tasks/batch", models.BatchRequest{Atomic: atomic, Operations: ops}, &response)
+	var apiErr *Error
+	if err != nil && (!errors.As(err, &apiErr) || len(response.Results) == 0) {
+		return nil, err
+	}
+
+	results := make([]models.BatchItemResult, len(response.Results))
+	var batchErr error
+	for i, result := range response.Results {
+		results[i].Task = result.Task
+		if result.Error == "" {
+			continue
+		}
+		results[i].Err = &Error{StatusCode: result.Status, Message: result.Error}
+		if batchErr == nil && result.Status != http.StatusFailedDependency {
+			batchErr = results[i].Err
+		}
+	}
+	if err != nil && batchErr == nil {
+		batchErr = err
+	}
+	return results, batchErr
+}
+
+// ImportTasksContext импортирует задачи через POST /tasks/import в формате JSON Lines.
+// Результаты идут в порядке задач. Поле Updated не заполняется: ответ API сообщает
+// только число обновленных задач, но не какие именно.
+func (c *Client) ImportTasksContext(ctx context.Context, tasks []*models.Task, opts models.ImportOptions) ([]models.BatchItemResult, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, task := range tasks {
+		if err := encoder.Encode(task); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{"format": {"ndjson"}, "ids": {"remap"}, "timestamps": {"reset"}, "uids": {"new"}}
+	if opts.PreserveIDs {
+		query.Set("ids", "preserve")
+	}
+	if opts.PreserveTimestamps {
+		query.Set("timestamps", "preserve")
+	}
+	if opts.MatchUID {
+		query.Set("uids", "match")
+	}
+	if opts.DryRun {
+		query.Set("dry_run", "true")
+	}
+
+	var response models.ImportResponse
+	req := request{method: http.MethodPost, path: "/tasks/import?" + query.Encode(), contentType: "application/x-ndjson", body: body.Bytes()}
+	if err := c.send(ctx, req, &response); err != nil {
+		return nil, err
+	}
+
+	// Каждая задача занимает одну строку файла, поэтому номер строки — это номер задачи
+	failed := make(map[int]models.ImportError, len(response.Errors))
+	for _, importErr := range response.Errors {
+		failed[importErr.Line] = importErr
+	}
+	results := make([]models.BatchItemResult, len(tasks))
+	imported := response.Tasks
+	for i := range tasks {
+		if importErr, ok := failed[i+1]; ok {
+			results[i].Err = &Error{StatusCode: importErr.Status, Message: importErr.Error}
+			continue
+		}
+		if len(imported) > 0 {
+			results[i].Task, imported = imported[0], imported[1:]
+		}
+	}
+	return results, nil
+}
+
+// search выполняет GET /tasks/search в режиме exact или fuzzy
+func (c *Client) search(ctx context.Context, query string, limit int, mode string) ([]*models.SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	var results []*models.SearchResult
+	err := c.do(ctx, http.MethodGet, "/tasks/search?"+withLimit(url.Values{"q": {query}, "mode": {mode}}, limit), nil, &results)
+	return results, err
+}
+
+// SearchTasksContext выполняет полнотекстовый поиск
+func (c *Client) SearchTasksContext(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	return c.search(ctx, query, limit, "exact")
+}
+
+// FuzzySearchTasksContext выполняет поиск с учетом опечаток
+func (c *Client) FuzzySearchTasksContext(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	return c.search(ctx, query, limit, "fuzzy")
+}
+
+// SuggestTasksContext возвращает подсказки автодополнения для вводимой строки
+func (c *Client) SuggestTasksContext(ctx context.Context, input string, limit int) (*models.Suggestions, error) {
+	var suggestions models.Suggestions
+	if err := c.do(ctx, http.MethodGet, "/tasks/autocomplete?"+withLimit(url.Values{"q": {input}}, limit), nil, &suggestions); err != nil {
+		return nil, err
+	}
+	return &suggestions, nil
+}
+
+// Методы интерфейсов models, которые не возвращают ошибку
+
+// CreateTask создает задачу; при ошибке возвращает nil
+func (c *Client) CreateTask(ctx context.Context, title, description string, opts ...models.TaskOption) *models.Task {
+	task, err := c.CreateTaskContext(ctx, title, description, opts...)
+	if err != nil {
+		c.errorLog(err)
+	}
+	return task
+}
+
+// GetAllTasks возвращает все задачи; при ошибке возвращает nil
+func (c *Client) GetAllTasks(ctx context.Context) []*models.Task {
+	tasks, err := c.GetAllTasksContext(ctx)
+	if err != nil {
+		c.errorLog(err)
+	}
+	return tasks
+}
+
+// ImportTasks импортирует задачи. Если запрос не удался целиком, ошибка
+// передается обработчику и повторяется в результате каждой задачи.
+func (c *Client) ImportTasks(ctx context.Context, tasks []*models.Task, opts models.ImportOptions) []models.BatchItemResult {
+	results, err := c.ImportTasksContext(ctx, tasks, opts)
+	if err != nil {
+		c.errorLog(err)
+		results = make([]models.BatchItemResult, len(tasks))
+		for i := range results {
+			results[i].Err = err
+		}
+	}
+	return results
+}
+
+// SearchTasks выполняет полнотекстовый поиск; при ошибке возвращает nil
+func (c *Client) SearchTasks(ctx context.Context, query string, limit int) []*models.SearchResult {
+	results, err := c.SearchTasksContext(ctx, query, limit)
+	if err != nil {
+		c.errorLog(err)
+	}
+	return results
+}
+
+// FuzzySearchTasks выполняет поиск с учетом опечаток; при ошибке возвращает nil
+func (c *Client) FuzzySearchTasks(ctx context.Context, query string, limit int) []*models.SearchResult {
+	results, err := c.FuzzySearchTasksContext(ctx, query, limit)
+	if err != nil {
+		c.errorLog(err)
+	}
+	return results
+}
+
+// SuggestTasks возвращает подсказки автодополнения; при ошибке — пустые подсказки
+func (c *Client) SuggestTasks(ctx context.Context, input string, limit int) *models.Suggestions {
+	suggestions, err := c.SuggestTasksContext(ctx, input, limit)
+	if err != nil {
+		c.errorLog(err)
+		return &models.Suggestions{Suggestions: []string{}}
+	}
+	return suggestions
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-api/client"
+)
+
+// clientTaskService — возможности, общие для сервиса задач и клиента
+type clientTaskService interface {
+	TaskServiceInterface
+	TaskSearcher
+	TaskBatcher
+	TaskImporter
+	TaskSubscriber
+}
+
+// remoteTaskService запускает сервер с новым сервисом задач и возвращает клиент для него
+func remoteTaskService(t *testing.T) clientTaskService {
+	t.Helper()
+
+	router, _ := newCalDAVRouter(t)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return client.New(server.URL, client.WithErrorHandler(func(err error) {
+		t.Errorf("Ошибка клиента: %v", err)
+	}))
+}
+
+// TestClient_MatchesTaskService проверяет, что клиент по HTTP ведет себя так же, как сервис в процессе
+func TestClient_MatchesTaskService(t *testing.T) {
+	implementations := []struct {
+		name       string
+		newService func(t *testing.T) clientTaskService
+	}{
+		{"local", func(*testing.T) clientTaskService { return NewTaskService() }},
+		{"remote", remoteTaskService},
+	}
+
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			service := impl.newService(t)
+			events, cancel := service.Subscribe(t.Context())
+			defer cancel()
+
+			due := time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)
+			task := service.CreateTask(t.Context(), "Купить молоко", "2 литра", WithTags([]string{"Дом", "#дом"}), WithPriority(PriorityHigh), WithDueDate(&due))
+			if task == nil || task.ID != 1 || len(task.Tags) != 1 || task.Tags[0] != "дом" || task.Priority != PriorityHigh || !task.DueDate.Equal(due) {
+				t.Fatalf("Неверная созданная задача: %+v", task)
+			}
+
+			// Опции обновления меняют только свои поля
+			task, err := service.UpdateTask(t.Context(), 1, "Купить кефир", "", true, WithProject(" Дом "))
+			if err != nil || task.Project != "Дом" || !task.Completed || len(task.Tags) != 1 || task.DueDate == nil {
+				t.Errorf("Неверная обновленная задача: %v %+v", err, task)
+			}
+
+			if _, err := service.GetTask(t.Context(), 42); !errors.Is(err, ErrNotFound) || err.Error() != "задача с ID 42 не найдена" {
+				t.Errorf("Ожидалась ErrNotFound, получено %v", err)
+			}
+			if err := service.DeleteTask(t.Context(), 42); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Ожидалась ErrNotFound при удалении, получено %v", err)
+			}
+
+			results, err := service.ApplyBatch(t.Context(), []BatchOperation{
+				{Op: BatchOpCreate, Title: "Новая"},
+				{Op: BatchOpUpdate, ID: 42, Title: "Нет такой"},
+			}, true)
+			if !errors.Is(err, ErrNotFound) || len(results) != 2 || !errors.Is(results[0].Err, ErrBatchAborted) || !errors.Is(results[1].Err, ErrNotFound) {
+				t.Errorf("Неверный результат атомарного пакета: %v %+v", err, results)
+			}
+
+			results = service.ImportTasks(t.Context(), []*Task{{ID: 1, Title: "Дубликат"}, {ID: 10, Title: "Из файла"}}, ImportOptions{PreserveIDs: true})
+			if len(results) != 2 || !errors.Is(results[0].Err, ErrConflict) || results[1].Err != nil || results[1].Task.ID != 10 {
+				t.Errorf("Неверный результат импорта: %+v", results)
+			}
+
+			if tasks := service.GetAllTasks(t.Context()); len(tasks) != 2 {
+				t.Errorf("Ожидалось 2 задачи, получено %d", len(tasks))
+			}
+			// Клиент не отбирает задачи сам: filterTasks отбирает их из GetAllTasks
+			if tasks := filterTasks(t.Context(), service, func(task *Task) bool { return !task.Completed }); len(tasks) != 1 || tasks[0].ID != 10 {
+				t.Errorf("Неверный результат filterTasks: %v", tasks)
+			}
+			if found := service.SearchTasks(t.Context(), "кефира", 10); len(found) != 1 || found[0].Task.ID != 1 {
+				t.Errorf("Неверный результат поиска: %v", found)
+			}
+			if found := service.FuzzySearchTasks(t.Context(), "кефри", 10); len(found) != 1 || found[0].Task.ID != 1 {
+				t.Errorf("Неверный результат нечеткого поиска: %v", found)
+			}
+			if suggestions := service.SuggestTasks(t.Context(), "ке", 5); len(suggestions.Suggestions) == 0 || suggestions.Suggestions[0] != "Купить кефир" {
+				t.Errorf("Неверные подсказки: %+v", suggestions)
+			}
+
+			if err := service.DeleteTask(t.Context(), 1); err != nil {
+				t.Errorf("Неожиданная ошибка удаления: %v", err)
+			}
+			expected := []struct {
+				kind string
+				id   int
+			}{{TaskEventCreated, 1}, {TaskEventUpdated, 1}, {TaskEventCreated, 10}, {TaskEventDeleted, 1}}
+			for _, want := range expected {
+				if event := nextEvent(t, events); event.Type != want.kind || event.Task.ID != want.id {
+					t.Errorf("Ожидалось событие %s задачи %d, получено %s %d", want.kind, want.id, event.Type, event.Task.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/client"
+	"todo-api/models"
+)
+
+// commands — подкоманды в порядке вывода в справке. Заполняются в init,
+// потому что completion перечисляет сами команды.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "add", args: "<заголовок>", summary: "создать задачу", setup: setupAdd},
+		{name: "list", args: "[текст]", summary: "показать задачи (по умолчанию невыполненные)", setup: setupList},
+		{name: "show", args: "<id>", summary: "показать задачу", setup: setupShow},
+		{name: "edit", args: "<id>", summary: "изменить поля задачи", setup: setupEdit},
+		{name: "done", args: "<id>...", summary: "отметить задачи выполненными", setup: setupDone},
+		{name: "rm", args: "<id>...", summary: "удалить задачи", setup: setupRemove},
+		{name: "tui", args: "", summary: "интерактивный список задач с обновлением в реальном времени", setup: setupTUI},
+		{name: "completion", args: "bash|zsh|fish", summary: "вывести скрипт автодополнения для оболочки", setup: setupCompletion},
+	}
+}
+
+// stringList — флаг, который можно указать несколько раз или через запятую
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// taskFields — флаги полей задачи, общие для add и edit
+type taskFields struct {
+	description string
+	tags        stringList
+	priority    string
+	project     string
+	due         string
+}
+
+func (f *taskFields) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.description, "description", "", "описание")
+	fs.Var(&f.tags, "tag", "тег (можно повторять или перечислить через запятую)")
+	fs.StringVar(&f.priority, "priority", "", "приоритет: low, medium или high")
+	fs.StringVar(&f.project, "project", "", "проект")
+	fs.StringVar(&f.due, "due", "", "срок: 2024-05-03, \"2024-05-03 18:00\" или RFC 3339")
+}
+
+// dueLayouts — допустимые форматы срока
+var dueLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// parseDue разбирает срок в местном времени
+func parseDue(value string) (*time.Time, error) {
+	for _, layout := range dueLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, newUsageError("неверный срок %q: ожидается 2024-05-03 или \"2024-05-03 18:00\"", value)
+}
+
+// parseIDs разбирает ID задач из позиционных аргументов
+func parseIDs(args []string) ([]int, error) {
+	if len(args) == 0 {
+		return nil, newUsageError("укажите ID задачи")
+	}
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+		if err != nil || id <= 0 {
+			return nil, newUsageError("неверный ID задачи %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func setupAdd(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	var fields taskFields
+	fields.register(fs)
+
+	return func(ctx context.Context, a *app, args []string) error {
+		title := strings.TrimSpace(strings.Join(args, " "))
+		if title == "" {
+			return newUsageError("укажите заголовок задачи")
+		}
+		opts := []models.TaskOption{
+			models.WithTags(fields.tags),
+			models.WithPriority(models.Priority(fields.priority)),
+			models.WithProject(fields.project),
+		}
+		if fields.due != "" {
+			due, err := parseDue(fields.due)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, models.WithDueDate(due))
+		}
+
+		task, err := a.client.CreateTaskContext(ctx, title, fields.description, opts...)
+		if err != nil {
+			return err
+		}
+		return a.printTask(task)
+	}
+}
+
+// updateRequest заполняет запрос на обновление текущими значениями задачи:
+// PUT заменяет задачу целиком, поэтому неизменяемые поля нужно передать как есть
+func updateRequest(task *models.Task) models.UpdateTaskRequest {
+	return models.UpdateTaskRequest{
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		Tags:        task.Tags,
+		Priority:    task.Priority,
+		Project:     task.Project,
+		DueDate:     task.DueDate,
+	}
+}
+
+// updateTask заменяет поля задачи значениями из req
+func updateTask(ctx context.Context, c *client.Client, id int, req models.UpdateTaskRequest) (*models.Task, error) {
+	return c.UpdateTask(ctx, id, req.Title, req.Description, req.Completed, models.WithTags(req.Tags),
+		models.WithPriority(req.Priority), models.WithProject(req.Project), models.WithDueDate(req.DueDate))
+}
+
+// quoteQueryValue заключает значение в кавычки, если без них язык запросов разберет его иначе
+func quoteQueryValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t()\"") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func setupList(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	var (
+		tags     stringList
+		all      = fs.Bool("all", false, "показать и выполненные задачи")
+		done     = fs.Bool("done", false, "показать только выполненные задачи")
+		project  = fs.String("project", "", "только задачи проекта")
+		priority = fs.String("priority", "", "только задачи с приоритетом low, medium или high")
+		query    = fs.String("q", "", "дополнительное условие на языке запросов (tag:дом OR due<tomorrow)")
+	)
+	fs.Var(&tags, "tag", "только задачи с тегом (можно повторять)")
+
+	return func(ctx context.Context, a *app, args []string) error {
+		var conditions []string
+		switch {
+		case *done:
+			conditions = append(conditions, "completed")
+		case !*all:
+			conditions = append(conditions, "completed:false")
+		}
+		for _, tag := range tags {
+			conditions = append(conditions, "tag:"+quoteQueryValue(tag))
+		}
+		if *project != "" {
+			conditions = append(conditions, "project="+quoteQueryValue(*project))
+		}
+		if *priority != "" {
+			conditions = append(conditions, "priority="+quoteQueryValue(*priority))
+		}
+		for _, word := range args {
+			conditions = append(conditions, quoteQueryValue(word))
+		}
+		if *query != "" {
+			conditions = append(conditions, "("+*query+")")
+		}
+
+		tasks, err := a.client.QueryTasksContext(ctx, strings.Join(conditions, " AND "))
+		if err != nil {
+			return err
+		}
+		sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+		return a.printTasks(tasks)
+	}
+}
+
+func setupShow(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	return func(ctx context.Context, a *app, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		if len(ids) != 1 {
+			return newUsageError("команда show принимает один ID")
+		}
+		task, err := a.client.GetTask(ctx, ids[0])
+		if err != nil {
+			return err
+		}
+		return a.printTask(task)
+	}
+}
+
+func setupEdit(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	var fields taskFields
+	title := fs.String("title", "", "новый заголовок")
+	fields.register(fs)
+	noDue := fs.Bool("no-due", false, "убрать срок")
+
+	return func(ctx context.Context, a *app, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		if len(ids) != 1 {
+			return newUsageError("команда edit принимает один ID")
+		}
+
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["title"] && !set["description"] && !set["tag"] && !set["priority"] && !set["project"] && !set["due"] && !*noDue {
+			return newUsageError("укажите хотя бы одно поле для изменения")
+		}
+
+		task, err := a.client.GetTask(ctx, ids[0])
+		if err != nil {
+			return err
+		}
+		req := updateRequest(task)
+		if set["title"] {
+			req.Title = *title
+		}
+		if set["description"] {
+			req.Description = fields.description
+		}
+		if set["tag"] {
+			req.Tags = fields.tags
+		}
+		if set["priority"] {
+			req.Priority = models.Priority(fields.priority)
+		}
+		if set["project"] {
+			req.Project = fields.project
+		}
+		if set["due"] {
+			if req.DueDate, err = parseDue(fields.due); err != nil {
+				return err
+			}
+		}
+		if *noDue {
+			req.DueDate = nil
+		}
+
+		updated, err := updateTask(ctx, a.client, task.ID, req)
+		if err != nil {
+			return err
+		}
+		return a.printTask(updated)
+	}
+}
+
+func setupDone(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	undo := fs.Bool("undo", false, "снять отметку о выполнении")
+
+	return func(ctx context.Context, a *app, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+
+		var tasks []*models.Task
+		for _, id := range ids {
+			task, err := a.client.GetTask(ctx, id)
+			if err != nil {
+				return fmt.Errorf("задача %d: %w", id, err)
+			}
+			req := updateRequest(task)
+			req.Completed = !*undo
+			if task, err = updateTask(ctx, a.client, id, req); err != nil {
+				return fmt.Errorf("задача %d: %w", id, err)
+			}
+			tasks = append(tasks, task)
+		}
+		return a.printTasks(tasks)
+	}
+}
+
+func setupRemove(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	return func(ctx context.Context, a *app, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := a.client.DeleteTask(ctx, id); err != nil {
+				return fmt.Errorf("задача %d: %w", id, err)
+			}
+			if a.output == "table" {
+				fmt.Fprintf(a.out, "Задача %d удалена\n", id)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completionShells — оболочки, для которых генерируется автодополнение
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// globalFlags — флаги, общие для всех подкоманд
+type globalFlags struct {
+	server string
+	output string
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.server, "server", "", "адрес сервера (по умолчанию из конфигурации или "+defaultServer+")")
+	fs.StringVar(&g.output, "o", "table", "формат вывода: table или json")
+	fs.BoolFunc("json", "то же, что -o json", func(string) error { g.output = "json"; return nil })
+}
+
+// completionFlag — флаг подкоманды в том виде, в каком он нужен скриптам дополнения
+type completionFlag struct {
+	name       string
+	usage      string
+	takesValue bool
+}
+
+// option возвращает флаг так, как его набирают: -o для однобуквенных, --name для остальных
+func (f completionFlag) option() string {
+	if len(f.name) == 1 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+// commandFlags перечисляет флаги подкоманды вместе с общими
+func commandFlags(cmd *command) []completionFlag {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	new(globalFlags).register(fs)
+	cmd.setup(fs)
+
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:       f.Name,
+			usage:      f.Usage,
+			takesValue: !ok || !boolFlag.IsBoolFlag(),
+		})
+	})
+	return flags
+}
+
+func setupCompletion(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	return func(_ context.Context, a *app, args []string) error {
+		if len(args) != 1 {
+			return newUsageError("укажите оболочку: %s", strings.Join(completionShells, ", "))
+		}
+		switch args[0] {
+		case "bash":
+			writeBashCompletion(a.out)
+		case "zsh":
+			writeZshCompletion(a.out)
+		case "fish":
+			writeFishCompletion(a.out)
+		default:
+			return newUsageError("неизвестная оболочка %q: ожидается %s", args[0], strings.Join(completionShells, ", "))
+		}
+		return nil
+	}
+}
+
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// writeBashCompletion выводит скрипт для bash: source <(todo completion bash)
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# Автодополнение todo для bash: source <(todo completion bash)")
+	fmt.Fprintln(w, "_todo() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `    if [ "$COMP_CWORD" -eq 1 ]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case "$prev" in`)
+	fmt.Fprintln(w, `        -o) COMPREPLY=($(compgen -W "table json" -- "$cur")); return ;;`)
+	fmt.Fprintf(w, "        completion) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", strings.Join(completionShells, " "))
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, `    local flags=""`)
+	fmt.Fprintln(w, `    case "${COMP_WORDS[1]}" in`)
+	for i := range commands {
+		var options []string
+		for _, f := range commandFlags(&commands[i]) {
+			options = append(options, f.option())
+		}
+		fmt.Fprintf(w, "        %s) flags=%q ;;\n", commands[i].name, strings.Join(options, " "))
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, `    if [[ "$cur" == -* ]]; then`)
+	fmt.Fprintln(w, `        COMPREPLY=($(compgen -W "$flags" -- "$cur"))`)
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _todo todo")
+}
+
+// zshQuote заключает текст в одинарные кавычки
+func zshQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// zshDescription экранирует скобки в описании флага для _arguments
+func zshDescription(value string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(value)
+}
+
+// writeZshCompletion выводит скрипт для zsh: source <(todo completion zsh)
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef todo")
+	fmt.Fprintln(w, "# Автодополнение todo для zsh: source <(todo completion zsh)")
+	fmt.Fprintln(w, "_todo() {")
+	fmt.Fprintln(w, "    local -a commands")
+	fmt.Fprintln(w, "    commands=(")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "        %s\n", zshQuote(cmd.name+":"+cmd.summary))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "    if (( CURRENT == 2 )); then")
+	fmt.Fprintln(w, "        _describe 'команда' commands")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    shift words")
+	fmt.Fprintln(w, "    (( CURRENT-- ))")
+	fmt.Fprintln(w, "    case $words[1] in")
+	for i := range commands {
+		specs := []string{}
+		for _, f := range commandFlags(&commands[i]) {
+			spec := f.option() + "[" + zshDescription(f.usage) + "]"
+			switch {
+			case f.name == "o":
+				spec += ":формат:(table json)"
+			case f.takesValue:
+				spec += ":значение:"
+			}
+			specs = append(specs, zshQuote(spec))
+		}
+		if commands[i].name == "completion" {
+			specs = append(specs, zshQuote("1:оболочка:("+strings.Join(completionShells, " ")+")"))
+		}
+		fmt.Fprintf(w, "        %s) _arguments %s ;;\n", commands[i].name, strings.Join(specs, " "))
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "compdef _todo todo")
+}
+
+// fishQuote заключает текст в одинарные кавычки fish
+func fishQuote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
+
+// writeFishCompletion выводит скрипт для fish: todo completion fish | source
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# Автодополнение todo для fish: todo completion fish | source")
+	fmt.Fprintln(w, "complete -c todo -f")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c todo -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+	for i := range commands {
+		condition := fishQuote("__fish_seen_subcommand_from " + commands[i].name)
+		for _, f := range commandFlags(&commands[i]) {
+			option := "-l " + f.name
+			if len(f.name) == 1 {
+				option = "-s " + f.name
+			}
+			if f.takesValue {
+				option += " -r"
+			}
+			if f.name == "o" {
+				option += " -a 'table json'"
+			}
+			fmt.Fprintf(w, "complete -c todo -n %s %s -d %s\n", condition, option, fishQuote(f.usage))
+		}
+	}
+	fmt.Fprintf(w, "complete -c todo -n %s -a %s\n",
+		fishQuote("__fish_seen_subcommand_from completion"), fishQuote(strings.Join(completionShells, " ")))
+}
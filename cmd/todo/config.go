@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"todo-api/client"
+)
+
+// defaultServer — адрес сервера, если он не задан ни в файле, ни в окружении
+const defaultServer = "http://localhost:8080"
+
+// Config описывает подключение к серверу. Значения берутся по возрастанию
+// приоритета: значения по умолчанию, файл конфигурации, переменные окружения, флаги.
+type Config struct {
+	Server   string `json:"server"`
+	Token    string `json:"token,omitempty"`    // отправляется как Authorization: Bearer
+	User     string `json:"user,omitempty"`     // вместе с Password — Basic-аутентификация
+	Password string `json:"password,omitempty"` // для прокси перед API
+}
+
+// newClient создает клиент API с учетными данными из конфигурации; ошибки потока
+// событий, которые клиент не может вернуть вызывающему, пишутся в stderr
+func newClient(config Config, stderr io.Writer) *client.Client {
+	opts := []client.Option{client.WithErrorHandler(func(err error) {
+		fmt.Fprintf(stderr, "todo: %v\n", err)
+	})}
+	switch {
+	case config.Token != "":
+		opts = append(opts, client.WithBearerToken(config.Token))
+	case config.User != "":
+		opts = append(opts, client.WithBasicAuth(config.User, config.Password))
+	}
+	return client.New(config.Server, opts...)
+}
+
+// configPath возвращает путь к файлу конфигурации: $TODO_CONFIG или ~/.config/todo/config.json
+func configPath(getenv func(string) string) string {
+	if path := getenv("TODO_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "todo", "config.json")
+}
+
+// loadConfig собирает конфигурацию из файла и окружения. Отсутствующий файл
+// не считается ошибкой, а файл с неверным JSON — считается.
+func loadConfig(getenv func(string) string) (Config, error) {
+	config := Config{Server: defaultServer}
+
+	if path := configPath(getenv); path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return config, fmt.Errorf("не удалось прочитать конфигурацию: %w", err)
+		default:
+			if err := json.Unmarshal(data, &config); err != nil {
+				return config, fmt.Errorf("неверный файл конфигурации %s: %w", path, err)
+			}
+		}
+	}
+
+	for name, target := range map[string]*string{
+		"TODO_SERVER":   &config.Server,
+		"TODO_TOKEN":    &config.Token,
+		"TODO_USER":     &config.User,
+		"TODO_PASSWORD": &config.Password,
+	} {
+		if value := getenv(name); value != "" {
+			*target = value
+		}
+	}
+
+	config.Server = strings.TrimRight(config.Server, "/")
+	return config, nil
+}
@@ -0,0 +1,162 @@
+// Команда todo — клиент командной строки для ToDo REST API.
+//
+//	todo add "Купить молоко" --tag дом --priority high --due 2024-05-03
+//	todo list --tag дом
+//	todo done 3
+//	todo list --all -o json | jq '.[].title'
+//
+// Адрес сервера и учетные данные берутся из ~/.config/todo/config.json,
+// переменных TODO_SERVER, TODO_TOKEN, TODO_USER, TODO_PASSWORD или флага --server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"todo-api/client"
+)
+
+// Коды выхода
+const (
+	exitOK    = 0
+	exitError = 1 // ошибка сервера или сети
+	exitUsage = 2 // неверные аргументы
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr))
+}
+
+// app — окружение, в котором выполняется подкоманда
+type app struct {
+	client *client.Client
+	server string // адрес сервера для сообщений
+	out    io.Writer
+	output string // table или json
+}
+
+// command описывает подкоманду. setup регистрирует флаги и возвращает функцию,
+// которая выполнит команду с уже разобранными флагами и позиционными аргументами.
+type command struct {
+	name    string
+	args    string
+	summary string
+	setup   func(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error
+}
+
+// usageError — ошибка в аргументах командной строки
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func newUsageError(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// run разбирает аргументы и выполняет подкоманду; возвращает код выхода
+func run(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stdout)
+		return exitOK
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "todo: неизвестная команда %q\n\n", args[0])
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("todo "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var global globalFlags
+	global.register(fs)
+	runner := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Использование: todo %s %s\n\n%s\n\nФлаги:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if global.output != "table" && global.output != "json" {
+		fmt.Fprintf(stderr, "todo: неизвестный формат вывода %q\n", global.output)
+		return exitUsage
+	}
+
+	config, err := loadConfig(getenv)
+	if err != nil {
+		fmt.Fprintf(stderr, "todo: %v\n", err)
+		return exitError
+	}
+	if global.server != "" {
+		config.Server = strings.TrimRight(global.server, "/")
+	}
+
+	a := &app{client: newClient(config, stderr), server: config.Server, out: stdout, output: global.output}
+	if err := runner(context.Background(), a, positional); err != nil {
+		fmt.Fprintf(stderr, "todo: %s\n", errorText(err))
+		var usage *usageError
+		if errors.As(err, &usage) {
+			return exitUsage
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+// parseInterspersed разбирает флаги, которые могут стоять и после позиционных
+// аргументов (todo add "Купить молоко" --tag дом); "--" завершает флаги
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "todo — клиент ToDo API")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Использование: todo <команда> [аргументы] [флаги]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Команды:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Подробнее о команде: todo <команда> --help")
+}
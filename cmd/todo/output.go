@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"todo-api/client"
+	"todo-api/models"
+)
+
+// printTasks выводит задачи таблицей или массивом JSON
+func (a *app) printTasks(tasks []*models.Task) error {
+	if a.output == "json" {
+		if tasks == nil {
+			tasks = []*models.Task{}
+		}
+		return a.printJSON(tasks)
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(a.out, "Задач нет")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t \tПРИОРИТЕТ\tЗАГОЛОВОК\tПРОЕКТ\tТЕГИ\tСРОК")
+	for _, task := range tasks {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			task.ID, checkbox(task.Completed), string(task.Priority), task.Title,
+			task.Project, strings.Join(task.Tags, ", "), formatDue(task.DueDate))
+	}
+	return w.Flush()
+}
+
+// printTask выводит одну задачу списком полей или объектом JSON
+func (a *app) printTask(task *models.Task) error {
+	if a.output == "json" {
+		return a.printJSON(task)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", task.ID)
+	fmt.Fprintf(w, "Заголовок:\t%s\n", task.Title)
+	fmt.Fprintf(w, "Выполнена:\t%s\n", checkbox(task.Completed))
+	for _, field := range []struct{ name, value string }{
+		{"Описание:", task.Description},
+		{"Приоритет:", string(task.Priority)},
+		{"Проект:", task.Project},
+		{"Теги:", strings.Join(task.Tags, ", ")},
+		{"Срок:", formatDue(task.DueDate)},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(w, "%s\t%s\n", field.name, strings.ReplaceAll(field.value, "\n", "\n\t"))
+		}
+	}
+	fmt.Fprintf(w, "Создана:\t%s\n", task.CreatedAt.Local().Format("2006-01-02 15:04"))
+	fmt.Fprintf(w, "Изменена:\t%s\n", task.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	return w.Flush()
+}
+
+func (a *app) printJSON(value any) error {
+	encoder := json.NewEncoder(a.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func checkbox(completed bool) string {
+	if completed {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// formatDue показывает срок без времени, если он приходится на полночь
+func formatDue(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	local := due.Local()
+	if local.Hour() == 0 && local.Minute() == 0 {
+		return local.Format("2006-01-02")
+	}
+	return local.Format("2006-01-02 15:04")
+}
+
+// errorText возвращает текст ошибки для вывода; к ошибке сервера добавляется код ответа
+func errorText(err error) string {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		return fmt.Sprintf("%v (%d)", err, apiErr.StatusCode)
+	}
+	return err.Error()
+}
@@ -0,0 +1,29 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// terminalState — заглушка для платформ без поддержки сырого режима
+type terminalState struct{}
+
+var errNoTerminal = errors.New("интерактивный режим не поддерживается на этой платформе")
+
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errNoTerminal
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	return nil
+}
+
+func terminalSize(fd int) (width, height int, err error) {
+	return 0, 0, errNoTerminal
+}
+
+func resizeSignal() os.Signal {
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl выполняет системный вызов ioctl над дескриптором терминала
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// makeRaw переводит терминал в сырой режим: без эха, построчной буферизации
+// и обработки Ctrl+C. Возвращает прежние настройки для restoreTerminal.
+func makeRaw(fd int) (*syscall.Termios, error) {
+	var old syscall.Termios
+	if err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&old)); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return &old, nil
+}
+
+// restoreTerminal возвращает настройки терминала, сохраненные makeRaw
+func restoreTerminal(fd int, state *syscall.Termios) error {
+	return ioctl(fd, ioctlSetTermios, unsafe.Pointer(state))
+}
+
+// terminalSize возвращает ширину и высоту терминала в символах
+func terminalSize(fd int) (width, height int, err error) {
+	var size struct{ rows, cols, x, y uint16 }
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return 0, 0, err
+	}
+	return int(size.cols), int(size.rows), nil
+}
+
+// resizeSignal — сигнал об изменении размера терминала
+func resizeSignal() os.Signal {
+	return syscall.SIGWINCH
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-api/models"
+)
+
+// fakeServer повторяет контракт REST API задач в минимальном объеме и запоминает запросы
+type fakeServer struct {
+	mutex    sync.Mutex
+	tasks    map[int]*models.Task
+	nextID   int
+	queries  []string
+	authLine string
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeServer{tasks: make(map[int]*models.Task), nextID: 1}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		fake.queries = append(fake.queries, r.URL.Query().Get("q"))
+		fake.authLine = r.Header.Get("Authorization")
+		tasks := []*models.Task{}
+		for id := 1; id < fake.nextID; id++ {
+			if task, ok := fake.tasks[id]; ok {
+				tasks = append(tasks, task)
+			}
+		}
+		json.NewEncoder(w).Encode(tasks)
+	})
+	mux.HandleFunc("POST /tasks", func(w http.ResponseWriter, r *http.Request) {
+		var req models.CreateTaskRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		task := &models.Task{ID: fake.nextID, Title: req.Title, Description: req.Description, Tags: req.Tags,
+			Priority: req.Priority, Project: req.Project, DueDate: req.DueDate, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		fake.tasks[task.ID] = task
+		fake.nextID++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(task)
+	})
+	withTask := func(handle func(w http.ResponseWriter, r *http.Request, task *models.Task)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id, _ := strconv.Atoi(r.PathValue("id"))
+			fake.mutex.Lock()
+			defer fake.mutex.Unlock()
+			task, ok := fake.tasks[id]
+			if !ok {
+				http.Error(w, "задача с ID "+r.PathValue("id")+" не найдена", http.StatusNotFound)
+				return
+			}
+			handle(w, r, task)
+		}
+	}
+	mux.HandleFunc("GET /tasks/{id}", withTask(func(w http.ResponseWriter, r *http.Request, task *models.Task) {
+		json.NewEncoder(w).Encode(task)
+	}))
+	mux.HandleFunc("PUT /tasks/{id}", withTask(func(w http.ResponseWriter, r *http.Request, task *models.Task) {
+		var req models.UpdateTaskRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		task.Title, task.Description, task.Completed = req.Title, req.Description, req.Completed
+		task.Tags, task.Priority, task.Project, task.DueDate = req.Tags, req.Priority, req.Project, req.DueDate
+		json.NewEncoder(w).Encode(task)
+	}))
+	mux.HandleFunc("DELETE /tasks/{id}", withTask(func(w http.ResponseWriter, r *http.Request, task *models.Task) {
+		delete(fake.tasks, task.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// runTodo выполняет команду с окружением, где задан только адрес сервера
+func runTodo(t *testing.T, server *httptest.Server, env map[string]string, args ...string) (int, string, string) {
+	t.Helper()
+
+	getenv := func(name string) string {
+		switch name {
+		case "TODO_SERVER":
+			if server != nil {
+				return server.URL
+			}
+		case "TODO_CONFIG":
+			return filepath.Join(t.TempDir(), "missing.json")
+		}
+		return env[name]
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(args, getenv, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLI_AddShowDoneRemove(t *testing.T) {
+	fake, server := newFakeServer(t)
+
+	code, out, errOut := runTodo(t, server, nil, "add", "Купить", "молоко", "--tag", "дом,покупки", "--priority", "high", "--due", "2024-05-03", "--json")
+	if code != exitOK {
+		t.Fatalf("Ожидался код 0, получен %d: %s", code, errOut)
+	}
+	var created models.Task
+	if err := json.Unmarshal([]byte(out), &created); err != nil {
+		t.Fatalf("Вывод --json не является задачей: %v\n%s", err, out)
+	}
+	if created.Title != "Купить молоко" || strings.Join(created.Tags, ",") != "дом,покупки" || created.Priority != models.PriorityHigh || created.DueDate == nil {
+		t.Errorf("Флаги после заголовка не разобраны: %+v", created)
+	}
+
+	code, out, _ = runTodo(t, server, nil, "show", "1")
+	if code != exitOK || !strings.Contains(out, "Купить молоко") || !strings.Contains(out, "2024-05-03") {
+		t.Errorf("Неверный вывод show:\n%s", out)
+	}
+
+	code, out, _ = runTodo(t, server, nil, "done", "1")
+	if code != exitOK || !strings.Contains(out, "[x]") {
+		t.Errorf("Неверный вывод done:\n%s", out)
+	}
+	// PUT заменяет задачу целиком, поэтому остальные поля должны сохраниться
+	if task := fake.tasks[1]; !task.Completed || strings.Join(task.Tags, ",") != "дом,покупки" || task.DueDate == nil {
+		t.Errorf("done изменил другие поля: %+v", task)
+	}
+
+	code, _, errOut = runTodo(t, server, nil, "rm", "1", "7")
+	if code != exitError || !strings.Contains(errOut, "задача 7: задача с ID 7 не найдена (404)") {
+		t.Errorf("Ожидалась ошибка сервера для ID 7, получено %d %q", code, errOut)
+	}
+	if len(fake.tasks) != 0 {
+		t.Errorf("Задача 1 должна быть удалена до ошибки")
+	}
+}
+
+func TestCLI_ListBuildsQuery(t *testing.T) {
+	fake, server := newFakeServer(t)
+
+	cases := []struct {
+		args  []string
+		query string
+	}{
+		{[]string{"list"}, "completed:false"},
+		{[]string{"list", "--all"}, ""},
+		{[]string{"list", "молоко", "--tag", "дом", "--project", "Дом и сад", "--priority", "high"},
+			`completed:false AND tag:дом AND project="Дом и сад" AND priority=high AND молоко`},
+		{[]string{"list", "--done", "-q", "due<today OR tag:срочно"}, "completed AND (due<today OR tag:срочно)"},
+	}
+	for _, tc := range cases {
+		if code, _, errOut := runTodo(t, server, nil, tc.args...); code != exitOK {
+			t.Fatalf("%v: код %d: %s", tc.args, code, errOut)
+		}
+		if got := fake.queries[len(fake.queries)-1]; got != tc.query {
+			t.Errorf("%v: ожидался запрос %q, получен %q", tc.args, tc.query, got)
+		}
+	}
+
+	_, out, _ := runTodo(t, server, nil, "list")
+	if strings.TrimSpace(out) != "Задач нет" {
+		t.Errorf("Неверный вывод пустого списка: %q", out)
+	}
+	_, out, _ = runTodo(t, server, nil, "list", "-o", "json")
+	if strings.TrimSpace(out) != "[]" {
+		t.Errorf("Пустой список в JSON должен быть [], получено %q", out)
+	}
+}
+
+func TestCLI_Edit(t *testing.T) {
+	fake, server := newFakeServer(t)
+	runTodo(t, server, nil, "add", "Отчет", "--project", "работа", "--due", "2024-05-03 18:00", "--description", "черновик")
+
+	code, _, errOut := runTodo(t, server, nil, "edit", "1")
+	if code != exitUsage || !strings.Contains(errOut, "хотя бы одно поле") {
+		t.Errorf("edit без полей должен завершаться с ошибкой использования, получено %d %q", code, errOut)
+	}
+
+	code, _, errOut = runTodo(t, server, nil, "edit", "1", "--title", "Квартальный отчет", "--no-due")
+	if code != exitOK {
+		t.Fatalf("Ожидался код 0, получен %d: %s", code, errOut)
+	}
+	task := fake.tasks[1]
+	if task.Title != "Квартальный отчет" || task.DueDate != nil || task.Project != "работа" || task.Description != "черновик" {
+		t.Errorf("edit должен менять только указанные поля: %+v", task)
+	}
+
+	if code, _, _ := runTodo(t, server, nil, "edit", "abc", "--title", "x"); code != exitUsage {
+		t.Errorf("Неверный ID должен давать код %d, получен %d", exitUsage, code)
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"server": "http://file:8080/", "token": "из-файла", "user": "anna"}`), 0o600)
+
+	env := map[string]string{"TODO_CONFIG": path, "TODO_TOKEN": "из-окружения"}
+	config, err := loadConfig(func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if config.Server != "http://file:8080" || config.Token != "из-окружения" || config.User != "anna" {
+		t.Errorf("Окружение должно перекрывать файл: %+v", config)
+	}
+
+	os.WriteFile(path, []byte(`{"server": `), 0o600)
+	if _, err := loadConfig(func(name string) string { return env[name] }); err == nil {
+		t.Errorf("Ожидалась ошибка для неверного файла конфигурации")
+	}
+}
+
+func TestCLI_SendsCredentials(t *testing.T) {
+	fake, server := newFakeServer(t)
+
+	runTodo(t, server, map[string]string{"TODO_TOKEN": "secret"}, "list")
+	if fake.authLine != "Bearer secret" {
+		t.Errorf("Ожидался заголовок Bearer, получен %q", fake.authLine)
+	}
+	runTodo(t, server, map[string]string{"TODO_USER": "anna", "TODO_PASSWORD": "pw"}, "list")
+	if !strings.HasPrefix(fake.authLine, "Basic ") {
+		t.Errorf("Ожидался заголовок Basic, получен %q", fake.authLine)
+	}
+}
+
+func TestCLI_Completion(t *testing.T) {
+	for _, shell := range completionShells {
+		code, out, errOut := runTodo(t, nil, nil, "completion", shell)
+		if code != exitOK {
+			t.Fatalf("%s: код %d: %s", shell, code, errOut)
+		}
+		for _, expected := range []string{"add", "list", "priority", "no-due", "undo"} {
+			if !strings.Contains(out, expected) {
+				t.Errorf("%s: в скрипте нет %q", shell, expected)
+			}
+		}
+	}
+
+	if code, _, _ := runTodo(t, nil, nil, "completion", "powershell"); code != exitUsage {
+		t.Errorf("Неизвестная оболочка должна давать код %d, получен %d", exitUsage, code)
+	}
+}
@@ -0,0 +1,643 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"todo-api/client"
+	"todo-api/models"
+)
+
+// Состояние интерфейса отделено от терминала: tuiModel получает клавиши и события
+// сервера и возвращает действия, а ввод-вывод выполняет runTUI.
+
+// keyCode — распознанная клавиша
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyDelete
+	keyCtrlC
+)
+
+// key — нажатие клавиши; для keyRune в r хранится символ
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// csiKeys — управляющие последовательности ESC [ ... и ESC O ... для стрелок и Home/End
+var csiKeys = map[string]keyCode{
+	"A": keyUp, "B": keyDown, "C": keyRight, "D": keyLeft,
+	"H": keyHome, "1~": keyHome, "7~": keyHome,
+	"F": keyEnd, "4~": keyEnd, "8~": keyEnd,
+	"3~": keyDelete,
+}
+
+// decodeKeys разбирает байты, прочитанные из терминала в сыром режиме
+func decodeKeys(data []byte) []key {
+	var keys []key
+	for len(data) > 0 {
+		b := data[0]
+		switch {
+		case b == 0x1b && len(data) > 2 && (data[1] == '[' || data[1] == 'O'):
+			end := 2
+			for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+				end++
+			}
+			if end == len(data) {
+				return keys // обрезанная последовательность
+			}
+			if code, ok := csiKeys[string(data[2:end+1])]; ok {
+				keys = append(keys, key{code: code})
+			}
+			data = data[end+1:]
+			continue
+		case b == 0x1b:
+			keys = append(keys, key{code: keyEsc})
+		case b == '\r' || b == '\n':
+			keys = append(keys, key{code: keyEnter})
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+		case b == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+		case b == 0x01:
+			keys = append(keys, key{code: keyHome})
+		case b == 0x05:
+			keys = append(keys, key{code: keyEnd})
+		case b < 0x20:
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, key{code: keyRune, r: r})
+			data = data[size:]
+			continue
+		}
+		data = data[1:]
+	}
+	return keys
+}
+
+// tuiMode — режим интерфейса
+type tuiMode int
+
+const (
+	modeList tuiMode = iota
+	modeFilter
+	modeEditTitle
+	modeEditDescription
+)
+
+// tuiActionKind — действие, которое нужно выполнить после нажатия клавиши
+type tuiActionKind int
+
+const (
+	actionNone tuiActionKind = iota
+	actionQuit
+	actionReload
+	actionUpdate
+)
+
+// tuiAction — действие с данными для запроса к серверу
+type tuiAction struct {
+	kind tuiActionKind
+	id   int
+	req  models.UpdateTaskRequest
+}
+
+// tuiModel — состояние интерфейса: задачи, фильтр, курсор и редактируемая строка
+type tuiModel struct {
+	tasks    []*models.Task // все задачи в порядке ID
+	visible  []*models.Task // задачи, подходящие под фильтр
+	cursor   int
+	offset   int // первая видимая строка списка
+	filter   string
+	mode     tuiMode
+	input    []rune
+	inputPos int
+	status   string
+	live     bool // поток изменений подключен
+}
+
+// selected возвращает задачу под курсором
+func (m *tuiModel) selected() *models.Task {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return nil
+	}
+	return m.visible[m.cursor]
+}
+
+// setTasks заменяет список задач, сохраняя курсор на той же задаче
+func (m *tuiModel) setTasks(tasks []*models.Task) {
+	m.tasks = append([]*models.Task(nil), tasks...)
+	sort.Slice(m.tasks, func(i, j int) bool { return m.tasks[i].ID < m.tasks[j].ID })
+	m.refilter()
+}
+
+// applyEvent применяет изменение, пришедшее с сервера или из ответа на запрос
+func (m *tuiModel) applyEvent(event models.TaskEvent) {
+	if event.Task == nil {
+		return
+	}
+	i := sort.Search(len(m.tasks), func(i int) bool { return m.tasks[i].ID >= event.Task.ID })
+	exists := i < len(m.tasks) && m.tasks[i].ID == event.Task.ID
+
+	switch {
+	case event.Type == models.TaskEventDeleted:
+		if exists {
+			m.tasks = append(m.tasks[:i], m.tasks[i+1:]...)
+		}
+	case exists:
+		m.tasks[i] = event.Task
+	default:
+		m.tasks = append(m.tasks, nil)
+		copy(m.tasks[i+1:], m.tasks[i:])
+		m.tasks[i] = event.Task
+	}
+	m.refilter()
+}
+
+// refilter пересчитывает видимые задачи; курсор остается на прежней задаче, если она видна
+func (m *tuiModel) refilter() {
+	current := 0
+	if task := m.selected(); task != nil {
+		current = task.ID
+	}
+
+	m.visible = m.visible[:0]
+	for _, task := range m.tasks {
+		if matchesFilter(task, m.filter) {
+			m.visible = append(m.visible, task)
+		}
+	}
+
+	for i, task := range m.visible {
+		if task.ID == current {
+			m.cursor = i
+			return
+		}
+	}
+	m.cursor = min(m.cursor, len(m.visible)-1)
+	m.cursor = max(m.cursor, 0)
+}
+
+// matchesFilter ищет текст без учета регистра в заголовке, описании, проекте и тегах
+func matchesFilter(task *models.Task, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	filter = strings.ToLower(filter)
+	for _, field := range append([]string{task.Title, task.Description, task.Project}, task.Tags...) {
+		if strings.Contains(strings.ToLower(field), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// startInput переключает интерфейс в режим ввода строки с начальным значением
+func (m *tuiModel) startInput(mode tuiMode, value string) {
+	m.mode = mode
+	m.input = []rune(value)
+	m.inputPos = len(m.input)
+	m.status = ""
+}
+
+// editInput применяет клавишу к редактируемой строке; возвращает false, если клавиша не для строки
+func (m *tuiModel) editInput(k key) bool {
+	switch k.code {
+	case keyRune:
+		m.input = append(m.input[:m.inputPos], append([]rune{k.r}, m.input[m.inputPos:]...)...)
+		m.inputPos++
+	case keyBackspace:
+		if m.inputPos > 0 {
+			m.input = append(m.input[:m.inputPos-1], m.input[m.inputPos:]...)
+			m.inputPos--
+		}
+	case keyDelete:
+		if m.inputPos < len(m.input) {
+			m.input = append(m.input[:m.inputPos], m.input[m.inputPos+1:]...)
+		}
+	case keyLeft:
+		m.inputPos = max(m.inputPos-1, 0)
+	case keyRight:
+		m.inputPos = min(m.inputPos+1, len(m.input))
+	case keyHome:
+		m.inputPos = 0
+	case keyEnd:
+		m.inputPos = len(m.input)
+	default:
+		return false
+	}
+	return true
+}
+
+// handleKey меняет состояние и возвращает действие, которое нужно выполнить
+func (m *tuiModel) handleKey(k key) tuiAction {
+	if k.code == keyCtrlC {
+		return tuiAction{kind: actionQuit}
+	}
+
+	switch m.mode {
+	case modeFilter:
+		switch k.code {
+		case keyEnter:
+			m.mode = modeList
+		case keyEsc:
+			m.mode = modeList
+			m.filter = ""
+			m.refilter()
+		default:
+			if m.editInput(k) {
+				m.filter = string(m.input)
+				m.refilter()
+			}
+		}
+		return tuiAction{}
+
+	case modeEditTitle, modeEditDescription:
+		switch k.code {
+		case keyEsc:
+			m.mode = modeList
+		case keyEnter:
+			return m.finishEdit()
+		default:
+			m.editInput(k)
+		}
+		return tuiAction{}
+	}
+
+	task := m.selected()
+	switch {
+	case k.code == keyUp || k.r == 'k':
+		m.cursor = max(m.cursor-1, 0)
+	case k.code == keyDown || k.r == 'j':
+		m.cursor = max(min(m.cursor+1, len(m.visible)-1), 0)
+	case k.code == keyHome || k.r == 'g':
+		m.cursor = 0
+	case k.code == keyEnd || k.r == 'G':
+		m.cursor = max(len(m.visible)-1, 0)
+	case k.r == 'q':
+		return tuiAction{kind: actionQuit}
+	case k.r == 'r':
+		return tuiAction{kind: actionReload}
+	case k.r == '/':
+		m.startInput(modeFilter, m.filter)
+	case k.code == keyEsc && m.filter != "":
+		m.filter = ""
+		m.refilter()
+	case task == nil:
+	case k.r == ' ' || k.r == 'x':
+		req := updateRequest(task)
+		req.Completed = !task.Completed
+		return tuiAction{kind: actionUpdate, id: task.ID, req: req}
+	case k.code == keyEnter || k.r == 'e':
+		m.startInput(modeEditTitle, task.Title)
+	case k.r == 'd':
+		m.startInput(modeEditDescription, task.Description)
+	}
+	return tuiAction{}
+}
+
+// finishEdit завершает редактирование и возвращает запрос на сохранение
+func (m *tuiModel) finishEdit() tuiAction {
+	mode := m.mode
+	m.mode = modeList
+	task := m.selected()
+	if task == nil {
+		return tuiAction{}
+	}
+
+	req := updateRequest(task)
+	value := string(m.input)
+	if mode == modeEditTitle {
+		if strings.TrimSpace(value) == "" {
+			m.status = "Заголовок не может быть пустым"
+			return tuiAction{}
+		}
+		req.Title = strings.TrimSpace(value)
+	} else {
+		req.Description = value
+	}
+	return tuiAction{kind: actionUpdate, id: task.ID, req: req}
+}
+
+// Управляющие последовательности для оформления
+const (
+	styleReverse = "\x1b[7m"
+	styleDim     = "\x1b[2m"
+	styleReset   = "\x1b[0m"
+)
+
+// fit обрезает строку до ширины экрана и дополняет пробелами
+func fit(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:max(width, 0)])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+// taskRow форматирует задачу для строки списка
+func taskRow(task *models.Task) string {
+	row := fmt.Sprintf("%s %3d  %s", checkbox(task.Completed), task.ID, task.Title)
+	var details []string
+	if task.Priority != models.PriorityNone {
+		details = append(details, "!"+string(task.Priority))
+	}
+	if task.Project != "" {
+		details = append(details, task.Project)
+	}
+	for _, tag := range task.Tags {
+		details = append(details, "#"+tag)
+	}
+	if due := formatDue(task.DueDate); due != "" {
+		details = append(details, "до "+due)
+	}
+	if len(details) > 0 {
+		row += "  · " + strings.Join(details, " · ")
+	}
+	return row
+}
+
+// inputLine показывает редактируемую строку с курсором в инверсных цветах;
+// переводы строк в описании отображаются как ↵
+func (m *tuiModel) inputLine(label string, width int) string {
+	before := strings.ReplaceAll(string(m.input[:m.inputPos]), "\n", "↵")
+	at, after := " ", ""
+	if m.inputPos < len(m.input) {
+		at = strings.ReplaceAll(string(m.input[m.inputPos]), "\n", "↵")
+		after = strings.ReplaceAll(string(m.input[m.inputPos+1:]), "\n", "↵")
+	}
+	// Если строка не помещается, показываем ее хвост вокруг курсора
+	room := width - utf8.RuneCountInString(label) - 1
+	if tail := []rune(before); room > 0 && len(tail) > room {
+		before = "…" + string(tail[len(tail)-room+1:])
+	}
+	return label + before + styleReverse + at + styleReset + after
+}
+
+// render рисует экран заданного размера; строки разделяются \r\n, как требует сырой режим
+func (m *tuiModel) render(width, height int) string {
+	width = max(width, 20)
+	height = max(height, 8)
+
+	done := 0
+	for _, task := range m.tasks {
+		if task.Completed {
+			done++
+		}
+	}
+	state := "○ нет связи с сервером"
+	if m.live {
+		state = "● обновляется"
+	}
+	header := fmt.Sprintf("todo — задач: %d, выполнено: %d", len(m.tasks), done)
+	if m.filter != "" {
+		header += fmt.Sprintf(", по фильтру: %d", len(m.visible))
+	}
+	gap := width - utf8.RuneCountInString(header) - utf8.RuneCountInString(state)
+	lines := []string{styleReverse + fit(header+strings.Repeat(" ", max(gap, 1))+state, width) + styleReset}
+
+	// Внизу — описание выбранной задачи (до 3 строк) и строка ввода или подсказки
+	listHeight := height - 6
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+listHeight {
+		m.offset = m.cursor - listHeight + 1
+	}
+	for i := m.offset; i < m.offset+listHeight; i++ {
+		switch {
+		case i >= len(m.visible):
+			if i == 0 {
+				lines = append(lines, styleDim+fit("  Задач нет", width)+styleReset)
+			} else {
+				lines = append(lines, "")
+			}
+		case i == m.cursor:
+			lines = append(lines, styleReverse+fit(taskRow(m.visible[i]), width)+styleReset)
+		default:
+			lines = append(lines, fit(taskRow(m.visible[i]), width))
+		}
+	}
+
+	lines = append(lines, styleDim+strings.Repeat("─", width)+styleReset)
+	description := []string{}
+	if task := m.selected(); task != nil && task.Description != "" {
+		description = strings.Split(task.Description, "\n")
+	}
+	for i := 0; i < 3; i++ {
+		if i < len(description) {
+			lines = append(lines, fit(description[i], width))
+		} else {
+			lines = append(lines, "")
+		}
+	}
+
+	switch m.mode {
+	case modeFilter:
+		lines = append(lines, m.inputLine("Фильтр: ", width))
+	case modeEditTitle:
+		lines = append(lines, m.inputLine("Заголовок: ", width))
+	case modeEditDescription:
+		lines = append(lines, m.inputLine("Описание: ", width))
+	default:
+		help := "↑↓ выбор  пробел выполнено  e заголовок  d описание  / фильтр  r обновить  q выход"
+		if m.status != "" {
+			help = m.status
+		}
+		lines = append(lines, styleDim+fit(help, width)+styleReset)
+	}
+
+	return strings.Join(lines, "\x1b[K\r\n") + "\x1b[K"
+}
+
+// tuiMessage — сообщение от фоновой подписки на изменения
+type tuiMessage struct {
+	loaded bool // задачи загружены заново
+	tasks  []*models.Task
+	event  *models.TaskEvent
+	err    error // поток изменений оборвался
+}
+
+// handleMessage применяет сообщение от подписки
+func (m *tuiModel) handleMessage(msg tuiMessage) {
+	switch {
+	case msg.err != nil:
+		m.live = false
+		m.status = "Нет связи с сервером: " + errorText(msg.err)
+	case msg.loaded:
+		m.live = true
+		m.status = ""
+		m.setTasks(msg.tasks)
+	case msg.event != nil:
+		m.applyEvent(*msg.event)
+	}
+}
+
+// watchTasks подписывается на изменения и после каждого подключения заново
+// загружает задачи; при обрыве переподключается с растущей паузой
+func watchTasks(ctx context.Context, c *client.Client, messages chan<- tuiMessage) {
+	send := func(msg tuiMessage) bool {
+		select {
+		case messages <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	delay := time.Second
+	for {
+		err := watchOnce(ctx, c, func(tasks []*models.Task) {
+			delay = time.Second
+			send(tuiMessage{loaded: true, tasks: tasks})
+		}, func(event models.TaskEvent) {
+			send(tuiMessage{event: &event})
+		})
+		if ctx.Err() != nil || !send(tuiMessage{err: err}) {
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+// watchOnce подписывается на события и передает loaded задачи, загруженные после
+// подтверждения подписки: так изменения между загрузкой и подпиской не теряются.
+// Возвращается, когда поток оборвался или отменен ctx.
+func watchOnce(ctx context.Context, c *client.Client, loaded func([]*models.Task), handle func(models.TaskEvent)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := c.SubscribeContext(ctx)
+	if err != nil {
+		return err
+	}
+	tasks, err := c.GetAllTasksContext(ctx)
+	if err != nil {
+		return err
+	}
+	loaded(tasks)
+	for event := range events {
+		handle(event)
+	}
+	return errors.New("сервер закрыл поток событий")
+}
+
+func setupTUI(fs *flag.FlagSet) func(ctx context.Context, a *app, args []string) error {
+	return func(ctx context.Context, a *app, args []string) error {
+		if len(args) > 0 {
+			return newUsageError("команда tui не принимает аргументов")
+		}
+		return runTUI(ctx, a.client, a.server, os.Stdin, a.out)
+	}
+}
+
+// runTUI показывает интерфейс на альтернативном экране терминала, пока пользователь не выйдет
+func runTUI(ctx context.Context, c *client.Client, server string, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	state, err := makeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("не удалось перевести терминал в интерактивный режим: %w", err)
+	}
+	defer restoreTerminal(fd, state)
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l\x1b[2J")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			select {
+			case keys <- append([]byte(nil), buf[:n]...):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	messages := make(chan tuiMessage)
+	go watchTasks(ctx, c, messages)
+
+	resize := make(chan os.Signal, 1)
+	if sig := resizeSignal(); sig != nil {
+		signal.Notify(resize, sig)
+		defer signal.Stop(resize)
+	}
+
+	model := &tuiModel{status: "Подключение к " + server + "…"}
+	for {
+		width, height, err := terminalSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		fmt.Fprint(out, "\x1b[H"+model.render(width, height))
+
+		select {
+		case data, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range decodeKeys(data) {
+				action := model.handleKey(k)
+				switch action.kind {
+				case actionQuit:
+					return nil
+				case actionReload:
+					tasks, err := c.GetAllTasksContext(ctx)
+					if err != nil {
+						model.status = errorText(err)
+						continue
+					}
+					model.setTasks(tasks)
+				case actionUpdate:
+					task, err := updateTask(ctx, c, action.id, action.req)
+					if err != nil {
+						model.status = errorText(err)
+						continue
+					}
+					// Событие с сервера придет позже; применяем ответ сразу, чтобы не ждать
+					model.applyEvent(models.TaskEvent{Type: models.TaskEventUpdated, Task: task})
+				}
+			}
+		case msg := <-messages:
+			model.handleMessage(msg)
+		case <-resize:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo-api/models"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	keys := decodeKeys([]byte("j\x1b[Aя\r\x7f\x1b[3~\x1bOF\x03\x1b"))
+	expected := []key{
+		{code: keyRune, r: 'j'}, {code: keyUp}, {code: keyRune, r: 'я'}, {code: keyEnter},
+		{code: keyBackspace}, {code: keyDelete}, {code: keyEnd}, {code: keyCtrlC}, {code: keyEsc},
+	}
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("Ожидалось %v, получено %v", expected, keys)
+	}
+}
+
+// typeKeys передает модели строку посимвольно и возвращает последнее действие
+func typeKeys(m *tuiModel, text string) tuiAction {
+	var action tuiAction
+	for _, k := range decodeKeys([]byte(text)) {
+		action = m.handleKey(k)
+	}
+	return action
+}
+
+func testModel() *tuiModel {
+	m := &tuiModel{}
+	m.setTasks([]*models.Task{
+		{ID: 3, Title: "Позвонить маме", Tags: []string{"семья"}},
+		{ID: 1, Title: "Купить молоко", Description: "2 литра\nобезжиренное", Project: "Дом"},
+		{ID: 2, Title: "Отчет", Completed: true},
+	})
+	return m
+}
+
+func TestTUIModel_ToggleAndEdit(t *testing.T) {
+	m := testModel()
+
+	action := typeKeys(m, "j ")
+	if action.kind != actionUpdate || action.id != 2 || action.req.Completed || action.req.Title != "Отчет" {
+		t.Errorf("Пробел должен снимать отметку со второй задачи: %+v", action)
+	}
+
+	action = typeKeys(m, "ke\x7f\x7f\x7f\x7f\x7f\x7fкефир\r")
+	if action.kind != actionUpdate || action.id != 1 || action.req.Title != "Купить кефир" || action.req.Project != "Дом" {
+		t.Errorf("Ожидалось сохранение заголовка с прежними полями: %+v", action)
+	}
+
+	action = typeKeys(m, "d\x1b[H\x1b[3~\x1b[3~\x1b")
+	if action.kind != actionNone || m.mode != modeList {
+		t.Errorf("Esc должен отменять редактирование: %+v", action)
+	}
+
+	if action = typeKeys(m, "e\x01\x05\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\r"); action.kind != actionNone || m.status == "" {
+		t.Errorf("Пустой заголовок не должен сохраняться: %+v", action)
+	}
+
+	if action = typeKeys(m, "q"); action.kind != actionQuit {
+		t.Errorf("q должен завершать работу")
+	}
+}
+
+func TestTUIModel_Filter(t *testing.T) {
+	m := testModel()
+	typeKeys(m, "G")
+
+	typeKeys(m, "/СЕМЬ")
+	if len(m.visible) != 1 || m.selected().ID != 3 {
+		t.Fatalf("Фильтр должен искать по тегам без учета регистра: %v", m.visible)
+	}
+	typeKeys(m, "\r")
+	if m.mode != modeList || m.filter != "СЕМЬ" {
+		t.Errorf("Enter должен оставлять фильтр")
+	}
+
+	typeKeys(m, "\x1b")
+	if len(m.visible) != 3 || m.selected().ID != 3 {
+		t.Errorf("Esc должен снимать фильтр и сохранять курсор на задаче: %v", m.selected())
+	}
+}
+
+func TestTUIModel_ApplyEvent(t *testing.T) {
+	m := testModel()
+	typeKeys(m, "jj")
+
+	m.applyEvent(models.TaskEvent{Type: models.TaskEventCreated, Task: &models.Task{ID: 0, Title: "Первая"}})
+	m.applyEvent(models.TaskEvent{Type: models.TaskEventUpdated, Task: &models.Task{ID: 1, Title: "Купить кефир"}})
+	if m.selected().ID != 3 || m.tasks[0].ID != 0 || m.tasks[1].Title != "Купить кефир" {
+		t.Errorf("События должны сохранять порядок и курсор: %v", m.tasks)
+	}
+
+	m.applyEvent(models.TaskEvent{Type: models.TaskEventDeleted, Task: &models.Task{ID: 3}})
+	if len(m.tasks) != 3 || m.selected().ID != 2 {
+		t.Errorf("После удаления выбранной задачи курсор должен остаться в списке: %v", m.selected())
+	}
+
+	m.handleMessage(tuiMessage{err: fmt.Errorf("обрыв")})
+	if m.live || !strings.Contains(m.render(80, 24), "обрыв") {
+		t.Errorf("Обрыв связи должен отображаться в строке состояния")
+	}
+}
+
+func TestTUIModel_Render(t *testing.T) {
+	m := testModel()
+	screen := m.render(60, 12)
+
+	lines := strings.Split(screen, "\r\n")
+	if len(lines) != 12 {
+		t.Fatalf("Ожидалось 12 строк, получено %d", len(lines))
+	}
+	for _, expected := range []string{"задач: 3, выполнено: 1", "[ ]   1  Купить молоко  · Дом", "[x]   2  Отчет", "2 литра"} {
+		if !strings.Contains(screen, expected) {
+			t.Errorf("На экране нет %q:\n%s", expected, screen)
+		}
+	}
+
+	// Курсор прокручивает список, когда уходит за нижний край
+	typeKeys(m, "jj")
+	if screen := m.render(60, 8); !strings.Contains(screen, "Позвонить маме") || strings.Contains(screen, "Купить молоко") {
+		t.Errorf("Список должен прокручиваться за курсором:\n%s", screen)
+	}
+}
+
+func TestWatchOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "требуется авторизация", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/tasks" {
+			fmt.Fprint(w, `[{"id": 1, "title": "Купить молоко"}]`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": subscribed\n\n: keep-alive\n\n")
+		fmt.Fprint(w, "event: updated\nid: 7\ndata: {\"seq\":7,\"type\":\"updated\",\"task\":{\"id\":1,\"title\":\"Купить кефир\"}}\n\n")
+	}))
+	defer server.Close()
+
+	var log []string
+	c := newClient(Config{Server: server.URL, Token: "secret"}, io.Discard)
+	err := watchOnce(context.Background(), c, func(tasks []*models.Task) {
+		log = append(log, fmt.Sprintf("loaded %d", len(tasks)))
+	}, func(event models.TaskEvent) {
+		log = append(log, fmt.Sprintf("%d %s %s", event.Seq, event.Type, event.Task.Title))
+	})
+	if err == nil || strings.Join(log, "; ") != "loaded 1; 7 updated Купить кефир" {
+		t.Errorf("Неверная обработка потока: %v %q", err, log)
+	}
+
+	c = newClient(Config{Server: server.URL}, io.Discard)
+	err = watchOnce(context.Background(), c, func([]*models.Task) {}, func(models.TaskEvent) {})
+	if errorText(err) != "требуется авторизация (401)" {
+		t.Errorf("Ожидалась ошибка 401, получено %v", err)
+	}
+}
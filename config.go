@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config — настройки сервера. Источники применяются по порядку, каждый следующий
+// переопределяет предыдущий: значения по умолчанию, файл (YAML или TOML), переменные
+// окружения TODO_*, флаги командной строки.
+type Config struct {
+	HTTP    HTTPConfig    `yaml:"http" toml:"http"`
+	GRPC    GRPCConfig    `yaml:"grpc" toml:"grpc"`
+	RPC     RPCConfig     `yaml:"rpc" toml:"rpc"`
+	Storage StorageConfig `yaml:"storage" toml:"storage"`
+	CORS    CORSConfig    `yaml:"cors" toml:"cors"`
+	Log     LogConfig     `yaml:"log" toml:"log"`
+	OpenAPI OpenAPIConfig `yaml:"openapi" toml:"openapi"`
+	Limits  LimitsConfig  `yaml:"limits" toml:"limits"`
+	Tracing TracingConfig `yaml:"tracing" toml:"tracing"`
+}
+
+// HTTPConfig — адрес и тайм-ауты HTTP сервера
+type HTTPConfig struct {
+	Addr              string        `yaml:"addr" toml:"addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout — сколько при остановке ждать начатых запросов HTTP, gRPC и JSON-RPC
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+// GRPCConfig — адрес gRPC сервера; пустой адрес отключает gRPC
+type GRPCConfig struct {
+	Addr string `yaml:"addr" toml:"addr"`
+}
+
+// RPCConfig — Unix сокет JSON-RPC; пустой путь отключает сокет
+type RPCConfig struct {
+	Socket string `yaml:"socket" toml:"socket"`
+}
+
+// StorageConfig — хранилище данных. Задачи хранятся в памяти (backend memory),
+// вложения и сохраненные представления — в каталоге Path.
+type StorageConfig struct {
+	Backend string `yaml:"backend" toml:"backend"`
+	Path    string `yaml:"path" toml:"path"`
+}
+
+// CORSConfig — источники, которым браузер разрешает запросы к API; "*" — любым
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+}
+
+// LogConfig — уровень журнала: debug, info, warn или error
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+// OpenAPIConfig — режим проверки запросов и ответов по спецификации
+type OpenAPIConfig struct {
+	Validation string `yaml:"validation" toml:"validation"`
+}
+
+// LimitsConfig — ограничения запросов
+type LimitsConfig struct {
+	GraphQLMaxDepth      int           `yaml:"graphql_max_depth" toml:"graphql_max_depth"`
+	GraphQLMaxComplexity int           `yaml:"graphql_max_complexity" toml:"graphql_max_complexity"`
+	IdempotencyTTL       time.Duration `yaml:"idempotency_ttl" toml:"idempotency_ttl"`
+}
+
+// TracingConfig — куда записывать трассировку: off, stdout или file (в файл Path)
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	Path     string `yaml:"path" toml:"path"`
+}
+
+// DefaultConfig возвращает настройки по умолчанию — те, с которыми сервер работал до
+// появления конфигурации. Тайм-аут записи выключен: потоки событий открыты долго.
+func DefaultConfig() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		GRPC:    GRPCConfig{Addr: ":9090"},
+		Storage: StorageConfig{Backend: "memory", Path: "data"},
+		CORS:    CORSConfig{AllowedOrigins: []string{"*"}},
+		Log:     LogConfig{Level: "info"},
+		OpenAPI: OpenAPIConfig{Validation: string(OpenAPIValidationOff)},
+		Limits: LimitsConfig{
+			GraphQLMaxDepth:      DefaultGraphQLMaxDepth,
+			GraphQLMaxComplexity: DefaultGraphQLMaxComplexity,
+			IdempotencyTTL:       DefaultIdempotencyTTL,
+		},
+		Tracing: TracingConfig{Exporter: string(TracingExporterOff), Path: "traces.jsonl"},
+	}
+}
+
+// configSetting — одна настройка: ключ в файле, из которого выводятся имя переменной
+// окружения (http.addr → TODO_HTTP_ADDR) и флага (--http-addr)
+type configSetting struct {
+	key   string
+	usage string
+	// aliases — прежние имена переменных окружения, они действуют, если основная не задана
+	aliases []string
+	// reloadable — настройку можно применить по SIGHUP без перезапуска
+	reloadable bool
+	field      func(c *Config) any
+}
+
+var configSettings = []configSetting{
+	{key: "http.addr", usage: "адрес HTTP сервера", field: func(c *Config) any { return &c.HTTP.Addr }},
+	{key: "http.read_header_timeout", usage: "тайм-аут чтения заголовков запроса", field: func(c *Config) any { return &c.HTTP.ReadHeaderTimeout }},
+	{key: "http.read_timeout", usage: "тайм-аут чтения запроса (0 — без ограничения)", field: func(c *Config) any { return &c.HTTP.ReadTimeout }},
+	{key: "http.write_timeout", usage: "тайм-аут записи ответа (0 — без ограничения)", field: func(c *Config) any { return &c.HTTP.WriteTimeout }},
+	{key: "http.idle_timeout", usage: "тайм-аут простоя keep-alive соединения", field: func(c *Config) any { return &c.HTTP.IdleTimeout }},
+	{key: "http.shutdown_timeout", usage: "сколько при остановке ждать начатых запросов", field: func(c *Config) any { return &c.HTTP.ShutdownTimeout }},
+	{key: "grpc.addr", usage: "адрес gRPC сервера (пусто — gRPC выключен)", field: func(c *Config) any { return &c.GRPC.Addr }},
+	{key: "rpc.socket", usage: "Unix сокет JSON-RPC (пусто — выключен)", aliases: []string{"RPC_SOCKET"}, field: func(c *Config) any { return &c.RPC.Socket }},
+	{key: "storage.backend", usage: "хранилище задач: memory", field: func(c *Config) any { return &c.Storage.Backend }},
+	{key: "storage.path", usage: "каталог для вложений и представлений", field: func(c *Config) any { return &c.Storage.Path }},
+	{key: "cors.allowed_origins", usage: "источники CORS через запятую (* — любые)", reloadable: true, field: func(c *Config) any { return &c.CORS.AllowedOrigins }},
+	{key: "log.level", usage: "уровень журнала: debug, info, warn, error", reloadable: true, field: func(c *Config) any { return &c.Log.Level }},
+	{key: "openapi.validation", usage: "проверка по OpenAPI: off, requests, strict", aliases: []string{"OPENAPI_VALIDATION"}, field: func(c *Config) any { return &c.OpenAPI.Validation }},
+	{key: "limits.graphql_max_depth", usage: "максимальная глубина GraphQL запроса (0 — без ограничения)", field: func(c *Config) any { return &c.Limits.GraphQLMaxDepth }},
+	{key: "limits.graphql_max_complexity", usage: "максимальная сложность GraphQL запроса (0 — без ограничения)", field: func(c *Config) any { return &c.Limits.GraphQLMaxComplexity }},
+	{key: "limits.idempotency_ttl", usage: "сколько хранится ответ на запрос с Idempotency-Key", field: func(c *Config) any { return &c.Limits.IdempotencyTTL }},
+	{key: "tracing.exporter", usage: "экспорт трассировки: off, stdout, file", field: func(c *Config) any { return &c.Tracing.Exporter }},
+	{key: "tracing.path", usage: "файл трассировки для tracing.exporter=file", field: func(c *Config) any { return &c.Tracing.Path }},
+}
+
+func (s configSetting) envName() string {
+	return "TODO_" + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+func (s configSetting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// set разбирает значение из переменной окружения или флага
+func (s configSetting) set(c *Config, value string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("ожидалось целое число, получено %q", value)
+		}
+		*field = n
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("ожидалась длительность вида 30s или 5m, получено %q", value)
+		}
+		*field = d
+	case *[]string:
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	}
+	return nil
+}
+
+// ConfigSource — откуда собирается конфигурация: файл, окружение и флаги командной строки.
+// Load можно вызывать повторно, чтобы перечитать файл и окружение при перезагрузке.
+type ConfigSource struct {
+	// Path — файл конфигурации (--config или TODO_CONFIG); пусто — без файла
+	Path string
+	// PrintConfig — вывести итоговую конфигурацию и завершиться (--print-config)
+	PrintConfig bool
+
+	getenv func(string) string
+	flags  []configFlag
+}
+
+// configFlag — значение флага в порядке командной строки
+type configFlag struct {
+	setting configSetting
+	value   string
+}
+
+// ParseConfigFlags разбирает флаги командной строки. Справку и ошибки разбора флаги
+// выводят в output; для -h возвращается flag.ErrHelp.
+func ParseConfigFlags(args []string, getenv func(string) string, output io.Writer) (*ConfigSource, error) {
+	source := &ConfigSource{Path: getenv("TODO_CONFIG"), getenv: getenv}
+
+	fs := flag.NewFlagSet("todo-api", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&source.Path, "config", source.Path, "файл конфигурации YAML или TOML (TODO_CONFIG)")
+	fs.BoolVar(&source.PrintConfig, "print-config", false, "вывести итоговую конфигурацию в YAML и завершиться")
+	for _, setting := range configSettings {
+		fs.Func(setting.flagName(), setting.usage+" ("+setting.envName()+")", func(value string) error {
+			source.flags = append(source.flags, configFlag{setting: setting, value: value})
+			return nil
+		})
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: todo-api [флаги]\n       todo-api mcp\n\nФлаги:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("неизвестный аргумент %q", fs.Arg(0))
+	}
+	return source, nil
+}
+
+// Load собирает конфигурацию из всех источников и проверяет ее
+func (s *ConfigSource) Load() (*Config, error) {
+	config := DefaultConfig()
+	if s.Path != "" {
+		if err := config.loadFile(s.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, setting := range configSettings {
+		for _, name := range append([]string{setting.envName()}, setting.aliases...) {
+			if value := s.getenv(name); value != "" {
+				if err := setting.set(config, value); err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				break
+			}
+		}
+	}
+	for _, f := range s.flags {
+		if err := f.setting.set(config, f.value); err != nil {
+			return nil, fmt.Errorf("--%s: %w", f.setting.flagName(), err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadFile читает файл конфигурации; формат определяется по расширению.
+// Незаданные в файле настройки сохраняют прежние значения, неизвестные ключи — ошибка.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать файл конфигурации: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: неизвестный ключ %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("%s: неизвестный формат файла конфигурации %q, ожидался .yaml, .yml или .toml", path, ext)
+	}
+	return nil
+}
+
+// Validate проверяет все настройки и возвращает все найденные ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	check("http.addr", validateListenAddr(c.HTTP.Addr))
+	if c.GRPC.Addr != "" {
+		check("grpc.addr", validateListenAddr(c.GRPC.Addr))
+	}
+	if c.HTTP.ReadHeaderTimeout < 0 || c.HTTP.ReadTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 {
+		check("http", errors.New("тайм-ауты не могут быть отрицательными"))
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		check("http.shutdown_timeout", errors.New("должно быть больше нуля"))
+	}
+	if c.Storage.Backend != "memory" {
+		check("storage.backend", fmt.Errorf("неизвестное хранилище %q, поддерживается memory", c.Storage.Backend))
+	}
+	if c.Storage.Path == "" {
+		check("storage.path", errors.New("путь не может быть пустым"))
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		check("cors.allowed_origins", validateOrigin(origin))
+	}
+	if _, err := parseLogLevel(c.Log.Level); err != nil {
+		check("log.level", err)
+	}
+	if _, err := ParseOpenAPIValidation(c.OpenAPI.Validation); err != nil {
+		check("openapi.validation", err)
+	}
+	if c.Limits.GraphQLMaxDepth < 0 || c.Limits.GraphQLMaxComplexity < 0 {
+		check("limits", errors.New("ограничения GraphQL не могут быть отрицательными"))
+	}
+	if c.Limits.IdempotencyTTL <= 0 {
+		check("limits.idempotency_ttl", errors.New("должно быть больше нуля"))
+	}
+	if exporter, err := ParseTracingExporter(c.Tracing.Exporter); err != nil {
+		check("tracing.exporter", err)
+	} else if exporter == TracingExporterFile && c.Tracing.Path == "" {
+		check("tracing.path", errors.New("путь не может быть пустым при tracing.exporter=file"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateListenAddr(addr string) error {
+	if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+		return fmt.Errorf("ожидался адрес вида :8080 или 127.0.0.1:8080, получено %q", addr)
+	}
+	return nil
+}
+
+// validateOrigin проверяет источник CORS: "*" или схема с хостом. Заголовок Origin
+// браузера не содержит пути, даже "/", поэтому источник с путем никогда бы не совпал.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil ||
+		u.Path != "" || u.RawQuery != "" || u.ForceQuery || u.Fragment != "" {
+		return fmt.Errorf("ожидался источник вида https://example.com, получено %q", origin)
+	}
+	return nil
+}
+
+// parseLogLevel разбирает уровень журнала без учета регистра
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error":
+		l.UnmarshalText([]byte(level))
+		return l, nil
+	}
+	return l, fmt.Errorf("уровень должен быть debug, info, warn или error, получено %q", level)
+}
+
+// WriteYAML выводит конфигурацию в формате файла конфигурации
+func (c *Config) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// ChangedSettings возвращает ключи настроек, значения которых в next отличаются:
+// отдельно те, что применяются на лету, и те, что требуют перезапуска
+func (c *Config) ChangedSettings(next *Config) (reloadable, restart []string) {
+	for _, setting := range configSettings {
+		if reflect.DeepEqual(setting.field(c), setting.field(next)) {
+			continue
+		}
+		if setting.reloadable {
+			reloadable = append(reloadable, setting.key)
+		} else {
+			restart = append(restart, setting.key)
+		}
+	}
+	return reloadable, restart
+}
+
+// ReloadOnSignal перечитывает конфигурацию при каждом сигнале из signals. Настройки,
+// которые можно применить на лету, передаются в apply; об остальных изменениях пишется
+// в журнал — они вступят в силу после перезапуска. Неверная конфигурация не применяется.
+// Возвращается, когда канал signals закрыт.
+func (s *ConfigSource) ReloadOnSignal(signals <-chan os.Signal, current *Config, apply func(*Config)) {
+	running := *current
+	for range signals {
+		next, err := s.Load()
+		if err != nil {
+			slog.Error("Конфигурация не перезагружена", "error", err)
+			continue
+		}
+
+		reloadable, restart := running.ChangedSettings(next)
+		if len(restart) > 0 {
+			slog.Warn("Изменения вступят в силу после перезапуска", "settings", strings.Join(restart, ", "))
+		}
+		if len(reloadable) == 0 {
+			continue
+		}
+		for _, setting := range configSettings {
+			if setting.reloadable {
+				reflect.ValueOf(setting.field(&running)).Elem().Set(reflect.ValueOf(setting.field(next)).Elem())
+			}
+		}
+		apply(&running)
+		slog.Info("Конфигурация перезагружена", "settings", strings.Join(reloadable, ", "))
+	}
+}
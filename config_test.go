@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// loadConfig собирает конфигурацию из флагов и переменных окружения env
+func loadConfig(t *testing.T, env map[string]string, args ...string) (*Config, error) {
+	t.Helper()
+	source, err := ParseConfigFlags(args, func(name string) string { return env[name] }, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	return source.Load()
+}
+
+// writeConfigFile создает файл конфигурации во временном каталоге
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	config, err := loadConfig(t, nil)
+	if err != nil {
+		t.Fatalf("Конфигурация по умолчанию должна быть верной: %v", err)
+	}
+	if !reflect.DeepEqual(config, DefaultConfig()) {
+		t.Errorf("Без источников ожидались значения по умолчанию: %+v", config)
+	}
+	if config.HTTP.Addr != ":8080" || config.GRPC.Addr != ":9090" || config.Limits.GraphQLMaxDepth != DefaultGraphQLMaxDepth {
+		t.Errorf("Неверные значения по умолчанию: %+v", config)
+	}
+}
+
+func TestConfig_Precedence(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+http:
+  addr: ":7000"
+  read_timeout: 5s
+grpc:
+  addr: ":7001"
+log:
+  level: debug
+cors:
+  allowed_origins: [https://app.example.com]
+`,
+		"config.toml": `
+[http]
+addr = ":7000"
+read_timeout = "5s"
+
+[grpc]
+addr = ":7001"
+
+[log]
+level = "debug"
+
+[cors]
+allowed_origins = ["https://app.example.com"]
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, name, content)
+			env := map[string]string{
+				"TODO_CONFIG":    path,
+				"TODO_HTTP_ADDR": ":7100",
+				"TODO_LOG_LEVEL": "warn",
+			}
+			config, err := loadConfig(t, env, "--http-addr", ":7200")
+			if err != nil {
+				t.Fatalf("Ошибка загрузки: %v", err)
+			}
+
+			// Флаг важнее переменной окружения, переменная — важнее файла, файл — значений по умолчанию
+			if config.HTTP.Addr != ":7200" {
+				t.Errorf("http.addr: ожидалось значение флага, получено %q", config.HTTP.Addr)
+			}
+			if config.Log.Level != "warn" {
+				t.Errorf("log.level: ожидалось значение переменной окружения, получено %q", config.Log.Level)
+			}
+			if config.GRPC.Addr != ":7001" || config.HTTP.ReadTimeout != 5*time.Second || config.CORS.AllowedOrigins[0] != "https://app.example.com" {
+				t.Errorf("Ожидались значения из файла: %+v", config)
+			}
+			if config.HTTP.IdleTimeout != DefaultConfig().HTTP.IdleTimeout || config.Storage.Path != "data" {
+				t.Errorf("Незаданные в файле настройки должны сохранить значения по умолчанию: %+v", config)
+			}
+		})
+	}
+}
+
+func TestConfig_EnvAndFlags(t *testing.T) {
+	config, err := loadConfig(t, map[string]string{
+		"RPC_SOCKET":                    "/tmp/old.sock",
+		"OPENAPI_VALIDATION":            "strict",
+		"TODO_OPENAPI_VALIDATION":       "requests",
+		"TODO_CORS_ALLOWED_ORIGINS":     "https://a.example.com, https://b.example.com",
+		"TODO_LIMITS_GRAPHQL_MAX_DEPTH": "4",
+		"TODO_LIMITS_IDEMPOTENCY_TTL":   "1h",
+		"TODO_HTTP_READ_HEADER_TIMEOUT": "2s",
+	}, "--grpc-addr=", "--storage-path", "/var/lib/todo")
+	if err != nil {
+		t.Fatalf("Ошибка загрузки: %v", err)
+	}
+
+	// Прежние имена переменных действуют, пока не задана новая
+	if config.RPC.Socket != "/tmp/old.sock" || config.OpenAPI.Validation != "requests" {
+		t.Errorf("Неверная обработка прежних имен переменных: %+v", config)
+	}
+	if !reflect.DeepEqual(config.CORS.AllowedOrigins, []string{"https://a.example.com", "https://b.example.com"}) {
+		t.Errorf("Неверный список источников: %q", config.CORS.AllowedOrigins)
+	}
+	if config.Limits.GraphQLMaxDepth != 4 || config.Limits.IdempotencyTTL != time.Hour || config.HTTP.ReadHeaderTimeout != 2*time.Second {
+		t.Errorf("Неверные значения из окружения: %+v", config)
+	}
+	if config.GRPC.Addr != "" || config.Storage.Path != "/var/lib/todo" {
+		t.Errorf("Неверные значения из флагов: %+v", config)
+	}
+}
+
+func TestValidateOrigin(t *testing.T) {
+	for _, origin := range []string{"*", "https://app.example.com", "http://localhost:3000", "https://[::1]:8443"} {
+		if err := validateOrigin(origin); err != nil {
+			t.Errorf("validateOrigin(%q): неожиданная ошибка %v", origin, err)
+		}
+	}
+	// Заголовок Origin не содержит пути, поэтому такие источники никогда бы не совпали
+	for _, origin := range []string{"https://app.example.com/", "https://app.example.com/app", "https://app.example.com?x=1", "https://user@app.example.com", "app.example.com", "ftp://app.example.com"} {
+		if err := validateOrigin(origin); err == nil {
+			t.Errorf("validateOrigin(%q): ожидалась ошибка", origin)
+		}
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		args    []string
+		want    []string
+	}{
+		{name: "неизвестный ключ YAML", file: "c.yaml", content: "http:\n  port: 8080\n", want: []string{"port"}},
+		{name: "неизвестный ключ TOML", file: "c.toml", content: "[http]\nport = 8080\n", want: []string{"http.port"}},
+		{name: "неизвестный формат", file: "c.json", content: "{}", want: []string{"неизвестный формат"}},
+		{name: "неверное число", env: map[string]string{"TODO_LIMITS_GRAPHQL_MAX_DEPTH": "много"}, want: []string{"TODO_LIMITS_GRAPHQL_MAX_DEPTH", "целое число"}},
+		{name: "неверная длительность", args: []string{"--http-read-timeout", "5"}, want: []string{"--http-read-timeout", "длительность"}},
+		{name: "файл трассировки без пути", args: []string{"--tracing-exporter", "file", "--tracing-path", ""}, want: []string{"tracing.path"}},
+		{name: "нет файла", env: map[string]string{"TODO_CONFIG": "/nonexistent/todo.yaml"}, want: []string{"не удалось прочитать"}},
+		{
+			name: "все ошибки проверки сразу",
+			env: map[string]string{
+				"TODO_HTTP_ADDR":              "8080",
+				"TODO_STORAGE_BACKEND":        "postgres",
+				"TODO_LOG_LEVEL":              "verbose",
+				"TODO_CORS_ALLOWED_ORIGINS":   "example.com",
+				"TODO_OPENAPI_VALIDATION":     "always",
+				"TODO_HTTP_IDLE_TIMEOUT":      "-1s",
+				"TODO_LIMITS_IDEMPOTENCY_TTL": "0s",
+				"TODO_HTTP_SHUTDOWN_TIMEOUT":  "0s",
+				"TODO_TRACING_EXPORTER":       "jaeger",
+			},
+			want: []string{"http.addr", "storage.backend", "log.level", "cors.allowed_origins", "openapi.validation", "http:", "limits.idempotency_ttl", "http.shutdown_timeout", "tracing.exporter"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			if tt.file != "" {
+				env = map[string]string{"TODO_CONFIG": writeConfigFile(t, tt.file, tt.content)}
+			}
+			_, err := loadConfig(t, env, tt.args...)
+			if err == nil {
+				t.Fatalf("Ожидалась ошибка")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Ошибка %q должна содержать %q", err, want)
+				}
+			}
+		})
+	}
+
+	if _, err := loadConfig(t, nil, "--port", "8080"); err == nil {
+		t.Errorf("Ожидалась ошибка неизвестного флага")
+	}
+	if _, err := loadConfig(t, nil, "serve"); err == nil {
+		t.Errorf("Ожидалась ошибка лишнего аргумента")
+	}
+	if _, err := loadConfig(t, nil, "-h"); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Ожидалась flag.ErrHelp, получено %v", err)
+	}
+}
+
+func TestConfig_PrintConfig(t *testing.T) {
+	source, err := ParseConfigFlags([]string{"--print-config", "--log-level", "error", "--cors-allowed-origins", "https://app.example.com"}, func(string) string { return "" }, io.Discard)
+	if err != nil || !source.PrintConfig {
+		t.Fatalf("Ожидался режим --print-config: %v", err)
+	}
+	config, err := source.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := config.WriteYAML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "read_header_timeout: 10s") {
+		t.Errorf("Длительности должны выводиться в читаемом виде:\n%s", buf.String())
+	}
+
+	// Выведенная конфигурация — готовый файл конфигурации
+	reloaded, err := loadConfig(t, map[string]string{"TODO_CONFIG": writeConfigFile(t, "printed.yaml", buf.String())})
+	if err != nil {
+		t.Fatalf("Выведенную конфигурацию не удалось загрузить: %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(reloaded, config) {
+		t.Errorf("Конфигурация изменилась после вывода и загрузки:\n%+v\n%+v", config, reloaded)
+	}
+}
+
+func TestConfig_ReloadOnSignal(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "log:\n  level: info\n")
+	source, err := ParseConfigFlags(nil, func(name string) string {
+		if name == "TODO_CONFIG" {
+			return path
+		}
+		return ""
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := source.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signals := make(chan os.Signal, 3)
+	applied := make(chan Config, 3)
+	done := make(chan struct{})
+	go func() {
+		source.ReloadOnSignal(signals, config, func(c *Config) { applied <- *c })
+		close(done)
+	}()
+
+	// Уровень журнала применяется на лету, адрес сервера — только после перезапуска
+	os.WriteFile(path, []byte("log:\n  level: debug\nhttp:\n  addr: \":9000\"\ncors:\n  allowed_origins: [https://app.example.com]\n"), 0o644)
+	signals <- syscall.SIGHUP
+	got := <-applied
+	if got.Log.Level != "debug" || got.CORS.AllowedOrigins[0] != "https://app.example.com" || got.HTTP.Addr != ":8080" {
+		t.Errorf("Неверно примененная конфигурация: %+v", got)
+	}
+
+	// Неверная конфигурация не применяется
+	os.WriteFile(path, []byte("log:\n  level: verbose\n"), 0o644)
+	signals <- syscall.SIGHUP
+	// Изменение только адреса не требует применения
+	os.WriteFile(path, []byte("log:\n  level: debug\nhttp:\n  addr: \":9001\"\ncors:\n  allowed_origins: [https://app.example.com]\n"), 0o644)
+	signals <- syscall.SIGHUP
+	close(signals)
+	<-done
+	if len(applied) != 0 {
+		t.Errorf("Конфигурация не должна была применяться: %+v", <-applied)
+	}
+	if config.Log.Level != "info" {
+		t.Errorf("Исходная конфигурация не должна меняться: %+v", config)
+	}
+}
+
+func TestConfig_ChangedSettings(t *testing.T) {
+	current, next := DefaultConfig(), DefaultConfig()
+	next.Log.Level = "debug"
+	next.HTTP.Addr = ":9000"
+	next.Limits.IdempotencyTTL = time.Hour
+
+	reloadable, restart := current.ChangedSettings(next)
+	if !reflect.DeepEqual(reloadable, []string{"log.level"}) {
+		t.Errorf("Неверные настройки для применения на лету: %v", reloadable)
+	}
+	if !reflect.DeepEqual(restart, []string{"http.addr", "limits.idempotency_ttl"}) {
+		t.Errorf("Неверные настройки, требующие перезапуска: %v", restart)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"sync/atomic"
+)
+
+// CORSPolicy разрешает браузерам запросы к API с заданных источников.
+// Список источников можно заменить на лету, например при перезагрузке конфигурации.
+type CORSPolicy struct {
+	origins atomic.Pointer[[]string]
+}
+
+// NewCORSPolicy создает политику; "*" в списке разрешает любой источник
+func NewCORSPolicy(origins []string) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.SetOrigins(origins)
+	return p
+}
+
+// SetOrigins заменяет список разрешенных источников
+func (p *CORSPolicy) SetOrigins(origins []string) {
+	origins = slices.Clone(origins)
+	p.origins.Store(&origins)
+}
+
+// allowOrigin возвращает значение Access-Control-Allow-Origin для источника запроса или ""
+func (p *CORSPolicy) allowOrigin(origin string) string {
+	origins := *p.origins.Load()
+	switch {
+	case slices.Contains(origins, "*"):
+		return "*"
+	case origin != "" && slices.Contains(origins, origin):
+		return origin
+	}
+	return ""
+}
+
+// Middleware добавляет заголовки CORS и отвечает на предварительные запросы
+func (p *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ответ зависит от Origin, если разрешены не все источники
+		allowed := p.allowOrigin(r.Header.Get("Origin"))
+		if allowed != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Idempotency-Key, traceparent, tracestate")
+		}
+
+		// Предварительный запрос CORS; обычный OPTIONS (например, от клиентов CalDAV) идет дальше
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSPolicy(t *testing.T) {
+	policy := NewCORSPolicy([]string{"https://app.example.com"})
+	handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	request := func(method, origin string, preflight bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/tasks", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "https://app.example.com", false)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Vary") != "Origin" || w.Code != http.StatusTeapot {
+		t.Errorf("Разрешенный источник: неверный ответ %d %v", w.Code, w.Header())
+	}
+	if w := request(http.MethodGet, "https://evil.example.com", false); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Чужой источник не должен получать заголовки CORS: %v", w.Header())
+	}
+	if w := request(http.MethodOptions, "https://app.example.com", true); w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("Предварительный запрос: неверный ответ %d %v", w.Code, w.Header())
+	}
+	// Обычный OPTIONS (например, от клиента CalDAV) доходит до обработчика
+	if w := request(http.MethodOptions, "", false); w.Code != http.StatusTeapot {
+		t.Errorf("OPTIONS без Access-Control-Request-Method должен дойти до обработчика, статус %d", w.Code)
+	}
+
+	// Список источников меняется на лету
+	policy.SetOrigins([]string{"*"})
+	if w := request(http.MethodGet, "https://evil.example.com", false); w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "" {
+		t.Errorf("С * разрешен любой источник: %v", w.Header())
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// eventBufferSize — сколько событий может накопиться у подписчика, прежде чем его отключат
+const eventBufferSize = 256
+
+// eventKeepAlive — интервал комментариев, которые не дают прокси закрыть простаивающий поток
+const eventKeepAlive = 30 * time.Second
+
+// eventHub рассылает события об изменении задач подписчикам
+type eventHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan TaskEvent]struct{}
+	seq         int64
+	closed      bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[chan TaskEvent]struct{})}
+}
+
+// subscribe возвращает канал событий и функцию отписки. Канал закрывается
+// после отписки или если подписчик не успевает читать события.
+func (h *eventHub) subscribe() (<-chan TaskEvent, func()) {
+	ch := make(chan TaskEvent, eventBufferSize)
+
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	h.subscribers[ch] = struct{}{}
+	h.mutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+			if _, ok := h.subscribers[ch]; ok {
+				delete(h.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+// publish нумерует и рассылает события. Подписчик с переполненным буфером
+// отключается, чтобы медленный клиент не задерживал изменения задач;
+// после переподключения он должен заново загрузить задачи.
+func (h *eventHub) publish(events []TaskEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, event := range events {
+		h.seq++
+		event.Seq = h.seq
+		for ch := range h.subscribers {
+			select {
+			case ch <- event:
+			default:
+				delete(h.subscribers, ch)
+				close(ch)
+			}
+		}
+	}
+}
+
+// close закрывает каналы всех подписчиков; новые подписки сразу получают закрытый канал
+func (h *eventHub) close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// recordLocked запоминает событие до конца операции; вызывающий должен удерживать мьютекс на запись
+func (ts *TaskService) recordLocked(kind string, task *Task) {
+	snapshot := *task
+	ts.pending = append(ts.pending, TaskEvent{Type: kind, Task: &snapshot, Time: time.Now()})
+}
+
+// publishLocked рассылает события завершенной операции и вызывает hooks удаленных задач
+// с контекстом операции
+func (ts *TaskService) publishLocked(ctx context.Context) {
+	for _, event := range ts.pending {
+		if event.Type != TaskEventDeleted {
+			continue
+		}
+		for _, hook := range ts.deleteHooks {
+			hook(ctx, event.Task.ID)
+		}
+	}
+	ts.events.publish(ts.pending)
+	ts.pending = nil
+}
+
+// Subscribe подписывает на события об изменении задач
+func (ts *TaskService) Subscribe(context.Context) (<-chan TaskEvent, func()) {
+	return ts.events.subscribe()
+}
+
+// Close завершает подписки на события: потоки GET /tasks/events, подписки GraphQL и
+// WatchTasks заканчиваются, как только прочитают накопленные события. Сами задачи
+// остаются доступны, чтобы выполняющиеся запросы могли завершиться.
+func (ts *TaskService) Close() {
+	ts.events.close()
+}
+
+// TaskEvents обрабатывает GET /tasks/events — поток событий об изменении задач
+// в формате Server-Sent Events. Каждое событие — строка `event: <тип>`, `id: <seq>`
+// и `data: <TaskEvent в JSON>`. Если клиент не успевает читать, поток закрывается.
+func (th *TaskHandler) TaskEvents(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.TaskEvents")
+	defer span.End()
+
+	subscriber, err := capability[TaskSubscriber](th.service, "Subscribe")
+	if err != nil {
+		http.Error(w, err.Error(), batchErrorStatus(err))
+		return
+	}
+	controller := http.NewResponseController(w)
+	events, cancel := subscriber.Subscribe(r.Context())
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// Первый комментарий сообщает клиенту, что подписка уже действует
+	fmt.Fprint(w, ": subscribed\n\n")
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", event.Type, strconv.FormatInt(event.Seq, 10), data)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nextEvent читает событие из канала или завершает тест по таймауту
+func nextEvent(t *testing.T, events <-chan TaskEvent) TaskEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Событие не пришло")
+		return TaskEvent{}
+	}
+}
+
+func TestTaskService_Subscribe(t *testing.T) {
+	service := NewTaskService()
+	events, cancel := service.Subscribe(t.Context())
+	defer cancel()
+
+	task := service.CreateTask(t.Context(), "Купить молоко", "")
+	service.UpdateTask(t.Context(), task.ID, "Купить кефир", "", true)
+	service.DeleteTask(t.Context(), task.ID)
+
+	for i, expected := range []string{TaskEventCreated, TaskEventUpdated, TaskEventDeleted} {
+		event := nextEvent(t, events)
+		if event.Type != expected || event.Seq != int64(i+1) || event.Task.ID != task.ID {
+			t.Errorf("Событие %d: ожидалось %s, получено %+v", i+1, expected, event)
+		}
+	}
+
+	// Откаченный атомарный пакет не порождает событий
+	service.ApplyBatch(t.Context(), []BatchOperation{{Op: BatchOpCreate, Title: "Новая"}, {Op: BatchOpDelete, ID: 99}}, true)
+	service.ImportTasks(t.Context(), []*Task{{Title: "Из файла"}}, ImportOptions{})
+	if event := nextEvent(t, events); event.Type != TaskEventCreated || event.Task.Title != "Из файла" {
+		t.Errorf("Ожидалось только событие импорта, получено %+v", event)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Errorf("После отписки канал должен быть закрыт")
+	}
+}
+
+func TestEventHub_DropsSlowSubscriber(t *testing.T) {
+	hub := newEventHub()
+	events, cancel := hub.subscribe()
+	defer cancel()
+
+	hub.publish(make([]TaskEvent, eventBufferSize+1))
+	received := 0
+	for range events {
+		received++
+	}
+	if received != eventBufferSize {
+		t.Errorf("Ожидалось %d событий до отключения, получено %d", eventBufferSize, received)
+	}
+}
+
+func TestEventHub_Close(t *testing.T) {
+	hub := newEventHub()
+	events, cancel := hub.subscribe()
+	defer cancel()
+
+	// Накопленные события дочитываются, затем канал закрыт
+	hub.publish([]TaskEvent{{Type: TaskEventCreated}})
+	hub.close()
+	if event := nextEvent(t, events); event.Type != TaskEventCreated {
+		t.Errorf("Ожидалось накопленное событие, получено %+v", event)
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("После close канал должен быть закрыт")
+	}
+
+	// Подписка после close сразу получает закрытый канал
+	late, lateCancel := hub.subscribe()
+	lateCancel()
+	if _, ok := <-late; ok {
+		t.Errorf("Подписка после close должна получить закрытый канал")
+	}
+	hub.publish([]TaskEvent{{Type: TaskEventDeleted}})
+}
+
+func TestTaskHandler_TaskEvents(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/tasks/events")
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Неверный ответ: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	// Комментарий ": subscribed" приходит, когда подписка уже действует
+	if line, _ := reader.ReadString('\n'); line != ": subscribed\n" {
+		t.Fatalf("Ожидался комментарий о подписке, получено %q", line)
+	}
+	reader.ReadString('\n')
+
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Ошибка чтения потока: %v", err)
+		}
+		if line == "\n" {
+			break
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	if len(lines) != 3 || lines[0] != "event: created" || lines[1] != "id: 1" {
+		t.Fatalf("Неверное событие: %q", lines)
+	}
+
+	var event TaskEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &event); err != nil || event.Task.Title != "Позвонить маме" {
+		t.Errorf("Неверные данные события: %v %+v", err, event)
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// completion — слово заголовка задачи в отсортированном списке автодополнения
+type completion struct {
+	word string
+	id   int
+}
+
+// addWord регистрирует вхождение слова в задачу; новые слова попадают в триграммный индекс
+func (si *SearchIndex) addWord(word string, id int, inTitle bool) {
+	if _, exists := si.words[word]; !exists {
+		for _, trigram := range trigramsOf(word) {
+			words, exists := si.trigrams[trigram]
+			if !exists {
+				words = make(map[string]struct{})
+				si.trigrams[trigram] = words
+			}
+			words[word] = struct{}{}
+		}
+	}
+	addPosting(si.words, word, id)
+
+	if !inTitle {
+		return
+	}
+	if _, exists := si.titleWords[word][id]; exists {
+		return
+	}
+	addPosting(si.titleWords, word, id)
+
+	entry := completion{word: word, id: id}
+	i := sort.Search(len(si.completions), func(i int) bool { return !completionLess(si.completions[i], entry) })
+	si.completions = append(si.completions, completion{})
+	copy(si.completions[i+1:], si.completions[i:])
+	si.completions[i] = entry
+}
+
+// removeWord отменяет addWord; слово удаляется из триграммного индекса, когда не осталось задач
+func (si *SearchIndex) removeWord(word string, id int, inTitle bool) {
+	removePosting(si.words, word, id)
+	if _, exists := si.words[word]; !exists {
+		for _, trigram := range trigramsOf(word) {
+			delete(si.trigrams[trigram], word)
+			if len(si.trigrams[trigram]) == 0 {
+				delete(si.trigrams, trigram)
+			}
+		}
+	}
+
+	if !inTitle {
+		return
+	}
+	if _, exists := si.titleWords[word][id]; !exists {
+		return
+	}
+	removePosting(si.titleWords, word, id)
+
+	entry := completion{word: word, id: id}
+	i := sort.Search(len(si.completions), func(i int) bool { return !completionLess(si.completions[i], entry) })
+	if i < len(si.completions) && si.completions[i] == entry {
+		si.completions = append(si.completions[:i], si.completions[i+1:]...)
+	}
+}
+
+func completionLess(a, b completion) bool {
+	if a.word != b.word {
+		return a.word < b.word
+	}
+	return a.id < b.id
+}
+
+// FuzzySearch ищет задачи по заголовкам с допуском опечаток.
+// Каждое слово запроса должно совпасть с каким-либо словом заголовка с точностью до maxEdits правок.
+func (si *SearchIndex) FuzzySearch(query string, limit int) []*SearchResult {
+	queryTokens := tokenize(query)
+	if len(queryTokens) == 0 {
+		return []*SearchResult{}
+	}
+
+	type fuzzyMatch struct {
+		score     float64
+		matched   int
+		positions map[int]bool
+	}
+	matches := make(map[int]*fuzzyMatch)
+
+	for qi, qt := range queryTokens {
+		// Для каждой задачи берем лучшее совпадение слова запроса
+		best := make(map[int]float64)
+		for word, similarity := range si.similarWords(qt.word) {
+			for id := range si.titleWords[word] {
+				if similarity > best[id] {
+					best[id] = similarity
+				}
+			}
+		}
+
+		for id, similarity := range best {
+			m, exists := matches[id]
+			if !exists {
+				if qi > 0 {
+					continue
+				}
+				m = &fuzzyMatch{positions: make(map[int]bool)}
+				matches[id] = m
+			}
+			if m.matched != qi {
+				continue
+			}
+			m.score += similarity
+			m.matched++
+
+			for pos, tok := range si.docs[id].fields[fieldTitle] {
+				if wordSimilarity(qt.word, tok.word) == similarity {
+					m.positions[pos] = true
+				}
+			}
+		}
+	}
+
+	results := make([]*SearchResult, 0)
+	for id, m := range matches {
+		if m.matched != len(queryTokens) {
+			continue
+		}
+		doc := si.docs[id]
+		results = append(results, &SearchResult{
+			Task:  doc.task,
+			Score: m.score,
+			Snippets: map[string]string{
+				"title": snippet(doc.task.Title, doc.fields[fieldTitle], m.positions, true),
+			},
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Suggest возвращает заголовки задач для автодополнения ввода и исправленный вариант запроса.
+// Последнее слово ввода считается незаконченным и сопоставляется как префикс.
+func (si *SearchIndex) Suggest(input string, limit int) *Suggestions {
+	suggestions := &Suggestions{Suggestions: []string{}}
+
+	tokens := tokenize(input)
+	if len(tokens) == 0 {
+		return suggestions
+	}
+	last := tokens[len(tokens)-1].word
+	complete := tokens[:len(tokens)-1]
+
+	// Двоичный поиск первого слова с нужным префиксом
+	start := sort.Search(len(si.completions), func(i int) bool { return si.completions[i].word >= last })
+	candidates := make(map[int]struct{})
+	for i := start; i < len(si.completions) && strings.HasPrefix(si.completions[i].word, last); i++ {
+		candidates[si.completions[i].id] = struct{}{}
+	}
+
+	normalized := strings.ToLower(strings.TrimSpace(input))
+	type candidate struct {
+		title  string
+		leads  bool
+		taskID int
+	}
+	var ranked []candidate
+	seen := make(map[string]bool)
+
+	for id := range candidates {
+		title := si.docs[id].task.Title
+		if seen[title] || !si.titleHasWords(id, complete) {
+			continue
+		}
+		seen[title] = true
+		ranked = append(ranked, candidate{
+			title:  title,
+			leads:  strings.HasPrefix(strings.ToLower(title), normalized),
+			taskID: id,
+		})
+	}
+
+	// Сначала заголовки, начинающиеся с ввода, затем более короткие
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.leads != b.leads {
+			return a.leads
+		}
+		if len(a.title) != len(b.title) {
+			return len(a.title) < len(b.title)
+		}
+		return a.taskID < b.taskID
+	})
+
+	for _, c := range ranked {
+		if limit > 0 && len(suggestions.Suggestions) >= limit {
+			break
+		}
+		suggestions.Suggestions = append(suggestions.Suggestions, c.title)
+	}
+
+	if len(suggestions.Suggestions) == 0 {
+		suggestions.DidYouMean = si.didYouMean(input, tokens)
+	}
+	return suggestions
+}
+
+// titleHasWords проверяет, что в заголовке задачи есть все перечисленные слова
+func (si *SearchIndex) titleHasWords(id int, tokens []token) bool {
+	for _, tok := range tokens {
+		if _, exists := si.titleWords[tok.word][id]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// didYouMean заменяет неизвестные слова запроса ближайшими словами из индекса.
+// Возвращает пустую строку, если исправлять нечего.
+func (si *SearchIndex) didYouMean(input string, tokens []token) string {
+	var b strings.Builder
+	cursor, changed := 0, false
+
+	for _, tok := range tokens {
+		b.WriteString(input[cursor:tok.start])
+		cursor = tok.end
+
+		if _, known := si.words[tok.word]; known {
+			b.WriteString(input[tok.start:tok.end])
+			continue
+		}
+
+		best, bestSimilarity := "", 0.0
+		for word, similarity := range si.similarWords(tok.word) {
+			if similarity > bestSimilarity ||
+				(similarity == bestSimilarity && len(si.words[word]) > len(si.words[best])) ||
+				(similarity == bestSimilarity && len(si.words[word]) == len(si.words[best]) && word < best) {
+				best, bestSimilarity = word, similarity
+			}
+		}
+
+		if best == "" {
+			b.WriteString(input[tok.start:tok.end])
+			continue
+		}
+		b.WriteString(best)
+		changed = true
+	}
+	b.WriteString(input[cursor:])
+
+	if !changed {
+		return ""
+	}
+	return b.String()
+}
+
+// similarWords находит слова индекса, отличающиеся от word не более чем на maxEdits правок,
+// и возвращает их сходство от 0 до 1
+func (si *SearchIndex) similarWords(word string) map[string]float64 {
+	trigrams := trigramsOf(word)
+	shared := make(map[string]int)
+	for _, trigram := range trigrams {
+		for candidate := range si.trigrams[trigram] {
+			shared[candidate]++
+		}
+	}
+
+	// Одна правка затрагивает не более четырех триграмм, поэтому слова
+	// с меньшим числом общих триграмм заведомо слишком далеки
+	required := max(1, len(trigrams)-4*maxEdits(len([]rune(word))))
+
+	similar := make(map[string]float64)
+	for candidate, count := range shared {
+		if count < required {
+			continue
+		}
+		if similarity := wordSimilarity(word, candidate); similarity > 0 {
+			similar[candidate] = similarity
+		}
+	}
+	return similar
+}
+
+// wordSimilarity возвращает 1 - d/len для слов на расстоянии d <= maxEdits и 0 для остальных
+func wordSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+
+	distance := editDistance(ra, rb)
+	if distance > maxEdits(min(len(ra), len(rb))) {
+		return 0
+	}
+	return 1 - float64(distance)/float64(longest)
+}
+
+// maxEdits определяет допустимое число опечаток в зависимости от длины слова
+func maxEdits(length int) int {
+	switch {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance вычисляет расстояние Дамерау-Левенштейна (с перестановкой соседних букв)
+func editDistance(a, b []rune) int {
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1)
+			}
+		}
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+
+	return prev[len(b)]
+}
+
+// trigramsOf разбивает слово на триграммы с двойными маркерами начала и конца,
+// чтобы даже у коротких слов с опечаткой оставались общие триграммы
+func trigramsOf(word string) []string {
+	runes := []rune("$$" + word + "$$")
+	trigrams := make([]string, 0, len(runes)-2)
+	for i := 0; i+3 <= len(runes); i++ {
+		trigrams = append(trigrams, string(runes[i:i+3]))
+	}
+	return trigrams
+}
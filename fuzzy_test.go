@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"молоко", "молоко", 0},
+		{"молоко", "малоко", 1},
+		{"купить", "кпуить", 1}, // перестановка соседних букв
+		{"кот", "кит", 1},
+		{"задача", "зд", 4},
+	}
+
+	for _, c := range cases {
+		if d := editDistance([]rune(c.a), []rune(c.b)); d != c.expected {
+			t.Errorf("editDistance(%q, %q): ожидалось %d, получено %d", c.a, c.b, c.expected, d)
+		}
+	}
+}
+
+func TestTaskService_FuzzySearchTasks(t *testing.T) {
+	service := NewTaskService()
+
+	service.CreateTask(t.Context(), "Купить молоко", "")
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+	service.CreateTask(t.Context(), "Купить хлеб", "")
+
+	results := service.FuzzySearchTasks(t.Context(), "кпуить малоко", 0)
+	if len(results) != 1 || results[0].Task.ID != 1 {
+		t.Fatalf("Ожидалась задача 1, получено %d результатов", len(results))
+	}
+
+	if results[0].Snippets["title"] != "<mark>Купить</mark> <mark>молоко</mark>" {
+		t.Errorf("Неожиданный фрагмент заголовка: '%s'", results[0].Snippets["title"])
+	}
+
+	// Точное совпадение ранжируется выше совпадения с опечаткой
+	results = service.FuzzySearchTasks(t.Context(), "купить", 0)
+	if len(results) != 2 {
+		t.Fatalf("Ожидалось 2 результата, получено %d", len(results))
+	}
+
+	if results := service.FuzzySearchTasks(t.Context(), "абвгдеж", 0); len(results) != 0 {
+		t.Errorf("Далекие слова не должны совпадать, получено %d результатов", len(results))
+	}
+}
+
+func TestTaskService_SuggestTasks(t *testing.T) {
+	service := NewTaskService()
+
+	service.CreateTask(t.Context(), "Купить молоко", "")
+	service.CreateTask(t.Context(), "Срочно купить билеты", "")
+	service.CreateTask(t.Context(), "Купить", "")
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+
+	suggestions := service.SuggestTasks(t.Context(), "куп", 0)
+	expected := []string{"Купить", "Купить молоко", "Срочно купить билеты"}
+	if len(suggestions.Suggestions) != len(expected) {
+		t.Fatalf("Ожидалось %d подсказки, получено %v", len(expected), suggestions.Suggestions)
+	}
+	for i := range expected {
+		if suggestions.Suggestions[i] != expected[i] {
+			t.Errorf("Подсказка %d: ожидалось '%s', получено '%s'", i, expected[i], suggestions.Suggestions[i])
+		}
+	}
+
+	suggestions = service.SuggestTasks(t.Context(), "купить мол", 0)
+	if len(suggestions.Suggestions) != 1 || suggestions.Suggestions[0] != "Купить молоко" {
+		t.Errorf("Ожидалась подсказка 'Купить молоко', получено %v", suggestions.Suggestions)
+	}
+}
+
+func TestTaskService_SuggestTasks_DidYouMean(t *testing.T) {
+	service := NewTaskService()
+
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+
+	suggestions := service.SuggestTasks(t.Context(), "позвнить маме", 0)
+	if len(suggestions.Suggestions) != 0 {
+		t.Errorf("Подсказок быть не должно, получено %v", suggestions.Suggestions)
+	}
+
+	if suggestions.DidYouMean != "позвонить маме" {
+		t.Errorf("Ожидалось исправление 'позвонить маме', получено '%s'", suggestions.DidYouMean)
+	}
+}
+
+func TestTaskService_SuggestTasks_AfterDelete(t *testing.T) {
+	service := NewTaskService()
+
+	task := service.CreateTask(t.Context(), "Купить молоко", "")
+	service.DeleteTask(t.Context(), task.ID)
+
+	if suggestions := service.SuggestTasks(t.Context(), "куп", 0); len(suggestions.Suggestions) != 0 {
+		t.Errorf("Удаленная задача не должна предлагаться, получено %v", suggestions.Suggestions)
+	}
+}
+
+func TestTaskHandler_AutocompleteTasks(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	service.CreateTask(t.Context(), "Купить молоко", "")
+	service.CreateTask(t.Context(), "Купить хлеб", "")
+
+	req := httptest.NewRequest("GET", "/tasks/autocomplete?q=куп&limit=1", nil)
+	w := httptest.NewRecorder()
+	handler.AutocompleteTasks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, w.Code)
+	}
+
+	var suggestions Suggestions
+	if err := json.Unmarshal(w.Body.Bytes(), &suggestions); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+
+	if len(suggestions.Suggestions) != 1 {
+		t.Errorf("Ожидалась 1 подсказка, получено %d", len(suggestions.Suggestions))
+	}
+}
+
+func TestTaskHandler_SearchTasks_InvalidMode(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	req := httptest.NewRequest("GET", "/tasks/search?q=тест&mode=magic", nil)
+	w := httptest.NewRecorder()
+	handler.SearchTasks(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// GraphQLHandler обрабатывает запросы к /graphql: запросы и мутации задач
+// и подписку на их изменения поверх тех же сервисов, что и REST API
+type GraphQLHandler struct {
+	tasks         TaskServiceInterface
+	attachments   AttachmentServiceInterface
+	views         ViewServiceInterface
+	schema        graphql.Schema
+	maxDepth      int
+	maxComplexity int
+}
+
+// graphQLSubscribedKey — ключ контекста функции, которую резолвер подписки
+// вызывает, когда подписка на события уже действует
+type graphQLSubscribedKey struct{}
+
+// graphQLRequest — тело запроса GraphQL по HTTP
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// NewGraphQLHandler создает обработчик GraphQL. Запросы глубже maxDepth или
+// сложнее maxComplexity отклоняются до выполнения; 0 снимает ограничение.
+func NewGraphQLHandler(tasks TaskServiceInterface, attachments AttachmentServiceInterface, views ViewServiceInterface, maxDepth, maxComplexity int) (*GraphQLHandler, error) {
+	gh := &GraphQLHandler{
+		tasks:         tasks,
+		attachments:   attachments,
+		views:         views,
+		maxDepth:      maxDepth,
+		maxComplexity: maxComplexity,
+	}
+	schema, err := gh.buildSchema()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка построения схемы GraphQL: %w", err)
+	}
+	gh.schema = schema
+	return gh, nil
+}
+
+// ServeHTTP обрабатывает GET и POST /graphql. Запрос передается в параметрах
+// query, operationName и variables (GET) или в JSON теле (POST); мутации
+// выполняются только через POST. Подписки отдаются потоком Server-Sent Events,
+// если клиент принимает text/event-stream.
+func (gh *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, errors.New("Неверный параметр 'variables'"))
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGraphQLError(w, http.StatusBadRequest, errors.New("Неверный JSON"))
+		return
+	}
+	if req.Query == "" {
+		writeGraphQLError(w, http.StatusBadRequest, errors.New("Поле 'query' обязательно"))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		writeGraphQLResult(w, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if validation := graphql.ValidateDocument(&gh.schema, doc, nil); !validation.IsValid {
+		writeGraphQLResult(w, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	// Если операцию не удалось выбрать, graphql.Execute сообщит об этом сам
+	operation := findOperation(doc, req.OperationName)
+	if operation != nil {
+		if err := gh.checkLimits(doc, operation, req.Variables); err != nil {
+			writeGraphQLResult(w, &graphql.Result{Errors: []gqlerrors.FormattedError{formatGraphQLError(err)}})
+			return
+		}
+		switch operation.Operation {
+		case ast.OperationTypeMutation:
+			if r.Method == http.MethodGet {
+				w.Header().Set("Allow", http.MethodPost)
+				writeGraphQLError(w, http.StatusMethodNotAllowed, errors.New("Мутации выполняются только через POST"))
+				return
+			}
+		case ast.OperationTypeSubscription:
+			if !acceptsEventStream(r) {
+				writeGraphQLError(w, http.StatusNotAcceptable, errors.New("Подписки доступны только в формате text/event-stream"))
+				return
+			}
+			gh.serveSubscription(w, r, doc, req)
+			return
+		}
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        gh.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withGraphQLLoaders(r.Context(), newGraphQLLoaders(r.Context(), gh.tasks, gh.attachments)),
+	})
+	writeGraphQLResult(w, result)
+}
+
+// serveSubscription отдает результаты подписки потоком Server-Sent Events в
+// формате протокола graphql-sse: каждый результат — событие `next`, конец
+// потока — событие `complete`. Как и в GET /tasks/events, первый комментарий
+// сообщает, что подписка уже действует.
+func (gh *GraphQLHandler) serveSubscription(w http.ResponseWriter, r *http.Request, doc *ast.Document, req graphQLRequest) {
+	controller := http.NewResponseController(w)
+	subscribed := make(chan struct{})
+	ctx := context.WithValue(r.Context(), graphQLSubscribedKey{}, func() { close(subscribed) })
+
+	results := graphql.ExecuteSubscription(graphql.ExecuteParams{
+		Schema:        gh.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	// Горутина подписки отправляет результаты без буфера; после выхода
+	// обработчика она завершится по отмене контекста, если ее дочитать
+	defer func() {
+		go func() {
+			for range results {
+			}
+		}()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-subscribed:
+			subscribed = nil
+			fmt.Fprint(w, ": subscribed\n\n")
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case result, ok := <-results:
+			if !ok {
+				fmt.Fprint(w, "event: complete\ndata: \n\n")
+				controller.Flush()
+				return
+			}
+			data, _ := json.Marshal(result)
+			fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// findOperation выбирает операцию по имени или единственную операцию документа
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+// acceptsEventStream проверяет, что клиент принимает ответ text/event-stream
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == "text/event-stream" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatGraphQLError переводит ошибку в элемент errors ответа GraphQL
+func formatGraphQLError(err error) gqlerrors.FormattedError {
+	formatted := gqlerrors.FormattedError{Message: err.Error(), Locations: []location.SourceLocation{}}
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+	return formatted
+}
+
+// writeGraphQLResult отдает результат GraphQL. Ошибки разбора, проверки и
+// выполнения возвращаются в поле errors со статусом 200, как принято в GraphQL по HTTP.
+func writeGraphQLResult(w http.ResponseWriter, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeGraphQLError отклоняет запрос, который не удалось передать GraphQL
+func writeGraphQLError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&graphql.Result{Errors: []gqlerrors.FormattedError{formatGraphQLError(err)}})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Ограничения GraphQL запросов по умолчанию
+const (
+	DefaultGraphQLMaxDepth      = 8
+	DefaultGraphQLMaxComplexity = 2000
+)
+
+// queryCost — глубина и сложность операции
+type queryCost struct {
+	depth      int
+	complexity int
+}
+
+// measureOperation считает глубину и сложность операции. Каждое поле стоит 1;
+// вложенные поля списка с аргументом `first` (или постраничного поля без него)
+// умножаются на размер страницы, так что `tasks(first: 100) { attachments { task { ... } } }`
+// стоит в сто раз больше, чем один `task`. Служебные поля `__schema`, `__type`
+// и `__typename` не учитываются, чтобы клиенты могли получать схему.
+// Документ должен пройти валидацию: циклы фрагментов не проверяются.
+func measureOperation(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]any) queryCost {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+	m := &costMeasurer{fragments: fragments, variables: variables}
+	return m.selectionSet(operation.SelectionSet)
+}
+
+type costMeasurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+func (m *costMeasurer) selectionSet(set *ast.SelectionSet) queryCost {
+	var total queryCost
+	if set == nil {
+		return total
+	}
+	for _, selection := range set.Selections {
+		var cost queryCost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			children := m.selectionSet(selection.SelectionSet)
+			cost = queryCost{
+				depth:      children.depth + 1,
+				complexity: 1 + children.complexity*m.pageSize(selection),
+			}
+		case *ast.InlineFragment:
+			cost = m.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[selection.Name.Value]; ok {
+				cost = m.selectionSet(fragment.SelectionSet)
+			}
+		}
+		total.depth = max(total.depth, cost.depth)
+		total.complexity += cost.complexity
+	}
+	return total
+}
+
+// pageSize возвращает множитель для вложенных полей: значение `first`,
+// размер страницы по умолчанию для постраничных полей или 1
+func (m *costMeasurer) pageSize(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		if n, ok := m.intValue(argument.Value); ok {
+			return max(n, 1)
+		}
+	}
+	if field.SelectionSet != nil && graphQLPaginatedFields[field.Name.Value] {
+		return defaultGraphQLPageSize
+	}
+	return 1
+}
+
+func (m *costMeasurer) intValue(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := m.variables[value.Name.Value].(type) {
+		case float64:
+			return int(n), true
+		case int:
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// checkLimits возвращает ошибку, если операция превышает ограничения обработчика
+func (gh *GraphQLHandler) checkLimits(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]any) error {
+	cost := measureOperation(doc, operation, variables)
+	if gh.maxDepth > 0 && cost.depth > gh.maxDepth {
+		return &graphQLError{
+			code:    "QUERY_TOO_DEEP",
+			message: fmt.Sprintf("глубина запроса %d превышает допустимую %d", cost.depth, gh.maxDepth),
+		}
+	}
+	if gh.maxComplexity > 0 && cost.complexity > gh.maxComplexity {
+		return &graphQLError{
+			code:    "QUERY_TOO_COMPLEX",
+			message: fmt.Sprintf("сложность запроса %d превышает допустимую %d", cost.complexity, gh.maxComplexity),
+		}
+	}
+	return nil
+}
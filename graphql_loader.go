@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// batchLoader откладывает загрузку по ключу, пока GraphQL не соберет все ключи
+// уровня запроса, и загружает их одним вызовом fetch. Так список из N задач с
+// вложениями стоит одного обращения к сервису вложений, а не N.
+//
+// load возвращает thunk: graphql-go вызывает его после того, как разрешены все
+// поля текущего уровня, поэтому к первому вызову все ключи уже собраны.
+type batchLoader[K comparable, V any] struct {
+	fetch   func(keys []K) map[K]V
+	mutex   sync.Mutex
+	pending []K
+	cache   map[K]V
+	queued  map[K]bool
+}
+
+func newBatchLoader[K comparable, V any](fetch func(keys []K) map[K]V) *batchLoader[K, V] {
+	return &batchLoader[K, V]{fetch: fetch, cache: make(map[K]V), queued: make(map[K]bool)}
+}
+
+// load ставит ключ в очередь и возвращает функцию, которая вернет значение
+func (l *batchLoader[K, V]) load(key K) func() (any, error) {
+	l.mutex.Lock()
+	if _, ok := l.cache[key]; !ok && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mutex.Unlock()
+
+	return func() (any, error) {
+		return l.get(key), nil
+	}
+}
+
+// get загружает все ключи из очереди, если нужного ключа еще нет в кеше
+func (l *batchLoader[K, V]) get(key K) V {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if value, ok := l.cache[key]; ok {
+		return value
+	}
+	keys := l.pending
+	l.pending = nil
+	if !l.queued[key] {
+		keys = append(keys, key)
+	}
+	loaded := l.fetch(keys)
+	for _, k := range keys {
+		l.cache[k] = loaded[k]
+		delete(l.queued, k)
+	}
+	return l.cache[key]
+}
+
+// graphQLLoaders — загрузчики одного запроса; кеш не переживает запрос,
+// поэтому изменения, сделанные мутацией, видны в следующем запросе
+type graphQLLoaders struct {
+	tasks       *batchLoader[int, *Task]
+	attachments *batchLoader[int, []*Attachment]
+}
+
+type graphQLLoadersKey struct{}
+
+// newGraphQLLoaders создает загрузчики для запроса с контекстом ctx
+func newGraphQLLoaders(ctx context.Context, tasks TaskServiceInterface, attachments AttachmentServiceInterface) *graphQLLoaders {
+	return &graphQLLoaders{
+		tasks: newBatchLoader(func(ids []int) map[int]*Task {
+			wanted := make(map[int]bool, len(ids))
+			for _, id := range ids {
+				wanted[id] = true
+			}
+			found := make(map[int]*Task, len(ids))
+			for _, task := range filterTasks(ctx, tasks, func(task *Task) bool { return wanted[task.ID] }) {
+				found[task.ID] = task
+			}
+			return found
+		}),
+		attachments: newBatchLoader(func(taskIDs []int) map[int][]*Attachment {
+			return attachments.GetAttachmentsForTasks(ctx, taskIDs)
+		}),
+	}
+}
+
+func withGraphQLLoaders(ctx context.Context, loaders *graphQLLoaders) context.Context {
+	return context.WithValue(ctx, graphQLLoadersKey{}, loaders)
+}
+
+func loadersFrom(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey{}).(*graphQLLoaders)
+}
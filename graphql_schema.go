@@ -0,0 +1,600 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Размеры страниц списка задач
+const (
+	defaultGraphQLPageSize = 20
+	maxGraphQLPageSize     = 100
+)
+
+// graphQLPaginatedFields — поля со страницами; без `first` их вложенные поля
+// учитываются в сложности с размером страницы по умолчанию
+var graphQLPaginatedFields = map[string]bool{"tasks": true}
+
+// graphQLError — ошибка резолвера с кодом в extensions.code
+type graphQLError struct {
+	code    string
+	message string
+}
+
+func (e *graphQLError) Error() string {
+	return e.message
+}
+
+// Extensions добавляет код ошибки в ответ, как это принято в GraphQL серверах
+func (e *graphQLError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+func badUserInput(message string) error {
+	return &graphQLError{code: "BAD_USER_INPUT", message: message}
+}
+
+// graphQLErrorFrom сопоставляет ошибки сервисов с кодами ошибок GraphQL,
+// как writeViewError и grpcCode — со статусами HTTP и gRPC
+func graphQLErrorFrom(err error) error {
+	var syntaxErr *QuerySyntaxError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return &graphQLError{code: "NOT_FOUND", message: err.Error()}
+	case errors.Is(err, ErrInvalidInput), errors.Is(err, ErrViewNameRequired), errors.As(err, &syntaxErr):
+		return badUserInput(err.Error())
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrViewExists):
+		return &graphQLError{code: "CONFLICT", message: err.Error()}
+	default:
+		return &graphQLError{code: "INTERNAL_SERVER_ERROR", message: "Внутренняя ошибка сервера"}
+	}
+}
+
+// parseGraphQLID разбирает аргумент ID задачи
+func parseGraphQLID(value any) (int, error) {
+	s, _ := value.(string)
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, badUserInput("Неверный ID задачи")
+	}
+	return id, nil
+}
+
+// taskConnection — страница задач в стиле Relay
+type taskConnection struct {
+	Edges      []taskEdge
+	Nodes      []*Task
+	PageInfo   pageInfo
+	TotalCount int
+}
+
+type taskEdge struct {
+	Cursor string
+	Node   *Task
+}
+
+type pageInfo struct {
+	HasNextPage bool
+	EndCursor   *string
+}
+
+// Курсор — base64 от "task:<id>"; задачи упорядочены по ID, поэтому
+// курсор остается верным, даже если задачи перед ним удалены
+func encodeTaskCursor(id int) string {
+	return base64.StdEncoding.EncodeToString([]byte("task:" + strconv.Itoa(id)))
+}
+
+func decodeTaskCursor(cursor string) (int, error) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil {
+		if raw, ok := strings.CutPrefix(string(data), "task:"); ok {
+			if id, err := strconv.Atoi(raw); err == nil {
+				return id, nil
+			}
+		}
+	}
+	return 0, badUserInput("Неверный курсор 'after'")
+}
+
+// paginateTasks возвращает страницу задач после курсора after; tasks упорядочены по ID
+func paginateTasks(tasks []*Task, args map[string]any) (*taskConnection, error) {
+	first, _ := args["first"].(int)
+	if first < 0 || first > maxGraphQLPageSize {
+		return nil, badUserInput(fmt.Sprintf("Аргумент 'first' должен быть от 0 до %d", maxGraphQLPageSize))
+	}
+	start := 0
+	if after, ok := args["after"].(string); ok {
+		afterID, err := decodeTaskCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		for start < len(tasks) && tasks[start].ID <= afterID {
+			start++
+		}
+	}
+	end := min(start+first, len(tasks))
+
+	connection := &taskConnection{
+		Edges:      make([]taskEdge, 0, end-start),
+		Nodes:      tasks[start:end],
+		PageInfo:   pageInfo{HasNextPage: end < len(tasks)},
+		TotalCount: len(tasks),
+	}
+	for _, task := range connection.Nodes {
+		connection.Edges = append(connection.Edges, taskEdge{Cursor: encodeTaskCursor(task.ID), Node: task})
+	}
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+	return connection, nil
+}
+
+// taskFilter собирает условие из аргументов filter и query поля tasks
+func taskFilter(args map[string]any) (func(*Task) bool, error) {
+	conditions := make([]func(*Task) bool, 0)
+
+	if source, ok := args["query"].(string); ok && source != "" {
+		query, err := ParseQuery(source)
+		if err != nil {
+			return nil, graphQLErrorFrom(err)
+		}
+		conditions = append(conditions, query.Match)
+	}
+
+	filter, _ := args["filter"].(map[string]any)
+	if completed, ok := filter["completed"].(bool); ok {
+		conditions = append(conditions, func(task *Task) bool { return task.Completed == completed })
+	}
+	if tag, ok := filter["tag"].(string); ok {
+		// Тег нормализуется так же, как при сохранении задачи
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "#")))
+		conditions = append(conditions, func(task *Task) bool { return slices.Contains(task.Tags, tag) })
+	}
+	if project, ok := filter["project"].(string); ok {
+		conditions = append(conditions, func(task *Task) bool { return strings.EqualFold(task.Project, project) })
+	}
+	if priority, ok := filter["priority"].(Priority); ok {
+		conditions = append(conditions, func(task *Task) bool { return task.Priority == priority })
+	}
+	if before, ok := filter["dueBefore"].(time.Time); ok {
+		conditions = append(conditions, func(task *Task) bool { return task.DueDate != nil && task.DueDate.Before(before) })
+	}
+	if after, ok := filter["dueAfter"].(time.Time); ok {
+		conditions = append(conditions, func(task *Task) bool { return task.DueDate != nil && task.DueDate.After(after) })
+	}
+
+	return func(task *Task) bool {
+		for _, condition := range conditions {
+			if !condition(task) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// taskOptions собирает опции задачи из полей ввода tags, priority, project и dueDate
+func taskOptions(input map[string]any) []TaskOption {
+	opts := make([]TaskOption, 0, 4)
+	if tags, ok := input["tags"].([]any); ok {
+		values := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok {
+				values = append(values, s)
+			}
+		}
+		opts = append(opts, WithTags(values))
+	}
+	if priority, ok := input["priority"].(Priority); ok {
+		opts = append(opts, WithPriority(priority))
+	}
+	if project, ok := input["project"].(string); ok {
+		opts = append(opts, WithProject(project))
+	}
+	if due, ok := input["dueDate"].(time.Time); ok {
+		opts = append(opts, WithDueDate(&due))
+	}
+	return opts
+}
+
+// loaders возвращает загрузчики запроса. В подписке запрос выполняется заново
+// для каждого события с тем же контекстом, поэтому кеш загрузчиков устарел бы
+// между событиями; событие содержит одну задачу, и пакетная загрузка не нужна.
+func (gh *GraphQLHandler) loaders(p graphql.ResolveParams) *graphQLLoaders {
+	if operation, ok := p.Info.Operation.(*ast.OperationDefinition); ok && operation.Operation == ast.OperationTypeSubscription {
+		return newGraphQLLoaders(p.Context, gh.tasks, gh.attachments)
+	}
+	return loadersFrom(p.Context)
+}
+
+// buildSchema описывает схему GraphQL поверх сервисов задач, вложений и представлений
+func (gh *GraphQLHandler) buildSchema() (graphql.Schema, error) {
+	priorityEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "Priority",
+		Description: "Приоритет задачи",
+		Values: graphql.EnumValueConfigMap{
+			"LOW":    &graphql.EnumValueConfig{Value: PriorityLow},
+			"MEDIUM": &graphql.EnumValueConfig{Value: PriorityMedium},
+			"HIGH":   &graphql.EnumValueConfig{Value: PriorityHigh},
+		},
+	})
+
+	eventTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "TaskEventType",
+		Description: "Тип изменения задачи",
+		Values: graphql.EnumValueConfigMap{
+			"CREATED": &graphql.EnumValueConfig{Value: TaskEventCreated},
+			"UPDATED": &graphql.EnumValueConfig{Value: TaskEventUpdated},
+			"DELETED": &graphql.EnumValueConfig{Value: TaskEventDeleted},
+		},
+	})
+
+	var taskType *graphql.Object
+
+	attachmentType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Attachment",
+		Description: "Файл, прикрепленный к задаче",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"filename":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"contentType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"size":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"checksum":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"url": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Путь для скачивания содержимого через REST API",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						attachment := p.Source.(*Attachment)
+						return fmt.Sprintf("/tasks/%d/attachments/%d", attachment.TaskID, attachment.ID), nil
+					},
+				},
+				"task": &graphql.Field{
+					Type: taskType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return gh.loaders(p).tasks.load(p.Source.(*Attachment).TaskID), nil
+					},
+				},
+			}
+		}),
+	})
+
+	// Пустые строки отдаются как null, как поля с omitempty в JSON REST API
+	emptyAsNull := func(p graphql.ResolveParams) (any, error) {
+		value, _ := graphql.DefaultResolveFn(p)
+		if value == "" {
+			return nil, nil
+		}
+		return value, nil
+	}
+
+	taskType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Task",
+		Description: "Задача в ToDo списке",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"title":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"completed":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"tags":        &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"priority":    &graphql.Field{Type: priorityEnum},
+			"project":     &graphql.Field{Type: graphql.String, Resolve: emptyAsNull},
+			"dueDate":     &graphql.Field{Type: graphql.DateTime},
+			"uid":         &graphql.Field{Type: graphql.String, Resolve: emptyAsNull, Description: "UID задачи, импортированной из календаря"},
+			"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"attachments": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(attachmentType))),
+				// Вложения всех задач страницы загружаются одним вызовом сервиса
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return gh.loaders(p).attachments.load(p.Source.(*Task).ID), nil
+				},
+			},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	taskEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TaskEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(taskType)},
+		},
+	})
+
+	taskConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "TaskConnection",
+		Description: "Страница задач, упорядоченных по ID",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taskEdgeType)))},
+			"nodes":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taskType)))},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Число задач на всех страницах"},
+		},
+	})
+
+	pageArgs := graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: defaultGraphQLPageSize,
+			Description:  fmt.Sprintf("Размер страницы, не больше %d", maxGraphQLPageSize),
+		},
+		"after": &graphql.ArgumentConfig{Type: graphql.String, Description: "Курсор последней задачи предыдущей страницы"},
+	}
+
+	viewType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SavedView",
+		Description: "Сохраненный именованный запрос к задачам",
+		Fields: graphql.Fields{
+			"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"query":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"tasks": &graphql.Field{
+				Type:        graphql.NewNonNull(taskConnectionType),
+				Description: "Задачи, подходящие под запрос представления",
+				Args:        pageArgs,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					query, err := ParseQuery(p.Source.(*SavedView).Query)
+					if err != nil {
+						return nil, graphQLErrorFrom(err)
+					}
+					return paginateTasks(filterTasks(p.Context, gh.tasks, query.Match), p.Args)
+				},
+			},
+		},
+	})
+
+	taskFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "TaskFilter",
+		Description: "Условия отбора задач; заданные условия объединяются через И",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"completed": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"tag":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"project":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"priority":  &graphql.InputObjectFieldConfig{Type: priorityEnum},
+			"dueBefore": &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"dueAfter":  &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+
+	createTaskInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateTaskInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"priority":    &graphql.InputObjectFieldConfig{Type: priorityEnum},
+			"project":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"dueDate":     &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+
+	updateTaskInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdateTaskInput",
+		Description: "Изменения задачи; незаданные поля остаются прежними",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"completed":   &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"priority":    &graphql.InputObjectFieldConfig{Type: priorityEnum},
+			"project":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"dueDate":     &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+
+	taskEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "TaskEvent",
+		Description: "Изменение задачи; для удаленной задачи task содержит ее последнее состояние",
+		Fields: graphql.Fields{
+			"seq":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"type": &graphql.Field{Type: graphql.NewNonNull(eventTypeEnum)},
+			"task": &graphql.Field{Type: graphql.NewNonNull(taskType)},
+			"time": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"task": &graphql.Field{
+				Type:        taskType,
+				Description: "Задача по ID или null, если ее нет",
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := parseGraphQLID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return gh.loaders(p).tasks.load(id), nil
+				},
+			},
+			"tasks": &graphql.Field{
+				Type:        graphql.NewNonNull(taskConnectionType),
+				Description: "Задачи, подходящие под фильтр и запрос на языке фильтров",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: taskFilterType},
+					"query":  &graphql.ArgumentConfig{Type: graphql.String, Description: "Запрос на языке фильтров, как параметр q в GET /tasks"},
+					"first":  pageArgs["first"],
+					"after":  pageArgs["after"],
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					match, err := taskFilter(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return paginateTasks(filterTasks(p.Context, gh.tasks, match), p.Args)
+				},
+			},
+			"views": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(viewType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return gh.views.GetAllViews(), nil
+				},
+			},
+			"view": &graphql.Field{
+				Type:        viewType,
+				Description: "Представление по имени или null, если его нет",
+				Args:        graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					view, err := gh.views.GetView(p.Args["name"].(string))
+					if errors.Is(err, ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, graphQLErrorFrom(err)
+					}
+					return view, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTask": &graphql.Field{
+				Type: graphql.NewNonNull(taskType),
+				Args: graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createTaskInput)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					input := p.Args["input"].(map[string]any)
+					title, _ := input["title"].(string)
+					if title == "" {
+						return nil, badUserInput("Поле 'title' обязательно")
+					}
+					description, _ := input["description"].(string)
+					return gh.tasks.CreateTask(p.Context, title, description, taskOptions(input)...), nil
+				},
+			},
+			"updateTask": &graphql.Field{
+				Type:        graphql.NewNonNull(taskType),
+				Description: "Изменяет только заданные поля задачи",
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateTaskInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := parseGraphQLID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					task, err := gh.tasks.GetTask(p.Context, id)
+					if err != nil {
+						return nil, graphQLErrorFrom(err)
+					}
+
+					input := p.Args["input"].(map[string]any)
+					title, description, completed := task.Title, task.Description, task.Completed
+					if value, ok := input["title"].(string); ok {
+						if value == "" {
+							return nil, badUserInput("Поле 'title' обязательно")
+						}
+						title = value
+					}
+					if value, ok := input["description"].(string); ok {
+						description = value
+					}
+					if value, ok := input["completed"].(bool); ok {
+						completed = value
+					}
+
+					// UpdateTask заменяет задачу целиком, поэтому незаданные поля берутся из текущей задачи
+					opts := append([]TaskOption{
+						WithTags(task.Tags), WithPriority(task.Priority), WithProject(task.Project), WithDueDate(task.DueDate),
+					}, taskOptions(input)...)
+					updated, err := gh.tasks.UpdateTask(p.Context, id, title, description, completed, opts...)
+					if err != nil {
+						return nil, graphQLErrorFrom(err)
+					}
+					return updated, nil
+				},
+			},
+			"deleteTask": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := parseGraphQLID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					if err := gh.tasks.DeleteTask(p.Context, id); err != nil {
+						return nil, graphQLErrorFrom(err)
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"taskChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(taskEventType),
+				Description: "События об изменении задач, как в GET /tasks/events",
+				Subscribe:   gh.subscribeTaskChanged,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+	})
+}
+
+// subscribeTaskChanged подписывается на события задач. Поток событий
+// закрывается вместе с контекстом запроса или если подписчик отстал.
+func (gh *GraphQLHandler) subscribeTaskChanged(p graphql.ResolveParams) (any, error) {
+	subscriber, err := capability[TaskSubscriber](gh.tasks, "Subscribe")
+	if err != nil {
+		return nil, err
+	}
+	events, cancel := subscriber.Subscribe(p.Context)
+	if subscribed, ok := p.Context.Value(graphQLSubscribedKey{}).(func()); ok {
+		subscribed()
+	}
+
+	out := make(chan any)
+	go func() {
+		defer cancel()
+		defer close(out)
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				select {
+				case out <- &event:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// graphQLResponse — ответ GraphQL, data которого разбирается в тесте
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// errorCode возвращает код первой ошибки ответа
+func (r graphQLResponse) errorCode() string {
+	if len(r.Errors) == 0 {
+		return ""
+	}
+	code, _ := r.Errors[0].Extensions["code"].(string)
+	return code
+}
+
+// graphQLDo выполняет POST /graphql и разбирает data в out
+func graphQLDo(t *testing.T, router http.Handler, query string, variables map[string]any, out any) graphQLResponse {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Неверный статус: %d %s", w.Code, w.Body.String())
+	}
+
+	var response graphQLResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Неверный JSON ответа: %v", err)
+	}
+	if out != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, out); err != nil {
+			t.Fatalf("Неверные данные ответа: %v %s", err, response.Data)
+		}
+	}
+	return response
+}
+
+func TestGraphQL_QueryTasks(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+	due := time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)
+	service.CreateTask(t.Context(), "Купить молоко", "", WithTags([]string{"дом"}), WithPriority(PriorityHigh), WithDueDate(&due))
+	service.CreateTask(t.Context(), "Позвонить маме", "", WithTags([]string{"семья"}))
+	service.CreateTask(t.Context(), "Вынести мусор", "", WithTags([]string{"дом"}), WithProject("Быт"))
+	service.CreateTask(t.Context(), "Полить цветы", "", WithTags([]string{"дом"}))
+
+	const query = `query($after: String) {
+		tasks(filter: {tag: "ДОМ", completed: false}, first: 2, after: $after) {
+			totalCount
+			edges { cursor node { id title priority tags project dueDate } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+	type page struct {
+		Tasks struct {
+			TotalCount int
+			Edges      []struct {
+				Cursor string
+				Node   struct {
+					ID       string
+					Title    string
+					Priority *string
+					Tags     []string
+					Project  *string
+					DueDate  *time.Time
+				}
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+		}
+	}
+
+	var first page
+	if response := graphQLDo(t, router, query, nil, &first); len(response.Errors) > 0 {
+		t.Fatalf("Неожиданные ошибки: %+v", response.Errors)
+	}
+	edges := first.Tasks.Edges
+	if first.Tasks.TotalCount != 3 || len(edges) != 2 || !first.Tasks.PageInfo.HasNextPage || first.Tasks.PageInfo.EndCursor != edges[1].Cursor {
+		t.Fatalf("Неверная первая страница: %+v", first.Tasks)
+	}
+	if node := edges[0].Node; node.ID != "1" || node.Priority == nil || *node.Priority != "HIGH" || node.DueDate == nil || !node.DueDate.Equal(due) || node.Project != nil {
+		t.Errorf("Неверная задача: %+v", node)
+	}
+	if node := edges[1].Node; node.ID != "3" || node.Priority != nil || node.Project == nil || *node.Project != "Быт" {
+		t.Errorf("Неверная задача: %+v", node)
+	}
+
+	var second page
+	graphQLDo(t, router, query, map[string]any{"after": first.Tasks.PageInfo.EndCursor}, &second)
+	if len(second.Tasks.Edges) != 1 || second.Tasks.Edges[0].Node.ID != "4" || second.Tasks.PageInfo.HasNextPage {
+		t.Errorf("Неверная вторая страница: %+v", second.Tasks)
+	}
+
+	// Запрос на языке фильтров, задача по ID и отсутствующая задача
+	var data struct {
+		Tasks   struct{ Nodes []struct{ Title string } }
+		Task    struct{ Title string }
+		Missing *struct{ Title string }
+	}
+	response := graphQLDo(t, router, `{
+		tasks(query: "tag:дом AND priority:high") { nodes { title } }
+		task(id: "2") { title }
+		missing: task(id: "42") { title }
+	}`, nil, &data)
+	if len(response.Errors) > 0 || len(data.Tasks.Nodes) != 1 || data.Tasks.Nodes[0].Title != "Купить молоко" || data.Task.Title != "Позвонить маме" || data.Missing != nil {
+		t.Errorf("Неверный ответ: %+v %+v", response.Errors, data)
+	}
+
+	if response := graphQLDo(t, router, `{ tasks(query: "tag:") { totalCount } }`, nil, nil); response.errorCode() != "BAD_USER_INPUT" {
+		t.Errorf("Ожидалась ошибка BAD_USER_INPUT, получено %+v", response.Errors)
+	}
+	if response := graphQLDo(t, router, `{ tasks(after: "мусор") { totalCount } }`, nil, nil); response.errorCode() != "BAD_USER_INPUT" {
+		t.Errorf("Ожидалась ошибка неверного курсора, получено %+v", response.Errors)
+	}
+	if response := graphQLDo(t, router, `{ tasks(first: 101) { totalCount } }`, nil, nil); response.errorCode() != "BAD_USER_INPUT" {
+		t.Errorf("Ожидалась ошибка размера страницы, получено %+v", response.Errors)
+	}
+}
+
+func TestGraphQL_Mutations(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+
+	var created struct {
+		CreateTask struct {
+			ID       string
+			Tags     []string
+			Priority string
+		}
+	}
+	response := graphQLDo(t, router, `mutation($input: CreateTaskInput!) {
+		createTask(input: $input) { id tags priority }
+	}`, map[string]any{"input": map[string]any{"title": "Купить молоко", "tags": []string{"Дом", "#дом"}, "priority": "HIGH"}}, &created)
+	if len(response.Errors) > 0 || created.CreateTask.ID != "1" || len(created.CreateTask.Tags) != 1 || created.CreateTask.Priority != "HIGH" {
+		t.Fatalf("Неверная созданная задача: %+v %+v", response.Errors, created)
+	}
+
+	// Обновляются только заданные поля
+	var updated struct {
+		UpdateTask struct {
+			Title     string
+			Completed bool
+			Tags      []string
+			Priority  string
+		}
+	}
+	response = graphQLDo(t, router, `mutation { updateTask(id: "1", input: {completed: true}) { title completed tags priority } }`, nil, &updated)
+	if len(response.Errors) > 0 || updated.UpdateTask.Title != "Купить молоко" || !updated.UpdateTask.Completed || len(updated.UpdateTask.Tags) != 1 || updated.UpdateTask.Priority != "HIGH" {
+		t.Errorf("Неверная обновленная задача: %+v %+v", response.Errors, updated)
+	}
+
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{`mutation { createTask(input: {title: ""}) { id } }`, "BAD_USER_INPUT"},
+		{`mutation { updateTask(id: "1", input: {title: ""}) { id } }`, "BAD_USER_INPUT"},
+		{`mutation { updateTask(id: "42", input: {completed: true}) { id } }`, "NOT_FOUND"},
+		{`mutation { deleteTask(id: "abc") }`, "BAD_USER_INPUT"},
+		{`mutation { deleteTask(id: "42") }`, "NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		if response := graphQLDo(t, router, tt.query, nil, nil); response.errorCode() != tt.code {
+			t.Errorf("%s: ожидалась ошибка %s, получено %+v", tt.query, tt.code, response.Errors)
+		}
+	}
+
+	var deleted struct{ DeleteTask bool }
+	graphQLDo(t, router, `mutation { deleteTask(id: "1") }`, nil, &deleted)
+	if _, err := service.GetTask(t.Context(), 1); !deleted.DeleteTask || err == nil {
+		t.Errorf("Задача не удалена: %v %v", deleted, err)
+	}
+
+	// Мутации через GET не выполняются
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`mutation { deleteTask(id: "2") }`), nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("Ожидался статус 405, получено %d", w.Code)
+	}
+}
+
+// newTestGraphQLHandler создает обработчик GraphQL без маршрутизатора
+func newTestGraphQLHandler(t *testing.T, tasks TaskServiceInterface, attachments AttachmentServiceInterface, maxDepth, maxComplexity int) *GraphQLHandler {
+	t.Helper()
+	views, _ := NewViewService("")
+	handler, err := NewGraphQLHandler(tasks, attachments, views, maxDepth, maxComplexity)
+	if err != nil {
+		t.Fatalf("Ошибка построения схемы: %v", err)
+	}
+	return handler
+}
+
+func TestGraphQL_InvalidRequests(t *testing.T) {
+	// Обработчик без маршрутизатора: проверка по спецификации отклонила бы часть запросов раньше
+	tasks, attachments := newTestAttachmentService(t)
+	handler := newTestGraphQLHandler(t, tasks, attachments, DefaultGraphQLMaxDepth, DefaultGraphQLMaxComplexity)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"неверный JSON", http.MethodPost, "/graphql", `{"query":`, http.StatusBadRequest},
+		{"без запроса", http.MethodGet, "/graphql", "", http.StatusBadRequest},
+		{"неверные переменные", http.MethodGet, "/graphql?query=%7Btasks%7BtotalCount%7D%7D&variables=%7B", "", http.StatusBadRequest},
+		{"подписка без text/event-stream", http.MethodPost, "/graphql", `{"query":"subscription { taskChanged { seq } }"}`, http.StatusNotAcceptable},
+		{"ошибка разбора", http.MethodPost, "/graphql", `{"query":"{ tasks "}`, http.StatusOK},
+		{"неизвестное поле", http.MethodGet, "/graphql?query=%7Bnope%7D", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			var response graphQLResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != tt.status || len(response.Errors) != 1 {
+				t.Errorf("Ожидался статус %d с ошибкой, получено %d %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGraphQL_Subscription(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	body := `{"query":"subscription { taskChanged { seq type task { title attachments { id } } } }"}`
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/graphql", strings.NewReader(body))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Неверный ответ: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != ": subscribed\n" {
+		t.Fatalf("Ожидался комментарий о подписке, получено %q", line)
+	}
+	reader.ReadString('\n')
+
+	task := service.CreateTask(t.Context(), "Позвонить маме", "")
+	service.DeleteTask(t.Context(), task.ID)
+
+	for i, expected := range []string{"CREATED", "DELETED"} {
+		event, _ := reader.ReadString('\n')
+		data, _ := reader.ReadString('\n')
+		reader.ReadString('\n')
+		if event != "event: next\n" {
+			t.Fatalf("Ожидалось событие next, получено %q", event)
+		}
+
+		var result struct {
+			Data struct {
+				TaskChanged struct {
+					Seq  int
+					Type string
+					Task struct {
+						Title       string
+						Attachments []struct{ ID string }
+					}
+				}
+			}
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &result); err != nil {
+			t.Fatalf("Неверные данные события: %v %q", err, data)
+		}
+		changed := result.Data.TaskChanged
+		if changed.Seq != i+1 || changed.Type != expected || changed.Task.Title != "Позвонить маме" || changed.Task.Attachments == nil {
+			t.Errorf("Событие %d: ожидалось %s, получено %+v", i+1, expected, changed)
+		}
+	}
+}
+
+func TestMeasureOperation(t *testing.T) {
+	tests := []struct {
+		query      string
+		variables  map[string]any
+		depth      int
+		complexity int
+	}{
+		{`{ task(id: "1") { id title } }`, nil, 2, 3},
+		// Вложенные поля постраничного списка умножаются на размер страницы
+		{`{ tasks(first: 10) { nodes { id } } }`, nil, 3, 1 + 10*2},
+		{`query($n: Int) { tasks(first: $n) { nodes { id } } }`, map[string]any{"n": float64(50)}, 3, 1 + 50*2},
+		{`{ tasks { totalCount } }`, nil, 2, 1 + defaultGraphQLPageSize},
+		// Фрагменты раскрываются, служебные поля не учитываются
+		{`{ task(id: "1") { ...f __typename } } fragment f on Task { attachments { ... on Attachment { id } } }`, nil, 3, 3},
+		{`{ __schema { types { name fields { name } } } }`, nil, 0, 0},
+	}
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatalf("Ошибка разбора %q: %v", tt.query, err)
+		}
+		cost := measureOperation(doc, findOperation(doc, ""), tt.variables)
+		if cost.depth != tt.depth || cost.complexity != tt.complexity {
+			t.Errorf("%s: ожидалось %d/%d, получено %d/%d", tt.query, tt.depth, tt.complexity, cost.depth, cost.complexity)
+		}
+	}
+}
+
+func TestGraphQL_Limits(t *testing.T) {
+	tasks, attachments := newTestAttachmentService(t)
+	handler := newTestGraphQLHandler(t, tasks, attachments, 4, 500)
+
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{`{ tasks { nodes { attachments { task { attachments { id } } } } } }`, "QUERY_TOO_DEEP"},
+		{`{ tasks(first: 100) { nodes { id title completed tags priority } } }`, "QUERY_TOO_COMPLEX"},
+		{`{ tasks(first: 100) { nodes { id } } }`, ""},
+	}
+	for _, tt := range tests {
+		if response := graphQLDo(t, handler, tt.query, nil, nil); response.errorCode() != tt.code {
+			t.Errorf("%s: ожидалась ошибка %q, получено %+v", tt.query, tt.code, response.Errors)
+		}
+	}
+}
+
+// countingAttachments считает обращения к сервису вложений
+type countingAttachments struct {
+	AttachmentServiceInterface
+	calls atomic.Int32
+}
+
+func (c *countingAttachments) GetAttachmentsForTasks(ctx context.Context, taskIDs []int) map[int][]*Attachment {
+	c.calls.Add(1)
+	return c.AttachmentServiceInterface.GetAttachmentsForTasks(ctx, taskIDs)
+}
+
+// countingTasks считает обращения к сервису задач за списками задач
+type countingTasks struct {
+	ServerTaskService
+	calls atomic.Int32
+}
+
+func (c *countingTasks) FilterTasks(ctx context.Context, match func(*Task) bool) []*Task {
+	c.calls.Add(1)
+	return c.ServerTaskService.FilterTasks(ctx, match)
+}
+
+func (c *countingTasks) GetTask(ctx context.Context, id int) (*Task, error) {
+	c.calls.Add(1)
+	return c.ServerTaskService.GetTask(ctx, id)
+}
+
+func TestGraphQL_BatchesLoads(t *testing.T) {
+	taskService, attachmentService := newTestAttachmentService(t)
+	for i := 0; i < 5; i++ {
+		task := taskService.CreateTask(t.Context(), "Задача", "")
+		attachmentService.AddAttachment(t.Context(), task.ID, "notes.txt", strings.NewReader("заметки"))
+		attachmentService.AddAttachment(t.Context(), task.ID, "more.txt", strings.NewReader("еще заметки"))
+	}
+	tasks := &countingTasks{ServerTaskService: taskService}
+	attachments := &countingAttachments{AttachmentServiceInterface: attachmentService}
+	handler := newTestGraphQLHandler(t, tasks, attachments, DefaultGraphQLMaxDepth, DefaultGraphQLMaxComplexity)
+
+	var data struct {
+		Tasks struct {
+			Nodes []struct {
+				ID          string
+				Attachments []struct {
+					ID   string
+					URL  string
+					Task struct{ ID string }
+				}
+			}
+		}
+	}
+	response := graphQLDo(t, handler, `{ tasks { nodes { id attachments { id url task { id } } } } }`, nil, &data)
+	if len(response.Errors) > 0 || len(data.Tasks.Nodes) != 5 {
+		t.Fatalf("Неверный ответ: %+v %+v", response.Errors, data)
+	}
+	for _, node := range data.Tasks.Nodes {
+		if len(node.Attachments) != 2 || node.Attachments[0].Task.ID != node.ID || !strings.HasPrefix(node.Attachments[0].URL, "/tasks/"+node.ID+"/attachments/") {
+			t.Errorf("Неверные вложения задачи %s: %+v", node.ID, node.Attachments)
+		}
+	}
+
+	// Один вызов за списком, один — за задачами вложений, один — за вложениями
+	if calls := tasks.calls.Load(); calls != 2 {
+		t.Errorf("Ожидалось 2 обращения к сервису задач, получено %d", calls)
+	}
+	if calls := attachments.calls.Load(); calls != 1 {
+		t.Errorf("Ожидалось 1 обращение к сервису вложений, получено %d", calls)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	todov1 "todo-api/proto/todo/v1"
+)
+
+// TaskGRPCServer реализует gRPC сервис todo.v1.TaskService поверх TaskServiceInterface.
+// Проверки и тексты ошибок совпадают с REST обработчиками.
+type TaskGRPCServer struct {
+	todov1.UnimplementedTaskServiceServer
+	service TaskServiceInterface
+}
+
+// NewTaskGRPCServer создает gRPC сервис задач
+func NewTaskGRPCServer(service TaskServiceInterface) *TaskGRPCServer {
+	return &TaskGRPCServer{service: service}
+}
+
+// NewGRPCServer создает gRPC сервер с зарегистрированным сервисом задач. Паника в
+// обработчике возвращается клиенту как codes.Internal, а не останавливает сервер.
+func NewGRPCServer(service TaskServiceInterface, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcRecoverUnary),
+		grpc.ChainStreamInterceptor(grpcRecoverStream),
+	}, opts...)
+	server := grpc.NewServer(opts...)
+	todov1.RegisterTaskServiceServer(server, NewTaskGRPCServer(service))
+	return server
+}
+
+// grpcRecoverUnary — перехватчик, превращающий панику обработчика в ошибку Internal,
+// как middleware Recoverer для HTTP
+func grpcRecoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer grpcRecover(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+// grpcRecoverStream — то же для потоковых методов
+func grpcRecoverStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer grpcRecover(info.FullMethod, &err)
+	return handler(srv, stream)
+}
+
+// grpcRecover вызывается через defer и записывает в err ошибку Internal при панике
+func grpcRecover(method string, err *error) {
+	if p := recover(); p != nil {
+		log.Printf("gRPC: паника в %s: %v\n%s", method, p, debug.Stack())
+		*err = status.Error(codes.Internal, "Внутренняя ошибка сервера")
+	}
+}
+
+// grpcCode сопоставляет ошибку сервиса с кодом gRPC, как batchErrorStatus — с HTTP статусом
+func grpcCode(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, ErrInvalidInput):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrConflict):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrBatchAborted):
+		return codes.Aborted
+	case errors.Is(err, ErrNotSupported):
+		return codes.Unimplemented
+	default:
+		return codes.Internal
+	}
+}
+
+// grpcError переводит ошибку сервиса в статус gRPC с тем же текстом
+func grpcError(err error) error {
+	return status.Error(grpcCode(err), err.Error())
+}
+
+// Соответствие приоритетов и типов событий значениям перечислений protobuf
+var (
+	priorityToProto = map[Priority]todov1.Priority{
+		PriorityNone:   todov1.Priority_PRIORITY_UNSPECIFIED,
+		PriorityLow:    todov1.Priority_PRIORITY_LOW,
+		PriorityMedium: todov1.Priority_PRIORITY_MEDIUM,
+		PriorityHigh:   todov1.Priority_PRIORITY_HIGH,
+	}
+	eventTypeToProto = map[string]todov1.TaskEvent_Type{
+		TaskEventCreated: todov1.TaskEvent_TYPE_CREATED,
+		TaskEventUpdated: todov1.TaskEvent_TYPE_UPDATED,
+		TaskEventDeleted: todov1.TaskEvent_TYPE_DELETED,
+	}
+	batchOpFromProto = map[todov1.BatchOperation_Op]string{
+		todov1.BatchOperation_OP_CREATE: BatchOpCreate,
+		todov1.BatchOperation_OP_UPDATE: BatchOpUpdate,
+		todov1.BatchOperation_OP_DELETE: BatchOpDelete,
+	}
+)
+
+// priorityFromProto переводит приоритет из protobuf; неизвестное значение — ошибка InvalidArgument
+func priorityFromProto(p todov1.Priority) (Priority, error) {
+	for priority, value := range priorityToProto {
+		if value == p {
+			return priority, nil
+		}
+	}
+	return "", status.Error(codes.InvalidArgument, "Поле 'priority' должно быть 'low', 'medium' или 'high'")
+}
+
+func timestampToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timestampFromProto(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.AsTime()
+	return &value
+}
+
+// taskToProto переводит задачу в сообщение protobuf
+func taskToProto(task *Task) *todov1.Task {
+	if task == nil {
+		return nil
+	}
+	return &todov1.Task{
+		Id:          int64(task.ID),
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		Tags:        task.Tags,
+		Priority:    priorityToProto[task.Priority],
+		Project:     task.Project,
+		DueDate:     timestampToProto(task.DueDate),
+		Uid:         task.UID,
+		CreatedAt:   timestamppb.New(task.CreatedAt),
+		UpdatedAt:   timestamppb.New(task.UpdatedAt),
+	}
+}
+
+func tasksToProto(tasks []*Task) []*todov1.Task {
+	result := make([]*todov1.Task, len(tasks))
+	for i, task := range tasks {
+		result[i] = taskToProto(task)
+	}
+	return result
+}
+
+// CreateTask создает задачу
+func (s *TaskGRPCServer) CreateTask(ctx context.Context, req *todov1.CreateTaskRequest) (*todov1.Task, error) {
+	if req.Title == "" {
+		return nil, status.Error(codes.InvalidArgument, "Поле 'title' обязательно")
+	}
+	priority, err := priorityFromProto(req.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	task := s.service.CreateTask(ctx, req.Title, req.Description,
+		WithTags(req.Tags), WithPriority(priority), WithProject(req.Project), WithDueDate(timestampFromProto(req.DueDate)))
+	return taskToProto(task), nil
+}
+
+// GetTask возвращает задачу по ID
+func (s *TaskGRPCServer) GetTask(ctx context.Context, req *todov1.GetTaskRequest) (*todov1.Task, error) {
+	task, err := s.service.GetTask(ctx, int(req.Id))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// ListTasks возвращает все задачи или задачи, подходящие под запрос на языке фильтров
+func (s *TaskGRPCServer) ListTasks(ctx context.Context, req *todov1.ListTasksRequest) (*todov1.ListTasksResponse, error) {
+	var tasks []*Task
+	if req.Query != "" {
+		query, err := ParseQuery(req.Query)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		tasks = filterTasks(ctx, s.service, query.Match)
+	} else {
+		tasks = s.service.GetAllTasks(ctx)
+	}
+	return &todov1.ListTasksResponse{Tasks: tasksToProto(tasks)}, nil
+}
+
+// UpdateTask заменяет задачу целиком
+func (s *TaskGRPCServer) UpdateTask(ctx context.Context, req *todov1.UpdateTaskRequest) (*todov1.Task, error) {
+	if req.Title == "" {
+		return nil, status.Error(codes.InvalidArgument, "Поле 'title' обязательно")
+	}
+	priority, err := priorityFromProto(req.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.service.UpdateTask(ctx, int(req.Id), req.Title, req.Description, req.Completed,
+		WithTags(req.Tags), WithPriority(priority), WithProject(req.Project), WithDueDate(timestampFromProto(req.DueDate)))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// DeleteTask удаляет задачу
+func (s *TaskGRPCServer) DeleteTask(ctx context.Context, req *todov1.DeleteTaskRequest) (*emptypb.Empty, error) {
+	if err := s.service.DeleteTask(ctx, int(req.Id)); err != nil {
+		return nil, grpcError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// BatchTasks выполняет пакет операций. Если атомарный пакет отменен, ответ с
+// результатами операций передается в деталях статуса ошибки.
+func (s *TaskGRPCServer) BatchTasks(ctx context.Context, req *todov1.BatchTasksRequest) (*todov1.BatchTasksResponse, error) {
+	if len(req.Operations) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Поле 'operations' обязательно")
+	}
+	if len(req.Operations) > MaxBatchOperations {
+		return nil, status.Errorf(codes.ResourceExhausted, "Не больше %d операций в одном запросе", MaxBatchOperations)
+	}
+
+	ops := make([]BatchOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		priority, err := priorityFromProto(op.Priority)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Операция %d: %s", i, status.Convert(err).Message())
+		}
+		ops[i] = BatchOperation{
+			Op:          batchOpFromProto[op.Op],
+			ID:          int(op.Id),
+			Title:       op.Title,
+			Description: op.Description,
+			Completed:   op.Completed,
+			Tags:        op.Tags,
+			Priority:    priority,
+			Project:     op.Project,
+			DueDate:     timestampFromProto(op.DueDate),
+		}
+	}
+
+	batcher, err := capability[TaskBatcher](s.service, "ApplyBatch")
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	items, batchErr := batcher.ApplyBatch(ctx, ops, req.Atomic)
+
+	response := &todov1.BatchTasksResponse{Atomic: req.Atomic, Results: make([]*todov1.BatchResult, len(items))}
+	for i, item := range items {
+		result := &todov1.BatchResult{Index: int32(i), Code: int32(grpcCode(item.Err)), Task: taskToProto(item.Task)}
+		if item.Err != nil {
+			result.Error = item.Err.Error()
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results[i] = result
+	}
+
+	if batchErr != nil {
+		st, err := status.New(grpcCode(batchErr), batchErr.Error()).WithDetails(response)
+		if err != nil {
+			return nil, grpcError(batchErr)
+		}
+		return nil, st.Err()
+	}
+	return response, nil
+}
+
+// SearchTasks выполняет полнотекстовый поиск в режиме exact или fuzzy
+func (s *TaskGRPCServer) SearchTasks(ctx context.Context, req *todov1.SearchTasksRequest) (*todov1.SearchTasksResponse, error) {
+	if req.Query == "" {
+		return nil, status.Error(codes.InvalidArgument, "Поле 'query' обязательно")
+	}
+	limit, err := grpcLimit(req.Limit, defaultSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := capability[TaskSearcher](s.service, "SearchTasks")
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var results []*SearchResult
+	switch req.Mode {
+	case todov1.SearchTasksRequest_MODE_EXACT:
+		results = searcher.SearchTasks(ctx, req.Query, limit)
+	case todov1.SearchTasksRequest_MODE_FUZZY:
+		results = searcher.FuzzySearchTasks(ctx, req.Query, limit)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Поле 'mode' должно быть MODE_EXACT или MODE_FUZZY")
+	}
+
+	response := &todov1.SearchTasksResponse{Results: make([]*todov1.SearchResult, len(results))}
+	for i, result := range results {
+		response.Results[i] = &todov1.SearchResult{Task: taskToProto(result.Task), Score: result.Score, Snippets: result.Snippets}
+	}
+	return response, nil
+}
+
+// AutocompleteTasks возвращает подсказки заголовков
+func (s *TaskGRPCServer) AutocompleteTasks(ctx context.Context, req *todov1.AutocompleteTasksRequest) (*todov1.AutocompleteTasksResponse, error) {
+	limit, err := grpcLimit(req.Limit, defaultAutocompleteLimit)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := capability[TaskSearcher](s.service, "SuggestTasks")
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	suggestions := searcher.SuggestTasks(ctx, req.Query, limit)
+	return &todov1.AutocompleteTasksResponse{Suggestions: suggestions.Suggestions, DidYouMean: suggestions.DidYouMean}, nil
+}
+
+// grpcLimit проверяет поле limit; 0 означает значение по умолчанию
+func grpcLimit(limit int32, defaultLimit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, status.Error(codes.InvalidArgument, "Неверное поле 'limit'")
+	case limit == 0:
+		return defaultLimit, nil
+	}
+	return int(limit), nil
+}
+
+// WatchTasks передает события об изменении задач. Заголовки ответа отправляются
+// после подписки: задачи, загруженные клиентом после их получения, не пропустят
+// изменений. Если клиент не успевает читать или сервер останавливается, поток
+// завершается с UNAVAILABLE.
+func (s *TaskGRPCServer) WatchTasks(req *todov1.WatchTasksRequest, stream todov1.TaskService_WatchTasksServer) error {
+	subscriber, err := capability[TaskSubscriber](s.service, "Subscribe")
+	if err != nil {
+		return grpcError(err)
+	}
+	events, cancel := subscriber.Subscribe(stream.Context())
+	defer cancel()
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "поток событий закрыт: клиент не успевает читать события или сервер останавливается")
+			}
+			err := stream.Send(&todov1.TaskEvent{
+				Seq:  event.Seq,
+				Type: eventTypeToProto[event.Type],
+				Task: taskToProto(event.Task),
+				Time: timestamppb.New(event.Time),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
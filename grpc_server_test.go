@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	todov1 "todo-api/proto/todo/v1"
+)
+
+// newGRPCClient запускает gRPC сервер на соединении в памяти и возвращает клиент для него
+func newGRPCClient(t *testing.T) (todov1.TaskServiceClient, TaskServiceInterface) {
+	t.Helper()
+
+	service := NewTaskService()
+	return dialGRPC(t, NewGRPCServer(service)), service
+}
+
+// dialGRPC запускает server на соединении в памяти и подключает к нему клиент
+func dialGRPC(t *testing.T, server *grpc.Server) todov1.TaskServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return todov1.NewTaskServiceClient(conn)
+}
+
+// panickingTaskService паникует в GetTask, как сервис с ошибкой в коде
+type panickingTaskService struct {
+	TaskServiceInterface
+}
+
+func (panickingTaskService) GetTask(context.Context, int) (*Task, error) {
+	panic("сбой сервиса")
+}
+
+// expectCode проверяет код и текст ошибки gRPC
+func expectCode(t *testing.T, err error, code codes.Code, message string) {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != code || (message != "" && st.Message() != message) {
+		t.Errorf("Ожидалась ошибка %s %q, получено %s %q", code, message, st.Code(), st.Message())
+	}
+}
+
+func TestGRPC_TaskLifecycle(t *testing.T) {
+	client, _ := newGRPCClient(t)
+	ctx := context.Background()
+
+	due := time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)
+	task, err := client.CreateTask(ctx, &todov1.CreateTaskRequest{
+		Title:    "Купить молоко",
+		Tags:     []string{"Дом", "#дом"},
+		Priority: todov1.Priority_PRIORITY_HIGH,
+		DueDate:  timestamppb.New(due),
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания: %v", err)
+	}
+	if task.Id != 1 || len(task.Tags) != 1 || task.Tags[0] != "дом" || task.Priority != todov1.Priority_PRIORITY_HIGH || !task.DueDate.AsTime().Equal(due) || task.CreatedAt == nil {
+		t.Errorf("Неверная созданная задача: %v", task)
+	}
+
+	got, err := client.GetTask(ctx, &todov1.GetTaskRequest{Id: 1})
+	if err != nil || got.Title != "Купить молоко" {
+		t.Errorf("Неверная задача: %v %v", err, got)
+	}
+
+	// Обновление заменяет задачу целиком, как PUT /tasks/{id}
+	updated, err := client.UpdateTask(ctx, &todov1.UpdateTaskRequest{Id: 1, Title: "Купить кефир", Completed: true, Project: "Дом"})
+	if err != nil || !updated.Completed || updated.Project != "Дом" || len(updated.Tags) != 0 || updated.DueDate != nil || updated.Priority != todov1.Priority_PRIORITY_UNSPECIFIED {
+		t.Errorf("Неверная обновленная задача: %v %v", err, updated)
+	}
+
+	client.CreateTask(ctx, &todov1.CreateTaskRequest{Title: "Позвонить маме", Tags: []string{"семья"}})
+	list, err := client.ListTasks(ctx, &todov1.ListTasksRequest{Query: "tag:семья"})
+	if err != nil || len(list.Tasks) != 1 || list.Tasks[0].Id != 2 {
+		t.Errorf("Неверный результат фильтра: %v %v", err, list)
+	}
+	if list, _ := client.ListTasks(ctx, &todov1.ListTasksRequest{}); len(list.GetTasks()) != 2 {
+		t.Errorf("Ожидалось 2 задачи, получено %d", len(list.GetTasks()))
+	}
+
+	if _, err := client.DeleteTask(ctx, &todov1.DeleteTaskRequest{Id: 1}); err != nil {
+		t.Errorf("Ошибка удаления: %v", err)
+	}
+	_, err = client.GetTask(ctx, &todov1.GetTaskRequest{Id: 1})
+	expectCode(t, err, codes.NotFound, "задача с ID 1 не найдена")
+}
+
+func TestGRPC_ErrorCodes(t *testing.T) {
+	client, _ := newGRPCClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateTask(ctx, &todov1.CreateTaskRequest{})
+	expectCode(t, err, codes.InvalidArgument, "Поле 'title' обязательно")
+
+	_, err = client.CreateTask(ctx, &todov1.CreateTaskRequest{Title: "Задача", Priority: todov1.Priority(42)})
+	expectCode(t, err, codes.InvalidArgument, "Поле 'priority' должно быть 'low', 'medium' или 'high'")
+
+	_, err = client.UpdateTask(ctx, &todov1.UpdateTaskRequest{Id: 42, Title: "Нет такой"})
+	expectCode(t, err, codes.NotFound, "задача с ID 42 не найдена")
+
+	_, err = client.DeleteTask(ctx, &todov1.DeleteTaskRequest{Id: 42})
+	expectCode(t, err, codes.NotFound, "")
+
+	_, err = client.ListTasks(ctx, &todov1.ListTasksRequest{Query: "tag:"})
+	expectCode(t, err, codes.InvalidArgument, "")
+
+	_, err = client.ListTasks(ctx, &todov1.ListTasksRequest{Query: "completed AND NOT"})
+	expectCode(t, err, codes.InvalidArgument, "")
+
+	_, err = client.SearchTasks(ctx, &todov1.SearchTasksRequest{})
+	expectCode(t, err, codes.InvalidArgument, "Поле 'query' обязательно")
+
+	_, err = client.AutocompleteTasks(ctx, &todov1.AutocompleteTasksRequest{Limit: -1})
+	expectCode(t, err, codes.InvalidArgument, "Неверное поле 'limit'")
+
+	_, err = client.BatchTasks(ctx, &todov1.BatchTasksRequest{})
+	expectCode(t, err, codes.InvalidArgument, "Поле 'operations' обязательно")
+}
+
+func TestGRPC_Recover(t *testing.T) {
+	client := dialGRPC(t, NewGRPCServer(panickingTaskService{NewTaskService()}))
+	ctx := context.Background()
+
+	// Паника в обработчике — ошибка Internal для этого вызова, сервер продолжает работать
+	_, err := client.GetTask(ctx, &todov1.GetTaskRequest{Id: 1})
+	expectCode(t, err, codes.Internal, "Внутренняя ошибка сервера")
+	if _, err := client.CreateTask(ctx, &todov1.CreateTaskRequest{Title: "Купить молоко"}); err != nil {
+		t.Errorf("После паники сервер должен отвечать: %v", err)
+	}
+}
+
+func TestGRPC_NotSupported(t *testing.T) {
+	// Сервис только с основными операциями: поиска и пакетов у него нет
+	client := dialGRPC(t, NewGRPCServer(struct{ TaskServiceInterface }{NewTaskService()}))
+	ctx := context.Background()
+
+	_, err := client.SearchTasks(ctx, &todov1.SearchTasksRequest{Query: "молоко"})
+	expectCode(t, err, codes.Unimplemented, "операция не поддерживается: SearchTasks")
+	_, err = client.BatchTasks(ctx, &todov1.BatchTasksRequest{Operations: []*todov1.BatchOperation{{Op: todov1.BatchOperation_OP_CREATE, Title: "Новая"}}})
+	expectCode(t, err, codes.Unimplemented, "")
+	// Отбор по запросу выполняется и без FilterTasks
+	if _, err := client.CreateTask(ctx, &todov1.CreateTaskRequest{Title: "Купить молоко", Tags: []string{"дом"}}); err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.ListTasks(ctx, &todov1.ListTasksRequest{Query: "tag:дом"})
+	if err != nil || len(response.Tasks) != 1 {
+		t.Errorf("Неверный результат отбора: %v %v", err, response)
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{nil, codes.OK},
+		{newNotFoundError("задача с ID %d не найдена", 1), codes.NotFound},
+		{newInvalidInputError("поле 'title' обязательно"), codes.InvalidArgument},
+		{newConflictError("задача с ID %d уже существует", 1), codes.AlreadyExists},
+		{ErrBatchAborted, codes.Aborted},
+		{ErrNotSupported, codes.Unimplemented},
+		{context.DeadlineExceeded, codes.Internal},
+	}
+	for _, tt := range tests {
+		if code := grpcCode(tt.err); code != tt.code {
+			t.Errorf("grpcCode(%v) = %s, ожидалось %s", tt.err, code, tt.code)
+		}
+	}
+}
+
+func TestGRPC_BatchTasks(t *testing.T) {
+	client, service := newGRPCClient(t)
+	ctx := context.Background()
+	service.CreateTask(t.Context(), "Купить молоко", "")
+
+	response, err := client.BatchTasks(ctx, &todov1.BatchTasksRequest{Operations: []*todov1.BatchOperation{
+		{Op: todov1.BatchOperation_OP_CREATE, Title: "Новая"},
+		{Op: todov1.BatchOperation_OP_UPDATE, Id: 1, Title: "Купить кефир", Completed: true},
+		{Op: todov1.BatchOperation_OP_DELETE, Id: 42},
+	}})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка пакета: %v", err)
+	}
+	if response.Succeeded != 2 || response.Failed != 1 || response.Results[2].Code != int32(codes.NotFound) || response.Results[0].Task.GetId() != 2 {
+		t.Errorf("Неверный результат пакета: %v", response)
+	}
+
+	// Отмененный атомарный пакет возвращает ошибку операции, а результаты — в деталях статуса
+	_, err = client.BatchTasks(ctx, &todov1.BatchTasksRequest{Atomic: true, Operations: []*todov1.BatchOperation{
+		{Op: todov1.BatchOperation_OP_CREATE, Title: "Еще одна"},
+		{Op: todov1.BatchOperation_OP_DELETE, Id: 42},
+	}})
+	st := status.Convert(err)
+	if st.Code() != codes.NotFound || len(st.Details()) != 1 {
+		t.Fatalf("Ожидалась ошибка NotFound с деталями, получено %v", err)
+	}
+	aborted, ok := st.Details()[0].(*todov1.BatchTasksResponse)
+	if !ok || aborted.Failed != 2 || aborted.Results[0].Code != int32(codes.Aborted) || aborted.Results[1].Code != int32(codes.NotFound) {
+		t.Errorf("Неверные детали отмененного пакета: %v", st.Details())
+	}
+	if tasks := service.GetAllTasks(t.Context()); len(tasks) != 2 {
+		t.Errorf("Отмененный пакет не должен менять задачи, задач: %d", len(tasks))
+	}
+}
+
+func TestGRPC_Search(t *testing.T) {
+	client, service := newGRPCClient(t)
+	ctx := context.Background()
+	service.CreateTask(t.Context(), "Купить кефир", "в магазине у дома")
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+
+	found, err := client.SearchTasks(ctx, &todov1.SearchTasksRequest{Query: "магазин"})
+	if err != nil || len(found.Results) != 1 || found.Results[0].Task.Id != 1 || found.Results[0].Score <= 0 {
+		t.Errorf("Неверный результат поиска: %v %v", err, found)
+	}
+	fuzzy, err := client.SearchTasks(ctx, &todov1.SearchTasksRequest{Query: "кефри", Mode: todov1.SearchTasksRequest_MODE_FUZZY})
+	if err != nil || len(fuzzy.Results) != 1 || fuzzy.Results[0].Task.Id != 1 {
+		t.Errorf("Неверный результат нечеткого поиска: %v %v", err, fuzzy)
+	}
+	suggestions, err := client.AutocompleteTasks(ctx, &todov1.AutocompleteTasksRequest{Query: "по"})
+	if err != nil || len(suggestions.Suggestions) != 1 || suggestions.Suggestions[0] != "Позвонить маме" {
+		t.Errorf("Неверные подсказки: %v %v", err, suggestions)
+	}
+}
+
+func TestGRPC_WatchTasks(t *testing.T) {
+	client, service := newGRPCClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchTasks(ctx, &todov1.WatchTasksRequest{})
+	if err != nil {
+		t.Fatalf("Ошибка подписки: %v", err)
+	}
+	// Заголовки приходят, когда подписка уже действует
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("Ошибка получения заголовков: %v", err)
+	}
+
+	task := service.CreateTask(t.Context(), "Купить молоко", "")
+	service.UpdateTask(t.Context(), task.ID, "Купить кефир", "", true)
+	service.DeleteTask(t.Context(), task.ID)
+
+	expected := []todov1.TaskEvent_Type{todov1.TaskEvent_TYPE_CREATED, todov1.TaskEvent_TYPE_UPDATED, todov1.TaskEvent_TYPE_DELETED}
+	for i, kind := range expected {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Ошибка получения события: %v", err)
+		}
+		if event.Type != kind || event.Seq != int64(i+1) || event.Task.Id != int64(task.ID) || event.Time == nil {
+			t.Errorf("Событие %d: ожидалось %s, получено %v", i+1, kind, event)
+		}
+	}
+
+	// Отмена контекста завершает поток
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("Ожидалось завершение потока с Canceled, получено %v", err)
+	}
+}
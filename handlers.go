@@ -2,12 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// Число результатов поиска и подсказок по умолчанию
+const (
+	defaultSearchLimit       = 20
+	defaultAutocompleteLimit = 10
+)
+
 // TaskHandler обрабатывает HTTP запросы для задач
 type TaskHandler struct {
 	service TaskServiceInterface
@@ -20,8 +32,11 @@ func NewTaskHandler(service TaskServiceInterface) *TaskHandler {
 
 // CreateTask обрабатывает POST /tasks
 func (th *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.CreateTask")
+	defer span.End()
+
 	var req CreateTaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSON(r, &req); err != nil {
 		http.Error(w, "Неверный JSON", http.StatusBadRequest)
 		return
 	}
@@ -31,21 +46,373 @@ func (th *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task := th.service.CreateTask(req.Title, req.Description)
+	if !req.Priority.Valid() {
+		http.Error(w, "Поле 'priority' должно быть 'low', 'medium' или 'high'", http.StatusBadRequest)
+		return
+	}
+
+	task := th.service.CreateTask(r.Context(), req.Title, req.Description,
+		WithTags(req.Tags), WithPriority(req.Priority), WithProject(req.Project), WithDueDate(req.DueDate))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(task)
 }
 
-// GetTasks обрабатывает GET /tasks
+// QuickAddTask обрабатывает POST /tasks/quick — создает задачу из свободного текста.
+// С параметром dry_run=true только возвращает результат разбора.
+func (th *TaskHandler) QuickAddTask(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.QuickAddTask")
+	defer span.End()
+
+	var req QuickAddRequest
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, "Неверный JSON", http.StatusBadRequest)
+		return
+	}
+
+	parsed := ParseQuickAdd(req.Text, time.Now())
+	if parsed.Title == "" {
+		http.Error(w, "Не удалось выделить заголовок задачи", http.StatusBadRequest)
+		return
+	}
+
+	response := QuickAddResponse{Recognized: parsed.Recognized}
+	status := http.StatusCreated
+	if r.URL.Query().Get("dry_run") == "true" {
+		response.Task = &Task{Title: parsed.Title}
+		for _, opt := range parsed.Options() {
+			opt(response.Task)
+		}
+		status = http.StatusOK
+	} else {
+		response.Task = th.service.CreateTask(r.Context(), parsed.Title, "", parsed.Options()...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// BatchTasks обрабатывает POST /tasks/batch — выполняет набор операций create/update/delete.
+// При atomic=true пакет выполняется целиком или не выполняется вовсе.
+func (th *TaskHandler) BatchTasks(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.BatchTasks")
+	defer span.End()
+
+	var req BatchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, "Неверный JSON", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Operations) == 0 {
+		http.Error(w, "Поле 'operations' обязательно", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) > MaxBatchOperations {
+		http.Error(w, fmt.Sprintf("Не больше %d операций в одном запросе", MaxBatchOperations), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	batcher, err := capability[TaskBatcher](th.service, "ApplyBatch")
+	if err != nil {
+		http.Error(w, err.Error(), batchErrorStatus(err))
+		return
+	}
+	items, batchErr := batcher.ApplyBatch(r.Context(), req.Operations, req.Atomic)
+
+	response := BatchResponse{Atomic: req.Atomic, Results: make([]BatchResult, len(items))}
+	for i, item := range items {
+		result := BatchResult{Index: i, Task: item.Task}
+		switch {
+		case item.Err != nil:
+			result.Status = batchErrorStatus(item.Err)
+			result.Error = item.Err.Error()
+			response.Failed++
+		case req.Operations[i].Op == BatchOpCreate:
+			result.Status = http.StatusCreated
+			response.Succeeded++
+		case req.Operations[i].Op == BatchOpDelete:
+			result.Status = http.StatusNoContent
+			response.Succeeded++
+		default:
+			result.Status = http.StatusOK
+			response.Succeeded++
+		}
+		response.Results[i] = result
+	}
+
+	status := http.StatusOK
+	if batchErr != nil {
+		status = batchErrorStatus(batchErr)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// batchErrorStatus сопоставляет ошибку операции пакета с HTTP статусом
+func batchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrBatchAborted):
+		return http.StatusFailedDependency
+	case errors.Is(err, ErrNotSupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ExportTasks обрабатывает GET /tasks/export?format=csv|ndjson|markdown
+func (th *TaskHandler) ExportTasks(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.ExportTasks")
+	defer span.End()
+
+	format, ok := LookupTaskFormat(r.URL.Query().Get("format"))
+	if !ok || !format.CanExport() {
+		http.Error(w, "Параметр 'format' должен быть одним из: "+TaskFormatNames(true), http.StatusBadRequest)
+		return
+	}
+
+	tasks := filterTasks(r.Context(), th.service, func(*Task) bool { return true })
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "tasks." + format.Extension}))
+	format.ExportTasks(w, tasks)
+}
+
+// CalendarFeed обрабатывает GET /tasks.ics?q=... — календарь задач для подписки в календарных приложениях
+func (th *TaskHandler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.CalendarFeed")
+	defer span.End()
+
+	match := func(*Task) bool { return true }
+	if source := r.URL.Query().Get("q"); source != "" {
+		query, err := ParseQuery(source)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		match = query.Match
+	}
+
+	format, _ := LookupTaskFormat("ics")
+	tasks := filterTasks(r.Context(), th.service, match)
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": "tasks.ics"}))
+	format.ExportTasks(w, tasks)
+}
+
+// ImportTasks обрабатывает POST /tasks/import?format=...&dry_run=true&ids=preserve&timestamps=reset&uids=match.
+// Строки с ошибками пропускаются и перечисляются в ответе, остальные импортируются.
+// Задачи с уже известным UID обновляются, а не создаются заново, если задано uids=match;
+// для формата ics это поведение по умолчанию.
+// Для файлов Todoist, Trello и Taskwarrior ответ перечисляет поля, которые не удалось перенести.
+func (th *TaskHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.ImportTasks")
+	defer span.End()
+
+	importer, err := capability[TaskImporter](th.service, "ImportTasks")
+	if err != nil {
+		http.Error(w, err.Error(), batchErrorStatus(err))
+		return
+	}
+
+	query := r.URL.Query()
+	format, ok := LookupTaskFormat(query.Get("format"))
+	if !ok {
+		http.Error(w, "Параметр 'format' должен быть одним из: "+TaskFormatNames(false), http.StatusBadRequest)
+		return
+	}
+
+	opts := ImportOptions{DryRun: query.Get("dry_run") == "true", MatchUID: format.matchUID}
+	switch query.Get("ids") {
+	case "", "remap":
+	case "preserve":
+		opts.PreserveIDs = true
+	default:
+		http.Error(w, "Параметр 'ids' должен быть 'preserve' или 'remap'", http.StatusBadRequest)
+		return
+	}
+	switch query.Get("timestamps") {
+	case "", "preserve":
+		opts.PreserveTimestamps = true
+	case "reset":
+	default:
+		http.Error(w, "Параметр 'timestamps' должен быть 'preserve' или 'reset'", http.StatusBadRequest)
+		return
+	}
+	switch query.Get("uids") {
+	case "":
+	case "match":
+		opts.MatchUID = true
+	case "new":
+		opts.MatchUID = false
+	default:
+		http.Error(w, "Параметр 'uids' должен быть 'match' или 'new'", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := format.DecodeTasks(http.MaxBytesReader(w, r.Body, MaxImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Файл импорта слишком большой", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := ImportResponse{DryRun: opts.DryRun, Tasks: []*Task{}, Errors: []ImportError{}}
+	var tasks []*Task
+	var lines []int
+	unmapped := make(map[string]int)
+	for _, row := range rows {
+		for _, field := range row.Unmapped {
+			unmapped[field]++
+		}
+		if row.Err != nil {
+			response.Errors = append(response.Errors, ImportError{Line: row.Line, Status: http.StatusBadRequest, Error: row.Err.Error()})
+			continue
+		}
+		tasks = append(tasks, row.Task)
+		lines = append(lines, row.Line)
+	}
+
+	for i, item := range importer.ImportTasks(r.Context(), tasks, opts) {
+		if item.Err != nil {
+			response.Errors = append(response.Errors, ImportError{Line: lines[i], Status: batchErrorStatus(item.Err), Error: item.Err.Error()})
+			continue
+		}
+		if item.Updated {
+			response.Updated++
+		}
+		response.Tasks = append(response.Tasks, item.Task)
+	}
+	sort.Slice(response.Errors, func(i, j int) bool { return response.Errors[i].Line < response.Errors[j].Line })
+	response.Imported = len(response.Tasks) - response.Updated
+	response.Failed = len(response.Errors)
+	if len(unmapped) > 0 {
+		response.Unmapped = unmapped
+	}
+
+	status := http.StatusOK
+	if !opts.DryRun && response.Imported > 0 {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTasks обрабатывает GET /tasks и GET /tasks?q=... с запросом на языке фильтров
 func (th *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
-	tasks := th.service.GetAllTasks()
+	r, span := startHandlerSpan(r, "TaskHandler.GetTasks")
+	defer span.End()
+
+	var tasks []*Task
+	if source := r.URL.Query().Get("q"); source != "" {
+		query, err := ParseQuery(source)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tasks = filterTasks(r.Context(), th.service, query.Match)
+	} else {
+		tasks = th.service.GetAllTasks(r.Context())
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(tasks)
 }
 
+// SearchTasks обрабатывает GET /tasks/search?q=...&limit=...&mode=fuzzy
+func (th *TaskHandler) SearchTasks(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.SearchTasks")
+	defer span.End()
+
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, "Параметр 'q' обязателен", http.StatusBadRequest)
+		return
+	}
+
+	limit, ok := parseLimit(w, r, defaultSearchLimit)
+	if !ok {
+		return
+	}
+
+	searcher, err := capability[TaskSearcher](th.service, "SearchTasks")
+	if err != nil {
+		http.Error(w, err.Error(), batchErrorStatus(err))
+		return
+	}
+
+	var results []*SearchResult
+	switch r.URL.Query().Get("mode") {
+	case "", "exact":
+		results = searcher.SearchTasks(r.Context(), query, limit)
+	case "fuzzy":
+		results = searcher.FuzzySearchTasks(r.Context(), query, limit)
+	default:
+		http.Error(w, "Параметр 'mode' должен быть 'exact' или 'fuzzy'", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// AutocompleteTasks обрабатывает GET /tasks/autocomplete?q=...&limit=...
+func (th *TaskHandler) AutocompleteTasks(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.AutocompleteTasks")
+	defer span.End()
+
+	limit, ok := parseLimit(w, r, defaultAutocompleteLimit)
+	if !ok {
+		return
+	}
+
+	searcher, err := capability[TaskSearcher](th.service, "SuggestTasks")
+	if err != nil {
+		http.Error(w, err.Error(), batchErrorStatus(err))
+		return
+	}
+	suggestions := searcher.SuggestTasks(r.Context(), r.URL.Query().Get("q"), limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// parseLimit читает необязательный параметр limit
+func parseLimit(w http.ResponseWriter, r *http.Request, defaultLimit int) (int, bool) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultLimit, true
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		http.Error(w, "Неверный параметр 'limit'", http.StatusBadRequest)
+		return 0, false
+	}
+	return limit, true
+}
+
 // GetTask обрабатывает GET /tasks/{id}
 func (th *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.GetTask")
+	defer span.End()
+
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -53,7 +420,7 @@ func (th *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := th.service.GetTask(id)
+	task, err := th.service.GetTask(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -65,6 +432,9 @@ func (th *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 
 // UpdateTask обрабатывает PUT /tasks/{id}
 func (th *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.UpdateTask")
+	defer span.End()
+
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -73,7 +443,7 @@ func (th *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateTaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSON(r, &req); err != nil {
 		http.Error(w, "Неверный JSON", http.StatusBadRequest)
 		return
 	}
@@ -83,7 +453,13 @@ func (th *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := th.service.UpdateTask(id, req.Title, req.Description, req.Completed)
+	if !req.Priority.Valid() {
+		http.Error(w, "Поле 'priority' должно быть 'low', 'medium' или 'high'", http.StatusBadRequest)
+		return
+	}
+
+	task, err := th.service.UpdateTask(r.Context(), id, req.Title, req.Description, req.Completed,
+		WithTags(req.Tags), WithPriority(req.Priority), WithProject(req.Project), WithDueDate(req.DueDate))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -95,6 +471,9 @@ func (th *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 // DeleteTask обрабатывает DELETE /tasks/{id}
 func (th *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TaskHandler.DeleteTask")
+	defer span.End()
+
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -102,7 +481,7 @@ func (th *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = th.service.DeleteTask(id)
+	err = th.service.DeleteTask(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthCheckTimeout ограничивает время одной проверки: зависший компонент не должен
+// задерживать ответ дольше, чем оркестратор ждет пробу
+const healthCheckTimeout = 2 * time.Second
+
+// Состояния в ответах /readyz и /health
+const (
+	HealthStatusOK           = "ok"
+	HealthStatusUnavailable  = "unavailable"
+	HealthStatusShuttingDown = "shutting_down"
+	HealthStatusError        = "error"
+)
+
+// HealthChecker реализуют компоненты, доступность которых влияет на готовность сервера:
+// хранилища, фоновые обработчики. Ошибка означает, что компонент сейчас недоступен.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheckFunc позволяет зарегистрировать функцию как HealthChecker
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheck вызывает f(ctx)
+func (f HealthCheckFunc) HealthCheck(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ComponentHealth — результат проверки одного компонента
+type ComponentHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport — ответ /readyz и /health. Failed перечисляет непрошедшие проверки,
+// Checks (только в /health) — результаты всех проверок в порядке регистрации.
+type HealthReport struct {
+	Status string            `json:"status"`
+	Failed []string          `json:"failed,omitempty"`
+	Checks []ComponentHealth `json:"checks,omitempty"`
+}
+
+type healthEntry struct {
+	name    string
+	checker HealthChecker
+}
+
+// HealthRegistry собирает проверки компонентов и отвечает на пробы оркестратора
+type HealthRegistry struct {
+	mutex        sync.RWMutex
+	entries      []healthEntry
+	shuttingDown atomic.Bool
+}
+
+// NewHealthRegistry создает пустой реестр проверок
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{}
+}
+
+// Register добавляет проверку компонента; проверка с тем же именем заменяется
+func (h *HealthRegistry) Register(name string, checker HealthChecker) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, entry := range h.entries {
+		if entry.name == name {
+			h.entries[i].checker = checker
+			return
+		}
+	}
+	h.entries = append(h.entries, healthEntry{name: name, checker: checker})
+}
+
+// SetShuttingDown отмечает начало остановки: с этого момента сервер не готов
+// принимать новые запросы, хотя начатые еще выполняются
+func (h *HealthRegistry) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Check выполняет все проверки параллельно, каждую не дольше healthCheckTimeout
+func (h *HealthRegistry) Check(ctx context.Context) HealthReport {
+	h.mutex.RLock()
+	entries := append([]healthEntry(nil), h.entries...)
+	h.mutex.RUnlock()
+
+	checks := make([]ComponentHealth, len(entries))
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = runHealthCheck(ctx, entry)
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: HealthStatusOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != HealthStatusOK {
+			report.Status = HealthStatusUnavailable
+			report.Failed = append(report.Failed, check.Name)
+		}
+	}
+	if h.shuttingDown.Load() {
+		report.Status = HealthStatusShuttingDown
+	}
+	return report
+}
+
+// runHealthCheck выполняет одну проверку. Проверка, не уважающая ctx, считается
+// проваленной по истечении срока, а ее результат отбрасывается.
+func runHealthCheck(ctx context.Context, entry healthEntry) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				result <- fmt.Errorf("паника при проверке: %v", p)
+			}
+		}()
+		result <- entry.checker.HealthCheck(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("проверка не завершилась: %w", ctx.Err())
+	}
+
+	check := ComponentHealth{
+		Name:      entry.name,
+		Status:    HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Status = HealthStatusError
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// Liveness обрабатывает GET /healthz: процесс жив и обслуживает запросы.
+// Зависимости не проверяются, чтобы недоступное хранилище не приводило к перезапуску.
+func (h *HealthRegistry) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, HealthReport{Status: HealthStatusOK})
+}
+
+// Readiness обрабатывает GET /readyz: 200, если все компоненты доступны и сервер
+// не останавливается, иначе 503 со списком непрошедших проверок
+func (h *HealthRegistry) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+	report.Checks = nil
+	writeHealthReport(w, report)
+}
+
+// Health обрабатывает GET /health: результат и время каждой проверки
+func (h *HealthRegistry) Health(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, h.Check(r.Context()))
+}
+
+// writeHealthReport отвечает 200 для готового сервера и 503 в остальных случаях
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	status := http.StatusOK
+	if report.Status != HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// checkWritableDir проверяет, что в каталоге можно создать файл: так хранилища
+// замечают отмонтированный или заполненный диск и отозванные права
+func checkWritableDir(dir string) error {
+	probe, err := os.CreateTemp(dir, ".health-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// healthDo выполняет запрос к обработчику проверок и разбирает ответ
+func healthDo(t *testing.T, handler http.HandlerFunc, path string) (int, HealthReport) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s: неверный ответ %q: %v", path, w.Body.String(), err)
+	}
+	return w.Code, report
+}
+
+func TestHealthRegistry_Check(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	health := NewHealthRegistry()
+	health.Register("tasks", HealthCheckFunc(func(ctx context.Context) error { return nil }))
+	health.Register("views", HealthCheckFunc(func(ctx context.Context) error { return errors.New("диск заполнен") }))
+	health.Register("slow", HealthCheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	// Проверка, не уважающая ctx, не задерживает ответ
+	health.Register("stuck", HealthCheckFunc(func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+	health.Register("panic", HealthCheckFunc(func(ctx context.Context) error { panic("сбой") }))
+	// Повторная регистрация заменяет проверку, сохраняя ее место
+	health.Register("views", HealthCheckFunc(func(ctx context.Context) error { return errors.New("нет доступа") }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	report := health.Check(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Проверки должны прерываться по сроку, прошло %s", elapsed)
+	}
+
+	if report.Status != HealthStatusUnavailable || !reflect.DeepEqual(report.Failed, []string{"views", "slow", "stuck", "panic"}) {
+		t.Errorf("Неверный итог проверок: %+v", report)
+	}
+	want := []struct{ name, status, err string }{
+		{"tasks", HealthStatusOK, ""},
+		{"views", HealthStatusError, "нет доступа"},
+		{"slow", HealthStatusError, "deadline exceeded"},
+		{"stuck", HealthStatusError, "проверка не завершилась"},
+		{"panic", HealthStatusError, "паника"},
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("Ожидалось %d проверок, получено %+v", len(want), report.Checks)
+	}
+	for i, w := range want {
+		check := report.Checks[i]
+		if check.Name != w.name || check.Status != w.status || !strings.Contains(check.Error, w.err) {
+			t.Errorf("Проверка %d: ожидалось %+v, получено %+v", i, w, check)
+		}
+	}
+	if report.Checks[2].LatencyMs < 40 {
+		t.Errorf("Время проверки должно учитывать ожидание: %+v", report.Checks[2])
+	}
+}
+
+func TestHealthRegistry_Handlers(t *testing.T) {
+	var storageErr error
+	health := NewHealthRegistry()
+	health.Register("storage", HealthCheckFunc(func(ctx context.Context) error { return storageErr }))
+
+	code, report := healthDo(t, health.Readiness, "/readyz")
+	if code != http.StatusOK || report.Status != HealthStatusOK || report.Checks != nil {
+		t.Errorf("Ожидалась готовность без подробностей: %d %+v", code, report)
+	}
+	code, report = healthDo(t, health.Health, "/health")
+	if code != http.StatusOK || len(report.Checks) != 1 || report.Checks[0].Name != "storage" {
+		t.Errorf("Ожидались результаты проверок: %d %+v", code, report)
+	}
+
+	// Недоступное хранилище делает сервер неготовым, но не мертвым
+	storageErr = errors.New("каталог недоступен")
+	code, report = healthDo(t, health.Readiness, "/readyz")
+	if code != http.StatusServiceUnavailable || !reflect.DeepEqual(report.Failed, []string{"storage"}) {
+		t.Errorf("Ожидалась неготовность из-за хранилища: %d %+v", code, report)
+	}
+	if code, report := healthDo(t, health.Liveness, "/healthz"); code != http.StatusOK || report.Status != HealthStatusOK {
+		t.Errorf("Проба живости не зависит от компонентов: %d %+v", code, report)
+	}
+
+	// При остановке сервер не готов, даже если компоненты доступны
+	storageErr = nil
+	health.SetShuttingDown()
+	code, report = healthDo(t, health.Readiness, "/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != HealthStatusShuttingDown {
+		t.Errorf("Ожидалась неготовность при остановке: %d %+v", code, report)
+	}
+	if code, _ := healthDo(t, health.Liveness, "/healthz"); code != http.StatusOK {
+		t.Errorf("При остановке процесс еще жив: %d", code)
+	}
+}
+
+func TestHealthRoutes(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+	for _, path := range []string{"/healthz", "/readyz", "/health"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s: неверный ответ %d %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestComponentHealthChecks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("вложения", func(t *testing.T) {
+		root := filepath.Join(t.TempDir(), "attachments")
+		store, err := NewFSBlobStore(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.HealthCheck(ctx); err != nil {
+			t.Errorf("Хранилище должно быть доступно: %v", err)
+		}
+		entries, _ := os.ReadDir(root)
+		if len(entries) != 0 {
+			t.Errorf("Проверка не должна оставлять файлов: %v", entries)
+		}
+		os.RemoveAll(root)
+		if err := store.HealthCheck(ctx); err == nil {
+			t.Errorf("Удаленный каталог хранилища должен провалить проверку")
+		}
+	})
+
+	t.Run("представления", func(t *testing.T) {
+		views, err := NewViewService(filepath.Join(t.TempDir(), "views.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := views.HealthCheck(ctx); err != nil {
+			t.Errorf("Сервис представлений должен быть доступен: %v", err)
+		}
+		views.Close()
+		if err := views.HealthCheck(ctx); !errors.Is(err, ErrViewServiceClosed) {
+			t.Errorf("Ожидалась ошибка ErrViewServiceClosed, получена %v", err)
+		}
+	})
+
+	t.Run("задачи", func(t *testing.T) {
+		service := NewTaskService()
+		if err := service.HealthCheck(ctx); err != nil {
+			t.Errorf("Сервис задач должен быть доступен: %v", err)
+		}
+
+		// Затянувшаяся операция записи делает сервис недоступным
+		service.mutex.Lock()
+		busyCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		err := service.HealthCheck(busyCtx)
+		service.mutex.Unlock()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Ожидалась ошибка занятого сервиса, получена %v", err)
+		}
+	})
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Формат iCalendar (RFC 5545): задачи выгружаются как компоненты VTODO.
+//
+// Соответствие полям Task:
+//   - UID — Task.UID, а для задач, созданных через API, task-<id>@todo-api;
+//   - SUMMARY, DESCRIPTION — Title, Description;
+//   - DUE — DueDate; STATUS:COMPLETED и COMPLETED — Completed;
+//   - PRIORITY 1–4, 5, 6–9 — high, medium, low;
+//   - CATEGORIES — Tags; X-TODO-PROJECT — Project;
+//   - CREATED, LAST-MODIFIED — CreatedAt, UpdatedAt.
+//
+// У задач нет повторений, поэтому RRULE не выгружается и при импорте игнорируется.
+
+const (
+	icalProductID     = "-//todo-api//Tasks//RU"
+	icalDateLayout    = "20060102"
+	icalDateTimeUTC   = "20060102T150405Z"
+	icalDateTimeLocal = "20060102T150405"
+	// icalLineLimit — максимальная длина строки в октетах без CRLF (RFC 5545, 3.1)
+	icalLineLimit = 75
+)
+
+// TaskUID возвращает UID задачи для календаря
+func TaskUID(task *Task) string {
+	if task.UID != "" {
+		return task.UID
+	}
+	return fmt.Sprintf("task-%d@todo-api", task.ID)
+}
+
+// icalendarEncoder записывает задачи в один VCALENDAR
+type icalendarEncoder struct {
+	writer  *bufio.Writer
+	started bool
+}
+
+func newICalendarEncoder(w io.Writer) taskEncoder {
+	return &icalendarEncoder{writer: bufio.NewWriter(w)}
+}
+
+func (e *icalendarEncoder) begin() {
+	if e.started {
+		return
+	}
+	e.started = true
+	e.writeLine("BEGIN:VCALENDAR")
+	e.writeLine("VERSION:2.0")
+	e.writeLine("PRODID:" + icalProductID)
+	e.writeLine("CALSCALE:GREGORIAN")
+	e.writeLine("X-WR-CALNAME:Задачи")
+}
+
+func (e *icalendarEncoder) Encode(task *Task) error {
+	e.begin()
+	e.writeLine("BEGIN:VTODO")
+	e.writeLine("UID:" + escapeICalText(TaskUID(task)))
+	e.writeLine("DTSTAMP:" + task.UpdatedAt.UTC().Format(icalDateTimeUTC))
+	e.writeLine("CREATED:" + task.CreatedAt.UTC().Format(icalDateTimeUTC))
+	e.writeLine("LAST-MODIFIED:" + task.UpdatedAt.UTC().Format(icalDateTimeUTC))
+	e.writeLine("SUMMARY:" + escapeICalText(task.Title))
+	if task.Description != "" {
+		e.writeLine("DESCRIPTION:" + escapeICalText(task.Description))
+	}
+	if task.DueDate != nil {
+		e.writeLine("DUE:" + task.DueDate.UTC().Format(icalDateTimeUTC))
+	}
+	if task.Completed {
+		e.writeLine("STATUS:COMPLETED")
+		e.writeLine("COMPLETED:" + task.UpdatedAt.UTC().Format(icalDateTimeUTC))
+	} else {
+		e.writeLine("STATUS:NEEDS-ACTION")
+	}
+	if priority := icalPriority(task.Priority); priority != 0 {
+		e.writeLine("PRIORITY:" + strconv.Itoa(priority))
+	}
+	if len(task.Tags) > 0 {
+		categories := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			categories[i] = escapeICalText(tag)
+		}
+		e.writeLine("CATEGORIES:" + strings.Join(categories, ","))
+	}
+	if task.Project != "" {
+		e.writeLine("X-TODO-PROJECT:" + escapeICalText(task.Project))
+	}
+	e.writeLine("END:VTODO")
+	return nil
+}
+
+func (e *icalendarEncoder) Flush() error {
+	e.begin()
+	e.writeLine("END:VCALENDAR")
+	return e.writer.Flush()
+}
+
+// writeLine записывает строку с переносом длинных строк (folding): каждая
+// физическая строка не длиннее 75 октетов, продолжение начинается с пробела,
+// многобайтовые символы UTF-8 не разрываются
+func (e *icalendarEncoder) writeLine(line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		e.writer.WriteString(line[:cut])
+		e.writer.WriteString("\r\n ")
+		line = line[cut:]
+		// Пробел в начале строки продолжения занимает один октет
+		limit = icalLineLimit - 1
+	}
+	e.writer.WriteString(line)
+	e.writer.WriteString("\r\n")
+}
+
+// icalPriority переводит приоритет в шкалу RFC 5545 (0 — не задан)
+func icalPriority(priority Priority) int {
+	switch priority {
+	case PriorityHigh:
+		return 1
+	case PriorityMedium:
+		return 5
+	case PriorityLow:
+		return 9
+	}
+	return 0
+}
+
+// parseICalPriority переводит значение PRIORITY в приоритет задачи
+func parseICalPriority(value string) (Priority, error) {
+	n, err := strconv.Atoi(value)
+	switch {
+	case err != nil || n < 0 || n > 9:
+		return PriorityNone, fmt.Errorf("неверное значение PRIORITY '%s'", value)
+	case n == 0:
+		return PriorityNone, nil
+	case n <= 4:
+		return PriorityHigh, nil
+	case n == 5:
+		return PriorityMedium, nil
+	}
+	return PriorityLow, nil
+}
+
+// icalTextEscaper экранирует значение типа TEXT (RFC 5545, 3.3.11)
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICalText(value string) string {
+	return icalTextEscaper.Replace(value)
+}
+
+// splitICalText снимает экранирование и разбивает значение по неэкранированным запятым
+func splitICalText(value string) []string {
+	var parts []string
+	var part strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			if r == 'n' || r == 'N' {
+				part.WriteRune('\n')
+			} else {
+				part.WriteRune(r)
+			}
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	return append(parts, part.String())
+}
+
+// unescapeICalText снимает экранирование с одиночного значения типа TEXT
+func unescapeICalText(value string) string {
+	return strings.Join(splitICalText(value), ",")
+}
+
+// icalProperty — разобранная строка содержимого NAME;PARAM=VALUE:value
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseICalProperty разбирает строку содержимого; двоеточия и точки с запятой
+// внутри кавычек в параметрах не считаются разделителями
+func parseICalProperty(line string) (icalProperty, error) {
+	prop := icalProperty{params: make(map[string]string)}
+
+	inQuotes := false
+	start := 0
+	var segments []string
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ';' && !inQuotes:
+			segments = append(segments, line[start:i])
+			start = i + 1
+		case c == ':' && !inQuotes:
+			segments = append(segments, line[start:i])
+			prop.value = line[i+1:]
+			prop.name = strings.ToUpper(segments[0])
+			for _, param := range segments[1:] {
+				key, value, _ := strings.Cut(param, "=")
+				prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+			return prop, nil
+		}
+	}
+	return prop, fmt.Errorf("в строке нет двоеточия: '%s'", line)
+}
+
+// parseICalTime разбирает значение DATE или DATE-TIME с учетом параметров TZID и VALUE
+func parseICalTime(prop icalProperty) (time.Time, error) {
+	value := strings.TrimSpace(prop.value)
+
+	location := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			location = loc
+		}
+	}
+
+	layouts := []string{icalDateTimeUTC, icalDateTimeLocal, icalDateLayout}
+	if prop.params["VALUE"] == "DATE" {
+		layouts = []string{icalDateLayout}
+	}
+	for _, layout := range layouts {
+		if layout == icalDateTimeUTC {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неверная дата в %s: '%s'", prop.name, value)
+}
+
+// icalLine — логическая строка после склейки продолжений и номер ее первой физической строки
+type icalLine struct {
+	text string
+	line int
+}
+
+// unfoldICalLines читает строки и склеивает продолжения, начинающиеся с пробела или табуляции
+func unfoldICalLines(r io.Reader) ([]icalLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var lines []icalLine
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if text != "" {
+			lines = append(lines, icalLine{text: text, line: n})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать iCalendar: %w", err)
+	}
+	return lines, nil
+}
+
+// decodeICalendar читает компоненты VTODO; остальные компоненты (VEVENT, VTIMEZONE, VALARM) пропускаются
+func decodeICalendar(r io.Reader) ([]ImportRow, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0].text, "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("файл не начинается с BEGIN:VCALENDAR")
+	}
+
+	var (
+		rows  []ImportRow
+		task  *Task
+		row   ImportRow
+		depth int // вложенность компонентов внутри VTODO
+	)
+	for _, l := range lines {
+		prop, err := parseICalProperty(l.text)
+		if err != nil {
+			if task != nil && row.Err == nil {
+				row.Err = err
+			}
+			continue
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VTODO") && task == nil:
+			task = &Task{}
+			row = ImportRow{Line: l.line}
+			continue
+		case task == nil:
+			continue
+		case prop.name == "BEGIN":
+			depth++
+			continue
+		case prop.name == "END" && depth > 0:
+			depth--
+			continue
+		case prop.name == "END":
+			if row.Err == nil {
+				row.Err = validateImportedTask(task)
+			}
+			if row.Err == nil {
+				row.Task = task
+			}
+			rows = append(rows, row)
+			task = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		if row.Err == nil {
+			row.Err = applyICalProperty(task, prop)
+		}
+	}
+
+	if task != nil {
+		rows = append(rows, ImportRow{Line: row.Line, Err: fmt.Errorf("компонент VTODO не закрыт")})
+	}
+	return rows, nil
+}
+
+// applyICalProperty переносит свойство VTODO в задачу
+func applyICalProperty(task *Task, prop icalProperty) error {
+	switch prop.name {
+	case "UID":
+		task.UID = unescapeICalText(prop.value)
+	case "SUMMARY":
+		task.Title = strings.TrimSpace(unescapeICalText(prop.value))
+	case "DESCRIPTION":
+		task.Description = unescapeICalText(prop.value)
+	case "STATUS":
+		task.Completed = strings.EqualFold(prop.value, "COMPLETED")
+	case "COMPLETED":
+		task.Completed = true
+	case "PRIORITY":
+		priority, err := parseICalPriority(strings.TrimSpace(prop.value))
+		if err != nil {
+			return err
+		}
+		task.Priority = priority
+	case "CATEGORIES":
+		// CATEGORIES может встречаться несколько раз
+		for _, category := range splitICalText(prop.value) {
+			if category = strings.TrimSpace(category); category != "" {
+				task.Tags = append(task.Tags, category)
+			}
+		}
+	case "X-TODO-PROJECT":
+		task.Project = unescapeICalText(prop.value)
+	case "DUE", "CREATED", "LAST-MODIFIED":
+		t, err := parseICalTime(prop)
+		if err != nil {
+			return err
+		}
+		switch prop.name {
+		case "DUE":
+			task.DueDate = &t
+		case "CREATED":
+			task.CreatedAt = t
+		default:
+			task.UpdatedAt = t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestICalendar_FoldingAndEscaping(t *testing.T) {
+	task := &Task{
+		ID:          5,
+		Title:       strings.Repeat("Очень длинный заголовок; с запятой, ", 4),
+		Description: "Строка 1\nСтрока 2 \\ слеш",
+		Tags:        []string{"дом", "a,b"},
+		Priority:    PriorityMedium,
+		CreatedAt:   time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	format, _ := LookupTaskFormat("ics")
+	var buf bytes.Buffer
+	format.ExportTasks(&buf, []*Task{task})
+	output := buf.String()
+
+	if !strings.HasSuffix(output, "END:VCALENDAR\r\n") {
+		t.Fatalf("Календарь должен заканчиваться END:VCALENDAR с CRLF")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\r\n"), "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Errorf("Строка длиннее %d октетов: %q", icalLineLimit, line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Перенос разорвал символ UTF-8: %q", line)
+		}
+	}
+	for _, expected := range []string{"UID:task-5@todo-api", "PRIORITY:5", `CATEGORIES:дом,a\,b`, `DESCRIPTION:Строка 1\nСтрока 2 \\ слеш`} {
+		if !strings.Contains(strings.ReplaceAll(output, "\r\n ", ""), expected) {
+			t.Errorf("В выводе нет %q", expected)
+		}
+	}
+
+	rows, err := format.DecodeTasks(strings.NewReader(output))
+	if err != nil || len(rows) != 1 || rows[0].Err != nil {
+		t.Fatalf("Ошибка разбора: %v %+v", err, rows)
+	}
+	got := rows[0].Task
+	if got.Title != strings.TrimSpace(task.Title) || got.Description != task.Description ||
+		strings.Join(got.Tags, "|") != "дом|a,b" || got.Priority != PriorityMedium || got.UID != "task-5@todo-api" {
+		t.Errorf("Задача не совпадает после разбора: %+v", got)
+	}
+}
+
+func TestDecodeICalendar_ForeignCalendar(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nUID:event-1\r\nSUMMARY:Встреча\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:abc-123@example.com\r\n" +
+		"SUMMARY:Купить \r\n  молоко\r\n" +
+		"DUE;TZID=\"Europe/Moscow\":20240503T180000\r\n" +
+		"PRIORITY:2\r\n" +
+		"CATEGORIES:Дом\r\n" +
+		"CATEGORIES:покупки\r\n" +
+		"RRULE:FREQ=WEEKLY\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nSUMMARY:Напоминание\r\nEND:VALARM\r\n" +
+		"STATUS:COMPLETED\r\n" +
+		"END:VTODO\r\n" +
+		"BEGIN:VTODO\r\nUID:bad\r\nSUMMARY:Плохая дата\r\nDUE;VALUE=DATE:2024-05-03\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	rows, err := decodeICalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Ожидалось 2 задачи, получено %d", len(rows))
+	}
+
+	task := rows[0].Task
+	if rows[0].Err != nil || task.Title != "Купить  молоко" || !task.Completed || task.Priority != PriorityHigh {
+		t.Errorf("Неверно разобрана задача: %v %+v", rows[0].Err, task)
+	}
+	if strings.Join(task.Tags, ",") != "Дом,покупки" {
+		t.Errorf("Ожидались теги из обоих CATEGORIES, получено %v", task.Tags)
+	}
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	if task.DueDate == nil || !task.DueDate.Equal(time.Date(2024, 5, 3, 18, 0, 0, 0, moscow)) {
+		t.Errorf("Неверно разобран срок с TZID: %v", task.DueDate)
+	}
+
+	if rows[1].Err == nil || rows[1].Line != 22 {
+		t.Errorf("Ожидалась ошибка даты для задачи со строки 22, получено %+v", rows[1])
+	}
+}
+
+func TestTaskHandler_ImportICalendar_UpdatesByUID(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+	task := service.CreateTask(t.Context(), "Старый заголовок", "")
+
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VTODO\r\nUID:" + TaskUID(task) + "\r\nSUMMARY:Новый заголовок\r\nSTATUS:COMPLETED\r\nEND:VTODO\r\n" +
+		"BEGIN:VTODO\r\nUID:external-1\r\nSUMMARY:Из календаря\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	importICS := func() ImportResponse {
+		req := httptest.NewRequest("POST", "/tasks/import?format=ics", strings.NewReader(input))
+		w := httptest.NewRecorder()
+		handler.ImportTasks(w, req)
+
+		var response ImportResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Ошибка при парсинге ответа: %v", err)
+		}
+		return response
+	}
+
+	response := importICS()
+	if response.Imported != 1 || response.Updated != 1 {
+		t.Errorf("Ожидались 1 новая и 1 обновленная задача, получено %+v", response)
+	}
+
+	updated, _ := service.GetTask(t.Context(), task.ID)
+	if updated.Title != "Новый заголовок" || !updated.Completed {
+		t.Errorf("Задача не обновилась по UID: %+v", updated)
+	}
+
+	// Повторный импорт того же файла ничего не создает
+	response = importICS()
+	if response.Imported != 0 || response.Updated != 2 || len(service.GetAllTasks(t.Context())) != 2 {
+		t.Errorf("Повторный импорт должен только обновить задачи, получено %+v", response)
+	}
+
+	req := httptest.NewRequest("GET", "/tasks.ics?q="+url.QueryEscape("completed"), nil)
+	w := httptest.NewRecorder()
+	handler.CalendarFeed(w, req)
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("Неверный ответ календаря: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := w.Body.String(); !strings.Contains(body, "SUMMARY:Новый заголовок") || strings.Contains(body, "Из календаря") {
+		t.Errorf("Календарь должен содержать только выполненные задачи:\n%s", body)
+	}
+}
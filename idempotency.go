@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader — заголовок, которым клиент помечает повторяемый запрос
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	// DefaultIdempotencyTTL — сколько хранится ответ на запрос с ключом идемпотентности
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength ограничивает длину ключа
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize — тело запроса с ключом больше этого не попадает в отпечаток
+	// целиком, и ответ на такой запрос не сохраняется
+	maxIdempotentBodySize = MaxImportSize
+	// maxIdempotentResponseSize — ответы больше этого не сохраняются
+	maxIdempotentResponseSize = 1 << 20
+	// defaultMaxIdempotencyEntries и defaultMaxIdempotencyBytes ограничивают число
+	// сохраненных ответов и их общий размер; при превышении удаляются самые старые
+	defaultMaxIdempotencyEntries = 10000
+	defaultMaxIdempotencyBytes   = 64 << 20
+)
+
+// idempotencyEntry хранит отпечаток запроса и, после его завершения, ответ
+type idempotencyEntry struct {
+	key         string
+	fingerprint string
+	done        chan struct{} // закрывается, когда первый запрос завершен
+	stored      bool          // ответ сохранен; читается после закрытия done
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time     // ноль, пока первый запрос выполняется
+	element     *list.Element // место в очереди IdempotencyStore.order
+}
+
+// IdempotencyStore хранит ответы на POST запросы с заголовком Idempotency-Key
+// и воспроизводит их при повторах с тем же ключом. Ключ действует в пределах
+// заголовка Authorization, метода и пути (idempotencyScope).
+type IdempotencyStore struct {
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int
+	entries    map[string]*idempotencyEntry
+	order      *list.List // записи от старых к новым
+	size       int        // общий размер сохраненных тел ответов
+	lastSweep  time.Time
+	now        func() time.Time
+	mutex      sync.Mutex
+}
+
+// NewIdempotencyStore создает хранилище, в котором ответы живут ttl
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:        ttl,
+		maxEntries: defaultMaxIdempotencyEntries,
+		maxBytes:   defaultMaxIdempotencyBytes,
+		entries:    make(map[string]*idempotencyEntry),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Middleware обрабатывает заголовок Idempotency-Key:
+//   - первый запрос с ключом выполняется, и его ответ сохраняется;
+//   - повтор с тем же телом получает сохраненный ответ без повторного выполнения;
+//   - повтор, пришедший пока первый запрос еще выполняется, дожидается его ответа;
+//   - тот же ключ с другим запросом отклоняется с 422.
+//
+// Ответы 5xx не сохраняются, чтобы клиент мог повторить запрос после сбоя; ответы
+// больше maxIdempotentResponseSize не сохраняются, чтобы не занимать память. Тело
+// запроса не буферизуется, и его размер ограничивает сам обработчик; ответ на запрос
+// с телом больше maxIdempotentBodySize выполняется как обычно, но не сохраняется.
+func (s *IdempotencyStore) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Заголовок Idempotency-Key слишком длинный", http.StatusBadRequest)
+			return
+		}
+
+		key = idempotencyScope(r) + key
+
+		for {
+			entry, owner := s.acquire(key)
+			if owner {
+				s.execute(key, entry, next, w, r)
+				return
+			}
+
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+			if !entry.stored {
+				// Первый запрос завершился ошибкой и не сохранился — выполняем заново
+				continue
+			}
+
+			// Тело повтора читается только сейчас: если бы первый запрос не сохранился,
+			// повтор выполнялся бы с непрочитанным телом
+			fingerprint, _ := requestFingerprint(r, r.Body)
+			if fingerprint != entry.fingerprint {
+				http.Error(w, "Ключ Idempotency-Key уже использован с другим запросом", http.StatusUnprocessableEntity)
+				return
+			}
+			replay(entry, w)
+			return
+		}
+	})
+}
+
+// acquire возвращает запись для ключа; owner=true означает, что запись только что
+// создана и вызывающий должен выполнить запрос
+func (s *IdempotencyStore) acquire(key string) (*idempotencyEntry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.sweepLocked(now)
+
+	if entry, exists := s.entries[key]; exists {
+		if entry.expires.IsZero() || now.Before(entry.expires) {
+			return entry, false
+		}
+		s.removeLocked(entry)
+	}
+
+	entry := &idempotencyEntry{key: key, done: make(chan struct{})}
+	entry.element = s.order.PushBack(entry)
+	s.entries[key] = entry
+	s.evictLocked()
+	return entry, true
+}
+
+// execute выполняет запрос, записывая ответ, и сохраняет его для повторов. Тело
+// запроса не копируется: отпечаток считается по мере того, как его читает обработчик.
+func (s *IdempotencyStore) execute(key string, entry *idempotencyEntry, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK, limit: maxIdempotentResponseSize}
+	body := &hashingReader{body: r.Body, hash: sha256.New()}
+	r.Body = body
+	stored := false
+
+	// Запись завершается и при панике обработчика, иначе ожидающие повторы зависнут
+	defer func() {
+		s.mutex.Lock()
+		if stored && s.entries[key] == entry {
+			entry.stored = true
+			entry.status = recorder.status
+			entry.header = recorder.Header().Clone()
+			entry.body = recorder.body.Bytes()
+			entry.expires = s.now().Add(s.ttl)
+			s.size += len(entry.body)
+			s.evictLocked()
+		} else {
+			s.removeLocked(entry)
+		}
+		s.mutex.Unlock()
+		close(entry.done)
+	}()
+
+	next.ServeHTTP(recorder, r)
+	if recorder.status >= http.StatusInternalServerError || recorder.overflow {
+		return
+	}
+	// Обработчик мог прочитать тело не до конца; без остатка отпечаток неполон
+	fingerprint, complete := requestFingerprint(r, body)
+	entry.fingerprint = fingerprint
+	stored = complete
+}
+
+// replay отправляет сохраненный ответ
+func replay(entry *idempotencyEntry, w http.ResponseWriter) {
+	for name, values := range entry.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+}
+
+// sweepLocked удаляет просроченные записи не чаще раза в минуту;
+// вызывающий должен удерживать мьютекс
+func (s *IdempotencyStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for element := s.order.Front(); element != nil; {
+		entry := element.Value.(*idempotencyEntry)
+		element = element.Next()
+		if !entry.expires.IsZero() && !now.Before(entry.expires) {
+			s.removeLocked(entry)
+		}
+	}
+}
+
+// evictLocked удаляет самые старые сохраненные ответы, пока записей или байт больше
+// допустимого. Выполняющиеся запросы не удаляются: их повторы ждут ответа, а число
+// таких записей ограничено числом одновременных запросов.
+func (s *IdempotencyStore) evictLocked() {
+	element := s.order.Front()
+	for element != nil && (len(s.entries) > s.maxEntries || s.size > s.maxBytes) {
+		entry := element.Value.(*idempotencyEntry)
+		element = element.Next()
+		if !entry.expires.IsZero() {
+			s.removeLocked(entry)
+		}
+	}
+}
+
+// removeLocked удаляет запись, если она еще в хранилище; вызывающий должен удерживать мьютекс
+func (s *IdempotencyStore) removeLocked(entry *idempotencyEntry) {
+	if s.entries[entry.key] != entry {
+		return
+	}
+	delete(s.entries, entry.key)
+	s.order.Remove(entry.element)
+	s.size -= len(entry.body)
+}
+
+// idempotencyScope возвращает префикс ключа: клиент, метод и путь запроса. Клиент
+// определяется по заголовку Authorization, поэтому чужой ответ нельзя получить,
+// угадав ключ. Без Authorization ключ действует в пределах метода и пути: адрес
+// клиента при повторе после сбоя сети может смениться, а X-Forwarded-For задает
+// сам клиент.
+func idempotencyScope(r *http.Request) string {
+	scope := r.Method + " " + r.URL.Path + "\n"
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		scope = "auth:" + hex.EncodeToString(sum[:]) + "\n" + scope
+	}
+	return scope
+}
+
+// requestFingerprint дочитывает тело запроса и вычисляет отпечаток по методу,
+// адресу и телу. Если body — hashingReader, уже прочитанная часть берется из него.
+// complete=false означает, что тело больше maxIdempotentBodySize или не прочиталось.
+func requestFingerprint(r *http.Request, body io.Reader) (fingerprint string, complete bool) {
+	reader, ok := body.(*hashingReader)
+	if !ok {
+		reader = &hashingReader{body: body, hash: sha256.New()}
+	}
+	_, err := io.Copy(io.Discard, io.LimitReader(reader, maxIdempotentBodySize+1-reader.size))
+
+	sum := sha256.New()
+	io.WriteString(sum, r.Method+"\n"+r.URL.RequestURI()+"\n")
+	sum.Write(reader.hash.Sum(nil))
+	return hex.EncodeToString(sum.Sum(nil)), err == nil && reader.size <= maxIdempotentBodySize
+}
+
+// hashingReader хеширует тело запроса по мере чтения, не сохраняя его
+type hashingReader struct {
+	body io.Reader
+	hash hash.Hash
+	size int64
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.body.Read(p)
+	h.hash.Write(p[:n])
+	h.size += int64(n)
+	return n, err
+}
+
+func (h *hashingReader) Close() error {
+	if closer, ok := h.body.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// responseRecorder передает ответ клиенту и одновременно запоминает его, пока тело
+// не превышает limit
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int
+	overflow    bool // тело больше limit и не запоминается
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.wroteHeader {
+		return
+	}
+	rr.wroteHeader = true
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+	if !rr.overflow {
+		if rr.body.Len()+len(p) > rr.limit {
+			rr.overflow = true
+			rr.body = bytes.Buffer{}
+		} else {
+			rr.body.Write(p)
+		}
+	}
+	return rr.ResponseWriter.Write(p)
+}
+
+// Flush передает клиенту записанную часть ответа, чтобы потоковые обработчики
+// работали и за этим middleware
+func (rr *responseRecorder) Flush() {
+	http.NewResponseController(rr.ResponseWriter).Flush()
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// postWithKey выполняет POST запрос с ключом идемпотентности через middleware
+func postWithKey(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/tasks", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	service := NewTaskService()
+	handler := NewIdempotencyStore(time.Hour).Middleware(http.HandlerFunc(NewTaskHandler(service).CreateTask))
+
+	first := postWithKey(handler, "abc", `{"title": "Купить молоко"}`)
+	second := postWithKey(handler, "abc", `{"title": "Купить молоко"}`)
+
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201 для обоих запросов, получены %d и %d", first.Code, second.Code)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("Повтор должен вернуть тот же ответ:\n%s\n%s", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Ожидался заголовок Idempotent-Replayed у повтора")
+	}
+	if n := len(service.GetAllTasks(t.Context())); n != 1 {
+		t.Errorf("Ожидалась 1 задача, создано %d", n)
+	}
+
+	// Тот же ключ с другим телом отклоняется
+	if w := postWithKey(handler, "abc", `{"title": "Другое"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	// Другой ключ создает новую задачу
+	postWithKey(handler, "def", `{"title": "Купить молоко"}`)
+	if n := len(service.GetAllTasks(t.Context())); n != 2 {
+		t.Errorf("Ожидалось 2 задачи, создано %d", n)
+	}
+}
+
+func TestIdempotency_ConcurrentDuplicates(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+	handler := NewIdempotencyStore(time.Hour).Middleware(next)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = postWithKey(handler, "same", "{}").Code
+		}(i)
+	}
+
+	// Даем запросам дойти до middleware, затем завершаем первый
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Обработчик должен выполниться один раз, выполнен %d", n)
+	}
+	for i, code := range codes {
+		if code != http.StatusCreated {
+			t.Errorf("Запрос %d: ожидался статус 201, получен %d", i, code)
+		}
+	}
+}
+
+func TestIdempotency_ServerErrorsAndExpiry(t *testing.T) {
+	var calls atomic.Int32
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "сбой", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	store := NewIdempotencyStore(time.Hour)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	handler := store.Middleware(next)
+
+	// Ответ 5xx не сохраняется, поэтому повтор выполняется заново
+	postWithKey(handler, "k", "{}")
+	if w := postWithKey(handler, "k", "{}"); w.Code != http.StatusCreated {
+		t.Errorf("Ожидался статус 201 после повтора, получен %d", w.Code)
+	}
+
+	postWithKey(handler, "k", "{}")
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Ожидалось 2 вызова обработчика, получено %d", n)
+	}
+
+	// После истечения TTL ключ можно использовать снова
+	now = now.Add(2 * time.Hour)
+	postWithKey(handler, "k", `{"другое": true}`)
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Ожидалось 3 вызова обработчика после истечения TTL, получено %d", n)
+	}
+}
+
+// keyedRequest создает POST запрос с ключом идемпотентности от клиента с адресом addr
+func keyedRequest(path, addr, key, body string) *http.Request {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.RemoteAddr = addr
+	req.Header.Set(IdempotencyKeyHeader, key)
+	return req
+}
+
+func TestIdempotency_ScopedKeys(t *testing.T) {
+	var calls atomic.Int32
+	handler := NewIdempotencyStore(time.Hour).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	serve(keyedRequest("/tasks", "192.0.2.1:1000", "k", "{}"))
+	// Повтор после сбоя сети может прийти с другого адреса и все равно получает сохраненный ответ
+	if w := serve(keyedRequest("/tasks", "198.51.100.7:2000", "k", "{}")); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Повтор с другого адреса должен получить сохраненный ответ")
+	}
+	// Клиент с другим токеном, угадавший ключ, чужой ответ не получает
+	withToken := keyedRequest("/tasks", "192.0.2.1:1000", "k", "{}")
+	withToken.Header.Set("Authorization", "Bearer secret")
+	if w := serve(withToken); w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Ответ не должен отдаваться клиенту с другим токеном")
+	}
+	// Тот же токен с другого адреса получает свой ответ
+	retry := keyedRequest("/tasks", "198.51.100.7:1000", "k", "{}")
+	retry.Header.Set("Authorization", "Bearer secret")
+	if w := serve(retry); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Повтор с тем же токеном должен получить сохраненный ответ")
+	}
+	// Один ключ на другом пути — другой запрос, а не конфликт
+	if w := serve(keyedRequest("/tasks/batch", "192.0.2.1:1000", "k", "{}")); w.Code != http.StatusCreated {
+		t.Errorf("Ожидался статус 201 на другом пути, получен %d", w.Code)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Ожидалось 3 вызова обработчика, получено %d", n)
+	}
+}
+
+func TestIdempotency_Limits(t *testing.T) {
+	var calls atomic.Int32
+	handler := func(store *IdempotencyStore, response []byte) http.Handler {
+		return store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Write(response)
+		}))
+	}
+
+	// При превышении числа записей удаляются самые старые
+	store := NewIdempotencyStore(time.Hour)
+	store.maxEntries = 2
+	h := handler(store, []byte("ok"))
+	for _, key := range []string{"a", "b", "c"} {
+		postWithKey(h, key, "{}")
+	}
+	if len(store.entries) != 2 || store.order.Len() != 2 {
+		t.Errorf("Ожидалось 2 записи, получено %d", len(store.entries))
+	}
+	postWithKey(h, "c", "{}")
+	postWithKey(h, "a", "{}")
+	if n := calls.Load(); n != 4 {
+		t.Errorf("Удаленная запись a должна выполниться заново, а c — воспроизвестись: %d вызовов", n)
+	}
+
+	// То же при превышении общего размера ответов
+	store = NewIdempotencyStore(time.Hour)
+	store.maxBytes = 10
+	h = handler(store, []byte("123456"))
+	postWithKey(h, "a", "{}")
+	postWithKey(h, "b", "{}")
+	if len(store.entries) != 1 || store.size != 6 {
+		t.Errorf("Ожидалась 1 запись на 6 байт, получено %d на %d", len(store.entries), store.size)
+	}
+
+	// Слишком большой ответ не сохраняется
+	calls.Store(0)
+	h = handler(NewIdempotencyStore(time.Hour), make([]byte, maxIdempotentResponseSize+1))
+	postWithKey(h, "big", "{}")
+	if w := postWithKey(h, "big", "{}"); w.Body.Len() != maxIdempotentResponseSize+1 || calls.Load() != 2 {
+		t.Errorf("Большой ответ должен выполняться заново: %d вызовов", calls.Load())
+	}
+
+}
+
+func TestIdempotency_LargeBody(t *testing.T) {
+	var calls atomic.Int32
+	var received atomic.Int64
+	handler := NewIdempotencyStore(time.Hour).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// Обработчик читает только начало тела; остаток учитывается в отпечатке
+		n, _ := io.Copy(io.Discard, io.LimitReader(r.Body, 10))
+		received.Store(n)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// Тело больше 1 МБ с ключом принимается и воспроизводится, а не отклоняется с 413
+	body := strings.Repeat(" ", 2<<20)
+	if w := postWithKey(handler, "big", body); w.Code != http.StatusCreated || received.Load() != 10 {
+		t.Fatalf("Ожидался статус 201, получен %d", w.Code)
+	}
+	if w := postWithKey(handler, "big", body); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Повтор большого запроса должен получить сохраненный ответ")
+	}
+	// Отличие в конце тела, которое обработчик не читал, — другой запрос
+	if w := postWithKey(handler, "big", body[:len(body)-1]+"x"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Ожидался статус 422, получен %d", w.Code)
+	}
+
+	// Тело больше maxIdempotentBodySize выполняется, но не сохраняется
+	calls.Store(0)
+	huge := strings.Repeat(" ", maxIdempotentBodySize+1)
+	postWithKey(handler, "huge", huge)
+	if w := postWithKey(handler, "huge", huge); w.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("Слишком большой запрос должен выполняться заново: %d вызовов", calls.Load())
+	}
+}
+
+func TestIdempotency_Flush(t *testing.T) {
+	handler := NewIdempotencyStore(time.Hour).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Ошибка Flush за middleware: %v", err)
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, keyedRequest("/tasks", "192.0.2.1:1234", "k", "{}"))
+	if !w.Flushed {
+		t.Errorf("Ответ должен быть передан клиенту через Flush")
+	}
+}
@@ -0,0 +1,743 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Импорт из чужих трекеров. Эти форматы только читаются: у каждого из них
+// своя модель (доски, секции, зависимости), и полностью перенести ее в Task
+// нельзя. Поля, которые импорт не смог сопоставить, попадают в ImportRow.Unmapped,
+// чтобы команда видела, что осталось в старом инструменте.
+
+// jsonFields — объект JSON с неразобранными значениями полей
+type jsonFields map[string]json.RawMessage
+
+// unmappedFields возвращает непустые поля объекта, которые не перенесены в задачу.
+// known — поля, которые разбирает импорт или которые не несут смысла для задачи
+// (служебные ID, порядок сортировки); они не попадают в отчет.
+func unmappedFields(prefix string, fields jsonFields, known map[string]bool) []string {
+	var unmapped []string
+	for name, value := range fields {
+		if known[name] || isEmptyJSON(value) {
+			continue
+		}
+		unmapped = append(unmapped, prefix+name)
+	}
+	sort.Strings(unmapped)
+	return unmapped
+}
+
+// isEmptyJSON проверяет, что значение не несет данных (null, пустая строка, false, 0, [] или {})
+func isEmptyJSON(value json.RawMessage) bool {
+	switch string(bytes.TrimSpace(value)) {
+	case "", "null", `""`, "false", "0", "[]", "{}":
+		return true
+	}
+	return false
+}
+
+// jsonID приводит идентификатор к строке: в разных версиях API он бывает и числом, и строкой
+func jsonID(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	if isEmptyJSON(value) {
+		return ""
+	}
+	return string(bytes.TrimSpace(value))
+}
+
+// importTag превращает имя метки или списка в тег без пробелов, чтобы по нему работали фильтры
+func importTag(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// checklistLine форматирует пункт чек-листа как элемент списка задач Markdown
+func checklistLine(depth int, done bool, text string) string {
+	box := "[ ]"
+	if done {
+		box = "[x]"
+	}
+	return strings.Repeat("  ", depth) + "- " + box + " " + strings.TrimSpace(text)
+}
+
+// appendDescription добавляет абзац к описанию задачи
+func appendDescription(description, paragraph string) string {
+	description = strings.TrimSpace(description)
+	paragraph = strings.TrimSpace(paragraph)
+	switch {
+	case paragraph == "":
+		return description
+	case description == "":
+		return paragraph
+	}
+	return description + "\n\n" + paragraph
+}
+
+// --- Todoist ---
+
+// todoistIgnored — служебные поля задачи Todoist, которые не нужно переносить
+var todoistIgnored = map[string]bool{
+	"id": true, "v2_id": true, "user_id": true, "project_id": true, "v2_project_id": true,
+	"parent_id": true, "v2_parent_id": true, "content": true, "description": true,
+	"labels": true, "priority": true, "due": true, "checked": true, "completed_at": true,
+	"added_at": true, "date_added": true, "updated_at": true, "child_order": true,
+	"day_order": true, "collapsed": true, "is_deleted": true, "sync_id": true,
+	"added_by_uid": true, "date_completed": true, "in_history": true,
+}
+
+// todoistItem — задача из резервной копии Todoist (Sync API)
+type todoistItem struct {
+	ID          json.RawMessage `json:"id"`
+	ProjectID   json.RawMessage `json:"project_id"`
+	ParentID    json.RawMessage `json:"parent_id"`
+	Content     string          `json:"content"`
+	Description string          `json:"description"`
+	Labels      []string        `json:"labels"`
+	Priority    int             `json:"priority"`
+	Due         *todoistDue     `json:"due"`
+	Checked     bool            `json:"checked"`
+	CompletedAt string          `json:"completed_at"`
+	AddedAt     string          `json:"added_at"`
+}
+
+// todoistDue — срок задачи Todoist: дата, плавающее время или время в UTC
+type todoistDue struct {
+	Date        string `json:"date"`
+	Timezone    string `json:"timezone"`
+	IsRecurring bool   `json:"is_recurring"`
+	String      string `json:"string"`
+}
+
+// todoistBackup — резервная копия Todoist в формате JSON
+type todoistBackup struct {
+	Items    []jsonFields `json:"items"`
+	Projects []struct {
+		ID   json.RawMessage `json:"id"`
+		Name string          `json:"name"`
+	} `json:"projects"`
+	Notes []struct {
+		ItemID  json.RawMessage `json:"item_id"`
+		Content string          `json:"content"`
+	} `json:"notes"`
+}
+
+// todoistAPIPriority переводит приоритет Sync API, где 4 — самый срочный (p1)
+func todoistAPIPriority(value int) Priority {
+	switch value {
+	case 4:
+		return PriorityHigh
+	case 3:
+		return PriorityMedium
+	case 2:
+		return PriorityLow
+	}
+	return PriorityNone
+}
+
+// parseTodoistDue разбирает срок; дата без времени и «плавающее» время считаются местными
+func parseTodoistDue(due *todoistDue) (*time.Time, error) {
+	location := time.Local
+	if due.Timezone != "" {
+		if tz, err := time.LoadLocation(due.Timezone); err == nil {
+			location = tz
+		}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, due.Date, location); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("неверный срок '%s'", due.Date)
+}
+
+// decodeTodoist читает резервную копию Todoist в JSON. Подзадачи становятся
+// чек-листом в описании корневой задачи, комментарии — абзацами описания.
+// Номер строки в отчете — номер задачи в массиве items.
+func decodeTodoist(r io.Reader) ([]ImportRow, error) {
+	var backup todoistBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("неверный JSON Todoist: %w", err)
+	}
+
+	projects := make(map[string]string, len(backup.Projects))
+	for _, project := range backup.Projects {
+		projects[jsonID(project.ID)] = project.Name
+	}
+	notes := make(map[string][]string)
+	for _, note := range backup.Notes {
+		id := jsonID(note.ItemID)
+		notes[id] = append(notes[id], note.Content)
+	}
+
+	items := make([]todoistItem, len(backup.Items))
+	parents := make(map[string]string)
+	var decodeErrs []error
+	for i, fields := range backup.Items {
+		raw, _ := json.Marshal(fields)
+		decodeErrs = append(decodeErrs, json.Unmarshal(raw, &items[i]))
+		if parent := jsonID(items[i].ParentID); parent != "" {
+			parents[jsonID(items[i].ID)] = parent
+		}
+	}
+
+	// root и depth находят корневую задачу подзадачи любой вложенности
+	root := func(id string) (string, int) {
+		depth := 0
+		for parents[id] != "" && depth < len(items) {
+			id = parents[id]
+			depth++
+		}
+		return id, depth
+	}
+
+	var rows []ImportRow
+	byID := make(map[string]*Task)
+	checklists := make(map[*Task][]string)
+	for i, item := range items {
+		line := i + 1
+		if decodeErrs[i] != nil {
+			rows = append(rows, ImportRow{Line: line, Err: fmt.Errorf("неверная задача: %w", decodeErrs[i])})
+			continue
+		}
+
+		id := jsonID(item.ID)
+		if rootID, depth := root(id); depth > 0 {
+			if parent, ok := byID[rootID]; ok {
+				done := item.Checked || item.CompletedAt != ""
+				checklists[parent] = append(checklists[parent], checklistLine(depth-1, done, item.Content))
+				continue
+			}
+		}
+
+		task := &Task{
+			Title:       strings.TrimSpace(item.Content),
+			Description: strings.TrimSpace(item.Description),
+			Tags:        item.Labels,
+			Priority:    todoistAPIPriority(item.Priority),
+			Project:     projects[jsonID(item.ProjectID)],
+			Completed:   item.Checked || item.CompletedAt != "",
+		}
+		for _, note := range notes[id] {
+			task.Description = appendDescription(task.Description, note)
+		}
+
+		row := ImportRow{Line: line, Unmapped: unmappedFields("items.", backup.Items[i], todoistIgnored)}
+		if item.Due != nil && item.Due.Date != "" {
+			due, err := parseTodoistDue(item.Due)
+			if err != nil {
+				rows = append(rows, ImportRow{Line: line, Err: err})
+				continue
+			}
+			task.DueDate = due
+			if item.Due.IsRecurring {
+				row.Unmapped = append(row.Unmapped, "items.due.is_recurring")
+			}
+		}
+		if added, err := time.Parse(time.RFC3339, item.AddedAt); err == nil {
+			task.CreatedAt = added
+			task.UpdatedAt = added
+		}
+		if completed, err := time.Parse(time.RFC3339, item.CompletedAt); err == nil {
+			task.UpdatedAt = completed
+		}
+		if err := validateImportedTask(task); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: err})
+			continue
+		}
+
+		row.Task = task
+		rows = append(rows, row)
+		byID[id] = task
+	}
+
+	for task, lines := range checklists {
+		task.Description = appendDescription(task.Description, strings.Join(lines, "\n"))
+	}
+	return rows, nil
+}
+
+// todoistCSVPriority переводит приоритет шаблона CSV, где 1 — самый срочный, как в интерфейсе
+func todoistCSVPriority(value string) Priority {
+	switch value {
+	case "1":
+		return PriorityHigh
+	case "2":
+		return PriorityMedium
+	case "3":
+		return PriorityLow
+	}
+	return PriorityNone
+}
+
+// splitTodoistLabels вынимает метки @label из текста задачи шаблона CSV
+func splitTodoistLabels(content string) (string, []string) {
+	var title, labels []string
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && strings.HasPrefix(word, "@") {
+			labels = append(labels, word[1:])
+			continue
+		}
+		title = append(title, word)
+	}
+	return strings.Join(title, " "), labels
+}
+
+// decodeTodoistCSV читает шаблон проекта Todoist в CSV (колонки TYPE, CONTENT,
+// DESCRIPTION, PRIORITY, INDENT, DATE, ...). Задачи с INDENT больше 1 становятся
+// чек-листом родителя, строки note — абзацами его описания. Шаблон описывает
+// один проект, поэтому поле project не заполняется.
+func decodeTodoistCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать заголовок CSV: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return nil, fmt.Errorf("в заголовке нет колонки 'CONTENT'")
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []ImportRow
+	var parent *Task // последняя задача верхнего уровня, к которой относятся подзадачи и заметки
+	checklists := make(map[*Task][]string)
+	inSection := false
+	now := time.Now()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, ImportRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		content := field(record, "CONTENT")
+		indent, _ := strconv.Atoi(field(record, "INDENT"))
+		switch strings.ToLower(field(record, "TYPE")) {
+		case "", "task":
+		case "note":
+			if parent != nil {
+				parent.Description = appendDescription(parent.Description, content)
+			}
+			continue
+		case "section":
+			// Секций у нас нет: задачи секции импортируются, а секция попадает в отчет
+			inSection = true
+			parent = nil
+			continue
+		default:
+			continue
+		}
+		if content == "" {
+			continue
+		}
+
+		if indent > 1 && parent != nil {
+			title, _ := splitTodoistLabels(content)
+			checklists[parent] = append(checklists[parent], checklistLine(indent-2, false, title))
+			continue
+		}
+
+		title, labels := splitTodoistLabels(content)
+		task := &Task{
+			Title:       title,
+			Description: field(record, "DESCRIPTION"),
+			Tags:        labels,
+			Priority:    todoistCSVPriority(field(record, "PRIORITY")),
+		}
+		row := ImportRow{Line: line, Task: task}
+		if inSection {
+			row.Unmapped = append(row.Unmapped, "section")
+		}
+		for _, name := range []string{"RESPONSIBLE", "DURATION"} {
+			if field(record, name) != "" {
+				row.Unmapped = append(row.Unmapped, name)
+			}
+		}
+
+		if date := field(record, "DATE"); date != "" {
+			// DATE — текст на естественном языке («2024-05-03», «tomorrow 18:00», «every day»)
+			due, err := parseImportTime("DATE", date)
+			if err != nil {
+				if quick := ParseQuickAdd(date, now); quick.DueDate != nil {
+					due, err = quick.DueDate, nil
+				}
+			}
+			if err != nil || strings.HasPrefix(strings.ToLower(date), "every") {
+				row.Unmapped = append(row.Unmapped, "DATE")
+			}
+			task.DueDate = due
+		}
+
+		if err := validateImportedTask(task); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: err})
+			parent = nil
+			continue
+		}
+		rows = append(rows, row)
+		parent = task
+	}
+
+	for task, lines := range checklists {
+		task.Description = appendDescription(task.Description, strings.Join(lines, "\n"))
+	}
+	return rows, nil
+}
+
+// --- Trello ---
+
+// trelloIgnored — поля карточки Trello, которые разбираются импортом или не несут данных задачи
+var trelloIgnored = map[string]bool{
+	"id": true, "name": true, "desc": true, "closed": true, "due": true, "dueComplete": true,
+	"idList": true, "idLabels": true, "labels": true, "idChecklists": true, "dateLastActivity": true,
+	"idBoard": true, "idShort": true, "pos": true, "shortLink": true, "shortUrl": true, "url": true,
+	"subscribed": true, "badges": true, "cover": true, "descData": true, "checkItemStates": true,
+	"dueReminder": true, "isTemplate": true, "cardRole": true, "nodeId": true, "limits": true,
+	"email": true, "manualCoverAttachment": true, "idAttachmentCover": true, "idMembersVoted": true,
+	"creationMethod": true, "pinned": true, "idOrganization": true, "mirrorSourceId": true,
+}
+
+// trelloCard — карточка доски Trello
+type trelloCard struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Desc             string   `json:"desc"`
+	Closed           bool     `json:"closed"`
+	Due              string   `json:"due"`
+	DueComplete      bool     `json:"dueComplete"`
+	IDList           string   `json:"idList"`
+	IDLabels         []string `json:"idLabels"`
+	DateLastActivity string   `json:"dateLastActivity"`
+}
+
+// trelloBoard — экспорт доски Trello в JSON
+type trelloBoard struct {
+	Name  string       `json:"name"`
+	Cards []jsonFields `json:"cards"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Labels []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Checklists []struct {
+		IDCard     string  `json:"idCard"`
+		Name       string  `json:"name"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+// trelloCreatedAt извлекает время создания из ID карточки: первые 4 байта ObjectId — секунды Unix
+func trelloCreatedAt(id string) (time.Time, bool) {
+	if len(id) < 8 {
+		return time.Time{}, false
+	}
+	prefix, err := hex.DecodeString(id[:8])
+	if err != nil {
+		return time.Time{}, false
+	}
+	seconds := int64(prefix[0])<<24 | int64(prefix[1])<<16 | int64(prefix[2])<<8 | int64(prefix[3])
+	return time.Unix(seconds, 0).UTC(), true
+}
+
+// decodeTrello читает экспорт доски Trello. Доска становится проектом, метки
+// и название списка — тегами, чек-листы — списком задач в описании. Архивные
+// карточки и карточки архивных списков считаются выполненными.
+// Номер строки в отчете — номер карточки в массиве cards.
+func decodeTrello(r io.Reader) ([]ImportRow, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("неверный JSON Trello: %w", err)
+	}
+
+	lists := make(map[string]string)
+	closedLists := make(map[string]bool)
+	for _, list := range board.Lists {
+		lists[list.ID] = list.Name
+		closedLists[list.ID] = list.Closed
+	}
+	labels := make(map[string]string)
+	for _, label := range board.Labels {
+		name := label.Name
+		if name == "" {
+			name = label.Color
+		}
+		labels[label.ID] = name
+	}
+
+	checklists := make(map[string][]string)
+	sort.SliceStable(board.Checklists, func(i, j int) bool { return board.Checklists[i].Pos < board.Checklists[j].Pos })
+	for _, checklist := range board.Checklists {
+		items := checklist.CheckItems
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		lines := []string{checklist.Name + ":"}
+		for _, item := range items {
+			lines = append(lines, checklistLine(0, item.State == "complete", item.Name))
+		}
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], strings.Join(lines, "\n"))
+	}
+
+	rows := make([]ImportRow, 0, len(board.Cards))
+	for i, fields := range board.Cards {
+		line := i + 1
+		var card trelloCard
+		raw, _ := json.Marshal(fields)
+		if err := json.Unmarshal(raw, &card); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: fmt.Errorf("неверная карточка: %w", err)})
+			continue
+		}
+
+		task := &Task{
+			Title:       strings.TrimSpace(card.Name),
+			Description: strings.TrimSpace(card.Desc),
+			Project:     board.Name,
+			Priority:    PriorityNone,
+			Completed:   card.DueComplete || card.Closed || closedLists[card.IDList],
+		}
+		for _, id := range card.IDLabels {
+			if name, ok := labels[id]; ok {
+				task.Tags = append(task.Tags, importTag(name))
+			}
+		}
+		if list := lists[card.IDList]; list != "" {
+			task.Tags = append(task.Tags, importTag(list))
+		}
+		for _, checklist := range checklists[card.ID] {
+			task.Description = appendDescription(task.Description, checklist)
+		}
+
+		if card.Due != "" {
+			due, err := time.Parse(time.RFC3339, card.Due)
+			if err != nil {
+				rows = append(rows, ImportRow{Line: line, Err: fmt.Errorf("неверный срок '%s'", card.Due)})
+				continue
+			}
+			task.DueDate = &due
+		}
+		if created, ok := trelloCreatedAt(card.ID); ok {
+			task.CreatedAt = created
+		}
+		if updated, err := time.Parse(time.RFC3339, card.DateLastActivity); err == nil {
+			task.UpdatedAt = updated
+		}
+
+		if err := validateImportedTask(task); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, ImportRow{Line: line, Task: task, Unmapped: unmappedFields("cards.", fields, trelloIgnored)})
+	}
+	return rows, nil
+}
+
+// --- Taskwarrior ---
+
+// taskwarriorIgnored — поля задачи Taskwarrior, которые разбираются импортом или вычисляются заново
+var taskwarriorIgnored = map[string]bool{
+	"id": true, "uuid": true, "description": true, "status": true, "project": true, "tags": true,
+	"priority": true, "due": true, "entry": true, "modified": true, "end": true, "annotations": true,
+	"urgency": true, "imask": true, "mask": true,
+}
+
+// taskwarriorTask — задача из вывода `task export`
+type taskwarriorTask struct {
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+	Priority    string   `json:"priority"`
+	Due         string   `json:"due"`
+	Entry       string   `json:"entry"`
+	Modified    string   `json:"modified"`
+	End         string   `json:"end"`
+	Annotations []struct {
+		Entry       string `json:"entry"`
+		Description string `json:"description"`
+	} `json:"annotations"`
+}
+
+// parseTaskwarriorTime разбирает дату Taskwarrior (20240503T180000Z)
+func parseTaskwarriorTime(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(icalDateTimeUTC, value)
+	if err != nil {
+		return nil, fmt.Errorf("неверная дата в поле '%s': '%s'", field, value)
+	}
+	return &t, nil
+}
+
+// readTaskwarriorExport возвращает объекты задач: `task export` выводит JSON-массив,
+// а версии до 2.4 — по объекту на строку
+func readTaskwarriorExport(r io.Reader) ([]jsonFields, error) {
+	reader := bufio.NewReader(r)
+	decoder := json.NewDecoder(reader)
+	for {
+		b, err := reader.Peek(1)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			if b[0] == '[' {
+				var tasks []jsonFields
+				if err := decoder.Decode(&tasks); err != nil {
+					return nil, fmt.Errorf("неверный JSON Taskwarrior: %w", err)
+				}
+				return tasks, nil
+			}
+			break
+		}
+		reader.ReadByte()
+	}
+
+	var tasks []jsonFields
+	for {
+		var fields jsonFields
+		err := decoder.Decode(&fields)
+		if err == io.EOF {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("неверный JSON Taskwarrior: %w", err)
+		}
+		tasks = append(tasks, fields)
+	}
+}
+
+// decodeTaskwarrior читает вывод `task export`. Аннотации становятся абзацами
+// описания, удаленные задачи пропускаются с ошибкой, зависимости, повторения
+// и пользовательские атрибуты (UDA) попадают в отчет о несопоставленных полях.
+// Номер строки в отчете — номер задачи в выводе.
+func decodeTaskwarrior(r io.Reader) ([]ImportRow, error) {
+	objects, err := readTaskwarriorExport(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]ImportRow, 0, len(objects))
+	for i, fields := range objects {
+		line := i + 1
+		var source taskwarriorTask
+		raw, _ := json.Marshal(fields)
+		if err := json.Unmarshal(raw, &source); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: fmt.Errorf("неверная задача: %w", err)})
+			continue
+		}
+		if source.Status == "deleted" {
+			rows = append(rows, ImportRow{Line: line, Err: fmt.Errorf("задача удалена в Taskwarrior")})
+			continue
+		}
+
+		row, err := taskwarriorRow(source)
+		if err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: err})
+			continue
+		}
+		row.Line = line
+		row.Unmapped = unmappedFields("", fields, taskwarriorIgnored)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// taskwarriorRow переносит поля задачи Taskwarrior в Task
+func taskwarriorRow(source taskwarriorTask) (ImportRow, error) {
+	task := &Task{
+		Title:     strings.TrimSpace(source.Description),
+		Tags:      source.Tags,
+		Project:   source.Project,
+		Completed: source.Status == "completed",
+	}
+	switch source.Priority {
+	case "H":
+		task.Priority = PriorityHigh
+	case "M":
+		task.Priority = PriorityMedium
+	case "L":
+		task.Priority = PriorityLow
+	case "":
+		task.Priority = PriorityNone
+	default:
+		return ImportRow{}, fmt.Errorf("неверный приоритет '%s'", source.Priority)
+	}
+	for _, annotation := range source.Annotations {
+		task.Description = appendDescription(task.Description, annotation.Description)
+	}
+
+	var err error
+	if task.DueDate, err = parseTaskwarriorTime("due", source.Due); err != nil {
+		return ImportRow{}, err
+	}
+	for _, stamp := range []struct {
+		field, value string
+		target       *time.Time
+	}{
+		{"entry", source.Entry, &task.CreatedAt},
+		{"modified", source.Modified, &task.UpdatedAt},
+	} {
+		t, err := parseTaskwarriorTime(stamp.field, stamp.value)
+		if err != nil {
+			return ImportRow{}, err
+		}
+		if t != nil {
+			*stamp.target = *t
+		}
+	}
+	if task.UpdatedAt.IsZero() {
+		if end, err := parseTaskwarriorTime("end", source.End); err == nil && end != nil {
+			task.UpdatedAt = *end
+		}
+	}
+
+	if err := validateImportedTask(task); err != nil {
+		return ImportRow{}, err
+	}
+	return ImportRow{Task: task}, nil
+}
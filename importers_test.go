@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeImportFixture разбирает файл из testdata/importers выбранным форматом
+func decodeImportFixture(t *testing.T, formatName, fixture string) []ImportRow {
+	t.Helper()
+
+	format, ok := LookupTaskFormat(formatName)
+	if !ok {
+		t.Fatalf("Формат %s не зарегистрирован", formatName)
+	}
+	file, err := os.Open(filepath.Join("testdata", "importers", fixture))
+	if err != nil {
+		t.Fatalf("Ошибка чтения фикстуры: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := format.DecodeTasks(file)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	return rows
+}
+
+func TestDecodeTodoist(t *testing.T) {
+	rows := decodeImportFixture(t, "todoist", "todoist-backup.json")
+	if len(rows) != 3 {
+		t.Fatalf("Ожидалось 3 строки (подзадачи уходят в чек-лист), получено %d: %+v", len(rows), rows)
+	}
+
+	task := rows[0].Task
+	if rows[0].Err != nil || task.Title != "Купить краску" || task.Project != "Ремонт" || task.Priority != PriorityHigh {
+		t.Fatalf("Неверно разобрана задача: %v %+v", rows[0].Err, task)
+	}
+	if strings.Join(task.Tags, ",") != "магазин,срочно" {
+		t.Errorf("Метки должны стать тегами, получено %v", task.Tags)
+	}
+	expected := "Белая, матовая\n\nЧек сохранить для отчета\n\n- [x] Выбрать оттенок\n  - [ ] Спросить у соседей"
+	if task.Description != expected {
+		t.Errorf("Неверное описание:\n%q\nожидалось\n%q", task.Description, expected)
+	}
+	if task.DueDate == nil || !task.DueDate.Equal(time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("Неверный срок: %v", task.DueDate)
+	}
+	if strings.Join(rows[0].Unmapped, ",") != "items.responsible_uid,items.section_id" {
+		t.Errorf("Неверный отчет о полях: %v", rows[0].Unmapped)
+	}
+
+	if rows[1].Task.Priority != PriorityLow || strings.Join(rows[1].Unmapped, ",") != "items.due.is_recurring" {
+		t.Errorf("Повторяющийся срок должен попасть в отчет: %+v", rows[1])
+	}
+	if rows[2].Err == nil || rows[2].Line != 5 {
+		t.Errorf("Ожидалась ошибка для задачи 5 без заголовка, получено %+v", rows[2])
+	}
+}
+
+func TestDecodeTodoistCSV(t *testing.T) {
+	rows := decodeImportFixture(t, "todoist-csv", "todoist-template.csv")
+	if len(rows) != 3 {
+		t.Fatalf("Ожидалось 3 строки, получено %d: %+v", len(rows), rows)
+	}
+
+	task := rows[0].Task
+	if task.Title != "Подготовить отчет" || strings.Join(task.Tags, ",") != "работа,квартал" || task.Priority != PriorityHigh {
+		t.Errorf("Неверно разобрана задача: %+v", task)
+	}
+	if task.Description != "Сводка за квартал\n\nШаблон в общей папке\n\n- [ ] Собрать цифры\n  - [ ] Проверить выгрузку" {
+		t.Errorf("Неверное описание: %q", task.Description)
+	}
+	if task.DueDate == nil || task.DueDate.Format("2006-01-02") != "2024-05-10" {
+		t.Errorf("Неверный срок: %v", task.DueDate)
+	}
+
+	if strings.Join(rows[1].Unmapped, ",") != "section,RESPONSIBLE,DURATION,DATE" {
+		t.Errorf("Неверный отчет о полях: %v", rows[1].Unmapped)
+	}
+	if rows[2].Err == nil || rows[2].Line != 9 {
+		t.Errorf("Ожидалась ошибка для задачи из одних меток в строке 9, получено %+v", rows[2])
+	}
+}
+
+func TestDecodeTrello(t *testing.T) {
+	rows := decodeImportFixture(t, "trello", "trello-board.json")
+	if len(rows) != 3 {
+		t.Fatalf("Ожидалось 3 карточки, получено %d", len(rows))
+	}
+
+	task := rows[0].Task
+	if rows[0].Err != nil || task.Project != "Запуск сайта" || task.Completed {
+		t.Fatalf("Неверно разобрана карточка: %v %+v", rows[0].Err, task)
+	}
+	if strings.Join(task.Tags, ",") != "Дизайн,red,To-Do" {
+		t.Errorf("Метки и список должны стать тегами, получено %v", task.Tags)
+	}
+	if task.Description != "Согласовать с заказчиком\n\nМакеты:\n- [x] Главная\n- [ ] Мобильная версия" {
+		t.Errorf("Неверное описание: %q", task.Description)
+	}
+	if task.CreatedAt.Year() != 2024 || task.DueDate == nil || !task.DueDate.Equal(time.Date(2024, 5, 3, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("Неверные даты: создана %v, срок %v", task.CreatedAt, task.DueDate)
+	}
+	if strings.Join(rows[0].Unmapped, ",") != "cards.idMembers,cards.start" {
+		t.Errorf("Неверный отчет о полях: %v", rows[0].Unmapped)
+	}
+
+	if !rows[1].Task.Completed || strings.Join(rows[1].Unmapped, ",") != "cards.attachments" {
+		t.Errorf("Карточка архивного списка должна быть выполненной: %+v", rows[1])
+	}
+	if rows[2].Err == nil || rows[2].Line != 3 {
+		t.Errorf("Ожидалась ошибка срока для карточки 3, получено %+v", rows[2])
+	}
+}
+
+func TestDecodeTaskwarrior(t *testing.T) {
+	rows := decodeImportFixture(t, "taskwarrior", "taskwarrior-export.json")
+	if len(rows) != 4 {
+		t.Fatalf("Ожидалось 4 строки, получено %d", len(rows))
+	}
+
+	task := rows[0].Task
+	if rows[0].Err != nil || task.Project != "backend" || task.Priority != PriorityHigh || strings.Join(task.Tags, ",") != "deps,security" {
+		t.Fatalf("Неверно разобрана задача: %v %+v", rows[0].Err, task)
+	}
+	if task.Description != "Начать с go.mod\n\nПроверить changelog chi" {
+		t.Errorf("Аннотации должны стать описанием, получено %q", task.Description)
+	}
+	if !task.CreatedAt.Equal(time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC)) || !task.UpdatedAt.Equal(time.Date(2024, 4, 21, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Неверные даты: %v %v", task.CreatedAt, task.UpdatedAt)
+	}
+	if strings.Join(rows[0].Unmapped, ",") != "depends,estimate" {
+		t.Errorf("Зависимости и UDA должны попасть в отчет, получено %v", rows[0].Unmapped)
+	}
+
+	if done := rows[1].Task; !done.Completed || !done.UpdatedAt.Equal(time.Date(2024, 4, 25, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("Выполненная задача разобрана неверно: %+v", done)
+	}
+	if rows[2].Err == nil || rows[3].Err == nil {
+		t.Errorf("Удаленная задача и неверный приоритет должны давать ошибки: %+v %+v", rows[2], rows[3])
+	}
+
+	// Старые версии выводят по объекту на строку без массива
+	format, _ := LookupTaskFormat("taskwarrior")
+	rows, err := format.DecodeTasks(strings.NewReader("{\"description\":\"Первая\",\"status\":\"pending\"}\n{\"description\":\"Вторая\",\"status\":\"pending\",\"recur\":\"daily\"}\n"))
+	if err != nil || len(rows) != 2 || rows[1].Task.Title != "Вторая" || strings.Join(rows[1].Unmapped, ",") != "recur" {
+		t.Errorf("Неверно разобран построчный вывод: %v %+v", err, rows)
+	}
+}
+
+func TestTaskHandler_ImportReportsUnmappedFields(t *testing.T) {
+	handler := NewTaskHandler(NewTaskService())
+	data, err := os.ReadFile(filepath.Join("testdata", "importers", "trello-board.json"))
+	if err != nil {
+		t.Fatalf("Ошибка чтения фикстуры: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/tasks/import?format=trello&dry_run=true", strings.NewReader(string(data)))
+	w := httptest.NewRecorder()
+	handler.ImportTasks(w, req)
+
+	var response ImportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+	if w.Code != http.StatusOK || response.Imported != 2 || response.Failed != 1 {
+		t.Errorf("Неверный результат импорта: %d %+v", w.Code, response)
+	}
+	if response.Unmapped["cards.idMembers"] != 1 || response.Unmapped["cards.attachments"] != 1 {
+		t.Errorf("Неверный отчет о несопоставленных полях: %v", response.Unmapped)
+	}
+
+	// Форматы других трекеров только импортируются
+	req = httptest.NewRequest("GET", "/tasks/export?format=trello", nil)
+	w = httptest.NewRecorder()
+	handler.ExportTasks(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %d для экспорта в trello, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
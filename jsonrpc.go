@@ -0,0 +1,683 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Стандартные коды ошибок JSON-RPC 2.0
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// Коды ошибок сервиса из диапазона, отведенного спецификацией под ошибки сервера
+const (
+	rpcNotFound     = -32001
+	rpcConflict     = -32002
+	rpcBatchAborted = -32003
+)
+
+// rpcError — объект error ответа JSON-RPC
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func invalidParams(format string, args ...any) *rpcError {
+	return &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// rpcErrorFrom сопоставляет ошибку сервиса с кодом JSON-RPC, как batchErrorStatus — с HTTP статусом
+func rpcErrorFrom(err error) *rpcError {
+	var rpcErr *rpcError
+	var syntaxErr *QuerySyntaxError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, ErrNotFound):
+		return &rpcError{Code: rpcNotFound, Message: err.Error()}
+	case errors.Is(err, ErrInvalidInput), errors.As(err, &syntaxErr):
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	case errors.Is(err, ErrConflict):
+		return &rpcError{Code: rpcConflict, Message: err.Error()}
+	case errors.Is(err, ErrBatchAborted):
+		return &rpcError{Code: rpcBatchAborted, Message: err.Error()}
+	case errors.Is(err, ErrNotSupported):
+		return &rpcError{Code: rpcMethodNotFound, Message: err.Error()}
+	default:
+		return &rpcError{Code: rpcInternalError, Message: "Внутренняя ошибка сервера"}
+	}
+}
+
+// rpcRequest — запрос JSON-RPC. Запрос без id — уведомление: он выполняется, но ответа на него нет.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcResponse — ответ JSON-RPC; result и error взаимоисключающие
+type rpcResponse struct {
+	JSONRPC string
+	Result  any
+	Error   *rpcError
+	ID      json.RawMessage
+}
+
+// MarshalJSON выводит либо error, либо result — и "result": null для методов
+// без результата: в ответе без ошибки поле result обязательно
+func (r rpcResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Error   *rpcError       `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{r.JSONRPC, r.Error, r.ID})
+	}
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  any             `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{r.JSONRPC, r.Result, r.ID})
+}
+
+// rpcFailure — ответ с ошибкой на запрос, id которого определить не удалось
+func rpcFailure(code int, message string) rpcResponse {
+	return rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: code, Message: message}, ID: json.RawMessage("null")}
+}
+
+// rpcMethod выполняет метод с параметрами из запроса в контексте ctx
+type rpcMethod func(ctx context.Context, params json.RawMessage) (any, error)
+
+// rpcMethods — таблица методов JSON-RPC по именам. Она разбирает запросы и пакеты и
+// формирует ответы; JSONRPCServer и MCPServer отличаются только набором методов.
+type rpcMethods map[string]rpcMethod
+
+// JSONRPCServer открывает методы TaskServiceInterface по протоколу JSON-RPC 2.0.
+// Проверки и тексты ошибок совпадают с REST обработчиками.
+type JSONRPCServer struct {
+	service TaskServiceInterface
+	methods rpcMethods
+
+	// Слушатели и соединения Serve, которые нужно закрыть при остановке
+	mutex        sync.Mutex
+	listeners    map[net.Listener]struct{}
+	conns        map[net.Conn]struct{}
+	shuttingDown bool
+	active       sync.WaitGroup
+}
+
+// NewJSONRPCServer создает сервер JSON-RPC поверх сервиса задач
+func NewJSONRPCServer(service TaskServiceInterface) *JSONRPCServer {
+	s := &JSONRPCServer{
+		service:   service,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+	s.methods = rpcMethods{
+		"TaskService.CreateTask":       s.createTask,
+		"TaskService.GetTask":          s.getTask,
+		"TaskService.GetAllTasks":      s.getAllTasks,
+		"TaskService.FilterTasks":      s.filterTasks,
+		"TaskService.UpdateTask":       s.updateTask,
+		"TaskService.DeleteTask":       s.deleteTask,
+		"TaskService.ApplyBatch":       s.applyBatch,
+		"TaskService.ImportTasks":      s.importTasks,
+		"TaskService.SearchTasks":      s.searchTasks,
+		"TaskService.FuzzySearchTasks": s.fuzzySearchTasks,
+		"TaskService.SuggestTasks":     s.suggestTasks,
+	}
+	return s
+}
+
+// ServeHTTP обрабатывает POST /rpc. Ответ на пакет из одних уведомлений — 204 без тела.
+func (s *JSONRPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readRPCBody(w, r)
+	if !ok {
+		return
+	}
+
+	response := s.methods.handle(r.Context(), body)
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// readRPCBody читает тело запроса не больше MaxImportSize; при ошибке ответ уже записан
+func readRPCBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Запрос слишком большой", http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, "Не удалось прочитать запрос", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// Serve принимает соединения, например на Unix сокете, и обслуживает их до закрытия
+// listener или остановки сервера (Shutdown)
+func (s *JSONRPCServer) Serve(listener net.Listener) error {
+	s.mutex.Lock()
+	if s.shuttingDown {
+		s.mutex.Unlock()
+		listener.Close()
+		return nil
+	}
+	s.listeners[listener] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.listeners, listener)
+		s.mutex.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		if s.shuttingDown {
+			s.mutex.Unlock()
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.active.Add(1)
+		s.mutex.Unlock()
+
+		go func() {
+			defer s.active.Done()
+			defer func() {
+				s.mutex.Lock()
+				delete(s.conns, conn)
+				s.mutex.Unlock()
+
+				// Сбой в одном соединении не должен останавливать сервер; ServeConn уже закрыл его
+				if p := recover(); p != nil {
+					log.Printf("JSON-RPC: паника при обслуживании соединения: %v\n%s", p, debug.Stack())
+				}
+			}()
+			s.ServeConn(conn)
+		}()
+	}
+}
+
+// Shutdown закрывает слушатели Serve и ждет, пока соединения выполнят начатые запросы:
+// чтение следующего запроса прерывается, и соединение закрывается. Если ctx истекает
+// раньше, оставшиеся соединения закрываются сразу, а Shutdown возвращает ошибку ctx.
+func (s *JSONRPCServer) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.shuttingDown = true
+	for listener := range s.listeners {
+		listener.Close()
+	}
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mutex.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// ServeConn обслуживает соединение как поток запросов (см. rpcMethods.serveStream)
+// и закрывает его
+func (s *JSONRPCServer) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	// У соединения нет контекста запроса: методы выполняются в фоновом контексте
+	if err := s.methods.serveStream(context.Background(), conn, conn); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		log.Printf("JSON-RPC: ошибка чтения соединения: %v", err)
+	}
+}
+
+// serveStream читает запросы и пакеты JSON-RPC, разделенные пробельными символами
+// (обычно переводом строки), и пишет ответ на каждый в отдельной строке. Запросы
+// выполняются по порядку. Возвращает nil, когда поток закончился или после
+// синтаксической ошибки, на которую уже отправлен ответ: дальше поток не разобрать.
+func (m rpcMethods) serveStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	encoder := json.NewEncoder(w)
+	for {
+		var message json.RawMessage
+		if err := decoder.Decode(&message); err != nil {
+			var syntaxErr *json.SyntaxError
+			switch {
+			case errors.Is(err, io.EOF):
+				return nil
+			case errors.As(err, &syntaxErr):
+				encoder.Encode(rpcFailure(rpcParseError, "Неверный JSON"))
+				return nil
+			}
+			return err
+		}
+		if response := m.handle(ctx, message); response != nil {
+			if err := encoder.Encode(response); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle выполняет запрос или пакет и возвращает ответ; nil — отвечать не нужно
+func (m rpcMethods) handle(ctx context.Context, message []byte) any {
+	message = bytes.TrimSpace(message)
+	if len(message) > 0 && message[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(message, &batch); err != nil {
+			return rpcFailure(rpcParseError, "Неверный JSON")
+		}
+		if len(batch) == 0 {
+			return rpcFailure(rpcInvalidRequest, "Пустой пакет")
+		}
+		if len(batch) > MaxBatchOperations {
+			return rpcFailure(rpcInvalidRequest, fmt.Sprintf("Не больше %d запросов в пакете", MaxBatchOperations))
+		}
+
+		// Запросы пакета выполняются по порядку, чтобы скрипт мог создать задачу и сразу изменить ее
+		responses := make([]rpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if response, ok := m.call(ctx, raw); ok {
+				responses = append(responses, response)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return responses
+	}
+
+	if !json.Valid(message) {
+		return rpcFailure(rpcParseError, "Неверный JSON")
+	}
+	if response, ok := m.call(ctx, message); ok {
+		return response
+	}
+	return nil
+}
+
+// call выполняет один запрос; ok=false — это уведомление, и ответа нет
+func (m rpcMethods) call(ctx context.Context, raw json.RawMessage) (rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" || !validRPCID(req.ID) {
+		return rpcFailure(rpcInvalidRequest, "Неверный запрос JSON-RPC 2.0"), true
+	}
+
+	var result any
+	var err error
+	if method, ok := m[req.Method]; ok {
+		result, err = callRPCMethod(ctx, req.Method, method, req.Params)
+	} else {
+		err = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("Метод '%s' не найден", req.Method)}
+	}
+
+	if req.ID == nil {
+		return rpcResponse{}, false
+	}
+	return rpcResponse{JSONRPC: "2.0", Result: result, Error: rpcErrorFrom(err), ID: req.ID}, true
+}
+
+// callRPCMethod выполняет метод, превращая панику во внутреннюю ошибку: на Unix сокете
+// и stdio нет middleware Recoverer, и паника в одном запросе остановила бы весь сервер
+func callRPCMethod(ctx context.Context, name string, method rpcMethod, params json.RawMessage) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("JSON-RPC: паника в методе %s: %v\n%s", name, p, debug.Stack())
+			result, err = nil, fmt.Errorf("паника в методе %s: %v", name, p)
+		}
+	}()
+	return method(ctx, params)
+}
+
+// validRPCID проверяет, что id — строка, число или null (nil — поля id нет, это уведомление)
+func validRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	switch id[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
+// decodeParams разбирает именованные параметры; неизвестные поля — ошибка, чтобы
+// опечатка в скрипте не проходила незамеченной
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if params[0] != '{' {
+		return invalidParams("Параметры передаются объектом с именами полей")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return invalidParams("Неверные параметры: %v", err)
+	}
+	return nil
+}
+
+// rpcTaskParams — поля задачи для CreateTask и UpdateTask
+type rpcTaskParams struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	Tags        []string   `json:"tags"`
+	Priority    Priority   `json:"priority"`
+	Project     string     `json:"project"`
+	DueDate     *time.Time `json:"due_date"`
+}
+
+func (p *rpcTaskParams) validate() error {
+	if p.Title == "" {
+		return invalidParams("Поле 'title' обязательно")
+	}
+	if !p.Priority.Valid() {
+		return invalidParams("Поле 'priority' должно быть 'low', 'medium' или 'high'")
+	}
+	return nil
+}
+
+func (p *rpcTaskParams) options() []TaskOption {
+	return []TaskOption{WithTags(p.Tags), WithPriority(p.Priority), WithProject(p.Project), WithDueDate(p.DueDate)}
+}
+
+// createTask: {title, description, tags, priority, project, due_date} → задача
+func (s *JSONRPCServer) createTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcTaskParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return s.service.CreateTask(ctx, p.Title, p.Description, p.options()...), nil
+}
+
+// getTask: {id} → задача
+func (s *JSONRPCServer) getTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		ID int `json:"id"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	return s.service.GetTask(ctx, p.ID)
+}
+
+// getAllTasks: без параметров → все задачи
+func (s *JSONRPCServer) getAllTasks(ctx context.Context, params json.RawMessage) (any, error) {
+	if err := decodeParams(params, &struct{}{}); err != nil {
+		return nil, err
+	}
+	return s.service.GetAllTasks(ctx), nil
+}
+
+// filterTasks: {query} на языке фильтров → задачи в порядке ID
+func (s *JSONRPCServer) filterTasks(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Query string `json:"query"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	query, err := ParseQuery(p.Query)
+	if err != nil {
+		return nil, err
+	}
+	return filterTasks(ctx, s.service, query.Match), nil
+}
+
+// updateTask: {id, title, description, completed, ...} → задача; заменяет задачу целиком, как PUT /tasks/{id}
+func (s *JSONRPCServer) updateTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcTaskParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return s.service.UpdateTask(ctx, p.ID, p.Title, p.Description, p.Completed, p.options()...)
+}
+
+// deleteTask: {id} → null
+func (s *JSONRPCServer) deleteTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		ID int `json:"id"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	return nil, s.service.DeleteTask(ctx, p.ID)
+}
+
+// rpcItemResult — результат одной операции пакета или одной импортируемой задачи
+type rpcItemResult struct {
+	Index   int       `json:"index"`
+	Task    *Task     `json:"task,omitempty"`
+	Updated bool      `json:"updated,omitempty"`
+	Error   *rpcError `json:"error,omitempty"`
+}
+
+// rpcItemResults переводит результаты сервиса и считает неудачные
+func rpcItemResults(items []BatchItemResult) ([]rpcItemResult, int) {
+	results := make([]rpcItemResult, len(items))
+	failed := 0
+	for i, item := range items {
+		results[i] = rpcItemResult{Index: i, Task: item.Task, Updated: item.Updated, Error: rpcErrorFrom(item.Err)}
+		if item.Err != nil {
+			failed++
+		}
+	}
+	return results, failed
+}
+
+// applyBatch: {operations, atomic} → {atomic, succeeded, failed, results}. Отмененный
+// атомарный пакет возвращает ошибку сбойной операции, а результаты — в ее data.
+func (s *JSONRPCServer) applyBatch(ctx context.Context, params json.RawMessage) (any, error) {
+	var p BatchRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Operations) == 0 {
+		return nil, invalidParams("Поле 'operations' обязательно")
+	}
+	if len(p.Operations) > MaxBatchOperations {
+		return nil, invalidParams("Не больше %d операций в одном запросе", MaxBatchOperations)
+	}
+
+	batcher, err := capability[TaskBatcher](s.service, "ApplyBatch")
+	if err != nil {
+		return nil, err
+	}
+	items, batchErr := batcher.ApplyBatch(ctx, p.Operations, p.Atomic)
+	results, failed := rpcItemResults(items)
+	result := map[string]any{
+		"atomic":    p.Atomic,
+		"succeeded": len(items) - failed,
+		"failed":    failed,
+		"results":   results,
+	}
+	if batchErr != nil {
+		rpcErr := rpcErrorFrom(batchErr)
+		return nil, &rpcError{Code: rpcErr.Code, Message: rpcErr.Message, Data: result}
+	}
+	return result, nil
+}
+
+// importTasks: {tasks, preserve_ids, preserve_timestamps, dry_run, match_uid} →
+// {dry_run, imported, updated, failed, results}
+func (s *JSONRPCServer) importTasks(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Tasks              []*Task `json:"tasks"`
+		PreserveIDs        bool    `json:"preserve_ids"`
+		PreserveTimestamps bool    `json:"preserve_timestamps"`
+		DryRun             bool    `json:"dry_run"`
+		MatchUID           bool    `json:"match_uid"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Tasks) == 0 {
+		return nil, invalidParams("Поле 'tasks' обязательно")
+	}
+	for i, task := range p.Tasks {
+		if task == nil {
+			return nil, invalidParams("Задача %d не может быть null", i)
+		}
+	}
+
+	importer, err := capability[TaskImporter](s.service, "ImportTasks")
+	if err != nil {
+		return nil, err
+	}
+	items := importer.ImportTasks(ctx, p.Tasks, ImportOptions{
+		PreserveIDs:        p.PreserveIDs,
+		PreserveTimestamps: p.PreserveTimestamps,
+		DryRun:             p.DryRun,
+		MatchUID:           p.MatchUID,
+	})
+	results, failed := rpcItemResults(items)
+	updated := 0
+	for _, item := range items {
+		if item.Updated {
+			updated++
+		}
+	}
+	return map[string]any{
+		"dry_run":  p.DryRun,
+		"imported": len(items) - failed - updated,
+		"updated":  updated,
+		"failed":   failed,
+		"results":  results,
+	}, nil
+}
+
+// rpcLimit проверяет поле limit; 0 означает значение по умолчанию
+func rpcLimit(limit, defaultLimit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, invalidParams("Неверное поле 'limit'")
+	case limit == 0:
+		return defaultLimit, nil
+	}
+	return limit, nil
+}
+
+// rpcSearchParams — параметры SearchTasks и FuzzySearchTasks
+type rpcSearchParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+func decodeSearchParams(params json.RawMessage) (rpcSearchParams, error) {
+	var p rpcSearchParams
+	if err := decodeParams(params, &p); err != nil {
+		return p, err
+	}
+	if p.Query == "" {
+		return p, invalidParams("Поле 'query' обязательно")
+	}
+	var err error
+	p.Limit, err = rpcLimit(p.Limit, defaultSearchLimit)
+	return p, err
+}
+
+// searchTasks: {query, limit} → результаты полнотекстового поиска
+func (s *JSONRPCServer) searchTasks(ctx context.Context, params json.RawMessage) (any, error) {
+	p, err := decodeSearchParams(params)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := capability[TaskSearcher](s.service, "SearchTasks")
+	if err != nil {
+		return nil, err
+	}
+	return searcher.SearchTasks(ctx, p.Query, p.Limit), nil
+}
+
+// fuzzySearchTasks: {query, limit} → результаты поиска с опечатками
+func (s *JSONRPCServer) fuzzySearchTasks(ctx context.Context, params json.RawMessage) (any, error) {
+	p, err := decodeSearchParams(params)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := capability[TaskSearcher](s.service, "FuzzySearchTasks")
+	if err != nil {
+		return nil, err
+	}
+	return searcher.FuzzySearchTasks(ctx, p.Query, p.Limit), nil
+}
+
+// suggestTasks: {input, limit} → подсказки заголовков
+func (s *JSONRPCServer) suggestTasks(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Input string `json:"input"`
+		Limit int    `json:"limit"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	limit, err := rpcLimit(p.Limit, defaultAutocompleteLimit)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := capability[TaskSearcher](s.service, "SuggestTasks")
+	if err != nil {
+		return nil, err
+	}
+	return searcher.SuggestTasks(ctx, p.Input, limit), nil
+}
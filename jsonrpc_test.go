@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rpcTestResponse — ответ JSON-RPC, result которого разбирается в тесте
+type rpcTestResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcDo выполняет POST /rpc и возвращает статус и тело ответа
+func rpcDo(t *testing.T, router http.Handler, body string) (int, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body)))
+	return w.Code, w.Body.String()
+}
+
+// rpcCall вызывает метод и разбирает result в out
+func rpcCall(t *testing.T, router http.Handler, method string, params any, out any) *rpcError {
+	t.Helper()
+	request, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": method, "params": params, "id": 1})
+	status, body := rpcDo(t, router, string(request))
+	if status != http.StatusOK {
+		t.Fatalf("%s: неверный статус %d %s", method, status, body)
+	}
+
+	var response rpcTestResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil || response.JSONRPC != "2.0" || string(response.ID) != "1" {
+		t.Fatalf("%s: неверный ответ %v %s", method, err, body)
+	}
+	if response.Error == nil && out != nil {
+		if err := json.Unmarshal(response.Result, out); err != nil {
+			t.Fatalf("%s: неверный result %v %s", method, err, response.Result)
+		}
+	}
+	return response.Error
+}
+
+func TestJSONRPC_TaskMethods(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+
+	var task Task
+	if err := rpcCall(t, router, "TaskService.CreateTask", map[string]any{"title": "Купить молоко", "tags": []string{"Дом"}, "priority": "high"}, &task); err != nil {
+		t.Fatalf("Ошибка создания: %v", err)
+	}
+	if task.ID != 1 || task.Tags[0] != "дом" || task.Priority != PriorityHigh {
+		t.Errorf("Неверная задача: %+v", task)
+	}
+	rpcCall(t, router, "TaskService.CreateTask", map[string]any{"title": "Позвонить маме", "description": "в магазине у дома"}, nil)
+
+	if err := rpcCall(t, router, "TaskService.UpdateTask", map[string]any{"id": 1, "title": "Купить кефир", "completed": true}, &task); err != nil || !task.Completed || len(task.Tags) != 0 {
+		t.Errorf("Неверная обновленная задача: %v %+v", err, task)
+	}
+	if err := rpcCall(t, router, "TaskService.GetTask", map[string]any{"id": 1}, &task); err != nil || task.Title != "Купить кефир" {
+		t.Errorf("Неверная задача: %v %+v", err, task)
+	}
+
+	var tasks []Task
+	if err := rpcCall(t, router, "TaskService.FilterTasks", map[string]any{"query": "completed:false"}, &tasks); err != nil || len(tasks) != 1 || tasks[0].ID != 2 {
+		t.Errorf("Неверный результат фильтра: %v %+v", err, tasks)
+	}
+	if err := rpcCall(t, router, "TaskService.GetAllTasks", nil, &tasks); err != nil || len(tasks) != 2 {
+		t.Errorf("Ожидалось 2 задачи: %v %+v", err, tasks)
+	}
+
+	var results []SearchResult
+	if err := rpcCall(t, router, "TaskService.SearchTasks", map[string]any{"query": "магазин"}, &results); err != nil || len(results) != 1 || results[0].Task.ID != 2 {
+		t.Errorf("Неверный результат поиска: %v %+v", err, results)
+	}
+	if err := rpcCall(t, router, "TaskService.FuzzySearchTasks", map[string]any{"query": "кефри", "limit": 5}, &results); err != nil || len(results) != 1 || results[0].Task.ID != 1 {
+		t.Errorf("Неверный результат нечеткого поиска: %v %+v", err, results)
+	}
+	var suggestions Suggestions
+	if err := rpcCall(t, router, "TaskService.SuggestTasks", map[string]any{"input": "по"}, &suggestions); err != nil || len(suggestions.Suggestions) != 1 {
+		t.Errorf("Неверные подсказки: %v %+v", err, suggestions)
+	}
+
+	// Метод без результата возвращает "result": null
+	_, body := rpcDo(t, router, `{"jsonrpc":"2.0","method":"TaskService.DeleteTask","params":{"id":1},"id":"a"}`)
+	if strings.TrimSpace(body) != `{"jsonrpc":"2.0","result":null,"id":"a"}` {
+		t.Errorf("Неверный ответ на удаление: %s", body)
+	}
+}
+
+func TestJSONRPC_Errors(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+	rpcCall(t, router, "TaskService.CreateTask", map[string]any{"title": "Купить молоко"}, nil)
+
+	tests := []struct {
+		body    string
+		code    int
+		message string
+	}{
+		{`{"jsonrpc":"2.0","method":`, rpcParseError, "Неверный JSON"},
+		{`[{"jsonrpc":"2.0"`, rpcParseError, "Неверный JSON"},
+		{`[]`, rpcInvalidRequest, "Пустой пакет"},
+		{`{"jsonrpc":"1.0","method":"TaskService.GetTask","id":1}`, rpcInvalidRequest, ""},
+		{`{"jsonrpc":"2.0","method":"TaskService.GetTask","id":{}}`, rpcInvalidRequest, ""},
+		{`"TaskService.GetTask"`, rpcInvalidRequest, ""},
+		{`{"jsonrpc":"2.0","method":"TaskService.Nope","id":1}`, rpcMethodNotFound, "Метод 'TaskService.Nope' не найден"},
+		{`{"jsonrpc":"2.0","method":"TaskService.GetTask","params":[1],"id":1}`, rpcInvalidParams, "Параметры передаются объектом с именами полей"},
+		{`{"jsonrpc":"2.0","method":"TaskService.GetTask","params":{"ID":1,"tilte":""},"id":1}`, rpcInvalidParams, ""},
+		{`{"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{},"id":1}`, rpcInvalidParams, "Поле 'title' обязательно"},
+		{`{"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{"title":"Задача","priority":"urgent"},"id":1}`, rpcInvalidParams, "Поле 'priority' должно быть 'low', 'medium' или 'high'"},
+		{`{"jsonrpc":"2.0","method":"TaskService.FilterTasks","params":{"query":"tag:"},"id":1}`, rpcInvalidParams, ""},
+		{`{"jsonrpc":"2.0","method":"TaskService.FilterTasks","params":{"query":"completed AND NOT"},"id":1}`, rpcInvalidParams, ""},
+		{`{"jsonrpc":"2.0","method":"TaskService.SearchTasks","params":{"limit":-1},"id":1}`, rpcInvalidParams, "Поле 'query' обязательно"},
+		{`{"jsonrpc":"2.0","method":"TaskService.SuggestTasks","params":{"limit":-1},"id":1}`, rpcInvalidParams, "Неверное поле 'limit'"},
+		{`{"jsonrpc":"2.0","method":"TaskService.GetTask","params":{"id":42},"id":1}`, rpcNotFound, "задача с ID 42 не найдена"},
+		{`{"jsonrpc":"2.0","method":"TaskService.ImportTasks","params":{"tasks":[{"id":1,"title":"Дубликат"}],"preserve_ids":true},"id":1}`, 0, ""},
+	}
+	for _, tt := range tests {
+		status, body := rpcDo(t, router, tt.body)
+		var response rpcTestResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil || status != http.StatusOK {
+			t.Errorf("%s: неверный ответ %d %s", tt.body, status, body)
+			continue
+		}
+		if tt.code == 0 {
+			continue
+		}
+		if response.Error == nil || response.Error.Code != tt.code || (tt.message != "" && response.Error.Message != tt.message) {
+			t.Errorf("%s: ожидалась ошибка %d %q, получено %s", tt.body, tt.code, tt.message, body)
+		}
+	}
+}
+
+func TestRPCErrorFrom(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{newNotFoundError("задача с ID %d не найдена", 1), rpcNotFound},
+		{newInvalidInputError("поле 'title' обязательно"), rpcInvalidParams},
+		{newConflictError("задача с ID %d уже существует", 1), rpcConflict},
+		{ErrBatchAborted, rpcBatchAborted},
+		{ErrNotSupported, rpcMethodNotFound},
+		{&QuerySyntaxError{Pos: 1, Msg: "ожидалось значение"}, rpcInvalidParams},
+		{invalidParams("Неверные параметры"), rpcInvalidParams},
+		{context.DeadlineExceeded, rpcInternalError},
+	}
+	for _, tt := range tests {
+		if err := rpcErrorFrom(tt.err); err.Code != tt.code {
+			t.Errorf("rpcErrorFrom(%v) = %d, ожидалось %d", tt.err, err.Code, tt.code)
+		}
+	}
+	if rpcErrorFrom(nil) != nil {
+		t.Errorf("rpcErrorFrom(nil) должен возвращать nil")
+	}
+}
+
+func TestJSONRPC_BatchAndNotifications(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+
+	// Уведомления выполняются без ответа; запросы пакета выполняются по порядку
+	status, body := rpcDo(t, router, `[
+		{"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{"title":"Купить молоко"},"id":1},
+		{"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{"title":"Позвонить маме"}},
+		{"jsonrpc":"2.0","method":"TaskService.UpdateTask","params":{"id":2,"title":"Позвонить папе"},"id":null},
+		{"jsonrpc":"2.0","method":"TaskService.Nope"},
+		42,
+		{"jsonrpc":"2.0","method":"TaskService.GetTask","params":{"id":99},"id":"last"}
+	]`)
+	var responses []rpcTestResponse
+	if err := json.Unmarshal([]byte(body), &responses); err != nil || status != http.StatusOK {
+		t.Fatalf("Неверный ответ на пакет: %d %v %s", status, err, body)
+	}
+	if len(responses) != 4 {
+		t.Fatalf("Ожидалось 4 ответа, получено %d: %s", len(responses), body)
+	}
+	if string(responses[0].ID) != "1" || responses[0].Error != nil {
+		t.Errorf("Неверный ответ на создание: %+v", responses[0])
+	}
+	if string(responses[1].ID) != "null" || responses[1].Error != nil {
+		t.Errorf("Запрос с id null должен получить ответ: %+v", responses[1])
+	}
+	if responses[2].Error == nil || responses[2].Error.Code != rpcInvalidRequest {
+		t.Errorf("Ожидалась ошибка неверного запроса: %+v", responses[2])
+	}
+	if string(responses[3].ID) != `"last"` || responses[3].Error == nil || responses[3].Error.Code != rpcNotFound {
+		t.Errorf("Ожидалась ошибка NotFound: %+v", responses[3])
+	}
+	if task, err := service.GetTask(t.Context(), 2); err != nil || task.Title != "Позвонить папе" {
+		t.Errorf("Уведомление не выполнено: %v %+v", err, task)
+	}
+
+	// На пакет из одних уведомлений ответа нет
+	status, body = rpcDo(t, router, `[{"jsonrpc":"2.0","method":"TaskService.DeleteTask","params":{"id":1}}]`)
+	if status != http.StatusNoContent || body != "" {
+		t.Errorf("Ожидался статус 204 без тела, получено %d %s", status, body)
+	}
+	if _, err := service.GetTask(t.Context(), 1); err == nil {
+		t.Errorf("Уведомление об удалении не выполнено")
+	}
+}
+
+func TestJSONRPC_ApplyBatchAndImport(t *testing.T) {
+	router, service := newCalDAVRouter(t)
+	service.CreateTask(t.Context(), "Купить молоко", "")
+
+	var result struct {
+		Succeeded int
+		Failed    int
+		Results   []rpcItemResult
+	}
+	err := rpcCall(t, router, "TaskService.ApplyBatch", map[string]any{"operations": []map[string]any{
+		{"op": "create", "title": "Новая"},
+		{"op": "delete", "id": 42},
+	}}, &result)
+	if err != nil || result.Succeeded != 1 || result.Failed != 1 || result.Results[1].Error.Code != rpcNotFound {
+		t.Errorf("Неверный результат пакета: %v %+v", err, result)
+	}
+
+	// Отмененный атомарный пакет возвращает ошибку, а результаты — в data
+	err = rpcCall(t, router, "TaskService.ApplyBatch", map[string]any{"atomic": true, "operations": []map[string]any{
+		{"op": "create", "title": "Еще одна"},
+		{"op": "delete", "id": 42},
+	}}, nil)
+	if err == nil || err.Code != rpcNotFound {
+		t.Fatalf("Ожидалась ошибка NotFound, получено %+v", err)
+	}
+	data, _ := json.Marshal(err.Data)
+	json.Unmarshal(data, &result)
+	if result.Failed != 2 || result.Results[0].Error.Code != rpcBatchAborted {
+		t.Errorf("Неверные данные отмененного пакета: %s", data)
+	}
+	if tasks := service.GetAllTasks(t.Context()); len(tasks) != 2 {
+		t.Errorf("Отмененный пакет не должен менять задачи, задач: %d", len(tasks))
+	}
+
+	var imported struct {
+		Imported int
+		Failed   int
+		Results  []rpcItemResult
+	}
+	err = rpcCall(t, router, "TaskService.ImportTasks", map[string]any{"preserve_ids": true, "tasks": []map[string]any{
+		{"id": 10, "title": "Из скрипта"},
+		{"id": 1, "title": "Дубликат"},
+	}}, &imported)
+	if err != nil || imported.Imported != 1 || imported.Failed != 1 || imported.Results[1].Error.Code != rpcConflict {
+		t.Errorf("Неверный результат импорта: %v %+v", err, imported)
+	}
+	if err := rpcCall(t, router, "TaskService.ApplyBatch", map[string]any{}, nil); err == nil || err.Message != "Поле 'operations' обязательно" {
+		t.Errorf("Ожидалась ошибка пустого пакета, получено %+v", err)
+	}
+}
+
+func TestJSONRPC_UnixSocket(t *testing.T) {
+	service := NewTaskService()
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "rpc.sock"))
+	if err != nil {
+		t.Fatalf("Ошибка открытия сокета: %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- NewJSONRPCServer(service).Serve(listener) }()
+
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer conn.Close()
+
+	// По запросу на строку; на уведомление ответа нет
+	conn.Write([]byte(`{"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{"title":"Купить молоко"},"id":1}` + "\n"))
+	conn.Write([]byte(`{"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{"title":"Позвонить маме"}}` + "\n"))
+	conn.Write([]byte(`[{"jsonrpc":"2.0","method":"TaskService.GetAllTasks","id":2}]` + "\n"))
+	conn.Write([]byte("{oops\n"))
+
+	reader := bufio.NewReader(conn)
+	var created rpcTestResponse
+	line, _ := reader.ReadString('\n')
+	if err := json.Unmarshal([]byte(line), &created); err != nil || string(created.ID) != "1" || created.Error != nil {
+		t.Errorf("Неверный ответ на создание: %s", line)
+	}
+	var batch []rpcTestResponse
+	line, _ = reader.ReadString('\n')
+	if err := json.Unmarshal([]byte(line), &batch); err != nil || len(batch) != 1 || !strings.Contains(string(batch[0].Result), "Позвонить маме") {
+		t.Errorf("Неверный ответ на пакет: %s", line)
+	}
+
+	// Ошибка разбора закрывает соединение: дальше поток не разобрать
+	var parseErr rpcTestResponse
+	line, _ = reader.ReadString('\n')
+	if err := json.Unmarshal([]byte(line), &parseErr); err != nil || parseErr.Error == nil || parseErr.Error.Code != rpcParseError {
+		t.Errorf("Ожидалась ошибка разбора: %s", line)
+	}
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Errorf("Соединение должно быть закрыто после ошибки разбора")
+	}
+
+	listener.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve должен завершаться без ошибки после закрытия listener: %v", err)
+	}
+}
+
+func TestJSONRPC_Recover(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "rpc.sock"))
+	if err != nil {
+		t.Fatalf("Ошибка открытия сокета: %v", err)
+	}
+	defer listener.Close()
+	go NewJSONRPCServer(panickingTaskService{NewTaskService()}).Serve(listener)
+
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// Паника в методе — внутренняя ошибка этого запроса, соединение продолжает работать
+	conn.Write([]byte(`{"jsonrpc":"2.0","method":"TaskService.GetTask","params":{"id":1},"id":1}` + "\n"))
+	conn.Write([]byte(`{"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{"title":"Купить молоко"},"id":2}` + "\n"))
+
+	var failed, created rpcTestResponse
+	line, _ := reader.ReadString('\n')
+	if err := json.Unmarshal([]byte(line), &failed); err != nil || failed.Error == nil || failed.Error.Code != rpcInternalError || failed.Error.Message != "Внутренняя ошибка сервера" {
+		t.Errorf("Ожидалась внутренняя ошибка: %s", line)
+	}
+	line, _ = reader.ReadString('\n')
+	if err := json.Unmarshal([]byte(line), &created); err != nil || created.Error != nil {
+		t.Errorf("После паники сервер должен отвечать: %s", line)
+	}
+}
+
+func TestJSONRPC_Shutdown(t *testing.T) {
+	// startBlocking запускает сервер с методом Test.Block, который ждет закрытия release
+	startBlocking := func(t *testing.T, release <-chan struct{}) (*JSONRPCServer, string, <-chan struct{}, <-chan error) {
+		t.Helper()
+		server := NewJSONRPCServer(NewTaskService())
+		started := make(chan struct{})
+		server.methods["Test.Block"] = func(context.Context, json.RawMessage) (any, error) {
+			close(started)
+			<-release
+			return "готово", nil
+		}
+		listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "rpc.sock"))
+		if err != nil {
+			t.Fatalf("Ошибка открытия сокета: %v", err)
+		}
+		served := make(chan error, 1)
+		go func() { served <- server.Serve(listener) }()
+		return server, listener.Addr().String(), started, served
+	}
+	dial := func(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+		t.Helper()
+		conn, err := net.Dial("unix", addr)
+		if err != nil {
+			t.Fatalf("Ошибка подключения: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn, bufio.NewReader(conn)
+	}
+
+	t.Run("завершение начатых запросов", func(t *testing.T) {
+		release := make(chan struct{})
+		server, addr, started, served := startBlocking(t, release)
+
+		// Простаивающее соединение: запрос выполнен, следующего нет
+		idle, idleReader := dial(t, addr)
+		idle.Write([]byte(`{"jsonrpc":"2.0","method":"TaskService.GetAllTasks","id":1}` + "\n"))
+		if _, err := idleReader.ReadString('\n'); err != nil {
+			t.Fatalf("Ошибка чтения ответа: %v", err)
+		}
+		busy, busyReader := dial(t, addr)
+		busy.Write([]byte(`{"jsonrpc":"2.0","method":"Test.Block","id":2}` + "\n"))
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped := make(chan error)
+		go func() { stopped <- server.Shutdown(ctx) }()
+
+		if err := <-served; err != nil {
+			t.Errorf("Serve должен завершаться без ошибки после Shutdown: %v", err)
+		}
+		if _, err := idleReader.ReadString('\n'); err == nil {
+			t.Errorf("Простаивающее соединение должно закрыться")
+		}
+		select {
+		case err := <-stopped:
+			t.Fatalf("Shutdown не дождался начатого запроса: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		line, err := busyReader.ReadString('\n')
+		if err != nil || !strings.Contains(line, "готово") {
+			t.Errorf("Начатый запрос должен получить ответ: %q %v", line, err)
+		}
+		if err := <-stopped; err != nil {
+			t.Errorf("Ожидалась остановка без ошибки, получено %v", err)
+		}
+		if _, err := net.Dial("unix", addr); err == nil {
+			t.Errorf("После Shutdown сокет не должен принимать соединения")
+		}
+	})
+
+	t.Run("истечение срока", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		server, addr, started, _ := startBlocking(t, release)
+
+		busy, busyReader := dial(t, addr)
+		busy.Write([]byte(`{"jsonrpc":"2.0","method":"Test.Block","id":1}` + "\n"))
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Ожидалось истечение срока остановки, получено %v", err)
+		}
+		if _, err := busyReader.ReadString('\n'); err == nil {
+			t.Errorf("Соединение должно быть закрыто принудительно")
+		}
+	})
+}
@@ -1,29 +1,322 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"todo-api/client"
 )
 
 func main() {
-	// Создаем сервис и обработчик
-	taskService := NewTaskService()
+	// todo-api mcp — сервер Model Context Protocol на stdin/stdout для ассистентов
+	if len(os.Args) > 1 && os.Args[1] == "mcp" {
+		if err := runMCP(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	os.Exit(run(os.Args[1:]))
+}
+
+// run запускает серверы и работает до SIGINT или SIGTERM, затем останавливает их,
+// дожидаясь начатых запросов. Возвращает код завершения процесса.
+func run(args []string) int {
+	// Конфигурация: значения по умолчанию < файл < переменные окружения < флаги
+	source, err := ParseConfigFlags(args, os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	config, err := source.Load()
+	if err != nil {
+		log.Printf("Неверная конфигурация:\n%v", err)
+		return exitUsage
+	}
+	if source.PrintConfig {
+		if err := config.WriteYAML(os.Stdout); err != nil {
+			log.Print(err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	// Журнал пишется в stderr; уровень меняется при перезагрузке конфигурации
+	var logLevel slog.LevelVar
+	level, _ := parseLogLevel(config.Log.Level)
+	logLevel.Set(level)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &logLevel})))
+
+	// Трассировка записывается в stdout или файл и не требует коллектора
+	shutdownTracing, err := SetupTracing(config.Tracing)
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+
+	// Создаем сервис и обработчик; все обращения к задачам проходят через обертки с метриками и трассировкой
+	metrics := NewMetrics()
+	tasks := NewTaskService()
+	taskService := metrics.InstrumentTaskService(TraceTaskService(tasks))
 	taskHandler := NewTaskHandler(taskService)
 
+	// Вложения храним в локальной файловой системе
+	blobStore, err := NewFSBlobStore(filepath.Join(config.Storage.Path, "attachments"))
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+	attachmentService := TraceAttachmentService(NewAttachmentService(taskService, blobStore))
+	attachmentHandler := NewAttachmentHandler(attachmentService)
+	// Вложения удаляются вместе с задачей, через какой бы API она ни была удалена
+	tasks.OnDelete(attachmentService.DeleteTaskAttachments)
+
+	// Сохраненные представления переживают перезапуск сервера
+	viewService, err := NewViewService(filepath.Join(config.Storage.Path, "views.json"))
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+	viewHandler := NewViewHandler(viewService, taskService)
+
+	// CalDAV-клиенты синхронизируют задачи напрямую с сервисом
+	caldavHandler := NewCalDAVHandler(taskService)
+
+	// GraphQL с ограничениями глубины и сложности запросов
+	graphQLHandler, err := NewGraphQLHandler(taskService, attachmentService, viewService, config.Limits.GraphQLMaxDepth, config.Limits.GraphQLMaxComplexity)
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+
+	// JSON-RPC доступен по HTTP и, если задан rpc.socket, на Unix сокете
+	rpcServer := NewJSONRPCServer(taskService)
+	mcpServer := NewMCPServer(taskService)
+
+	// Проверки готовности: хранилища и сервис задач регистрируют себя в реестре
+	health := NewHealthRegistry()
+	health.Register("tasks", tasks)
+	health.Register("attachments", blobStore)
+	health.Register("views", viewService)
+
+	cors := NewCORSPolicy(config.CORS.AllowedOrigins)
+	idempotency := NewIdempotencyStore(config.Limits.IdempotencyTTL)
+
+	// Проверка по спецификации OpenAPI: off, requests или strict
+	validation, _ := ParseOpenAPIValidation(config.OpenAPI.Validation)
+	validator, err := NewOpenAPIValidator(validation)
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+
 	// Настраиваем маршруты
-	r := SetupRoutes(taskHandler)
-
-	// Запускаем сервер
-	port := ":8080"
-	fmt.Printf("🚀 Сервер запущен на http://localhost%s\n", port)
-	fmt.Println("📋 Доступные эндпоинты:")
-	fmt.Println("  POST   /tasks     - создать задачу")
-	fmt.Println("  GET    /tasks     - получить все задачи")
-	fmt.Println("  GET    /tasks/{id} - получить задачу по ID")
-	fmt.Println("  PUT    /tasks/{id} - обновить задачу")
-	fmt.Println("  DELETE /tasks/{id} - удалить задачу")
-	fmt.Println("  GET    /           - информация об API")
-
-	log.Fatal(http.ListenAndServe(port, r))
+	r := SetupRoutes(taskHandler,
+		WithAttachments(attachmentHandler),
+		WithViews(viewHandler),
+		WithCalDAV(caldavHandler),
+		WithGraphQL(graphQLHandler),
+		WithJSONRPC(rpcServer),
+		WithMCP(mcpServer),
+		WithHealth(health),
+		WithMetrics(metrics),
+		WithCORS(cors),
+		WithIdempotency(idempotency),
+		WithOpenAPIValidator(validator),
+	)
+
+	// Все адреса занимаем до запуска серверов, чтобы ошибка остановила запуск целиком
+	httpListener, err := net.Listen("tcp", config.HTTP.Addr)
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+	server := &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: config.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       config.HTTP.ReadTimeout,
+		WriteTimeout:      config.HTTP.WriteTimeout,
+		IdleTimeout:       config.HTTP.IdleTimeout,
+	}
+	serveErr := make(chan error, 3)
+	go func() {
+		if err := server.Serve(httpListener); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("HTTP: %w", err)
+		}
+	}()
+	steps := []shutdownStep{{name: "HTTP", stop: shutdownHTTP(server)}}
+
+	// gRPC для внутренних сервисов работает на отдельном порту с тем же сервисом задач
+	if config.GRPC.Addr != "" {
+		grpcListener, err := net.Listen("tcp", config.GRPC.Addr)
+		if err != nil {
+			log.Print(err)
+			return exitError
+		}
+		grpcServer := NewGRPCServer(taskService)
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				serveErr <- fmt.Errorf("gRPC: %w", err)
+			}
+		}()
+		steps = append(steps, shutdownStep{name: "gRPC", stop: shutdownGRPC(grpcServer)})
+	}
+
+	if rpcSocket := config.RPC.Socket; rpcSocket != "" {
+		// Сокет, оставшийся от прошлого запуска, мешает начать прослушивание
+		if info, err := os.Stat(rpcSocket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(rpcSocket)
+		}
+		rpcListener, err := net.Listen("unix", rpcSocket)
+		if err != nil {
+			log.Print(err)
+			return exitError
+		}
+		go func() {
+			if err := rpcServer.Serve(rpcListener); err != nil {
+				serveErr <- fmt.Errorf("JSON-RPC: %w", err)
+			}
+		}()
+		steps = append(steps, shutdownStep{name: "JSON-RPC", stop: rpcServer.Shutdown})
+	}
+
+	// По SIGHUP перечитываем конфигурацию: уровень журнала и источники CORS меняются на лету
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer func() {
+		signal.Stop(reload)
+		close(reload)
+	}()
+	go source.ReloadOnSignal(reload, config, func(c *Config) {
+		level, _ := parseLogLevel(c.Log.Level)
+		logLevel.Set(level)
+		cors.SetOrigins(c.CORS.AllowedOrigins)
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	host, port, _ := net.SplitHostPort(config.HTTP.Addr)
+	if host == "" {
+		host = "localhost"
+	}
+	// Экспортер stdout пишет в стандартный вывод по span на строку; баннер его не перемешивает
+	banner := io.Writer(os.Stdout)
+	if exporter, _ := ParseTracingExporter(config.Tracing.Exporter); exporter == TracingExporterStdout {
+		banner = os.Stderr
+	}
+	fmt.Fprintf(banner, "🚀 Сервер запущен на http://%s\n", net.JoinHostPort(host, port))
+	if config.GRPC.Addr != "" {
+		fmt.Fprintf(banner, "🔌 gRPC сервис todo.v1.TaskService на %s\n", config.GRPC.Addr)
+	}
+	if config.RPC.Socket != "" {
+		fmt.Fprintf(banner, "🧩 JSON-RPC 2.0 на Unix сокете %s\n", config.RPC.Socket)
+	}
+	fmt.Fprintln(banner, "📋 Доступные эндпоинты:")
+	fmt.Fprintln(banner, "  POST   /tasks     - создать задачу")
+	fmt.Fprintln(banner, "  POST   /tasks/quick - создать задачу из свободного текста")
+	fmt.Fprintln(banner, "  POST   /tasks/batch - пакетное создание, обновление и удаление задач")
+	fmt.Fprintln(banner, "  GET    /tasks/export?format= - экспорт задач (csv, ndjson, markdown, todotxt, ics)")
+	fmt.Fprintln(banner, "  GET    /tasks.ics - календарь задач в формате iCalendar (q= - фильтр)")
+	fmt.Fprintln(banner, "  POST   /tasks/import?format= - импорт задач (dry_run=true - только проверка)")
+	fmt.Fprintln(banner, "  GET    /tasks     - получить все задачи (q= - фильтр на языке запросов)")
+	fmt.Fprintln(banner, "  GET    /tasks/search?q= - полнотекстовый поиск (mode=fuzzy - с опечатками)")
+	fmt.Fprintln(banner, "  GET    /tasks/autocomplete?q= - подсказки заголовков")
+	fmt.Fprintln(banner, "  GET    /tasks/events - поток изменений задач (Server-Sent Events)")
+	fmt.Fprintln(banner, "  GET    /tasks/{id} - получить задачу по ID")
+	fmt.Fprintln(banner, "  PUT    /tasks/{id} - обновить задачу")
+	fmt.Fprintln(banner, "  DELETE /tasks/{id} - удалить задачу")
+	fmt.Fprintln(banner, "  POST   /tasks/{id}/attachments - загрузить вложение")
+	fmt.Fprintln(banner, "  GET    /tasks/{id}/attachments - список вложений")
+	fmt.Fprintln(banner, "  GET    /tasks/{id}/attachments/{attachmentID} - скачать вложение")
+	fmt.Fprintln(banner, "  DELETE /tasks/{id}/attachments/{attachmentID} - удалить вложение")
+	fmt.Fprintln(banner, "  POST   /views     - сохранить представление")
+	fmt.Fprintln(banner, "  GET    /views     - список представлений")
+	fmt.Fprintln(banner, "  GET    /views/{name}/tasks - выполнить представление")
+	fmt.Fprintln(banner, "  CalDAV /dav/      - синхронизация с Apple Reminders, Thunderbird, DAVx5")
+	fmt.Fprintln(banner, "  POST   /graphql   - GraphQL: запросы, мутации и подписки (GET - запросы и подписки)")
+	fmt.Fprintln(banner, "  POST   /rpc       - JSON-RPC 2.0: методы TaskService, пакеты и уведомления")
+	fmt.Fprintln(banner, "  POST   /mcp       - Model Context Protocol для ассистентов (или todo-api mcp на stdio)")
+	fmt.Fprintln(banner, "  GET    /healthz, /readyz - пробы живости и готовности")
+	fmt.Fprintln(banner, "  GET    /health    - состояние компонентов с временем проверок")
+	fmt.Fprintln(banner, "  GET    /metrics   - метрики в формате Prometheus")
+	fmt.Fprintln(banner, "  GET    /openapi.json - спецификация OpenAPI 3.1")
+	fmt.Fprintln(banner, "  GET    /docs      - документация API в браузере")
+	fmt.Fprintln(banner, "  GET    /           - информация об API")
+
+	code := exitOK
+	select {
+	case <-ctx.Done():
+		log.Printf("Получен сигнал остановки, завершаем начатые запросы (не дольше %s)", config.HTTP.ShutdownTimeout)
+	case err := <-serveErr:
+		log.Printf("Сервер остановлен из-за ошибки: %v", err)
+		code = exitError
+	}
+	// Повторный сигнал завершает процесс сразу, не дожидаясь запросов
+	stop()
+	// Начатые запросы еще выполняются, но новые балансировщику направлять не нужно
+	health.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	// Потоки событий сами не заканчиваются: закрываем подписки, и их обработчики возвращаются
+	tasks.Close()
+	err = shutdownAll(shutdownCtx, steps)
+	if err != nil {
+		log.Printf("Остановка: %v", err)
+	}
+	// Хранилище закрываем последним, когда запросов к нему уже не будет
+	if err := viewService.Close(); err != nil {
+		log.Printf("Не удалось закрыть хранилище представлений: %v", err)
+		code = max(code, exitError)
+	}
+	// Spans последних запросов дописываются, когда серверы уже остановлены. Срок отдельный:
+	// общий мог истечь на ожидании запросов, а трассировка тайм-аута нужнее всего.
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("Не удалось дописать трассировку: %v", err)
+	}
+
+	if code == exitOK {
+		code = shutdownExitCode(err)
+	}
+	if code == exitOK {
+		log.Print("Сервер остановлен")
+	}
+	return code
+}
+
+// runMCP обслуживает MCP на stdin/stdout: stdout занят протоколом, журнал пишется в stderr.
+// Если задан TODO_SERVER (и TODO_TOKEN), инструменты работают с задачами запущенного
+// сервера через клиент, иначе — с собственным хранилищем в памяти.
+func runMCP() error {
+	var tasks TaskServiceInterface
+	if server := os.Getenv("TODO_SERVER"); server != "" {
+		var opts []client.Option
+		if token := os.Getenv("TODO_TOKEN"); token != "" {
+			opts = append(opts, client.WithBearerToken(token))
+		}
+		tasks = client.New(server, opts...)
+	} else {
+		tasks = NewTaskService()
+	}
+	return NewMCPServer(tasks).ServeStdio(os.Stdin, os.Stdout)
 }
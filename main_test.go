@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,7 +17,7 @@ import (
 func TestTaskService_CreateTask(t *testing.T) {
 	service := NewTaskService()
 
-	task := service.CreateTask("Тестовая задача", "Описание тестовой задачи")
+	task := service.CreateTask(t.Context(), "Тестовая задача", "Описание тестовой задачи")
 
 	if task.ID != 1 {
 		t.Errorf("Ожидался ID = 1, получен %d", task.ID)
@@ -46,10 +48,10 @@ func TestTaskService_GetTask(t *testing.T) {
 	service := NewTaskService()
 
 	// Создаем задачу
-	createdTask := service.CreateTask("Тест", "Описание")
+	createdTask := service.CreateTask(t.Context(), "Тест", "Описание")
 
 	// Получаем задачу
-	retrievedTask, err := service.GetTask(createdTask.ID)
+	retrievedTask, err := service.GetTask(t.Context(), createdTask.ID)
 	if err != nil {
 		t.Fatalf("Ошибка при получении задачи: %v", err)
 	}
@@ -66,7 +68,7 @@ func TestTaskService_GetTask(t *testing.T) {
 func TestTaskService_GetTask_NotFound(t *testing.T) {
 	service := NewTaskService()
 
-	_, err := service.GetTask(999)
+	_, err := service.GetTask(t.Context(), 999)
 	if err == nil {
 		t.Error("Ожидалась ошибка для несуществующей задачи")
 	}
@@ -81,11 +83,11 @@ func TestTaskService_GetAllTasks(t *testing.T) {
 	service := NewTaskService()
 
 	// Создаем несколько задач
-	service.CreateTask("Задача 1", "Описание 1")
-	service.CreateTask("Задача 2", "Описание 2")
-	service.CreateTask("Задача 3", "Описание 3")
+	service.CreateTask(t.Context(), "Задача 1", "Описание 1")
+	service.CreateTask(t.Context(), "Задача 2", "Описание 2")
+	service.CreateTask(t.Context(), "Задача 3", "Описание 3")
 
-	tasks := service.GetAllTasks()
+	tasks := service.GetAllTasks(t.Context())
 
 	if len(tasks) != 3 {
 		t.Errorf("Ожидалось 3 задачи, получено %d", len(tasks))
@@ -105,10 +107,10 @@ func TestTaskService_UpdateTask(t *testing.T) {
 	service := NewTaskService()
 
 	// Создаем задачу
-	createdTask := service.CreateTask("Исходная задача", "Исходное описание")
+	createdTask := service.CreateTask(t.Context(), "Исходная задача", "Исходное описание")
 
 	// Обновляем задачу
-	updatedTask, err := service.UpdateTask(createdTask.ID, "Обновленная задача", "Обновленное описание", true)
+	updatedTask, err := service.UpdateTask(t.Context(), createdTask.ID, "Обновленная задача", "Обновленное описание", true)
 	if err != nil {
 		t.Fatalf("Ошибка при обновлении задачи: %v", err)
 	}
@@ -133,7 +135,7 @@ func TestTaskService_UpdateTask(t *testing.T) {
 func TestTaskService_UpdateTask_NotFound(t *testing.T) {
 	service := NewTaskService()
 
-	_, err := service.UpdateTask(999, "Новый заголовок", "Новое описание", true)
+	_, err := service.UpdateTask(t.Context(), 999, "Новый заголовок", "Новое описание", true)
 	if err == nil {
 		t.Error("Ожидалась ошибка для несуществующей задачи")
 	}
@@ -148,16 +150,16 @@ func TestTaskService_DeleteTask(t *testing.T) {
 	service := NewTaskService()
 
 	// Создаем задачу
-	createdTask := service.CreateTask("Задача для удаления", "Описание")
+	createdTask := service.CreateTask(t.Context(), "Задача для удаления", "Описание")
 
 	// Удаляем задачу
-	err := service.DeleteTask(createdTask.ID)
+	err := service.DeleteTask(t.Context(), createdTask.ID)
 	if err != nil {
 		t.Fatalf("Ошибка при удалении задачи: %v", err)
 	}
 
 	// Проверяем, что задача удалена
-	_, err = service.GetTask(createdTask.ID)
+	_, err = service.GetTask(t.Context(), createdTask.ID)
 	if err == nil {
 		t.Error("Задача должна быть удалена")
 	}
@@ -166,7 +168,7 @@ func TestTaskService_DeleteTask(t *testing.T) {
 func TestTaskService_DeleteTask_NotFound(t *testing.T) {
 	service := NewTaskService()
 
-	err := service.DeleteTask(999)
+	err := service.DeleteTask(t.Context(), 999)
 	if err == nil {
 		t.Error("Ожидалась ошибка для несуществующей задачи")
 	}
@@ -249,8 +251,8 @@ func TestTaskHandler_GetTasks(t *testing.T) {
 	handler := NewTaskHandler(service)
 
 	// Создаем несколько задач
-	service.CreateTask("Задача 1", "Описание 1")
-	service.CreateTask("Задача 2", "Описание 2")
+	service.CreateTask(t.Context(), "Задача 1", "Описание 1")
+	service.CreateTask(t.Context(), "Задача 2", "Описание 2")
 
 	req := httptest.NewRequest("GET", "/tasks", nil)
 	w := httptest.NewRecorder()
@@ -271,12 +273,39 @@ func TestTaskHandler_GetTasks(t *testing.T) {
 	}
 }
 
+func TestTaskHandler_NotSupported(t *testing.T) {
+	// Сервис только с основными операциями: поиска и пакетов у него нет
+	service := struct{ TaskServiceInterface }{NewTaskService()}
+	handler := NewTaskHandler(service)
+	service.CreateTask(t.Context(), "Купить молоко", "", WithTags([]string{"дом"}))
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+
+	w := httptest.NewRecorder()
+	handler.SearchTasks(w, httptest.NewRequest("GET", "/tasks/search?q=молоко", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Ожидался статус %d для поиска, получен %d", http.StatusNotImplemented, w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler.BatchTasks(w, httptest.NewRequest("POST", "/tasks/batch", strings.NewReader(`{"operations":[{"op":"create","title":"Новая"}]}`)))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Ожидался статус %d для пакета, получен %d", http.StatusNotImplemented, w.Code)
+	}
+
+	// Запрос на языке фильтров выполняется и без FilterTasks
+	w = httptest.NewRecorder()
+	handler.GetTasks(w, httptest.NewRequest("GET", "/tasks?q="+url.QueryEscape("tag:дом"), nil))
+	var tasks []Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil || len(tasks) != 1 || tasks[0].ID != 1 {
+		t.Errorf("Неверный результат отбора: %d %s", w.Code, w.Body.String())
+	}
+}
+
 func TestTaskHandler_GetTask(t *testing.T) {
 	service := NewTaskService()
 	handler := NewTaskHandler(service)
 
 	// Создаем задачу
-	createdTask := service.CreateTask("Test Task", "Description")
+	createdTask := service.CreateTask(t.Context(), "Test Task", "Description")
 
 	req := httptest.NewRequest("GET", "/tasks/1", nil)
 	rctx := chi.NewRouteContext()
@@ -334,7 +363,7 @@ func TestTaskHandler_UpdateTask(t *testing.T) {
 	handler := NewTaskHandler(service)
 
 	// Создаем задачу
-	service.CreateTask("Original Task", "Original Description")
+	service.CreateTask(t.Context(), "Original Task", "Original Description")
 
 	reqBody := UpdateTaskRequest{
 		Title:       "Updated Task",
@@ -401,7 +430,7 @@ func TestTaskHandler_UpdateTask_EmptyTitle(t *testing.T) {
 	handler := NewTaskHandler(service)
 
 	// Создаем задачу
-	service.CreateTask("Original Task", "Original Description")
+	service.CreateTask(t.Context(), "Original Task", "Original Description")
 
 	reqBody := UpdateTaskRequest{
 		Title:       "",
@@ -429,7 +458,7 @@ func TestTaskHandler_DeleteTask(t *testing.T) {
 	handler := NewTaskHandler(service)
 
 	// Создаем задачу
-	service.CreateTask("Task to Delete", "Description")
+	service.CreateTask(t.Context(), "Task to Delete", "Description")
 
 	req := httptest.NewRequest("DELETE", "/tasks/1", nil)
 	rctx := chi.NewRouteContext()
@@ -493,7 +522,7 @@ func TestTaskService_Concurrency(t *testing.T) {
 
 	for i := 0; i < 10; i++ {
 		go func(i int) {
-			service.CreateTask("Задача", "Описание")
+			service.CreateTask(t.Context(), "Задача", "Описание")
 			done <- true
 		}(i)
 	}
@@ -503,7 +532,7 @@ func TestTaskService_Concurrency(t *testing.T) {
 		<-done
 	}
 
-	tasks := service.GetAllTasks()
+	tasks := service.GetAllTasks(t.Context())
 	if len(tasks) != 10 {
 		t.Errorf("Ожидалось 10 задач, получено %d", len(tasks))
 	}
@@ -514,7 +543,7 @@ func TestTask_Timestamps(t *testing.T) {
 	service := NewTaskService()
 
 	beforeCreate := time.Now()
-	task := service.CreateTask("Тест", "Описание")
+	task := service.CreateTask(t.Context(), "Тест", "Описание")
 	afterCreate := time.Now()
 
 	if task.CreatedAt.Before(beforeCreate) || task.CreatedAt.After(afterCreate) {
@@ -528,10 +557,10 @@ func TestTask_Timestamps(t *testing.T) {
 	// Обновляем задачу
 	time.Sleep(1 * time.Millisecond) // Небольшая задержка для различия во времени
 	beforeUpdate := time.Now()
-	service.UpdateTask(task.ID, "Обновлено", "Описание", true)
+	service.UpdateTask(t.Context(), task.ID, "Обновлено", "Описание", true)
 	afterUpdate := time.Now()
 
-	updatedTask, _ := service.GetTask(task.ID)
+	updatedTask, _ := service.GetTask(t.Context(), task.ID)
 
 	if updatedTask.UpdatedAt.Before(beforeUpdate) || updatedTask.UpdatedAt.After(afterUpdate) {
 		t.Error("UpdatedAt должно быть обновлено при изменении задачи")
@@ -541,3 +570,33 @@ func TestTask_Timestamps(t *testing.T) {
 		t.Error("CreatedAt не должно изменяться при обновлении")
 	}
 }
+
+// withURLParams добавляет в запрос параметры маршрута chi (пары имя, значение)
+func withURLParams(req *http.Request, pairs ...string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		rctx.URLParams.Add(pairs[i], pairs[i+1])
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestSetupRoutes_Options(t *testing.T) {
+	// Без опций подключается только API задач
+	router := SetupRoutes(NewTaskHandler(NewTaskService()))
+	for path, want := range map[string]int{"/tasks": http.StatusOK, "/metrics": http.StatusNotFound, "/healthz": http.StatusNotFound, "/views": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s: ожидался статус %d, получен %d", path, want, w.Code)
+		}
+	}
+
+	router = SetupRoutes(NewTaskHandler(NewTaskService()), WithHealth(NewHealthRegistry()), WithMetrics(NewMetrics()))
+	for _, path := range []string{"/healthz", "/metrics"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: опция должна подключать маршрут, получен статус %d", path, w.Code)
+		}
+	}
+}
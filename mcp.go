@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mcpProtocolVersions — версии Model Context Protocol, которые понимает сервер;
+// первая — последняя, ее сервер предлагает клиентам с незнакомой версией
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// mcpResourceNotFound — код ошибки MCP для неизвестного ресурса
+const mcpResourceNotFound = -32002
+
+// mcpDefaultListLimit — сколько задач list_tasks возвращает без аргумента limit
+const mcpDefaultListLimit = 50
+
+// Ресурсы задач: список всех задач и задача по ID
+const (
+	mcpTasksURI        = "todo://tasks"
+	mcpTaskURITemplate = "todo://tasks/{id}"
+)
+
+// mcpTool — инструмент MCP: описание для tools/list и обработчик аргументов
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	call func(ctx context.Context, arguments json.RawMessage) (any, error)
+}
+
+// mcpContent — текстовый блок результата инструмента или содержимое ресурса
+type mcpContent struct {
+	Type     string `json:"type,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// mcpToolResult — результат tools/call. Ошибки самого инструмента (задача не найдена,
+// неверные аргументы) возвращаются здесь с isError, чтобы ассистент видел их и мог исправиться.
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// mcpResource — элемент resources/list
+type mcpResource struct {
+	URI      string `json:"uri"`
+	Name     string `json:"name"`
+	Title    string `json:"title,omitempty"`
+	MimeType string `json:"mimeType"`
+}
+
+// MCPServer открывает задачи ассистентам по Model Context Protocol: инструменты для
+// просмотра, поиска и изменения задач и ресурсы todo://tasks. Сообщения MCP — это
+// JSON-RPC 2.0, поэтому сервер использует ту же таблицу методов, что и JSONRPCServer.
+type MCPServer struct {
+	tasks   TaskServiceInterface
+	tools   []mcpTool
+	methods rpcMethods
+}
+
+// NewMCPServer создает сервер MCP поверх сервиса задач
+func NewMCPServer(tasks TaskServiceInterface) *MCPServer {
+	s := &MCPServer{tasks: tasks}
+	s.tools = s.buildTools()
+	s.methods = rpcMethods{
+		"initialize":               s.initialize,
+		"ping":                     s.ping,
+		"tools/list":               s.listTools,
+		"tools/call":               s.callTool,
+		"resources/list":           s.listResources,
+		"resources/templates/list": s.listResourceTemplates,
+		"resources/read":           s.readResource,
+	}
+	return s
+}
+
+// ServeStdio обслуживает сессию MCP на потоках ввода и вывода процесса: по сообщению
+// JSON-RPC на строку. Возвращает nil, когда клиент закрыл поток ввода.
+func (s *MCPServer) ServeStdio(in io.Reader, out io.Writer) error {
+	return s.methods.serveStream(context.Background(), in, out)
+}
+
+// ServeHTTP обрабатывает POST /mcp — транспорт Streamable HTTP без сессий: ответ на
+// запрос приходит JSON телом, на уведомления — 202 без тела. Потоков Server-Sent Events
+// сервер не открывает, поэтому GET /mcp отвечает 405.
+func (s *MCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Проверка Origin защищает от DNS rebinding: страница в браузере не должна
+	// обращаться к серверу от имени пользователя
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			http.Error(w, "Недопустимый Origin", http.StatusForbidden)
+			return
+		}
+	}
+	if version := r.Header.Get("MCP-Protocol-Version"); version != "" && !slices.Contains(mcpProtocolVersions, version) {
+		http.Error(w, fmt.Sprintf("Неподдерживаемая версия протокола MCP '%s'", version), http.StatusBadRequest)
+		return
+	}
+
+	body, ok := readRPCBody(w, r)
+	if !ok {
+		return
+	}
+	response := s.methods.handle(r.Context(), body)
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// initialize согласует версию протокола: версию клиента, если сервер ее знает, иначе последнюю
+func (s *MCPServer) initialize(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, invalidParams("Неверные параметры: %v", err)
+	}
+	version := mcpProtocolVersions[0]
+	if slices.Contains(mcpProtocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities": map[string]any{
+			"tools":     map[string]any{"listChanged": false},
+			"resources": map[string]any{"subscribe": false, "listChanged": false},
+		},
+		"serverInfo": map[string]string{"name": "todo-api", "title": "ToDo API", "version": "1.0.0"},
+		"instructions": "Инструменты управляют списком задач. list_tasks принимает запрос на языке фильтров " +
+			"(tag:дом AND priority>=medium AND due<tomorrow); чтобы отметить задачу выполненной, используйте complete_task.",
+	}, nil
+}
+
+func (s *MCPServer) ping(context.Context, json.RawMessage) (any, error) {
+	return struct{}{}, nil
+}
+
+func (s *MCPServer) listTools(context.Context, json.RawMessage) (any, error) {
+	return map[string]any{"tools": s.tools}, nil
+}
+
+// callTool: {name, arguments} → результат инструмента. Неизвестный инструмент — ошибка
+// протокола, а ошибка при выполнении — результат с isError.
+func (s *MCPServer) callTool(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, invalidParams("Неверные параметры: %v", err)
+	}
+	i := slices.IndexFunc(s.tools, func(tool mcpTool) bool { return tool.Name == p.Name })
+	if i < 0 {
+		return nil, invalidParams("Неизвестный инструмент '%s'", p.Name)
+	}
+
+	value, err := s.tools[i].call(ctx, p.Arguments)
+	if err != nil {
+		return mcpToolResult{Content: []mcpContent{{Type: "text", Text: rpcErrorFrom(err).Message}}, IsError: true}, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	return mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}, nil
+}
+
+// listResources возвращает список всех задач и каждую задачу отдельным ресурсом
+func (s *MCPServer) listResources(ctx context.Context, _ json.RawMessage) (any, error) {
+	tasks := filterTasks(ctx, s.tasks, func(*Task) bool { return true })
+	resources := make([]mcpResource, 0, len(tasks)+1)
+	resources = append(resources, mcpResource{URI: mcpTasksURI, Name: "tasks", Title: "Все задачи", MimeType: "application/json"})
+	for _, task := range tasks {
+		resources = append(resources, mcpResource{
+			URI:      fmt.Sprintf("%s/%d", mcpTasksURI, task.ID),
+			Name:     fmt.Sprintf("task-%d", task.ID),
+			Title:    task.Title,
+			MimeType: "application/json",
+		})
+	}
+	return map[string]any{"resources": resources}, nil
+}
+
+func (s *MCPServer) listResourceTemplates(context.Context, json.RawMessage) (any, error) {
+	return map[string]any{"resourceTemplates": []map[string]string{{
+		"uriTemplate": mcpTaskURITemplate,
+		"name":        "task",
+		"title":       "Задача по ID",
+		"mimeType":    "application/json",
+	}}}, nil
+}
+
+// readResource: {uri} → содержимое todo://tasks или todo://tasks/{id} в JSON
+func (s *MCPServer) readResource(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, invalidParams("Неверные параметры: %v", err)
+	}
+	notFound := &rpcError{Code: mcpResourceNotFound, Message: fmt.Sprintf("Ресурс '%s' не найден", p.URI), Data: map[string]string{"uri": p.URI}}
+
+	var value any
+	if p.URI == mcpTasksURI {
+		value = filterTasks(ctx, s.tasks, func(*Task) bool { return true })
+	} else {
+		rest, ok := strings.CutPrefix(p.URI, mcpTasksURI+"/")
+		if !ok {
+			return nil, notFound
+		}
+		id, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, notFound
+		}
+		task, err := s.tasks.GetTask(ctx, id)
+		if err != nil {
+			return nil, notFound
+		}
+		value = task
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"contents": []mcpContent{{URI: p.URI, MimeType: "application/json", Text: string(data)}}}, nil
+}
+
+// mcpObjectSchema — JSON Schema аргументов инструмента; лишние поля запрещены,
+// как и при разборе аргументов
+func mcpObjectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// mcpTaskProperties — поля задачи в аргументах create_task и update_task
+func mcpTaskProperties() map[string]any {
+	return map[string]any{
+		"title":       map[string]any{"type": "string", "minLength": 1, "description": "Заголовок задачи"},
+		"description": map[string]any{"type": "string", "description": "Описание задачи"},
+		"tags":        map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Теги; приводятся к нижнему регистру"},
+		"priority":    map[string]any{"type": "string", "enum": []string{"low", "medium", "high"}, "description": "Приоритет"},
+		"project":     map[string]any{"type": "string", "description": "Проект"},
+		"due_date":    map[string]any{"type": "string", "description": "Срок в формате RFC 3339 (2025-01-31T18:00:00+03:00); пустая строка снимает срок"},
+	}
+}
+
+var mcpIDProperty = map[string]any{"type": "integer", "minimum": 1, "description": "ID задачи"}
+
+func (s *MCPServer) buildTools() []mcpTool {
+	updateProperties := mcpTaskProperties()
+	updateProperties["id"] = mcpIDProperty
+
+	return []mcpTool{
+		{
+			Name:        "list_tasks",
+			Description: "Список задач в порядке ID. Аргумент query отбирает задачи на языке фильтров: tag:дом, completed:false, priority>=medium, due<tomorrow, AND/OR/NOT.",
+			InputSchema: mcpObjectSchema(map[string]any{
+				"query": map[string]any{"type": "string", "description": "Запрос на языке фильтров; без него — все задачи"},
+				"limit": map[string]any{"type": "integer", "minimum": 1, "description": fmt.Sprintf("Сколько задач вернуть, по умолчанию %d", mcpDefaultListLimit)},
+			}),
+			call: s.listTasksTool,
+		},
+		{
+			Name:        "search_tasks",
+			Description: "Полнотекстовый поиск по заголовку, описанию и тегам с учетом словоформ; fuzzy=true находит слова с опечатками.",
+			InputSchema: mcpObjectSchema(map[string]any{
+				"query": map[string]any{"type": "string", "minLength": 1, "description": "Поисковый запрос"},
+				"fuzzy": map[string]any{"type": "boolean", "description": "Искать с опечатками"},
+				"limit": map[string]any{"type": "integer", "minimum": 1, "description": fmt.Sprintf("Сколько результатов вернуть, по умолчанию %d", defaultSearchLimit)},
+			}, "query"),
+			call: s.searchTasksTool,
+		},
+		{
+			Name:        "create_task",
+			Description: "Создает задачу и возвращает ее.",
+			InputSchema: mcpObjectSchema(mcpTaskProperties(), "title"),
+			call:        s.createTaskTool,
+		},
+		{
+			Name:        "update_task",
+			Description: "Изменяет заданные поля задачи, остальные остаются прежними. Возвращает задачу.",
+			InputSchema: mcpObjectSchema(updateProperties, "id"),
+			call:        s.updateTaskTool,
+		},
+		{
+			Name:        "complete_task",
+			Description: "Отмечает задачу выполненной (completed=false — возвращает в работу).",
+			InputSchema: mcpObjectSchema(map[string]any{
+				"id":        mcpIDProperty,
+				"completed": map[string]any{"type": "boolean", "default": true, "description": "Выполнена ли задача"},
+			}, "id"),
+			call: s.completeTaskTool,
+		},
+		{
+			Name:        "delete_task",
+			Description: "Удаляет задачу без возможности восстановления.",
+			InputSchema: mcpObjectSchema(map[string]any{"id": mcpIDProperty}, "id"),
+			call:        s.deleteTaskTool,
+		},
+	}
+}
+
+// listTasksTool: {query, limit} → {total, tasks}; total — сколько задач подошло всего
+func (s *MCPServer) listTasksTool(ctx context.Context, arguments json.RawMessage) (any, error) {
+	var p struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := decodeParams(arguments, &p); err != nil {
+		return nil, err
+	}
+	limit, err := rpcLimit(p.Limit, mcpDefaultListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	match := func(*Task) bool { return true }
+	if strings.TrimSpace(p.Query) != "" {
+		query, err := ParseQuery(p.Query)
+		if err != nil {
+			return nil, err
+		}
+		match = query.Match
+	}
+	tasks := filterTasks(ctx, s.tasks, match)
+	total := len(tasks)
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return map[string]any{"total": total, "tasks": tasks}, nil
+}
+
+// searchTasksTool: {query, fuzzy, limit} → результаты поиска с оценкой релевантности
+func (s *MCPServer) searchTasksTool(ctx context.Context, arguments json.RawMessage) (any, error) {
+	var p struct {
+		Query string `json:"query"`
+		Fuzzy bool   `json:"fuzzy"`
+		Limit int    `json:"limit"`
+	}
+	if err := decodeParams(arguments, &p); err != nil {
+		return nil, err
+	}
+	if p.Query == "" {
+		return nil, invalidParams("Поле 'query' обязательно")
+	}
+	limit, err := rpcLimit(p.Limit, defaultSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := capability[TaskSearcher](s.tasks, "SearchTasks")
+	if err != nil {
+		return nil, err
+	}
+	if p.Fuzzy {
+		return searcher.FuzzySearchTasks(ctx, p.Query, limit), nil
+	}
+	return searcher.SearchTasks(ctx, p.Query, limit), nil
+}
+
+// mcpTaskArguments — аргументы create_task и update_task; nil — поле не задано
+type mcpTaskArguments struct {
+	ID          int       `json:"id"`
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Tags        []string  `json:"tags"`
+	Priority    *Priority `json:"priority"`
+	Project     *string   `json:"project"`
+	DueDate     *string   `json:"due_date"`
+}
+
+// options проверяет заданные поля и возвращает опции для них
+func (a *mcpTaskArguments) options() ([]TaskOption, error) {
+	if a.Title != nil && *a.Title == "" {
+		return nil, invalidParams("Поле 'title' обязательно")
+	}
+
+	var opts []TaskOption
+	if a.Tags != nil {
+		opts = append(opts, WithTags(a.Tags))
+	}
+	if a.Priority != nil {
+		if !a.Priority.Valid() {
+			return nil, invalidParams("Поле 'priority' должно быть 'low', 'medium' или 'high'")
+		}
+		opts = append(opts, WithPriority(*a.Priority))
+	}
+	if a.Project != nil {
+		opts = append(opts, WithProject(*a.Project))
+	}
+	if a.DueDate != nil {
+		var due *time.Time
+		if *a.DueDate != "" {
+			t, err := time.Parse(time.RFC3339, *a.DueDate)
+			if err != nil {
+				return nil, invalidParams("Поле 'due_date' должно быть в формате RFC 3339")
+			}
+			due = &t
+		}
+		opts = append(opts, WithDueDate(due))
+	}
+	return opts, nil
+}
+
+func (s *MCPServer) createTaskTool(ctx context.Context, arguments json.RawMessage) (any, error) {
+	var a mcpTaskArguments
+	if err := decodeParams(arguments, &a); err != nil {
+		return nil, err
+	}
+	if a.ID != 0 {
+		return nil, invalidParams("ID задачи назначает сервер")
+	}
+	if a.Title == nil {
+		return nil, invalidParams("Поле 'title' обязательно")
+	}
+	opts, err := a.options()
+	if err != nil {
+		return nil, err
+	}
+	var description string
+	if a.Description != nil {
+		description = *a.Description
+	}
+	return s.tasks.CreateTask(ctx, *a.Title, description, opts...), nil
+}
+
+// updateTaskTool меняет только заданные поля: UpdateTask применяет опции к текущей
+// задаче, а заголовок, описание и отметку о выполнении берем из нее
+func (s *MCPServer) updateTaskTool(ctx context.Context, arguments json.RawMessage) (any, error) {
+	var a mcpTaskArguments
+	if err := decodeParams(arguments, &a); err != nil {
+		return nil, err
+	}
+	opts, err := a.options()
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.tasks.GetTask(ctx, a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	title, description := task.Title, task.Description
+	if a.Title != nil {
+		title = *a.Title
+	}
+	if a.Description != nil {
+		description = *a.Description
+	}
+	return s.tasks.UpdateTask(ctx, a.ID, title, description, task.Completed, opts...)
+}
+
+// completeTaskTool: {id, completed} → задача; completed по умолчанию true
+func (s *MCPServer) completeTaskTool(ctx context.Context, arguments json.RawMessage) (any, error) {
+	p := struct {
+		ID        int  `json:"id"`
+		Completed bool `json:"completed"`
+	}{Completed: true}
+	if err := decodeParams(arguments, &p); err != nil {
+		return nil, err
+	}
+	task, err := s.tasks.GetTask(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tasks.UpdateTask(ctx, p.ID, task.Title, task.Description, p.Completed)
+}
+
+func (s *MCPServer) deleteTaskTool(ctx context.Context, arguments json.RawMessage) (any, error) {
+	var p struct {
+		ID int `json:"id"`
+	}
+	if err := decodeParams(arguments, &p); err != nil {
+		return nil, err
+	}
+	if err := s.tasks.DeleteTask(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("Задача %d удалена", p.ID), nil
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// mcpSession — клиент MCP, подключенный к ServeStdio через каналы в памяти,
+// как ассистент, запустивший todo-api mcp
+type mcpSession struct {
+	t      *testing.T
+	stdin  *io.PipeWriter
+	stdout *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func newMCPSession(t *testing.T, tasks TaskServiceInterface) *mcpSession {
+	t.Helper()
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	s := &mcpSession{t: t, stdin: inWriter, stdout: bufio.NewReader(outReader), done: make(chan error, 1)}
+	go func() {
+		s.done <- NewMCPServer(tasks).ServeStdio(inReader, outWriter)
+		outWriter.Close()
+	}()
+	t.Cleanup(func() { inWriter.Close() })
+	return s
+}
+
+// send пишет сообщение в stdin сервера одной строкой
+func (s *mcpSession) send(message map[string]any) {
+	s.t.Helper()
+	data, _ := json.Marshal(message)
+	if _, err := s.stdin.Write(append(data, '\n')); err != nil {
+		s.t.Fatalf("Ошибка записи в stdin: %v", err)
+	}
+}
+
+// request отправляет запрос и читает ответ на него
+func (s *mcpSession) request(method string, params any) rpcTestResponse {
+	s.t.Helper()
+	s.nextID++
+	s.send(map[string]any{"jsonrpc": "2.0", "id": s.nextID, "method": method, "params": params})
+
+	line, err := s.stdout.ReadString('\n')
+	if err != nil {
+		s.t.Fatalf("%s: ошибка чтения stdout: %v", method, err)
+	}
+	var response rpcTestResponse
+	if err := json.Unmarshal([]byte(line), &response); err != nil || string(response.ID) != strconv.Itoa(s.nextID) {
+		s.t.Fatalf("%s: неверный ответ %v %s", method, err, line)
+	}
+	return response
+}
+
+// callTool вызывает инструмент и возвращает текст результата и признак ошибки
+func (s *mcpSession) callTool(name string, arguments any) (string, bool) {
+	s.t.Helper()
+	response := s.request("tools/call", map[string]any{"name": name, "arguments": arguments})
+	if response.Error != nil {
+		s.t.Fatalf("%s: ошибка протокола %+v", name, response.Error)
+	}
+	var result mcpToolResult
+	if err := json.Unmarshal(response.Result, &result); err != nil || len(result.Content) != 1 || result.Content[0].Type != "text" {
+		s.t.Fatalf("%s: неверный результат %v %s", name, err, response.Result)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestMCP_InitializeAndListTools(t *testing.T) {
+	session := newMCPSession(t, NewTaskService())
+
+	var initialized struct {
+		ProtocolVersion string
+		Capabilities    map[string]json.RawMessage
+		ServerInfo      struct{ Name string }
+	}
+	response := session.request("initialize", map[string]any{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "test", "version": "1.0"},
+	})
+	json.Unmarshal(response.Result, &initialized)
+	if initialized.ProtocolVersion != "2025-03-26" || initialized.ServerInfo.Name != "todo-api" || initialized.Capabilities["tools"] == nil || initialized.Capabilities["resources"] == nil {
+		t.Errorf("Неверный ответ на initialize: %s", response.Result)
+	}
+	// На уведомление ответа нет: следующая строка stdout — ответ на ping
+	session.send(map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+	if response := session.request("ping", nil); string(response.Result) != "{}" {
+		t.Errorf("Неверный ответ на ping: %s", response.Result)
+	}
+
+	// С незнакомой версией сервер предлагает последнюю
+	response = session.request("initialize", map[string]any{"protocolVersion": "1999-01-01"})
+	json.Unmarshal(response.Result, &initialized)
+	if initialized.ProtocolVersion != mcpProtocolVersions[0] {
+		t.Errorf("Ожидалась версия %s, получено %s", mcpProtocolVersions[0], initialized.ProtocolVersion)
+	}
+
+	var listed struct {
+		Tools []struct {
+			Name        string
+			Description string
+			InputSchema struct {
+				Type       string
+				Properties map[string]json.RawMessage
+				Required   []string
+			}
+		}
+	}
+	json.Unmarshal(session.request("tools/list", nil).Result, &listed)
+	var names []string
+	for _, tool := range listed.Tools {
+		names = append(names, tool.Name)
+		if tool.Description == "" || tool.InputSchema.Type != "object" || len(tool.InputSchema.Properties) == 0 {
+			t.Errorf("Неполное описание инструмента: %+v", tool)
+		}
+	}
+	if strings.Join(names, ",") != "list_tasks,search_tasks,create_task,update_task,complete_task,delete_task" {
+		t.Errorf("Неверный список инструментов: %v", names)
+	}
+
+	if response := session.request("prompts/list", nil); response.Error == nil || response.Error.Code != rpcMethodNotFound {
+		t.Errorf("Ожидалась ошибка MethodNotFound: %+v", response)
+	}
+
+	// Закрытый stdin завершает сессию без ошибки
+	session.stdin.Close()
+	if err := <-session.done; err != nil {
+		t.Errorf("ServeStdio должен завершаться без ошибки: %v", err)
+	}
+}
+
+func TestMCP_Tools(t *testing.T) {
+	service := NewTaskService()
+	session := newMCPSession(t, service)
+
+	text, isError := session.callTool("create_task", map[string]any{
+		"title":    "Купить молоко",
+		"tags":     []string{"Дом"},
+		"priority": "high",
+		"due_date": "2025-01-31T18:00:00Z",
+	})
+	var task Task
+	if err := json.Unmarshal([]byte(text), &task); err != nil || isError || task.ID != 1 || task.Tags[0] != "дом" || task.DueDate == nil {
+		t.Fatalf("Неверная созданная задача: %v %s", err, text)
+	}
+	session.callTool("create_task", map[string]any{"title": "Позвонить маме", "description": "по дороге из магазина"})
+
+	// update_task меняет только заданные поля
+	text, _ = session.callTool("update_task", map[string]any{"id": 1, "title": "Купить кефир", "due_date": ""})
+	task = Task{}
+	json.Unmarshal([]byte(text), &task)
+	if task.Title != "Купить кефир" || task.Priority != PriorityHigh || len(task.Tags) != 1 || task.DueDate != nil {
+		t.Errorf("Неверная обновленная задача: %s", text)
+	}
+
+	text, _ = session.callTool("complete_task", map[string]any{"id": 1})
+	json.Unmarshal([]byte(text), &task)
+	if !task.Completed || task.Title != "Купить кефир" || task.Priority != PriorityHigh {
+		t.Errorf("Задача должна быть выполнена без других изменений: %s", text)
+	}
+
+	var listed struct {
+		Total int
+		Tasks []Task
+	}
+	text, _ = session.callTool("list_tasks", map[string]any{"query": "completed:false"})
+	json.Unmarshal([]byte(text), &listed)
+	if listed.Total != 1 || listed.Tasks[0].ID != 2 {
+		t.Errorf("Неверный список задач: %s", text)
+	}
+	text, _ = session.callTool("list_tasks", map[string]any{"limit": 1})
+	json.Unmarshal([]byte(text), &listed)
+	if listed.Total != 2 || len(listed.Tasks) != 1 || listed.Tasks[0].ID != 1 {
+		t.Errorf("Неверный список задач с limit: %s", text)
+	}
+
+	var results []SearchResult
+	text, _ = session.callTool("search_tasks", map[string]any{"query": "магазин"})
+	if err := json.Unmarshal([]byte(text), &results); err != nil || len(results) != 1 || results[0].Task.ID != 2 {
+		t.Errorf("Неверный результат поиска: %s", text)
+	}
+	text, _ = session.callTool("search_tasks", map[string]any{"query": "кефри", "fuzzy": true})
+	if err := json.Unmarshal([]byte(text), &results); err != nil || len(results) != 1 || results[0].Task.ID != 1 {
+		t.Errorf("Неверный результат нечеткого поиска: %s", text)
+	}
+
+	if text, isError := session.callTool("delete_task", map[string]any{"id": 2}); isError || text != "Задача 2 удалена" {
+		t.Errorf("Неверный результат удаления: %s", text)
+	}
+	if _, err := service.GetTask(t.Context(), 2); err == nil {
+		t.Errorf("Задача не удалена")
+	}
+}
+
+func TestMCP_ToolErrors(t *testing.T) {
+	session := newMCPSession(t, NewTaskService())
+	session.callTool("create_task", map[string]any{"title": "Купить молоко"})
+
+	// Ошибки выполнения возвращаются результатом с isError, чтобы ассистент мог исправиться
+	tests := []struct {
+		name      string
+		arguments any
+		message   string
+	}{
+		{"create_task", map[string]any{}, "Поле 'title' обязательно"},
+		{"create_task", map[string]any{"title": "Задача", "priority": "urgent"}, "Поле 'priority' должно быть 'low', 'medium' или 'high'"},
+		{"create_task", map[string]any{"title": "Задача", "due_date": "завтра"}, "Поле 'due_date' должно быть в формате RFC 3339"},
+		{"create_task", map[string]any{"title": "Задача", "id": 5}, "ID задачи назначает сервер"},
+		{"update_task", map[string]any{"id": 42, "title": "Задача"}, "задача с ID 42 не найдена"},
+		{"update_task", map[string]any{"id": 1, "title": ""}, "Поле 'title' обязательно"},
+		{"complete_task", map[string]any{"id": 42}, "задача с ID 42 не найдена"},
+		{"delete_task", map[string]any{"id": 42}, "задача с ID 42 не найдена"},
+		{"list_tasks", map[string]any{"query": "tag:"}, ""},
+		{"list_tasks", map[string]any{"limit": -1}, "Неверное поле 'limit'"},
+		{"search_tasks", map[string]any{}, "Поле 'query' обязательно"},
+		{"delete_task", map[string]any{"ids": []int{1}}, ""},
+		{"delete_task", []int{1}, "Параметры передаются объектом с именами полей"},
+	}
+	for _, tt := range tests {
+		text, isError := session.callTool(tt.name, tt.arguments)
+		if !isError || (tt.message != "" && text != tt.message) {
+			t.Errorf("%s %v: ожидалась ошибка %q, получено %v %q", tt.name, tt.arguments, tt.message, isError, text)
+		}
+	}
+
+	// Неизвестный инструмент — ошибка протокола
+	response := session.request("tools/call", map[string]any{"name": "drop_all"})
+	if response.Error == nil || response.Error.Code != rpcInvalidParams {
+		t.Errorf("Ожидалась ошибка InvalidParams: %+v", response)
+	}
+}
+
+func TestMCP_Resources(t *testing.T) {
+	service := NewTaskService()
+	service.CreateTask(t.Context(), "Купить молоко", "")
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+	session := newMCPSession(t, service)
+
+	var listed struct {
+		Resources []mcpResource
+	}
+	json.Unmarshal(session.request("resources/list", nil).Result, &listed)
+	if len(listed.Resources) != 3 || listed.Resources[0].URI != "todo://tasks" ||
+		listed.Resources[2].URI != "todo://tasks/2" || listed.Resources[2].Title != "Позвонить маме" {
+		t.Errorf("Неверный список ресурсов: %+v", listed.Resources)
+	}
+
+	var templates struct {
+		ResourceTemplates []struct{ URITemplate string }
+	}
+	json.Unmarshal(session.request("resources/templates/list", nil).Result, &templates)
+	if len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate != "todo://tasks/{id}" {
+		t.Errorf("Неверные шаблоны ресурсов: %+v", templates)
+	}
+
+	var read struct {
+		Contents []mcpContent
+	}
+	json.Unmarshal(session.request("resources/read", map[string]any{"uri": "todo://tasks/1"}).Result, &read)
+	var task Task
+	if len(read.Contents) != 1 || read.Contents[0].URI != "todo://tasks/1" || read.Contents[0].MimeType != "application/json" ||
+		json.Unmarshal([]byte(read.Contents[0].Text), &task) != nil || task.Title != "Купить молоко" {
+		t.Errorf("Неверное содержимое ресурса: %+v", read)
+	}
+	json.Unmarshal(session.request("resources/read", map[string]any{"uri": "todo://tasks"}).Result, &read)
+	var tasks []Task
+	if json.Unmarshal([]byte(read.Contents[0].Text), &tasks) != nil || len(tasks) != 2 {
+		t.Errorf("Неверное содержимое списка задач: %+v", read)
+	}
+
+	for _, uri := range []string{"todo://tasks/42", "todo://tasks/abc", "file:///etc/passwd"} {
+		response := session.request("resources/read", map[string]any{"uri": uri})
+		if response.Error == nil || response.Error.Code != mcpResourceNotFound {
+			t.Errorf("%s: ожидалась ошибка ResourceNotFound: %+v", uri, response)
+		}
+	}
+}
+
+func TestMCP_HTTP(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+
+	post := func(body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"protocolVersion":"2025-06-18"`) {
+		t.Errorf("Неверный ответ на initialize: %d %s", w.Code, w.Body.String())
+	}
+	w = post(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create_task","arguments":{"title":"Из ассистента"}}}`,
+		http.Header{"Mcp-Protocol-Version": {"2025-06-18"}})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Из ассистента") {
+		t.Errorf("Неверный ответ на tools/call: %d %s", w.Code, w.Body.String())
+	}
+	if w = post(`{"jsonrpc":"2.0","method":"notifications/initialized"}`, nil); w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+		t.Errorf("Ожидался статус 202 без тела, получено %d %s", w.Code, w.Body.String())
+	}
+	if w = post(`{"jsonrpc":"2.0","id":3,"method":"ping"}`, http.Header{"Mcp-Protocol-Version": {"1999-01-01"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неизвестной версии, получено %d", w.Code)
+	}
+	if w = post(`{"jsonrpc":"2.0","id":3,"method":"ping"}`, http.Header{"Origin": {"http://evil.example"}}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус 403 для чужого Origin, получено %d", w.Code)
+	}
+	if w = post(`{"jsonrpc":"2.0","id":3,"method":"ping"}`, http.Header{"Origin": {"http://example.com"}}); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200 для своего Origin, получено %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mcp", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /mcp: ожидался статус 405, получено %d", w.Code)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute — значение метки route для запросов, не подошедших ни к одному маршруту.
+// Сырой путь в метку не попадает: иначе сканер адресов создал бы тысячи рядов.
+const unmatchedRoute = "unmatched"
+
+// otherMethod — значение метки method для методов вне списка metricMethods: клиент
+// может прислать любую строку, и каждая создавала бы новый ряд
+const otherMethod = "other"
+
+// metricMethods — методы HTTP, которые попадают в метку method как есть
+var metricMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+	http.MethodConnect: true, http.MethodTrace: true,
+	"PROPFIND": true, "REPORT": true, // CalDAV
+}
+
+// methodLabel возвращает значение метки method для метода запроса
+func methodLabel(method string) string {
+	if metricMethods[method] {
+		return method
+	}
+	return otherMethod
+}
+
+// Metrics собирает метрики сервера в формате Prometheus: запросы HTTP по маршрутам,
+// вызовы сервиса задач и число задач. У каждого экземпляра свой реестр.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	serviceDuration *prometheus.HistogramVec
+}
+
+// NewMetrics создает реестр с метриками HTTP, сервиса задач, среды Go и процесса
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Число обработанных запросов HTTP по маршрутам и статусам ответа.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Время обработки запросов HTTP по маршрутам.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Число выполняющихся запросов HTTP, включая открытые потоки событий.",
+		}, []string{"method", "route"}),
+		serviceDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "todo_task_service_duration_seconds",
+			Help:    "Время вызовов сервиса задач по операциям, включая ожидание блокировки.",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(
+		m.requests, m.duration, m.inFlight, m.serviceDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler обрабатывает GET /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware возвращает middleware, измеряющее запросы к маршрутизатору routes.
+// Метка route — шаблон маршрута (/tasks/{id}), а не путь запроса; маршрут ищется
+// до обработки, чтобы учитывать в http_requests_in_flight и незавершенные запросы.
+func (m *Metrics) Middleware(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := methodLabel(r.Method)
+			route := unmatchedRoute
+			if pattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path); pattern != "" {
+				route = openAPIPath(pattern)
+			}
+
+			inFlight := m.inFlight.WithLabelValues(method, route)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				// Обработчик ничего не записал: net/http ответит 200
+				status = http.StatusOK
+			}
+			m.duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		})
+	}
+}
+
+// InstrumentTaskService регистрирует метрику todo_tasks по состоянию задач service и
+// возвращает обертку, измеряющую время каждого вызова сервиса
+func (m *Metrics) InstrumentTaskService(service ServerTaskService) ServerTaskService {
+	m.registry.MustRegister(&taskCollector{service: service})
+	return &instrumentedTaskService{next: service, duration: m.serviceDuration}
+}
+
+// taskCollector считает задачи при каждом сборе метрик, поэтому число задач
+// не может разойтись с хранилищем
+type taskCollector struct {
+	service TaskServiceInterface
+}
+
+var taskCountDesc = prometheus.NewDesc("todo_tasks", "Число задач по состоянию (open, completed).", []string{"state"}, nil)
+
+func (c *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- taskCountDesc
+}
+
+func (c *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	var open, completed int
+	for _, task := range c.service.GetAllTasks(context.Background()) {
+		if task.Completed {
+			completed++
+		} else {
+			open++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(taskCountDesc, prometheus.GaugeValue, float64(open), "open")
+	ch <- prometheus.MustNewConstMetric(taskCountDesc, prometheus.GaugeValue, float64(completed), "completed")
+}
+
+// instrumentedTaskService передает вызовы next и записывает их время в duration
+type instrumentedTaskService struct {
+	next     ServerTaskService
+	duration *prometheus.HistogramVec
+}
+
+// observe записывает время операции; вызывается через defer в начале метода
+func (s *instrumentedTaskService) observe(operation string, start time.Time) {
+	s.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (s *instrumentedTaskService) CreateTask(ctx context.Context, title, description string, opts ...TaskOption) *Task {
+	defer s.observe("CreateTask", time.Now())
+	return s.next.CreateTask(ctx, title, description, opts...)
+}
+
+func (s *instrumentedTaskService) GetTask(ctx context.Context, id int) (*Task, error) {
+	defer s.observe("GetTask", time.Now())
+	return s.next.GetTask(ctx, id)
+}
+
+func (s *instrumentedTaskService) GetAllTasks(ctx context.Context) []*Task {
+	defer s.observe("GetAllTasks", time.Now())
+	return s.next.GetAllTasks(ctx)
+}
+
+func (s *instrumentedTaskService) FilterTasks(ctx context.Context, match func(*Task) bool) []*Task {
+	defer s.observe("FilterTasks", time.Now())
+	return s.next.FilterTasks(ctx, match)
+}
+
+func (s *instrumentedTaskService) UpdateTask(ctx context.Context, id int, title, description string, completed bool, opts ...TaskOption) (*Task, error) {
+	defer s.observe("UpdateTask", time.Now())
+	return s.next.UpdateTask(ctx, id, title, description, completed, opts...)
+}
+
+func (s *instrumentedTaskService) DeleteTask(ctx context.Context, id int) error {
+	defer s.observe("DeleteTask", time.Now())
+	return s.next.DeleteTask(ctx, id)
+}
+
+func (s *instrumentedTaskService) ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchItemResult, error) {
+	defer s.observe("ApplyBatch", time.Now())
+	return s.next.ApplyBatch(ctx, ops, atomic)
+}
+
+func (s *instrumentedTaskService) ImportTasks(ctx context.Context, tasks []*Task, opts ImportOptions) []BatchItemResult {
+	defer s.observe("ImportTasks", time.Now())
+	return s.next.ImportTasks(ctx, tasks, opts)
+}
+
+func (s *instrumentedTaskService) SearchTasks(ctx context.Context, query string, limit int) []*SearchResult {
+	defer s.observe("SearchTasks", time.Now())
+	return s.next.SearchTasks(ctx, query, limit)
+}
+
+func (s *instrumentedTaskService) FuzzySearchTasks(ctx context.Context, query string, limit int) []*SearchResult {
+	defer s.observe("FuzzySearchTasks", time.Now())
+	return s.next.FuzzySearchTasks(ctx, query, limit)
+}
+
+func (s *instrumentedTaskService) SuggestTasks(ctx context.Context, input string, limit int) *Suggestions {
+	defer s.observe("SuggestTasks", time.Now())
+	return s.next.SuggestTasks(ctx, input, limit)
+}
+
+// Subscribe не измеряется: подписка живет столько, сколько открыт поток
+func (s *instrumentedTaskService) Subscribe(ctx context.Context) (<-chan TaskEvent, func()) {
+	return s.next.Subscribe(ctx)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// scrapeMetrics возвращает ответ GET /metrics в текстовом формате
+func scrapeMetrics(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Неверный статус /metrics: %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	metrics := NewMetrics()
+	r := chi.NewRouter()
+	r.Use(metrics.Middleware(r))
+
+	inFlight := -1.0
+	r.Route("/tasks", func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			// Выполняющийся запрос уже учтен
+			inFlight = testutil.ToFloat64(metrics.inFlight.WithLabelValues(http.MethodGet, "/tasks/{id}"))
+			if chi.URLParam(r, "id") == "404" {
+				http.NotFound(w, r)
+				return
+			}
+			io.WriteString(w, "{}")
+		})
+	})
+
+	for _, path := range []string{"/tasks/", "/tasks/1", "/tasks/2", "/tasks/404", "/nope/1", "/nope/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Произвольные методы клиента сводятся к одному значению метки
+	for _, method := range []string{"FOO", "BAR", "get"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/tasks/1", nil))
+	}
+
+	if inFlight != 1 {
+		t.Errorf("Во время запроса http_requests_in_flight должен быть 1, получено %v", inFlight)
+	}
+	if got := testutil.ToFloat64(metrics.inFlight.WithLabelValues(http.MethodGet, "/tasks/{id}")); got != 0 {
+		t.Errorf("После запросов http_requests_in_flight должен быть 0, получено %v", got)
+	}
+
+	// Метка route — шаблон маршрута, а не путь запроса
+	tests := []struct {
+		route, status string
+		want          float64
+	}{
+		{"/tasks", "200", 1},
+		{"/tasks/{id}", "200", 2},
+		{"/tasks/{id}", "404", 1},
+		{unmatchedRoute, "404", 2},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(metrics.requests.WithLabelValues(http.MethodGet, tt.route, tt.status)); got != tt.want {
+			t.Errorf("http_requests_total{route=%q,status=%q} = %v, ожидалось %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+
+	body := scrapeMetrics(t, metrics.Handler())
+	for _, want := range []string{
+		`http_request_duration_seconds_count{method="GET",route="/tasks/{id}"} 3`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("В ответе /metrics нет %q", want)
+		}
+	}
+	if strings.Contains(body, `route="/tasks/1"`) || strings.Contains(body, `route="/nope/1"`) {
+		t.Errorf("Пути запросов не должны попадать в метки:\n%s", body)
+	}
+	if strings.Contains(body, `method="FOO"`) || strings.Contains(body, `method="get"`) || !strings.Contains(body, `method="other"`) {
+		t.Errorf("Нестандартные методы должны учитываться как other:\n%s", body)
+	}
+}
+
+func TestMetrics_InstrumentTaskService(t *testing.T) {
+	metrics := NewMetrics()
+	service := metrics.InstrumentTaskService(NewTaskService())
+
+	first := service.CreateTask(t.Context(), "Купить молоко", "")
+	service.CreateTask(t.Context(), "Позвонить маме", "")
+	service.UpdateTask(t.Context(), first.ID, first.Title, "", true)
+	service.GetTask(t.Context(), first.ID)
+	service.GetTask(t.Context(), 100)
+
+	body := scrapeMetrics(t, metrics.Handler())
+	for _, want := range []string{
+		`todo_tasks{state="open"} 1`,
+		`todo_tasks{state="completed"} 1`,
+		`todo_task_service_duration_seconds_count{operation="CreateTask"} 2`,
+		`todo_task_service_duration_seconds_count{operation="GetTask"} 2`,
+		`todo_task_service_duration_seconds_count{operation="UpdateTask"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("В ответе /metrics нет %q:\n%s", want, body)
+		}
+	}
+	// Подсчет задач при сборе метрик не учитывается как вызов сервиса
+	if strings.Contains(body, `operation="GetAllTasks"`) {
+		t.Errorf("Сбор метрик не должен измеряться как GetAllTasks")
+	}
+}
+
+func TestMetrics_Route(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tasks", nil))
+
+	body := scrapeMetrics(t, router)
+	if !strings.Contains(body, `http_requests_total{method="GET",route="/tasks",status="200"} 1`) {
+		t.Errorf("Запрос через маршрутизатор не учтен:\n%s", body)
+	}
+}
@@ -1,31 +1,62 @@
 package main
 
-import "time"
+import "todo-api/models"
 
-// Task представляет задачу в ToDo списке
-type Task struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Completed   bool      `json:"completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-}
+// Типы API и контракт сервиса задач объявлены в пакете models, чтобы их могли
+// импортировать клиенты; здесь они доступны под прежними именами.
+type (
+	Task              = models.Task
+	Priority          = models.Priority
+	CreateTaskRequest = models.CreateTaskRequest
+	UpdateTaskRequest = models.UpdateTaskRequest
+	ErrorResponse     = models.ErrorResponse
+	Attachment        = models.Attachment
+	SavedView         = models.SavedView
+	SaveViewRequest   = models.SaveViewRequest
+	QuickAddRequest   = models.QuickAddRequest
+	QuickAddToken     = models.QuickAddToken
+	QuickAddResponse  = models.QuickAddResponse
+	BatchOperation    = models.BatchOperation
+	BatchRequest      = models.BatchRequest
+	BatchResult       = models.BatchResult
+	BatchResponse     = models.BatchResponse
+	ImportError       = models.ImportError
+	ImportResponse    = models.ImportResponse
+	TaskEvent         = models.TaskEvent
 
-// CreateTaskRequest представляет запрос на создание задачи
-type CreateTaskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-}
+	TaskServiceInterface = models.TaskServiceInterface
+	TaskSearcher         = models.TaskSearcher
+	TaskBatcher          = models.TaskBatcher
+	TaskImporter         = models.TaskImporter
+	TaskSubscriber       = models.TaskSubscriber
+	TaskOption           = models.TaskOption
+	BatchItemResult      = models.BatchItemResult
+	ImportOptions        = models.ImportOptions
+	SearchResult         = models.SearchResult
+	Suggestions          = models.Suggestions
+)
 
-// UpdateTaskRequest представляет запрос на обновление задачи
-type UpdateTaskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Completed   bool   `json:"completed"`
-}
+const (
+	PriorityNone   = models.PriorityNone
+	PriorityLow    = models.PriorityLow
+	PriorityMedium = models.PriorityMedium
+	PriorityHigh   = models.PriorityHigh
 
-// ErrorResponse представляет ответ с ошибкой
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
+	TaskEventCreated = models.TaskEventCreated
+	TaskEventUpdated = models.TaskEventUpdated
+	TaskEventDeleted = models.TaskEventDeleted
+)
+
+var (
+	ErrNotFound     = models.ErrNotFound
+	ErrInvalidInput = models.ErrInvalidInput
+	ErrConflict     = models.ErrConflict
+	ErrBatchAborted = models.ErrBatchAborted
+	ErrNotSupported = models.ErrNotSupported
+
+	WithTags      = models.WithTags
+	WithDueDate   = models.WithDueDate
+	WithPriority  = models.WithPriority
+	WithProject   = models.WithProject
+	normalizeTags = models.NormalizeTags
+)
@@ -0,0 +1,186 @@
+// Package models содержит типы запросов и ответов API задач и контракт сервиса
+// задач TaskServiceInterface. Их используют и сервер, и клиенты (cmd/todo,
+// todo-api/client), поэтому формат JSON и поведение описаны в одном месте.
+package models
+
+import "time"
+
+// Task представляет задачу в ToDo списке
+type Task struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	Tags        []string   `json:"tags"`
+	Priority    Priority   `json:"priority,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	UID         string     `json:"uid,omitempty"` // UID задачи, импортированной из календаря
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Priority — приоритет задачи; пустая строка означает, что приоритет не задан
+type Priority string
+
+const (
+	PriorityNone   Priority = ""
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+)
+
+// Rank возвращает числовой вес приоритета для сравнения (0 — не задан)
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityMedium:
+		return 2
+	case PriorityHigh:
+		return 3
+	}
+	return 0
+}
+
+// Valid проверяет, что приоритет имеет допустимое значение
+func (p Priority) Valid() bool {
+	return p == PriorityNone || p.Rank() > 0
+}
+
+// CreateTaskRequest представляет запрос на создание задачи
+type CreateTaskRequest struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Tags        []string   `json:"tags,omitempty"`
+	Priority    Priority   `json:"priority,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+}
+
+// UpdateTaskRequest представляет запрос на обновление задачи
+type UpdateTaskRequest struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	Tags        []string   `json:"tags,omitempty"`
+	Priority    Priority   `json:"priority,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+}
+
+// ErrorResponse представляет ответ с ошибкой
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Attachment представляет файл, прикрепленный к задаче
+type Attachment struct {
+	ID          int       `json:"id"`
+	TaskID      int       `json:"task_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SavedView представляет сохраненный именованный запрос к задачам
+type SavedView struct {
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveViewRequest представляет запрос на создание или изменение сохраненного представления
+type SaveViewRequest struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// QuickAddRequest представляет запрос на создание задачи из свободного текста
+type QuickAddRequest struct {
+	Text string `json:"text"`
+}
+
+// QuickAddToken описывает фрагмент текста, распознанный при быстром добавлении
+type QuickAddToken struct {
+	Text  string `json:"text"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// QuickAddResponse содержит созданную задачу и то, что удалось распознать в тексте
+type QuickAddResponse struct {
+	Task       *Task           `json:"task"`
+	Recognized []QuickAddToken `json:"recognized"`
+}
+
+// BatchOperation представляет одну операцию пакетного запроса
+type BatchOperation struct {
+	Op          string     `json:"op"` // create, update или delete
+	ID          int        `json:"id,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Completed   bool       `json:"completed,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Priority    Priority   `json:"priority,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+}
+
+// BatchRequest представляет пакет операций над задачами
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult представляет результат одной операции в ответе
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	Task   *Task  `json:"task,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse представляет ответ на пакетный запрос
+type BatchResponse struct {
+	Atomic    bool          `json:"atomic"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// ImportError описывает строку импортируемого файла, которую не удалось импортировать
+type ImportError struct {
+	Line   int    `json:"line"`
+	Status int    `json:"status"` // HTTP статус, соответствующий ошибке: 400, 404 или 409
+	Error  string `json:"error"`
+}
+
+// ImportResponse представляет результат импорта задач
+type ImportResponse struct {
+	DryRun   bool           `json:"dry_run"`
+	Imported int            `json:"imported"`
+	Updated  int            `json:"updated"`
+	Failed   int            `json:"failed"`
+	Tasks    []*Task        `json:"tasks"`
+	Errors   []ImportError  `json:"errors"`
+	Unmapped map[string]int `json:"unmapped,omitempty"` // несопоставленное поле → число задач, в которых оно заполнено
+}
+
+// Типы событий об изменении задач
+const (
+	TaskEventCreated = "created"
+	TaskEventUpdated = "updated"
+	TaskEventDeleted = "deleted"
+)
+
+// TaskEvent описывает изменение задачи. Для удаленной задачи Task содержит ее последнее состояние.
+type TaskEvent struct {
+	Seq  int64     `json:"seq"` // номер события; растет на единицу с каждым событием
+	Type string    `json:"type"`
+	Task *Task     `json:"task"`
+	Time time.Time `json:"time"`
+}
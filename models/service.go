@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound позволяет проверить через errors.Is, что запрошенный объект не существует
+	ErrNotFound = errors.New("не найдено")
+	// ErrInvalidInput позволяет проверить через errors.Is, что переданы неверные данные
+	ErrInvalidInput = errors.New("неверные данные")
+	// ErrConflict позволяет проверить через errors.Is, что объект с таким идентификатором уже существует
+	ErrConflict = errors.New("конфликт")
+	// ErrBatchAborted помечает операции атомарного пакета, отмененные из-за ошибки в другой операции
+	ErrBatchAborted = errors.New("операция отменена из-за ошибки в пакете")
+	// ErrNotSupported позволяет проверить через errors.Is, что сервис задач не поддерживает операцию
+	ErrNotSupported = errors.New("операция не поддерживается")
+)
+
+// TaskServiceInterface определяет основные операции с задачами. Его реализуют
+// сервис задач сервера и клиент todo-api/client, работающий с ним по HTTP.
+// Поиск, пакеты, импорт и подписка на изменения описаны отдельными интерфейсами:
+// реализация может их не поддерживать, поэтому их наличие проверяется приведением типа.
+// Каждый метод принимает контекст запроса, в котором он выполняется: через него вызов
+// попадает в трассировку запроса, а клиент — отменяет запрос к серверу.
+type TaskServiceInterface interface {
+	CreateTask(ctx context.Context, title, description string, opts ...TaskOption) *Task
+	GetTask(ctx context.Context, id int) (*Task, error)
+	GetAllTasks(ctx context.Context) []*Task
+	UpdateTask(ctx context.Context, id int, title, description string, completed bool, opts ...TaskOption) (*Task, error)
+	DeleteTask(ctx context.Context, id int) error
+}
+
+// TaskSearcher ищет задачи по тексту и подсказывает варианты запроса
+type TaskSearcher interface {
+	SearchTasks(ctx context.Context, query string, limit int) []*SearchResult
+	FuzzySearchTasks(ctx context.Context, query string, limit int) []*SearchResult
+	SuggestTasks(ctx context.Context, input string, limit int) *Suggestions
+}
+
+// TaskBatcher выполняет пакет операций create/update/delete
+type TaskBatcher interface {
+	ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchItemResult, error)
+}
+
+// TaskImporter импортирует задачи
+type TaskImporter interface {
+	ImportTasks(ctx context.Context, tasks []*Task, opts ImportOptions) []BatchItemResult
+}
+
+// TaskSubscriber сообщает об изменениях задач
+type TaskSubscriber interface {
+	Subscribe(ctx context.Context) (<-chan TaskEvent, func())
+}
+
+// TaskOption задает дополнительные поля задачи при создании и обновлении
+type TaskOption func(*Task)
+
+// WithTags задает теги задачи; пустые и повторяющиеся теги отбрасываются
+func WithTags(tags []string) TaskOption {
+	return func(t *Task) {
+		t.Tags = NormalizeTags(tags)
+	}
+}
+
+// WithDueDate задает срок выполнения задачи; nil снимает срок
+func WithDueDate(due *time.Time) TaskOption {
+	return func(t *Task) {
+		t.DueDate = due
+	}
+}
+
+// WithPriority задает приоритет задачи
+func WithPriority(priority Priority) TaskOption {
+	return func(t *Task) {
+		t.Priority = priority
+	}
+}
+
+// WithProject задает проект задачи
+func WithProject(project string) TaskOption {
+	return func(t *Task) {
+		t.Project = strings.TrimSpace(project)
+	}
+}
+
+// NormalizeTags приводит теги к нижнему регистру и убирает пустые и повторяющиеся
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// BatchItemResult — результат выполнения одной операции пакета
+type BatchItemResult struct {
+	Task    *Task
+	Updated bool // импорт обновил существующую задачу вместо создания новой
+	Err     error
+}
+
+// ImportOptions управляет импортом задач
+type ImportOptions struct {
+	PreserveIDs        bool // сохранить ID из файла; иначе задачи получают новые ID
+	PreserveTimestamps bool // сохранить created_at и updated_at из файла; иначе используется текущее время
+	DryRun             bool // только проверить данные, ничего не сохраняя
+	MatchUID           bool // обновлять задачи с тем же UID вместо создания новых
+}
+
+// SearchResult представляет найденную задачу с оценкой релевантности и подсвеченными фрагментами
+type SearchResult struct {
+	Task     *Task             `json:"task"`
+	Score    float64           `json:"score"`
+	Snippets map[string]string `json:"snippets"`
+}
+
+// Suggestions представляет подсказки для строки поиска
+type Suggestions struct {
+	Suggestions []string `json:"suggestions"`
+	DidYouMean  string   `json:"did_you_mean,omitempty"`
+}
@@ -0,0 +1,635 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// openAPISpec — спецификация API в формате OpenAPI 3.1. Ее правят вместе с маршрутами:
+// тесты в openapi_test.go падают, если маршрут и спецификация расходятся.
+//
+//go:embed api/openapi.json
+var openAPISpec []byte
+
+// apiDocsPage — страница, которая показывает спецификацию в браузере. Она целиком
+// встроена в бинарный файл и не загружает скриптов со сторонних адресов, поэтому
+// работает без сети
+//
+//go:embed api/docs.html
+var apiDocsPage []byte
+
+// apiDocsPolicy — Content-Security-Policy страницы документации
+const apiDocsPolicy = "default-src 'none'; connect-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'"
+
+// ServeOpenAPISpec обрабатывает GET /openapi.json
+func ServeOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// ServeAPIDocs обрабатывает GET /docs
+func ServeAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Странице разрешены только встроенные скрипты и стили и запросы к этому же серверу
+	w.Header().Set("Content-Security-Policy", apiDocsPolicy)
+	w.Write(apiDocsPage)
+}
+
+// OpenAPIValidation — режим проверки запросов и ответов по спецификации
+type OpenAPIValidation string
+
+const (
+	// OpenAPIValidationOff отключает проверку
+	OpenAPIValidationOff OpenAPIValidation = "off"
+	// OpenAPIValidationRequests отклоняет запросы, не соответствующие спецификации, с 400
+	OpenAPIValidationRequests OpenAPIValidation = "requests"
+	// OpenAPIValidationStrict дополнительно проверяет ответы и сообщает о расхождениях в лог
+	OpenAPIValidationStrict OpenAPIValidation = "strict"
+)
+
+// ParseOpenAPIValidation разбирает режим проверки; пустая строка означает off
+func ParseOpenAPIValidation(s string) (OpenAPIValidation, error) {
+	switch mode := OpenAPIValidation(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", OpenAPIValidationOff:
+		return OpenAPIValidationOff, nil
+	case OpenAPIValidationRequests, OpenAPIValidationStrict:
+		return mode, nil
+	}
+	return "", fmt.Errorf("режим проверки OpenAPI должен быть off, requests или strict, получено %q", s)
+}
+
+// openAPIDocument — часть документа OpenAPI, которая нужна для проверки
+type openAPIDocument struct {
+	Paths      map[string]*openAPIPathItem `json:"paths"`
+	Components struct {
+		Schemas    map[string]*jsonSchema       `json:"schemas"`
+		Parameters map[string]*openAPIParameter `json:"parameters"`
+		Responses  map[string]*openAPIResponse  `json:"responses"`
+	} `json:"components"`
+}
+
+// openAPIPathItem описывает путь. Методы WebDAV (PROPFIND, REPORT) в OpenAPI
+// описать нельзя, поэтому они перечислены в расширении x-webdav-methods.
+type openAPIPathItem struct {
+	Parameters    []*openAPIParameter `json:"parameters"`
+	Get           *openAPIOperation   `json:"get"`
+	Put           *openAPIOperation   `json:"put"`
+	Post          *openAPIOperation   `json:"post"`
+	Delete        *openAPIOperation   `json:"delete"`
+	Options       *openAPIOperation   `json:"options"`
+	Head          *openAPIOperation   `json:"head"`
+	Patch         *openAPIOperation   `json:"patch"`
+	WebDAVMethods []string            `json:"x-webdav-methods"`
+}
+
+// operations возвращает операции пути по HTTP методам
+func (p *openAPIPathItem) operations() map[string]*openAPIOperation {
+	ops := make(map[string]*openAPIOperation)
+	for method, op := range map[string]*openAPIOperation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Parameters  []*openAPIParameter         `json:"parameters"`
+	RequestBody *openAPIRequestBody         `json:"requestBody"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Ref      string      `json:"$ref"`
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Ref     string                       `json:"$ref"`
+	Content map[string]*openAPIMediaType `json:"content"`
+}
+
+// jsonSchema — подмножество JSON Schema, которым пользуется спецификация
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 schemaTypes            `json:"type"`
+	Format               string                 `json:"format"`
+	Enum                 []any                  `json:"enum"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties"`
+	Minimum              *float64               `json:"minimum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+}
+
+// empty сообщает, что схема не накладывает ограничений (например, тело импорта JSON,
+// формат которого зависит от источника)
+func (s *jsonSchema) empty() bool {
+	return s == nil || (s.Ref == "" && len(s.Type) == 0 && s.Enum == nil && s.Properties == nil)
+}
+
+// schemaTypes — значение type: одна строка или список
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// openAPIRoute — операция, найденная для запроса, и значения параметров пути
+type openAPIRoute struct {
+	op         *openAPIOperation
+	parameters []*openAPIParameter
+	pathValues map[string]string
+}
+
+// OpenAPIValidator проверяет запросы и ответы по спецификации api/openapi.json
+type OpenAPIValidator struct {
+	mode   OpenAPIValidation
+	doc    *openAPIDocument
+	report func(r *http.Request, err error) // сообщает о несоответствии ответа спецификации
+}
+
+// NewOpenAPIValidator разбирает встроенную спецификацию и создает проверку в режиме mode
+func NewOpenAPIValidator(mode OpenAPIValidation) (*OpenAPIValidator, error) {
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		return nil, fmt.Errorf("ошибка разбора спецификации OpenAPI: %w", err)
+	}
+	return &OpenAPIValidator{
+		mode: mode,
+		doc:  &doc,
+		report: func(r *http.Request, err error) {
+			log.Printf("openapi: ответ на %s %s не соответствует спецификации: %v", r.Method, r.URL.Path, err)
+		},
+	}, nil
+}
+
+// openAPIPath переводит шаблон маршрута chi в путь спецификации:
+// /tasks/ → /tasks, /dav/* → /dav/{path}
+func openAPIPath(pattern string) string {
+	if strings.HasSuffix(pattern, "/*") {
+		pattern = strings.TrimSuffix(pattern, "*") + "{path}"
+	}
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+// findRoute ищет операцию спецификации для запроса. Маршрут определяет сам
+// маршрутизатор, поэтому проверка и обработка запроса не могут разойтись в том,
+// какой шаблон подходит под путь.
+func (v *OpenAPIValidator) findRoute(routes chi.Routes, r *http.Request) (*openAPIRoute, bool) {
+	rctx := chi.NewRouteContext()
+	pattern := routes.Find(rctx, r.Method, r.URL.Path)
+	if pattern == "" {
+		return nil, false
+	}
+
+	path := openAPIPath(pattern)
+	var item *openAPIPathItem
+	for specPath, candidate := range v.doc.Paths {
+		if openAPIPath(specPath) == path {
+			item = candidate
+			break
+		}
+	}
+	if item == nil {
+		return nil, false
+	}
+	op := item.operations()[r.Method]
+	if op == nil {
+		return nil, false
+	}
+
+	route := &openAPIRoute{op: op, pathValues: make(map[string]string)}
+	for i, key := range rctx.URLParams.Keys {
+		if key == "*" {
+			key = "path"
+		}
+		route.pathValues[key] = rctx.URLParams.Values[i]
+	}
+	for _, param := range append(append([]*openAPIParameter{}, item.Parameters...), op.Parameters...) {
+		route.parameters = append(route.parameters, v.resolveParameter(param))
+	}
+	return route, true
+}
+
+func (v *OpenAPIValidator) resolveParameter(p *openAPIParameter) *openAPIParameter {
+	if name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/"); ok {
+		if resolved := v.doc.Components.Parameters[name]; resolved != nil {
+			return resolved
+		}
+	}
+	return p
+}
+
+func (v *OpenAPIValidator) resolveResponse(resp *openAPIResponse) *openAPIResponse {
+	if name, ok := strings.CutPrefix(resp.Ref, "#/components/responses/"); ok {
+		if resolved := v.doc.Components.Responses[name]; resolved != nil {
+			return resolved
+		}
+	}
+	return resp
+}
+
+// Middleware возвращает middleware проверки для маршрутизатора routes.
+// Запросы, для которых в спецификации нет операции, пропускаются без проверки.
+// Ответы 5xx не проверяются: они означают сбой, а не часть контракта.
+func (v *OpenAPIValidator) Middleware(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if v.mode == OpenAPIValidationOff {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := v.findRoute(routes, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := v.validateRequest(route, r); err != nil {
+				http.Error(w, "Запрос не соответствует спецификации API: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if v.mode != OpenAPIValidationStrict {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			vw := &validatingWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(vw, r)
+			if err := v.validateResponse(route, vw.status, vw.Header().Get("Content-Type"), vw.buffer); err != nil {
+				v.report(r, err)
+			}
+			vw.finish()
+		})
+	}
+}
+
+// validateRequest проверяет параметры и JSON тело запроса
+func (v *OpenAPIValidator) validateRequest(route *openAPIRoute, r *http.Request) error {
+	query := r.URL.Query()
+	for _, param := range route.parameters {
+		var raw string
+		var present bool
+		switch param.In {
+		case "path":
+			raw, present = route.pathValues[param.Name]
+		case "query":
+			present = query.Has(param.Name)
+			raw = query.Get(param.Name)
+		case "header":
+			raw = r.Header.Get(param.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+		if !present {
+			if param.Required {
+				return fmt.Errorf("параметр %s обязателен", param.Name)
+			}
+			continue
+		}
+		if err := v.validateParameter(param, raw); err != nil {
+			return err
+		}
+	}
+
+	body := route.op.RequestBody
+	if body == nil {
+		return nil
+	}
+	// Тело без Content-Type разбирается как JSON, как это делают обработчики
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+	media := body.Content[mediaType]
+	if mediaType != "application/json" || media == nil || media.Schema.empty() {
+		return nil
+	}
+
+	// Тело читается не больше предела импорта; более длинное отклонит обработчик
+	data, err := io.ReadAll(io.LimitReader(r.Body, MaxImportSize+1))
+	if err != nil {
+		return fmt.Errorf("ошибка чтения тела: %w", err)
+	}
+	if len(data) > MaxImportSize {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+		return nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return errors.New("тело запроса обязательно")
+		}
+		return nil
+	}
+	value, err := decodeJSONValue(data)
+	if err != nil {
+		return fmt.Errorf("тело запроса: неверный JSON: %w", err)
+	}
+	if err := v.validateValue(media.Schema, value, "тело"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateParameter проверяет значение параметра из пути, строки запроса или заголовка
+func (v *OpenAPIValidator) validateParameter(param *openAPIParameter, raw string) error {
+	schema := v.resolveSchema(param.Schema)
+	if schema == nil {
+		return nil
+	}
+	var value any = raw
+	switch {
+	case schema.hasType("integer"):
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return fmt.Errorf("параметр %s: ожидается integer, получено %q", param.Name, raw)
+		}
+		value = json.Number(raw)
+	case schema.hasType("boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("параметр %s: ожидается boolean, получено %q", param.Name, raw)
+		}
+		value = b
+	}
+	return v.validateValue(schema, value, "параметр "+param.Name)
+}
+
+// validateResponse проверяет, что код ответа описан в спецификации, а JSON тело
+// соответствует схеме. body равно nil, если тело не JSON и не буферизовалось.
+func (v *OpenAPIValidator) validateResponse(route *openAPIRoute, status int, contentType string, body *bytes.Buffer) error {
+	if status >= http.StatusInternalServerError {
+		return nil
+	}
+	resp := route.op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = route.op.Responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if resp == nil {
+		resp = route.op.Responses["default"]
+	}
+	if resp == nil {
+		return fmt.Errorf("код %d не описан для операции %s", status, route.op.OperationID)
+	}
+	resp = v.resolveResponse(resp)
+	if body == nil {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media := resp.Content[mediaType]
+	if media == nil {
+		return fmt.Errorf("тип %s не описан для ответа %d операции %s", mediaType, status, route.op.OperationID)
+	}
+	value, err := decodeJSONValue(body.Bytes())
+	if err != nil {
+		return fmt.Errorf("неверный JSON в ответе: %w", err)
+	}
+	return v.validateValue(media.Schema, value, "ответ")
+}
+
+// decodeJSONValue разбирает JSON, сохраняя числа как json.Number, чтобы отличать целые
+func decodeJSONValue(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("лишние данные после значения")
+	}
+	return value, nil
+}
+
+func (v *OpenAPIValidator) resolveSchema(s *jsonSchema) *jsonSchema {
+	for s != nil && s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok {
+			return nil
+		}
+		s = v.doc.Components.Schemas[name]
+	}
+	return s
+}
+
+func (s *jsonSchema) hasType(name string) bool {
+	for _, t := range s.Type {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType возвращает тип JSON Schema значения, декодированного с UseNumber
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// validateValue проверяет значение по схеме; path указывает место значения в сообщении об ошибке
+func (v *OpenAPIValidator) validateValue(schema *jsonSchema, value any, path string) error {
+	schema = v.resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if len(schema.Type) > 0 {
+		actual := jsonType(value)
+		if !schema.hasType(actual) && !(actual == "integer" && schema.hasType("number")) {
+			return fmt.Errorf("%s: ожидается %s, получено %s", path, strings.Join(schema.Type, " или "), actual)
+		}
+	}
+	if schema.Enum != nil {
+		found := false
+		for _, allowed := range schema.Enum {
+			if allowed == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			got, _ := json.Marshal(value)
+			allowed, _ := json.Marshal(schema.Enum)
+			return fmt.Errorf("%s: значение %s не входит в %s", path, got, allowed)
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		length := len([]rune(value))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return fmt.Errorf("%s: строка короче %d символов", path, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fmt.Errorf("%s: строка длиннее %d символов", path, *schema.MaxLength)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return fmt.Errorf("%s: ожидается дата в формате RFC 3339, получено %q", path, value)
+			}
+		}
+	case json.Number:
+		if schema.Minimum != nil {
+			if number, _ := value.Float64(); number < *schema.Minimum {
+				return fmt.Errorf("%s: значение меньше %v", path, *schema.Minimum)
+			}
+		}
+	case []any:
+		if schema.MinItems != nil && len(value) < *schema.MinItems {
+			return fmt.Errorf("%s: ожидается не меньше %d элементов", path, *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(value) > *schema.MaxItems {
+			return fmt.Errorf("%s: ожидается не больше %d элементов", path, *schema.MaxItems)
+		}
+		for i, item := range value {
+			if err := v.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s: нет обязательного поля %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property := schema.Properties[name]
+			if property == nil {
+				property = schema.AdditionalProperties
+			}
+			if err := v.validateValue(property, value[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validatingWriter буферизует JSON ответ, чтобы проверить его до отправки клиенту.
+// Остальные ответы (файлы, поток событий) передаются сразу.
+type validatingWriter struct {
+	http.ResponseWriter
+	status      int // код ответа; 200, если обработчик его не задал
+	wroteHeader bool
+	buffer      *bytes.Buffer // не nil, если ответ буферизуется
+}
+
+func (w *validatingWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mediaType == "application/json" {
+		w.buffer = &bytes.Buffer{}
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *validatingWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffer != nil {
+		return w.buffer.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush нужен потоку событий; буферизованный JSON ответ отправляется целиком в finish
+func (w *validatingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffer == nil {
+		http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного writer
+func (w *validatingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish отправляет буферизованный ответ
+func (w *validatingWriter) finish() {
+	if w.buffer != nil {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buffer.Bytes())
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// specOperations возвращает операции спецификации: "МЕТОД путь" → путь в спецификации
+func specOperations(t *testing.T) map[string]string {
+	t.Helper()
+
+	validator, err := NewOpenAPIValidator(OpenAPIValidationOff)
+	if err != nil {
+		t.Fatalf("Ошибка разбора спецификации: %v", err)
+	}
+	operations := make(map[string]string)
+	for path, item := range validator.doc.Paths {
+		for method := range item.operations() {
+			operations[method+" "+openAPIPath(path)] = path
+		}
+		for _, method := range item.WebDAVMethods {
+			operations[method+" "+openAPIPath(path)] = path
+		}
+	}
+	return operations
+}
+
+// TestOpenAPI_SpecMatchesRoutes падает, если маршрут добавлен без описания в спецификации или наоборот
+func TestOpenAPI_SpecMatchesRoutes(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+	routes := router.(chi.Routes)
+	specified := specOperations(t)
+
+	// chi.Walk не видит методы, добавленные через chi.RegisterMethod (PROPFIND, REPORT),
+	// поэтому в эту сторону они не проверяются
+	var missing []string
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if operation := method + " " + openAPIPath(route); specified[operation] == "" {
+			missing = append(missing, operation)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Ошибка обхода маршрутов: %v", err)
+	}
+
+	// Каждая операция спецификации должна попадать в маршрут с тем же шаблоном
+	pathParam := regexp.MustCompile(`\{\w+\}`)
+	var stale []string
+	for operation, path := range specified {
+		method, _, _ := strings.Cut(operation, " ")
+		pattern := routes.Find(chi.NewRouteContext(), method, pathParam.ReplaceAllString(path, "1"))
+		if pattern == "" || openAPIPath(pattern) != openAPIPath(path) {
+			stale = append(stale, operation)
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(stale)
+	if len(missing) > 0 {
+		t.Errorf("Маршруты без описания в api/openapi.json: %v", missing)
+	}
+	if len(stale) > 0 {
+		t.Errorf("Операции api/openapi.json без маршрута: %v", stale)
+	}
+}
+
+// collectRefs собирает все значения $ref из документа
+func collectRefs(value any, refs map[string]bool) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				refs[ref] = true
+			}
+			collectRefs(item, refs)
+		}
+	case []any:
+		for _, item := range value {
+			collectRefs(item, refs)
+		}
+	}
+}
+
+func TestOpenAPI_SpecIsConsistent(t *testing.T) {
+	var raw map[string]any
+	if err := json.Unmarshal(openAPISpec, &raw); err != nil {
+		t.Fatalf("Спецификация не является JSON: %v", err)
+	}
+	if raw["openapi"] != "3.1.0" {
+		t.Errorf("Ожидалась версия OpenAPI 3.1.0, получено %v", raw["openapi"])
+	}
+
+	// Каждая ссылка указывает на существующий компонент
+	refs := make(map[string]bool)
+	collectRefs(raw, refs)
+	components := raw["components"].(map[string]any)
+	for ref := range refs {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		group, _ := components[parts[0]].(map[string]any)
+		if len(parts) != 2 || group[parts[1]] == nil {
+			t.Errorf("Ссылка %s никуда не ведет", ref)
+		}
+	}
+
+	validator, err := NewOpenAPIValidator(OpenAPIValidationOff)
+	if err != nil {
+		t.Fatalf("Ошибка разбора спецификации: %v", err)
+	}
+	operationIDs := make(map[string]bool)
+	pathParam := regexp.MustCompile(`\{(\w+)\}`)
+	for path, item := range validator.doc.Paths {
+		for method, op := range item.operations() {
+			if op.OperationID == "" || operationIDs[op.OperationID] {
+				t.Errorf("%s %s: operationId пуст или повторяется: %q", method, path, op.OperationID)
+			}
+			operationIDs[op.OperationID] = true
+			if len(op.Responses) == 0 {
+				t.Errorf("%s %s: не описаны ответы", method, path)
+			}
+
+			// Каждый параметр пути описан
+			declared := make(map[string]bool)
+			for _, param := range append(append([]*openAPIParameter{}, item.Parameters...), op.Parameters...) {
+				if param = validator.resolveParameter(param); param.In == "path" {
+					declared[param.Name] = true
+				}
+			}
+			for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+				if !declared[match[1]] {
+					t.Errorf("%s %s: не описан параметр пути %s", method, path, match[1])
+				}
+			}
+		}
+	}
+}
+
+// formatEnum возвращает допустимые значения параметра format операции
+func formatEnum(t *testing.T, path string, method string) string {
+	t.Helper()
+
+	validator, _ := NewOpenAPIValidator(OpenAPIValidationOff)
+	for _, param := range validator.doc.Paths[path].operations()[method].Parameters {
+		if param = validator.resolveParameter(param); param.Name == "format" {
+			names := make([]string, len(param.Schema.Enum))
+			for i, name := range param.Schema.Enum {
+				names[i] = name.(string)
+			}
+			sort.Strings(names)
+			return strings.Join(names, ", ")
+		}
+	}
+	t.Fatalf("%s %s: нет параметра format", method, path)
+	return ""
+}
+
+func TestOpenAPI_FormatsMatchRegistry(t *testing.T) {
+	if got, want := formatEnum(t, "/tasks/export", http.MethodGet), TaskFormatNames(true); got != want {
+		t.Errorf("Форматы экспорта в спецификации: %s, зарегистрированы: %s", got, want)
+	}
+	if got, want := formatEnum(t, "/tasks/import", http.MethodPost), TaskFormatNames(false); got != want {
+		t.Errorf("Форматы импорта в спецификации: %s, зарегистрированы: %s", got, want)
+	}
+}
+
+func TestOpenAPI_ServesSpecAndDocs(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var spec map[string]any
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &spec) != nil || spec["paths"] == nil {
+		t.Errorf("Неверный ответ /openapi.json: %d %.100s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `fetch("/openapi.json")`) {
+		t.Errorf("Неверный ответ /docs: %d", w.Code)
+	}
+	// Страница не зависит от CDN: внешние ресурсы запрещены и в разметке, и политикой
+	if strings.Contains(w.Body.String(), "https://") || strings.Contains(w.Body.String(), "http://") {
+		t.Errorf("Страница /docs загружает внешние ресурсы")
+	}
+	if w.Header().Get("Content-Security-Policy") != apiDocsPolicy {
+		t.Errorf("Неверная Content-Security-Policy: %q", w.Header().Get("Content-Security-Policy"))
+	}
+}
+
+func TestParseOpenAPIValidation(t *testing.T) {
+	for input, expected := range map[string]OpenAPIValidation{"": OpenAPIValidationOff, "off": OpenAPIValidationOff, "Requests": OpenAPIValidationRequests, " strict ": OpenAPIValidationStrict} {
+		if mode, err := ParseOpenAPIValidation(input); err != nil || mode != expected {
+			t.Errorf("ParseOpenAPIValidation(%q) = %q, %v; ожидалось %q", input, mode, err, expected)
+		}
+	}
+	if _, err := ParseOpenAPIValidation("always"); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного режима")
+	}
+}
+
+func TestOpenAPIValidator_RejectsInvalidRequests(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"неизвестный приоритет", http.MethodPost, "/tasks", `{"title": "Купить молоко", "priority": "urgent"}`},
+		{"теги не массив", http.MethodPost, "/tasks", `{"title": "Купить молоко", "tags": "дом"}`},
+		{"срок не дата", http.MethodPost, "/tasks", `{"title": "Купить молоко", "due_date": "завтра"}`},
+		{"нет заголовка", http.MethodPut, "/tasks/1", `{"completed": true}`},
+		{"пустой пакет", http.MethodPost, "/tasks/batch", `{"operations": []}`},
+		{"неизвестная операция", http.MethodPost, "/tasks/batch", `{"operations": [{"op": "archive"}]}`},
+		{"ID не число", http.MethodGet, "/tasks/abc", ""},
+		{"нет запроса поиска", http.MethodGet, "/tasks/search", ""},
+		{"неверный limit", http.MethodGet, "/tasks/autocomplete?limit=0", ""},
+		{"неизвестный формат", http.MethodGet, "/tasks/export?format=xml", ""},
+		{"нет тела", http.MethodPost, "/tasks/quick", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest || !strings.HasPrefix(w.Body.String(), "Запрос не соответствует спецификации API") {
+				t.Errorf("Ожидался 400 от проверки, получено %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidator_ReportsResponseMismatch(t *testing.T) {
+	validator, err := NewOpenAPIValidator(OpenAPIValidationStrict)
+	if err != nil {
+		t.Fatalf("Ошибка разбора спецификации: %v", err)
+	}
+	var reported []string
+	validator.report = func(r *http.Request, err error) {
+		reported = append(reported, err.Error())
+	}
+
+	r := chi.NewRouter()
+	r.Use(validator.Middleware(r))
+	r.Get("/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if chi.URLParam(r, "id") == "1" {
+			w.Write([]byte(`{"id": 1, "title": "Купить молоко"}`))
+			return
+		}
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		path     string
+		code     int
+		expected string
+	}{
+		{"/tasks/1", http.StatusOK, "нет обязательного поля"},
+		{"/tasks/2", http.StatusTeapot, "код 418 не описан"},
+	}
+	for _, tt := range tests {
+		reported = nil
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		// Ответ доходит до клиента без изменений, расхождение только сообщается
+		if w.Code != tt.code {
+			t.Errorf("%s: ожидался код %d, получено %d", tt.path, tt.code, w.Code)
+		}
+		if len(reported) != 1 || !strings.Contains(reported[0], tt.expected) {
+			t.Errorf("%s: ожидалось сообщение %q, получено %v", tt.path, tt.expected, reported)
+		}
+	}
+}
+
+// TestOpenAPIValidator_ResponsesMatchSpec проходит по JSON эндпоинтам через маршрутизатор
+// со строгой проверкой: любое расхождение ответа со спецификацией завершит тест ошибкой
+func TestOpenAPIValidator_ResponsesMatchSpec(t *testing.T) {
+	router, _ := newCalDAVRouter(t)
+
+	do := func(method, path, contentType string, body []byte, expected int) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("%s %s: ожидался код %d, получено %d: %s", method, path, expected, w.Code, w.Body.String())
+		}
+	}
+	jsonBody := func(s string) []byte { return []byte(s) }
+
+	do(http.MethodGet, "/", "", nil, http.StatusOK)
+	do(http.MethodPost, "/tasks", "application/json", jsonBody(`{"title": "Купить молоко", "tags": ["дом"], "priority": "high", "due_date": "2024-05-03T18:00:00Z"}`), http.StatusCreated)
+	do(http.MethodPost, "/tasks/quick", "application/json", jsonBody(`{"text": "Позвонить маме завтра"}`), http.StatusCreated)
+	do(http.MethodPost, "/tasks/quick?dry_run=true", "application/json", jsonBody(`{"text": "Позвонить врачу"}`), http.StatusOK)
+	do(http.MethodGet, "/tasks?q=tag:дом", "", nil, http.StatusOK)
+	do(http.MethodGet, "/tasks/1", "", nil, http.StatusOK)
+	do(http.MethodGet, "/tasks/42", "", nil, http.StatusNotFound)
+	do(http.MethodPut, "/tasks/1", "application/json", jsonBody(`{"title": "Купить кефир", "completed": true}`), http.StatusOK)
+	do(http.MethodPost, "/tasks/batch", "application/json", jsonBody(`{"operations": [{"op": "create", "title": "Новая"}, {"op": "delete", "id": 42}]}`), http.StatusOK)
+	do(http.MethodPost, "/tasks/batch", "application/json", jsonBody(`{"atomic": true, "operations": [{"op": "delete", "id": 42}]}`), http.StatusNotFound)
+	do(http.MethodGet, "/tasks/search?q=кефир&mode=fuzzy", "", nil, http.StatusOK)
+	do(http.MethodGet, "/tasks/autocomplete?q=ку", "", nil, http.StatusOK)
+	do(http.MethodGet, "/tasks/export?format=ndjson", "", nil, http.StatusOK)
+	do(http.MethodPost, "/tasks/import?format=ndjson&dry_run=true", "application/x-ndjson", jsonBody("{\"title\": \"Из файла\"}\n{\"title\": \"\"}\n"), http.StatusOK)
+	do(http.MethodPost, "/views", "application/json", jsonBody(`{"name": "дом", "query": "tag:дом"}`), http.StatusCreated)
+	do(http.MethodPost, "/views", "application/json", jsonBody(`{"name": "дом", "query": "tag:дом"}`), http.StatusConflict)
+	do(http.MethodGet, "/views", "", nil, http.StatusOK)
+	do(http.MethodPut, "/views/дом", "application/json", jsonBody(`{"query": "tag:дом AND NOT done"}`), http.StatusOK)
+	do(http.MethodGet, "/views/дом/tasks", "", nil, http.StatusOK)
+
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	part, _ := form.CreateFormFile("file", "список.txt")
+	part.Write([]byte("молоко, хлеб"))
+	form.Close()
+	do(http.MethodPost, "/tasks/1/attachments", form.FormDataContentType(), upload.Bytes(), http.StatusCreated)
+	do(http.MethodGet, "/tasks/1/attachments", "", nil, http.StatusOK)
+	do(http.MethodGet, "/tasks/1/attachments/1", "", nil, http.StatusOK)
+	do(http.MethodDelete, "/tasks/1/attachments/1", "", nil, http.StatusNoContent)
+
+	do(http.MethodDelete, "/views/дом", "", nil, http.StatusNoContent)
+	do(http.MethodDelete, "/tasks/1", "", nil, http.StatusNoContent)
+}
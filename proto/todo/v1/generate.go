@@ -0,0 +1,5 @@
+// Package todov1 содержит код, сгенерированный из todo.proto: сообщения и
+// клиент и сервер gRPC сервиса задач.
+package todov1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative todo/v1/todo.proto
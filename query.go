@@ -0,0 +1,624 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Язык запросов к задачам.
+//
+//	query      = or
+//	or         = and { "OR" and }
+//	and        = unary { ["AND"] unary }
+//	unary      = ("NOT" | "-") unary | primary
+//	primary    = "(" or ")" | comparison | text
+//	comparison = field op value          (op: ":" "=" "!=" "<" "<=" ">" ">=")
+//	text       = word | "строка в кавычках"
+//
+// Примеры: `not completed AND (tag:urgent OR due<tomorrow)`, `"купить молоко" -tag:дом`.
+// Слово без поля ищется как подстрока в заголовке и описании; `completed` без значения означает completed:true.
+
+// QuerySyntaxError описывает ошибку разбора запроса с позицией (в символах, начиная с 1)
+type QuerySyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("позиция %d: %s", e.Pos, e.Msg)
+}
+
+// QueryExpr — узел типизированного дерева запроса
+type QueryExpr interface {
+	// Match проверяет задачу; now используется для относительных дат вроде "tomorrow"
+	Match(task *Task, now time.Time) bool
+	String() string
+}
+
+// AndExpr истинно, если истинны оба операнда
+type AndExpr struct {
+	Left, Right QueryExpr
+}
+
+func (e *AndExpr) Match(task *Task, now time.Time) bool {
+	return e.Left.Match(task, now) && e.Right.Match(task, now)
+}
+
+func (e *AndExpr) String() string {
+	return "(" + e.Left.String() + " AND " + e.Right.String() + ")"
+}
+
+// OrExpr истинно, если истинен хотя бы один операнд
+type OrExpr struct {
+	Left, Right QueryExpr
+}
+
+func (e *OrExpr) Match(task *Task, now time.Time) bool {
+	return e.Left.Match(task, now) || e.Right.Match(task, now)
+}
+
+func (e *OrExpr) String() string {
+	return "(" + e.Left.String() + " OR " + e.Right.String() + ")"
+}
+
+// NotExpr отрицает операнд
+type NotExpr struct {
+	Expr QueryExpr
+}
+
+func (e *NotExpr) Match(task *Task, now time.Time) bool {
+	return !e.Expr.Match(task, now)
+}
+
+func (e *NotExpr) String() string {
+	return "NOT " + e.Expr.String()
+}
+
+// TextExpr ищет подстроку в заголовке или описании без учета регистра
+type TextExpr struct {
+	Text string
+}
+
+func (e *TextExpr) Match(task *Task, _ time.Time) bool {
+	return containsFold(task.Title, e.Text) || containsFold(task.Description, e.Text)
+}
+
+func (e *TextExpr) String() string {
+	return strconv.Quote(e.Text)
+}
+
+// queryFieldKind — тип значения поля задачи
+type queryFieldKind int
+
+const (
+	kindString queryFieldKind = iota
+	kindBool
+	kindInt
+	kindTime
+	kindTags
+	kindPriority
+)
+
+var queryFields = map[string]queryFieldKind{
+	"id":          kindInt,
+	"title":       kindString,
+	"description": kindString,
+	"project":     kindString,
+	"completed":   kindBool,
+	"priority":    kindPriority,
+	"tag":         kindTags,
+	"due":         kindTime,
+	"created":     kindTime,
+	"updated":     kindTime,
+}
+
+// Допустимые операторы для каждого типа поля
+var queryOperators = map[queryFieldKind][]string{
+	kindString: {":", "=", "!="},
+	kindBool:   {":", "=", "!="},
+	kindInt:    {":", "=", "!=", "<", "<=", ">", ">="},
+	kindTime:   {":", "=", "!=", "<", "<=", ">", ">="},
+	kindTags:   {":", "=", "!="},
+
+	kindPriority: {":", "=", "!=", "<", "<=", ">", ">="},
+}
+
+// CompareExpr сравнивает поле задачи со значением
+type CompareExpr struct {
+	Field string
+	Op    string
+	Raw   string
+
+	kind      queryFieldKind
+	boolValue bool
+	intValue  int // также ранг приоритета
+	timeValue timeValue
+}
+
+func (e *CompareExpr) Match(task *Task, now time.Time) bool {
+	switch e.kind {
+	case kindString:
+		value := task.Title
+		switch e.Field {
+		case "description":
+			value = task.Description
+		case "project":
+			value = task.Project
+		}
+		if e.Op == ":" {
+			return containsFold(value, e.Raw)
+		}
+		return strings.EqualFold(value, e.Raw) == (e.Op == "=")
+
+	case kindBool:
+		return (task.Completed == e.boolValue) == (e.Op != "!=")
+
+	case kindInt:
+		return compareOrdered(task.ID, e.intValue, e.Op)
+
+	case kindPriority:
+		return compareOrdered(task.Priority.Rank(), e.intValue, e.Op)
+
+	case kindTags:
+		has := slices.Contains(task.Tags, strings.ToLower(e.Raw))
+		return has == (e.Op != "!=")
+
+	case kindTime:
+		var value *time.Time
+		switch e.Field {
+		case "due":
+			value = task.DueDate
+		case "created":
+			value = &task.CreatedAt
+		case "updated":
+			value = &task.UpdatedAt
+		}
+		return e.timeValue.compare(value, e.Op, now)
+	}
+	return false
+}
+
+func (e *CompareExpr) String() string {
+	raw := e.Raw
+	if strings.ContainsAny(raw, " ()\"") || raw == "" {
+		raw = strconv.Quote(raw)
+	}
+	return e.Field + e.Op + raw
+}
+
+func compareOrdered[T int | int64](a, b T, op string) bool {
+	switch op {
+	case ":", "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// timeValue — значение даты в запросе: абсолютное или относительное к моменту выполнения
+type timeValue struct {
+	none     bool          // "none": срок не задан
+	absolute time.Time     // для явных дат
+	relative bool          // значение отсчитывается от now
+	days     int           // смещение в днях для относительных дат
+	offset   time.Duration // смещение для относительного времени ("+3h", "now")
+	day      bool          // значение обозначает целые сутки, а не момент
+}
+
+// resolve возвращает начало интервала, обозначаемого значением
+func (v timeValue) resolve(now time.Time) time.Time {
+	if !v.relative {
+		return v.absolute
+	}
+	if v.day {
+		y, m, d := now.Date()
+		return time.Date(y, m, d+v.days, 0, 0, 0, 0, now.Location())
+	}
+	return now.Add(v.offset)
+}
+
+func (v timeValue) compare(value *time.Time, op string, now time.Time) bool {
+	if v.none {
+		return (value == nil) == (op != "!=")
+	}
+	if value == nil {
+		return false
+	}
+
+	start := v.resolve(now)
+	if !v.day {
+		return compareOrdered(value.UnixNano(), start.UnixNano(), op)
+	}
+
+	// Для целых суток сравниваем с интервалом [начало дня; начало следующего дня)
+	end := start.AddDate(0, 0, 1)
+	inDay := !value.Before(start) && value.Before(end)
+	switch op {
+	case ":", "=":
+		return inDay
+	case "!=":
+		return !inDay
+	case "<":
+		return value.Before(start)
+	case "<=":
+		return value.Before(end)
+	case ">":
+		return !value.Before(end)
+	case ">=":
+		return !value.Before(start)
+	}
+	return false
+}
+
+// Query — разобранный запрос
+type Query struct {
+	Source string
+	Expr   QueryExpr
+}
+
+// Match проверяет задачу относительно текущего времени
+func (q *Query) Match(task *Task) bool {
+	return q.Expr.Match(task, time.Now())
+}
+
+// ParseQuery разбирает строку запроса в дерево выражений
+func ParseQuery(source string) (*Query, error) {
+	p := &queryParser{src: []rune(source)}
+
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf(p.pos, "пустой запрос")
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if !p.eof() {
+		if p.src[p.pos] == ')' {
+			return nil, p.errorf(p.pos, "лишняя ')'")
+		}
+		return nil, p.errorf(p.pos, "неожиданный символ %q", p.src[p.pos])
+	}
+
+	return &Query{Source: source, Expr: expr}, nil
+}
+
+type queryParser struct {
+	src []rune
+	pos int
+}
+
+func (p *queryParser) errorf(pos int, format string, args ...any) error {
+	return &QuerySyntaxError{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// keyword проверяет, стоит ли в текущей позиции ключевое слово, и пропускает его
+func (p *queryParser) keyword(word string) bool {
+	end := p.pos + len(word)
+	if end > len(p.src) || !strings.EqualFold(string(p.src[p.pos:end]), word) {
+		return false
+	}
+	if end < len(p.src) && !isQueryDelimiter(p.src[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *queryParser) parseOr() (QueryExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpace()
+		if !p.keyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &OrExpr{Left: left, Right: right}
+	}
+}
+
+func (p *queryParser) parseAnd() (QueryExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpace()
+		if p.eof() || p.src[p.pos] == ')' {
+			return left, nil
+		}
+
+		// OR обрабатывается уровнем выше
+		save := p.pos
+		if p.keyword("OR") {
+			p.pos = save
+			return left, nil
+		}
+		p.keyword("AND")
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &AndExpr{Left: left, Right: right}
+	}
+}
+
+func (p *queryParser) parseUnary() (QueryExpr, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf(p.pos, "ожидалось условие")
+	}
+
+	// После NOT запрос может закончиться, поэтому '-' проверяется только без NOT
+	negated := p.keyword("NOT")
+	if !negated && p.src[p.pos] == '-' {
+		p.pos++
+		negated = true
+	}
+	if negated {
+		if p.skipSpace(); p.eof() {
+			return nil, p.errorf(p.pos, "ожидалось условие")
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: expr}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (QueryExpr, error) {
+	start := p.pos
+
+	switch p.src[p.pos] {
+	case '(':
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.eof() || p.src[p.pos] != ')' {
+			return nil, p.errorf(p.pos, "ожидалась ')' для '(' в позиции %d", start+1)
+		}
+		p.pos++
+		return expr, nil
+
+	case ')':
+		return nil, p.errorf(p.pos, "ожидалось условие перед ')'")
+
+	case '"':
+		text, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &TextExpr{Text: text}, nil
+	}
+
+	word := p.parseWord()
+	if word == "" {
+		return nil, p.errorf(p.pos, "неожиданный символ %q", p.src[p.pos])
+	}
+
+	op := p.parseOperator()
+	if op == "" {
+		// Логическое поле без значения: `completed` означает completed:true
+		if kind, known := queryFields[strings.ToLower(word)]; known && kind == kindBool {
+			return &CompareExpr{Field: strings.ToLower(word), Op: ":", Raw: "true", kind: kindBool, boolValue: true}, nil
+		}
+		return &TextExpr{Text: word}, nil
+	}
+
+	field := strings.ToLower(word)
+	kind, known := queryFields[field]
+	if !known {
+		return nil, p.errorf(start, "неизвестное поле '%s'", word)
+	}
+	if !slices.Contains(queryOperators[kind], op) {
+		return nil, p.errorf(start+len([]rune(word)), "оператор '%s' неприменим к полю '%s'", op, field)
+	}
+
+	valuePos := p.pos
+	var raw string
+	if !p.eof() && p.src[p.pos] == '"' {
+		var err error
+		if raw, err = p.parseString(); err != nil {
+			return nil, err
+		}
+	} else {
+		raw = p.parseValue()
+	}
+	if raw == "" {
+		return nil, p.errorf(valuePos, "ожидалось значение для поля '%s'", field)
+	}
+
+	expr := &CompareExpr{Field: field, Op: op, Raw: raw, kind: kind}
+	if err := expr.parseValue(); err != nil {
+		return nil, p.errorf(valuePos, "%s", err)
+	}
+	return expr, nil
+}
+
+// parseValue приводит строковое значение к типу поля
+func (e *CompareExpr) parseValue() error {
+	switch e.kind {
+	case kindBool:
+		switch strings.ToLower(e.Raw) {
+		case "true", "yes", "да":
+			e.boolValue = true
+		case "false", "no", "нет":
+			e.boolValue = false
+		default:
+			return fmt.Errorf("ожидалось true или false, получено '%s'", e.Raw)
+		}
+
+	case kindInt:
+		value, err := strconv.Atoi(e.Raw)
+		if err != nil {
+			return fmt.Errorf("ожидалось целое число, получено '%s'", e.Raw)
+		}
+		e.intValue = value
+
+	case kindPriority:
+		priority := Priority(strings.ToLower(e.Raw))
+		if priority == "none" {
+			priority = PriorityNone
+		}
+		if !priority.Valid() {
+			return fmt.Errorf("ожидался приоритет low, medium, high или none, получено '%s'", e.Raw)
+		}
+		e.intValue = priority.Rank()
+
+	case kindTime:
+		value, err := parseTimeValue(e.Raw)
+		if err != nil {
+			return err
+		}
+		if value.none && e.Op != ":" && e.Op != "=" && e.Op != "!=" {
+			return fmt.Errorf("значение 'none' можно использовать только с ':', '=' и '!='")
+		}
+		e.timeValue = value
+	}
+	return nil
+}
+
+// parseTimeValue понимает none, now, today/tomorrow/yesterday (и русские аналоги),
+// смещения вида +3d, -1w, +2h, даты 2006-01-02 и время в формате RFC 3339
+func parseTimeValue(raw string) (timeValue, error) {
+	switch strings.ToLower(raw) {
+	case "none":
+		return timeValue{none: true}, nil
+	case "now", "сейчас":
+		return timeValue{relative: true}, nil
+	case "today", "сегодня":
+		return timeValue{relative: true, day: true}, nil
+	case "tomorrow", "завтра":
+		return timeValue{relative: true, day: true, days: 1}, nil
+	case "yesterday", "вчера":
+		return timeValue{relative: true, day: true, days: -1}, nil
+	}
+
+	if len(raw) >= 3 && (raw[0] == '+' || raw[0] == '-') {
+		n, err := strconv.Atoi(raw[1 : len(raw)-1])
+		if err == nil {
+			if raw[0] == '-' {
+				n = -n
+			}
+			switch raw[len(raw)-1] {
+			case 'd':
+				return timeValue{relative: true, day: true, days: n}, nil
+			case 'w':
+				return timeValue{relative: true, day: true, days: n * 7}, nil
+			case 'h':
+				return timeValue{relative: true, offset: time.Duration(n) * time.Hour}, nil
+			case 'm':
+				return timeValue{relative: true, offset: time.Duration(n) * time.Minute}, nil
+			}
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		return timeValue{absolute: t, day: true}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return timeValue{absolute: t}, nil
+	}
+
+	return timeValue{}, fmt.Errorf("неверная дата '%s'", raw)
+}
+
+// parseWord читает имя поля или слово для поиска
+func (p *queryParser) parseWord() string {
+	start := p.pos
+	for !p.eof() && !isQueryDelimiter(p.src[p.pos]) && !strings.ContainsRune(":=!<>", p.src[p.pos]) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// parseValue читает значение после оператора; в нем допустимы ':' и '-' (например, в датах)
+func (p *queryParser) parseValue() string {
+	start := p.pos
+	for !p.eof() && !isQueryDelimiter(p.src[p.pos]) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *queryParser) parseOperator() string {
+	for _, op := range []string{"!=", "<=", ">=", ":", "=", "<", ">"} {
+		end := p.pos + len(op)
+		if end <= len(p.src) && string(p.src[p.pos:end]) == op {
+			p.pos = end
+			return op
+		}
+	}
+	return ""
+}
+
+// parseString читает строку в двойных кавычках; \" и \\ экранируют символы
+func (p *queryParser) parseString() (string, error) {
+	start := p.pos
+	p.pos++
+
+	var b strings.Builder
+	for !p.eof() {
+		r := p.src[p.pos]
+		p.pos++
+		switch {
+		case r == '"':
+			return b.String(), nil
+		case r == '\\' && !p.eof():
+			b.WriteRune(p.src[p.pos])
+			p.pos++
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", p.errorf(start, "незакрытая кавычка")
+}
+
+func isQueryDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
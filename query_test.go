@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseQuery_AST(t *testing.T) {
+	cases := map[string]string{
+		"not completed AND (tag:urgent OR due<tomorrow)": "(NOT completed:true AND (tag:urgent OR due<tomorrow))",
+		`молоко -tag:дом`:                                `("молоко" AND NOT tag:дом)`,
+		`"купить молоко" OR id>=10`:                      `("купить молоко" OR id>=10)`,
+		`a b OR c`:                                       `(("a" AND "b") OR "c")`,
+		`due:2024-01-01T10:00:00Z`:                       `due:2024-01-01T10:00:00Z`,
+		`title:"отчет за год" completed=нет`:             `(title:"отчет за год" AND completed=нет)`,
+	}
+
+	for source, expected := range cases {
+		query, err := ParseQuery(source)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): неожиданная ошибка %v", source, err)
+			continue
+		}
+		if query.Expr.String() != expected {
+			t.Errorf("ParseQuery(%q): ожидалось %s, получено %s", source, expected, query.Expr.String())
+		}
+	}
+}
+
+func TestParseQuery_SyntaxErrors(t *testing.T) {
+	cases := []struct {
+		source string
+		pos    int
+	}{
+		{"(tag:urgent OR completed", 25},
+		{"tag:urgent)", 11},
+		{"owner:me", 1},
+		{"priority>urgent", 10},
+		{"due<завтрашний", 5},
+		{"tag<urgent", 4},
+		{`title:"без конца`, 7},
+		{"completed AND", 14},
+		{"NOT", 4},
+		{"(NOT", 5},
+		{"completed AND NOT", 18},
+		{"-", 2},
+		{"   ", 4},
+	}
+
+	for _, c := range cases {
+		_, err := ParseQuery(c.source)
+		var syntaxErr *QuerySyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("ParseQuery(%q): ожидалась синтаксическая ошибка, получено %v", c.source, err)
+			continue
+		}
+		if syntaxErr.Pos != c.pos {
+			t.Errorf("ParseQuery(%q): ожидалась позиция %d, получена %d (%s)", c.source, c.pos, syntaxErr.Pos, syntaxErr.Msg)
+		}
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.Local)
+	today := time.Date(2024, 3, 10, 18, 0, 0, 0, time.Local)
+	nextWeek := now.AddDate(0, 0, 7)
+
+	urgentToday := &Task{ID: 1, Title: "Сдать отчет", Tags: []string{"urgent"}, Priority: PriorityHigh, DueDate: &today}
+	laterDone := &Task{ID: 2, Title: "Купить молоко", Completed: true, DueDate: &nextWeek}
+	noDue := &Task{ID: 3, Title: "Почитать", Tags: []string{"дом"}, Project: "Работа"}
+
+	cases := []struct {
+		source   string
+		expected []bool
+	}{
+		{"not completed AND (tag:urgent OR due<tomorrow)", []bool{true, false, false}},
+		{"due:today", []bool{true, false, false}},
+		{"due>=+7d", []bool{false, true, false}},
+		{"due:none", []bool{false, false, true}},
+		{"due!=none", []bool{true, true, false}},
+		{"МОЛОКО", []bool{false, true, false}},
+		{"-tag:дом id<3", []bool{true, true, false}},
+		{"completed", []bool{false, true, false}},
+		{"priority>=medium", []bool{true, false, false}},
+		{"project:работа", []bool{false, false, true}},
+	}
+
+	tasks := []*Task{urgentToday, laterDone, noDue}
+	for _, c := range cases {
+		query, err := ParseQuery(c.source)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", c.source, err)
+		}
+		for i, task := range tasks {
+			if got := query.Expr.Match(task, now); got != c.expected[i] {
+				t.Errorf("%q для задачи %d: ожидалось %v, получено %v", c.source, task.ID, c.expected[i], got)
+			}
+		}
+	}
+}
+
+func TestTaskHandler_GetTasks_Query(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	service.CreateTask(t.Context(), "Срочная", "", WithTags([]string{"Urgent"}))
+	service.CreateTask(t.Context(), "Обычная", "")
+
+	req := httptest.NewRequest("GET", "/tasks?q="+url.QueryEscape("tag:urgent"), nil)
+	w := httptest.NewRecorder()
+	handler.GetTasks(w, req)
+
+	var tasks []Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+
+	if len(tasks) != 1 || tasks[0].Title != "Срочная" {
+		t.Errorf("Ожидалась только задача 'Срочная', получено %v", tasks)
+	}
+
+	req = httptest.NewRequest("GET", "/tasks?q="+url.QueryEscape("(tag:urgent"), nil)
+	w = httptest.NewRecorder()
+	handler.GetTasks(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestViewService_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+
+	views, err := NewViewService(path)
+	if err != nil {
+		t.Fatalf("Ошибка при создании сервиса: %v", err)
+	}
+
+	if _, err := views.CreateView("срочное", "tag:urgent"); err != nil {
+		t.Fatalf("Ошибка при создании представления: %v", err)
+	}
+
+	if _, err := views.CreateView("срочное", "completed"); !errors.Is(err, ErrViewExists) {
+		t.Errorf("Ожидалась ошибка ErrViewExists, получена %v", err)
+	}
+
+	var syntaxErr *QuerySyntaxError
+	if _, err := views.CreateView("сломанное", "(tag:urgent"); !errors.As(err, &syntaxErr) {
+		t.Errorf("Ожидалась синтаксическая ошибка, получена %v", err)
+	}
+
+	// Новый экземпляр сервиса читает представления из файла
+	reloaded, err := NewViewService(path)
+	if err != nil {
+		t.Fatalf("Ошибка при загрузке представлений: %v", err)
+	}
+
+	view, err := reloaded.GetView("срочное")
+	if err != nil {
+		t.Fatalf("Представление не сохранилось: %v", err)
+	}
+
+	if view.Query != "tag:urgent" {
+		t.Errorf("Ожидался запрос 'tag:urgent', получен '%s'", view.Query)
+	}
+}
+
+func TestViewService_Close(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	views, err := NewViewService(path)
+	if err != nil {
+		t.Fatalf("Ошибка при создании сервиса: %v", err)
+	}
+	if _, err := views.CreateView("срочное", "tag:urgent"); err != nil {
+		t.Fatalf("Ошибка при создании представления: %v", err)
+	}
+	if err := views.Close(); err != nil {
+		t.Fatalf("Ошибка при закрытии: %v", err)
+	}
+
+	// После закрытия изменения отклоняются, а чтение работает
+	if _, err := views.CreateView("выполненное", "completed"); !errors.Is(err, ErrViewServiceClosed) {
+		t.Errorf("Ожидалась ошибка ErrViewServiceClosed, получена %v", err)
+	}
+	if err := views.DeleteView("срочное"); !errors.Is(err, ErrViewServiceClosed) {
+		t.Errorf("Ожидалась ошибка ErrViewServiceClosed, получена %v", err)
+	}
+	if len(views.GetAllViews()) != 1 {
+		t.Errorf("Отклоненные изменения не должны менять представления: %v", views.GetAllViews())
+	}
+
+	reloaded, err := NewViewService(path)
+	if err != nil || len(reloaded.GetAllViews()) != 1 {
+		t.Errorf("Файл представлений не должен меняться после закрытия: %v", err)
+	}
+}
+
+func TestViewHandler_RunView(t *testing.T) {
+	tasks := NewTaskService()
+	views, _ := NewViewService("")
+	handler := NewViewHandler(views, tasks)
+
+	tasks.CreateTask(t.Context(), "Открытая", "")
+	done := tasks.CreateTask(t.Context(), "Закрытая", "")
+	tasks.UpdateTask(t.Context(), done.ID, done.Title, "", true)
+	views.CreateView("open", "not completed")
+
+	req := httptest.NewRequest("GET", "/views/open/tasks", nil)
+	req = withURLParams(req, "name", "open")
+	w := httptest.NewRecorder()
+	handler.RunView(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, w.Code)
+	}
+
+	var result []Task
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+
+	if len(result) != 1 || result[0].Title != "Открытая" {
+		t.Errorf("Ожидалась только задача 'Открытая', получено %v", result)
+	}
+
+	req = withURLParams(httptest.NewRequest("GET", "/views/missing/tasks", nil), "name", "missing")
+	w = httptest.NewRecorder()
+	handler.RunView(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QuickAdd — результат разбора свободного текста задачи
+type QuickAdd struct {
+	Title      string
+	Tags       []string
+	Priority   Priority
+	Project    string
+	DueDate    *time.Time
+	Recognized []QuickAddToken
+}
+
+// Options возвращает распознанные поля в виде опций для TaskService
+func (qa *QuickAdd) Options() []TaskOption {
+	return []TaskOption{WithTags(qa.Tags), WithPriority(qa.Priority), WithProject(qa.Project), WithDueDate(qa.DueDate)}
+}
+
+// ParseQuickAdd разбирает текст вроде "Купить молоко завтра в 18:00 #дом !высокий +покупки".
+// Понимает хештеги, приоритеты (!высокий, !high, !!!), проекты (+проект), относительные
+// и абсолютные даты и время на русском и английском. Нераспознанные слова образуют заголовок.
+func ParseQuickAdd(text string, now time.Time) *QuickAdd {
+	p := &quickAddParser{now: now, result: &QuickAdd{Tags: []string{}, Recognized: []QuickAddToken{}}}
+	for _, field := range strings.Fields(text) {
+		p.words = append(p.words, quickWord{text: field, norm: normalizeQuickWord(field)})
+	}
+
+	var title []string
+	for p.pos < len(p.words) {
+		if !p.matchMarker() && !p.matchDate() && !p.matchTime() {
+			title = append(title, p.words[p.pos].text)
+			p.pos++
+		}
+	}
+
+	p.result.Title = strings.Trim(strings.Join(title, " "), " ,.;-—")
+	p.result.DueDate = p.due()
+	return p.result
+}
+
+type quickWord struct {
+	text string // исходное слово
+	norm string // в нижнем регистре, без завершающей пунктуации
+}
+
+type quickAddParser struct {
+	words  []quickWord
+	pos    int
+	now    time.Time
+	result *QuickAdd
+
+	date    *time.Time // распознанный день
+	instant *time.Time // точный момент ("через 2 часа")
+	hour    int
+	minute  int
+	hasTime bool
+}
+
+func normalizeQuickWord(word string) string {
+	word = strings.ToLower(strings.TrimRight(word, ",.;!?"))
+	return strings.ReplaceAll(word, "ё", "е")
+}
+
+// recognize фиксирует распознанный фрагмент из n слов начиная с текущей позиции
+func (p *quickAddParser) recognize(n int, kind, value string) bool {
+	parts := make([]string, n)
+	for i := range n {
+		parts[i] = p.words[p.pos+i].text
+	}
+	p.result.Recognized = append(p.result.Recognized, QuickAddToken{
+		Text:  strings.TrimRight(strings.Join(parts, " "), ",.;"),
+		Kind:  kind,
+		Value: value,
+	})
+	p.pos += n
+	return true
+}
+
+// word возвращает нормализованное слово со смещением offset от текущей позиции
+func (p *quickAddParser) word(offset int) string {
+	if p.pos+offset >= len(p.words) {
+		return ""
+	}
+	return p.words[p.pos+offset].norm
+}
+
+// --- Хештеги, приоритеты, проекты ---
+
+var quickPriorities = map[string]Priority{
+	"высокий": PriorityHigh, "высокая": PriorityHigh, "срочно": PriorityHigh, "high": PriorityHigh, "h": PriorityHigh, "3": PriorityHigh,
+	"средний": PriorityMedium, "средняя": PriorityMedium, "medium": PriorityMedium, "m": PriorityMedium, "2": PriorityMedium,
+	"низкий": PriorityLow, "низкая": PriorityLow, "low": PriorityLow, "l": PriorityLow, "1": PriorityLow,
+}
+
+func (p *quickAddParser) matchMarker() bool {
+	raw := p.words[p.pos].text
+	trimmed := strings.TrimRight(raw, ",.;")
+
+	switch {
+	case strings.HasPrefix(trimmed, "#") && len(trimmed) > 1:
+		tag := strings.ToLower(trimmed[1:])
+		p.result.Tags = normalizeTags(append(p.result.Tags, tag))
+		return p.recognize(1, "tag", tag)
+
+	case strings.HasPrefix(trimmed, "+") && len(trimmed) > 1 && !unicode.IsDigit(rune(trimmed[1])):
+		project := trimmed[1:]
+		p.result.Project = project
+		return p.recognize(1, "project", project)
+
+	case strings.HasPrefix(trimmed, "!"):
+		marker := strings.ToLower(strings.TrimLeft(trimmed, "!"))
+		var priority Priority
+		switch {
+		case marker == "":
+			// !!! — высокий, !! — средний, ! — низкий
+			priority = [...]Priority{PriorityLow, PriorityMedium, PriorityHigh}[min(len(trimmed), 3)-1]
+		default:
+			priority = quickPriorities[marker]
+		}
+		if priority == PriorityNone {
+			return false
+		}
+		p.result.Priority = priority
+		return p.recognize(1, "priority", string(priority))
+	}
+
+	return false
+}
+
+// --- Даты ---
+
+var quickWeekdays = map[string]time.Weekday{
+	"понедельник": time.Monday, "понедельника": time.Monday, "пн": time.Monday,
+	"вторник": time.Tuesday, "вторника": time.Tuesday, "вт": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "среды": time.Wednesday, "ср": time.Wednesday,
+	"четверг": time.Thursday, "четверга": time.Thursday, "чт": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пятницы": time.Friday, "пт": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "субботы": time.Saturday, "сб": time.Saturday,
+	"воскресенье": time.Sunday, "воскресенья": time.Sunday, "вс": time.Sunday,
+	// Дательный падеж: "к пятнице"
+	"понедельнику": time.Monday, "вторнику": time.Tuesday, "среде": time.Wednesday, "четвергу": time.Thursday,
+	"пятнице": time.Friday, "субботе": time.Saturday, "воскресенью": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+// quickAmbiguousWeekdays — полные формы дня недели, которые совпадают с обычным
+// существительным ("настроить среду разработки"); они принимаются только после предлога
+var quickAmbiguousWeekdays = map[string]bool{"среда": true, "среду": true, "среды": true, "среде": true}
+
+var quickMonths = map[string]time.Month{
+	"января": time.January, "февраля": time.February, "марта": time.March, "апреля": time.April,
+	"мая": time.May, "июня": time.June, "июля": time.July, "августа": time.August,
+	"сентября": time.September, "октября": time.October, "ноября": time.November, "декабря": time.December,
+	"january": time.January, "jan": time.January, "february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March, "april": time.April, "apr": time.April, "may": time.May,
+	"june": time.June, "jun": time.June, "july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August, "september": time.September, "sep": time.September,
+	"october": time.October, "oct": time.October, "november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var quickNumbers = map[string]int{
+	"один": 1, "одну": 1, "одна": 1, "два": 2, "две": 2, "три": 3, "четыре": 4, "пять": 5,
+	"шесть": 6, "семь": 7, "восемь": 8, "девять": 9, "десять": 10,
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+}
+
+// quickUnits сопоставляет единицам измерения шаг смещения: дни, месяцы или продолжительность
+var quickUnits = map[string]struct {
+	days     int
+	months   int
+	duration time.Duration
+}{
+	"день": {days: 1}, "дня": {days: 1}, "дней": {days: 1}, "day": {days: 1}, "days": {days: 1},
+	"неделю": {days: 7}, "недели": {days: 7}, "недель": {days: 7}, "week": {days: 7}, "weeks": {days: 7},
+	"месяц": {months: 1}, "месяца": {months: 1}, "месяцев": {months: 1}, "month": {months: 1}, "months": {months: 1},
+	"час": {duration: time.Hour}, "часа": {duration: time.Hour}, "часов": {duration: time.Hour},
+	"hour": {duration: time.Hour}, "hours": {duration: time.Hour},
+	"минуту": {duration: time.Minute}, "минуты": {duration: time.Minute}, "минут": {duration: time.Minute},
+	"minute": {duration: time.Minute}, "minutes": {duration: time.Minute},
+}
+
+// datePrepositions — предлоги, которые могут стоять перед датой и поглощаются вместе с ней
+var datePrepositions = map[string]bool{
+	"в": true, "во": true, "к": true, "до": true, "on": true, "by": true, "next": true, "this": true,
+}
+
+func (p *quickAddParser) today() time.Time {
+	y, m, d := p.now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, p.now.Location())
+}
+
+func (p *quickAddParser) setDate(n int, date time.Time) bool {
+	p.date = &date
+	return p.recognize(n, "date", date.Format("2006-01-02"))
+}
+
+func (p *quickAddParser) matchDate() bool {
+	prep := 0
+	if datePrepositions[p.word(0)] {
+		prep = 1
+	}
+	w := p.word(prep)
+	today := p.today()
+
+	switch w {
+	case "сегодня", "today":
+		return p.setDate(prep+1, today)
+	case "завтра", "tomorrow":
+		return p.setDate(prep+1, today.AddDate(0, 0, 1))
+	case "послезавтра":
+		return p.setDate(prep+1, today.AddDate(0, 0, 2))
+	case "day":
+		if p.word(prep+1) == "after" && p.word(prep+2) == "tomorrow" {
+			return p.setDate(prep+3, today.AddDate(0, 0, 2))
+		}
+	}
+
+	// День недели: ближайший после сегодняшнего. Сокращения ("пт", "sun") и формы,
+	// совпадающие с существительным ("среду"), принимаются только после предлога, чтобы
+	// не путать их с обычными словами; "в среде" — предложный падеж, а не дата
+	standalone := len([]rune(w)) > 3 && !quickAmbiguousWeekdays[w]
+	locative := w == "среде" && (p.word(0) == "в" || p.word(0) == "во")
+	if weekday, ok := quickWeekdays[w]; ok && (prep == 1 || standalone) && !locative {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return p.setDate(prep+1, today.AddDate(0, 0, days))
+	}
+
+	// "через 3 дня", "через неделю", "in 2 hours"
+	if p.word(0) == "через" || p.word(0) == "in" {
+		count, unitPos := 1, 1
+		if n, ok := parseQuickNumber(p.word(1)); ok {
+			count, unitPos = n, 2
+		}
+		if unit, ok := quickUnits[p.word(unitPos)]; ok {
+			if unit.duration > 0 {
+				instant := p.now.Add(time.Duration(count) * unit.duration)
+				p.instant = &instant
+				return p.recognize(unitPos+1, "datetime", instant.Format(time.RFC3339))
+			}
+			return p.setDate(unitPos+1, today.AddDate(0, count*unit.months, count*unit.days))
+		}
+	}
+
+	// ISO-дата 2024-05-01 и формат ДД.ММ или ДД.ММ.ГГГГ
+	if t, err := time.ParseInLocation("2006-01-02", w, p.now.Location()); err == nil {
+		return p.setDate(prep+1, t)
+	}
+	if date, ok := p.parseDottedDate(w); ok {
+		return p.setDate(prep+1, date)
+	}
+
+	// "5 мая", "5 may", "may 5"
+	if day, err := strconv.Atoi(w); err == nil {
+		if month, ok := quickMonths[p.word(prep+1)]; ok {
+			return p.setDate(prep+2, p.nextDate(month, day))
+		}
+	}
+	if month, ok := quickMonths[w]; ok {
+		if day, err := strconv.Atoi(p.word(prep + 1)); err == nil {
+			return p.setDate(prep+2, p.nextDate(month, day))
+		}
+	}
+
+	return false
+}
+
+func parseQuickNumber(word string) (int, bool) {
+	if n, err := strconv.Atoi(word); err == nil && n > 0 {
+		return n, true
+	}
+	n, ok := quickNumbers[word]
+	return n, ok
+}
+
+// parseDottedDate разбирает ДД.ММ и ДД.ММ.ГГГГ; дата без года берется ближайшая будущая
+func (p *quickAddParser) parseDottedDate(word string) (time.Time, bool) {
+	parts := strings.Split(word, ".")
+	if len(parts) != 2 && len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	day, err1 := strconv.Atoi(parts[0])
+	month, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	if len(parts) == 2 {
+		return p.nextDate(time.Month(month), day), true
+	}
+
+	year, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return time.Time{}, false
+	}
+	if year < 100 {
+		year += 2000
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, p.now.Location()), true
+}
+
+// nextDate возвращает ближайшую дату с указанными днем и месяцем, не раньше сегодняшней
+func (p *quickAddParser) nextDate(month time.Month, day int) time.Time {
+	today := p.today()
+	date := time.Date(today.Year(), month, day, 0, 0, 0, 0, today.Location())
+	if date.Before(today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date
+}
+
+// --- Время ---
+
+// matchTime распознает "в 18:00", "at 6pm", "18:30", "в 7 утра", "в 6 вечера"
+func (p *quickAddParser) matchTime() bool {
+	prep := 0
+	if w := p.word(0); w == "в" || w == "во" || w == "at" || w == "@" {
+		prep = 1
+	}
+	w := p.word(prep)
+
+	hour, minute, suffix, ok := parseClock(w)
+	if !ok {
+		return false
+	}
+
+	n := prep + 1
+	switch next := p.word(n); next {
+	case "утра", "am", "дня", "вечера", "ночи", "pm":
+		suffix = next
+		n++
+	case "часов", "часа", "час":
+		n++
+		if p.word(n) == "утра" || p.word(n) == "вечера" || p.word(n) == "дня" {
+			suffix = p.word(n)
+			n++
+		}
+	default:
+		// Голое число без двоеточия и без предлога слишком неоднозначно
+		if !strings.Contains(w, ":") && suffix == "" && prep == 0 {
+			return false
+		}
+		if !strings.Contains(w, ":") && suffix == "" && !p.timeContext(n) {
+			return false
+		}
+	}
+
+	switch suffix {
+	case "pm", "дня", "вечера":
+		if hour < 12 {
+			hour += 12
+		}
+	case "am", "утра", "ночи":
+		if hour == 12 {
+			hour = 0
+		}
+	}
+	if hour > 23 {
+		return false
+	}
+
+	p.hour, p.minute, p.hasTime = hour, minute, true
+	return p.recognize(n, "time", fmt.Sprintf("%02d:%02d", hour, minute))
+}
+
+// timeContext разрешает "в 18" без двоеточия, только если рядом уже есть дата
+func (p *quickAddParser) timeContext(next int) bool {
+	if p.date != nil {
+		return true
+	}
+	saved := p.pos
+	p.pos += next
+	defer func() { p.pos = saved }()
+	prep := 0
+	if datePrepositions[p.word(0)] {
+		prep = 1
+	}
+	switch p.word(prep) {
+	case "сегодня", "today", "завтра", "tomorrow", "послезавтра":
+		return true
+	}
+	_, isWeekday := quickWeekdays[p.word(prep)]
+	return isWeekday
+}
+
+// parseClock разбирает "18:00", "6pm", "6:30pm", "18"
+func parseClock(word string) (hour, minute int, suffix string, ok bool) {
+	for _, s := range []string{"am", "pm"} {
+		if strings.HasSuffix(word, s) && len(word) > len(s) {
+			word, suffix = strings.TrimSuffix(word, s), s
+			break
+		}
+	}
+
+	hourStr, minuteStr, hasMinutes := strings.Cut(word, ":")
+	if !hasMinutes {
+		hourStr, minuteStr, hasMinutes = strings.Cut(word, ".")
+		if hasMinutes && len(minuteStr) != 2 {
+			return 0, 0, "", false
+		}
+	}
+
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 23 || len(hourStr) > 2 {
+		return 0, 0, "", false
+	}
+	if hasMinutes {
+		minute, err = strconv.Atoi(minuteStr)
+		if err != nil || minute < 0 || minute > 59 {
+			return 0, 0, "", false
+		}
+	}
+	if suffix != "" && hour > 12 {
+		return 0, 0, "", false
+	}
+
+	return hour, minute, suffix, true
+}
+
+// due собирает срок из распознанных даты и времени
+func (p *quickAddParser) due() *time.Time {
+	if p.instant != nil {
+		return p.instant
+	}
+	if p.date == nil && !p.hasTime {
+		return nil
+	}
+
+	day := p.today()
+	if p.date != nil {
+		day = *p.date
+	}
+	if !p.hasTime {
+		return &day
+	}
+
+	due := time.Date(day.Year(), day.Month(), day.Day(), p.hour, p.minute, 0, 0, day.Location())
+	// Только время без даты: если оно уже прошло сегодня, переносим на завтра
+	if p.date == nil && due.Before(p.now) {
+		due = due.AddDate(0, 0, 1)
+	}
+	return &due
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Среда, 13 марта 2024 года, 10:00
+var quickAddNow = time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC)
+
+func TestParseQuickAdd_Russian(t *testing.T) {
+	parsed := ParseQuickAdd("Купить молоко завтра в 18:00 #дом !высокий +покупки", quickAddNow)
+
+	if parsed.Title != "Купить молоко" {
+		t.Errorf("Ожидался заголовок 'Купить молоко', получен '%s'", parsed.Title)
+	}
+
+	if len(parsed.Tags) != 1 || parsed.Tags[0] != "дом" {
+		t.Errorf("Ожидался тег 'дом', получено %v", parsed.Tags)
+	}
+
+	if parsed.Priority != PriorityHigh {
+		t.Errorf("Ожидался приоритет high, получен '%s'", parsed.Priority)
+	}
+
+	if parsed.Project != "покупки" {
+		t.Errorf("Ожидался проект 'покупки', получен '%s'", parsed.Project)
+	}
+
+	expected := time.Date(2024, 3, 14, 18, 0, 0, 0, time.UTC)
+	if parsed.DueDate == nil || !parsed.DueDate.Equal(expected) {
+		t.Errorf("Ожидался срок %v, получен %v", expected, parsed.DueDate)
+	}
+
+	if len(parsed.Recognized) != 5 {
+		t.Errorf("Ожидалось 5 распознанных фрагментов, получено %v", parsed.Recognized)
+	}
+}
+
+func TestParseQuickAdd_Dates(t *testing.T) {
+	cases := []struct {
+		text     string
+		title    string
+		expected time.Time
+	}{
+		{"Отчет к пятнице", "Отчет", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"Позвонить в среду в 9 утра", "Позвонить", time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC)},
+		{"Сдать код к среде", "Сдать код", time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"Оплатить счет через 3 дня", "Оплатить счет", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"Проверить духовку через 2 часа", "Проверить духовку", time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)},
+		{"День рождения 5 мая", "День рождения", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"Новый год 01.01", "Новый год", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"Созвон в 9:30", "Созвон", time.Date(2024, 3, 14, 9, 30, 0, 0, time.UTC)},
+		{"Call mom tomorrow at 6pm", "Call mom", time.Date(2024, 3, 14, 18, 0, 0, 0, time.UTC)},
+		{"Submit report next monday", "Submit report", time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"Renew passport in 2 weeks", "Renew passport", time.Date(2024, 3, 27, 0, 0, 0, 0, time.UTC)},
+		{"Dentist may 20 at 14:15", "Dentist", time.Date(2024, 5, 20, 14, 15, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		parsed := ParseQuickAdd(c.text, quickAddNow)
+		if parsed.Title != c.title {
+			t.Errorf("%q: ожидался заголовок '%s', получен '%s'", c.text, c.title, parsed.Title)
+		}
+		if parsed.DueDate == nil || !parsed.DueDate.Equal(c.expected) {
+			t.Errorf("%q: ожидался срок %v, получен %v", c.text, c.expected, parsed.DueDate)
+		}
+	}
+}
+
+func TestParseQuickAdd_PlainText(t *testing.T) {
+	for _, text := range []string{"Купить 2 батона", "Enjoy the sun in the park", "Прочитать главу 18",
+		"Настроить среду разработки", "Проверить сборку в среде CI"} {
+		parsed := ParseQuickAdd(text, quickAddNow)
+		if parsed.Title != text {
+			t.Errorf("%q: ожидался заголовок без изменений, получен '%s'", text, parsed.Title)
+		}
+		if parsed.DueDate != nil {
+			t.Errorf("%q: срок не должен распознаваться, получен %v", text, parsed.DueDate)
+		}
+	}
+}
+
+func TestTaskHandler_QuickAddTask(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	body, _ := json.Marshal(QuickAddRequest{Text: "Полить цветы #дом !!"})
+	req := httptest.NewRequest("POST", "/tasks/quick", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	handler.QuickAddTask(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusCreated, w.Code)
+	}
+
+	var response QuickAddResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+
+	if response.Task.ID != 1 || response.Task.Priority != PriorityMedium {
+		t.Errorf("Ожидалась задача 1 со средним приоритетом, получено %+v", response.Task)
+	}
+
+	if len(service.GetAllTasks(t.Context())) != 1 {
+		t.Errorf("Задача должна быть создана")
+	}
+}
+
+func TestTaskHandler_QuickAddTask_DryRun(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	body, _ := json.Marshal(QuickAddRequest{Text: "Полить цветы завтра"})
+	req := httptest.NewRequest("POST", "/tasks/quick?dry_run=true", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	handler.QuickAddTask(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, w.Code)
+	}
+
+	if len(service.GetAllTasks(t.Context())) != 0 {
+		t.Errorf("В режиме dry_run задача не должна создаваться")
+	}
+}
+
+func TestTaskHandler_QuickAddTask_NoTitle(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	body, _ := json.Marshal(QuickAddRequest{Text: "завтра #дом"})
+	req := httptest.NewRequest("POST", "/tasks/quick", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	handler.QuickAddTask(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -2,54 +2,215 @@ package main
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// SetupRoutes настраивает маршруты для приложения
-func SetupRoutes(taskHandler *TaskHandler) *chi.Mux {
+// routeConfig — обработчики и middleware, которые SetupRoutes подключает к маршрутизатору
+type routeConfig struct {
+	attachments *AttachmentHandler
+	views       *ViewHandler
+	caldav      *CalDAVHandler
+	graphQL     *GraphQLHandler
+	rpc         *JSONRPCServer
+	mcp         *MCPServer
+	health      *HealthRegistry
+	metrics     *Metrics
+	cors        *CORSPolicy
+	idempotency *IdempotencyStore
+	validator   *OpenAPIValidator
+}
+
+// RouteOption подключает к маршрутизатору дополнительный API или middleware.
+// Без опции соответствующие маршруты не регистрируются, а middleware не используется.
+type RouteOption func(*routeConfig)
+
+// WithAttachments подключает /tasks/{id}/attachments
+func WithAttachments(handler *AttachmentHandler) RouteOption {
+	return func(c *routeConfig) { c.attachments = handler }
+}
+
+// WithViews подключает /views
+func WithViews(handler *ViewHandler) RouteOption {
+	return func(c *routeConfig) { c.views = handler }
+}
+
+// WithCalDAV подключает /dav и /.well-known/caldav
+func WithCalDAV(handler *CalDAVHandler) RouteOption {
+	return func(c *routeConfig) { c.caldav = handler }
+}
+
+// WithGraphQL подключает /graphql
+func WithGraphQL(handler *GraphQLHandler) RouteOption {
+	return func(c *routeConfig) { c.graphQL = handler }
+}
+
+// WithJSONRPC подключает /rpc
+func WithJSONRPC(server *JSONRPCServer) RouteOption {
+	return func(c *routeConfig) { c.rpc = server }
+}
+
+// WithMCP подключает /mcp
+func WithMCP(server *MCPServer) RouteOption {
+	return func(c *routeConfig) { c.mcp = server }
+}
+
+// WithHealth подключает /healthz, /readyz и /health с проверками реестра health
+func WithHealth(health *HealthRegistry) RouteOption {
+	return func(c *routeConfig) { c.health = health }
+}
+
+// WithMetrics подключает /metrics и измерение запросов
+func WithMetrics(metrics *Metrics) RouteOption {
+	return func(c *routeConfig) { c.metrics = metrics }
+}
+
+// WithCORS подключает политику CORS
+func WithCORS(cors *CORSPolicy) RouteOption {
+	return func(c *routeConfig) { c.cors = cors }
+}
+
+// WithIdempotency подключает обработку заголовка Idempotency-Key
+func WithIdempotency(store *IdempotencyStore) RouteOption {
+	return func(c *routeConfig) { c.idempotency = store }
+}
+
+// WithOpenAPIValidator подключает проверку запросов и ответов по спецификации
+func WithOpenAPIValidator(validator *OpenAPIValidator) RouteOption {
+	return func(c *routeConfig) { c.validator = validator }
+}
+
+// SetupRoutes настраивает маршруты для приложения: API задач и то, что подключено опциями
+func SetupRoutes(taskHandler *TaskHandler, opts ...RouteOption) *chi.Mux {
+	var config routeConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	r := chi.NewRouter()
 
 	// Добавляем middleware
-	r.Use(middleware.Logger)
+	r.Use(middleware.RequestLogger(&middleware.DefaultLogFormatter{Logger: log.Default(), NoColor: true}))
+	// Трассировка продолжает traceparent клиента; span запроса — родитель spans обработчиков и сервиса
+	r.Use(TracingMiddleware(r))
+
+	// Метрики снаружи Recoverer, чтобы запросы с паникой учитывались со статусом 500
+	if config.metrics != nil {
+		r.Use(config.metrics.Middleware(r))
+	}
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 
-	// Настраиваем CORS для тестирования с Postman
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	// CORS для браузерных клиентов; источники задаются конфигурацией
+	if config.cors != nil {
+		r.Use(config.cors.Middleware)
+	}
 
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
+	// Запросы и ответы проверяются по спецификации api/openapi.json (если проверка включена)
+	if config.validator != nil {
+		r.Use(config.validator.Middleware(r))
+	}
 
-			next.ServeHTTP(w, r)
-		})
-	})
+	// Повторы POST запросов с заголовком Idempotency-Key не создают дубликатов
+	if config.idempotency != nil {
+		r.Use(config.idempotency.Middleware)
+	}
 
 	// Регистрируем маршруты
+	r.Get("/tasks.ics", taskHandler.CalendarFeed) // GET /tasks.ics
+
 	r.Route("/tasks", func(r chi.Router) {
-		r.Post("/", taskHandler.CreateTask)       // POST /tasks
-		r.Get("/", taskHandler.GetTasks)          // GET /tasks
-		r.Get("/{id}", taskHandler.GetTask)       // GET /tasks/{id}
-		r.Put("/{id}", taskHandler.UpdateTask)    // PUT /tasks/{id}
-		r.Delete("/{id}", taskHandler.DeleteTask) // DELETE /tasks/{id}
+		r.Post("/", taskHandler.CreateTask)                   // POST /tasks
+		r.Get("/", taskHandler.GetTasks)                      // GET /tasks
+		r.Post("/quick", taskHandler.QuickAddTask)            // POST /tasks/quick
+		r.Post("/batch", taskHandler.BatchTasks)              // POST /tasks/batch
+		r.Get("/export", taskHandler.ExportTasks)             // GET /tasks/export
+		r.Post("/import", taskHandler.ImportTasks)            // POST /tasks/import
+		r.Get("/search", taskHandler.SearchTasks)             // GET /tasks/search?q=
+		r.Get("/autocomplete", taskHandler.AutocompleteTasks) // GET /tasks/autocomplete?q=
+		r.Get("/events", taskHandler.TaskEvents)              // GET /tasks/events
+		r.Get("/{id}", taskHandler.GetTask)                   // GET /tasks/{id}
+		r.Put("/{id}", taskHandler.UpdateTask)                // PUT /tasks/{id}
+		r.Delete("/{id}", taskHandler.DeleteTask)             // DELETE /tasks/{id}
+
+		if attachmentHandler := config.attachments; attachmentHandler != nil {
+			r.Route("/{id}/attachments", func(r chi.Router) {
+				r.Post("/", attachmentHandler.UploadAttachment)                 // POST /tasks/{id}/attachments
+				r.Get("/", attachmentHandler.GetAttachments)                    // GET /tasks/{id}/attachments
+				r.Get("/{attachmentID}", attachmentHandler.DownloadAttachment)  // GET /tasks/{id}/attachments/{attachmentID}
+				r.Delete("/{attachmentID}", attachmentHandler.DeleteAttachment) // DELETE /tasks/{id}/attachments/{attachmentID}
+			})
+		}
 	})
 
+	if viewHandler := config.views; viewHandler != nil {
+		r.Route("/views", func(r chi.Router) {
+			r.Post("/", viewHandler.CreateView)         // POST /views
+			r.Get("/", viewHandler.GetViews)            // GET /views
+			r.Get("/{name}", viewHandler.GetView)       // GET /views/{name}
+			r.Put("/{name}", viewHandler.UpdateView)    // PUT /views/{name}
+			r.Delete("/{name}", viewHandler.DeleteView) // DELETE /views/{name}
+			r.Get("/{name}/tasks", viewHandler.RunView) // GET /views/{name}/tasks
+		})
+	}
+
+	// CalDAV для синхронизации с Apple Reminders, Thunderbird и DAVx5
+	if caldavHandler := config.caldav; caldavHandler != nil {
+		r.Get("/.well-known/caldav", caldavHandler.WellKnown) // GET /.well-known/caldav
+		r.Route("/dav", func(r chi.Router) {
+			r.Options("/*", caldavHandler.Options)                  // OPTIONS /dav/...
+			r.MethodFunc("PROPFIND", "/*", caldavHandler.Propfind)  // PROPFIND /dav/...
+			r.MethodFunc("REPORT", "/tasks/", caldavHandler.Report) // REPORT /dav/tasks/
+			r.Get("/tasks/{name}", caldavHandler.GetResource)       // GET /dav/tasks/{name}
+			r.Head("/tasks/{name}", caldavHandler.GetResource)      // HEAD /dav/tasks/{name}
+			r.Put("/tasks/{name}", caldavHandler.PutResource)       // PUT /dav/tasks/{name}
+			r.Delete("/tasks/{name}", caldavHandler.DeleteResource) // DELETE /dav/tasks/{name}
+		})
+	}
+
+	// GraphQL поверх тех же сервисов; подписки — через Server-Sent Events
+	if config.graphQL != nil {
+		r.Get("/graphql", config.graphQL.ServeHTTP)  // GET /graphql
+		r.Post("/graphql", config.graphQL.ServeHTTP) // POST /graphql
+	}
+
+	// JSON-RPC 2.0 для скриптов и внутренних инструментов
+	if config.rpc != nil {
+		r.Post("/rpc", config.rpc.ServeHTTP) // POST /rpc
+	}
+
+	// Model Context Protocol для ассистентов (Streamable HTTP)
+	if config.mcp != nil {
+		r.Post("/mcp", config.mcp.ServeHTTP) // POST /mcp
+	}
+
+	// Пробы оркестратора: процесс жив, сервер готов, состояние компонентов
+	if health := config.health; health != nil {
+		r.Get("/healthz", health.Liveness) // GET /healthz
+		r.Get("/readyz", health.Readiness) // GET /readyz
+		r.Get("/health", health.Health)    // GET /health
+	}
+
+	// Метрики в формате Prometheus
+	if config.metrics != nil {
+		r.Get("/metrics", config.metrics.Handler().ServeHTTP) // GET /metrics
+	}
+
+	// Спецификация OpenAPI и документация по ней
+	r.Get("/openapi.json", ServeOpenAPISpec) // GET /openapi.json
+	r.Get("/docs", ServeAPIDocs)             // GET /docs
+
 	// Добавляем корневой маршрут для проверки
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"message":   "ToDo API работает!",
 			"version":   "1.0.0",
-			"endpoints": "POST /tasks, GET /tasks, GET /tasks/{id}, PUT /tasks/{id}, DELETE /tasks/{id}",
+			"endpoints": "POST /tasks, POST /tasks/quick, POST /tasks/batch, GET /tasks/export?format=, GET /tasks.ics, POST /tasks/import?format=, GET /tasks?q=, GET /tasks/search?q=, GET /tasks/autocomplete?q=, GET /tasks/events, GET /tasks/{id}, PUT /tasks/{id}, DELETE /tasks/{id}, POST/GET /tasks/{id}/attachments, GET/DELETE /tasks/{id}/attachments/{attachmentID}, POST/GET /views, GET/PUT/DELETE /views/{name}, GET /views/{name}/tasks, CalDAV /dav/, GET/POST /graphql, POST /rpc, POST /mcp, GET /healthz, GET /readyz, GET /health, GET /metrics, GET /openapi.json, GET /docs",
 		})
 	})
 
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Поля задачи, участвующие в поиске, и их веса при ранжировании
+const (
+	fieldTitle = iota
+	fieldDescription
+	fieldCount
+)
+
+var fieldNames = [fieldCount]string{"title", "description"}
+var fieldBoosts = [fieldCount]float64{2.0, 1.0}
+
+// Параметры BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetRadius — сколько слов вокруг первого совпадения показывать во фрагменте
+const snippetRadius = 8
+
+// token — слово текста с его основой и позицией в исходной строке
+type token struct {
+	word       string
+	stem       string
+	start, end int
+}
+
+// indexedDoc хранит разобранные поля задачи
+type indexedDoc struct {
+	task   *Task
+	fields [fieldCount][]token
+}
+
+// SearchIndex — инвертированный индекс по основам слов.
+// Не потокобезопасен: синхронизацию обеспечивает владелец (TaskService).
+type SearchIndex struct {
+	docs     map[int]*indexedDoc
+	postings map[string]map[int]struct{} // основа -> ID задач
+	words    map[string]map[int]struct{} // слово -> ID задач, для префиксных запросов
+	totalLen [fieldCount]int
+
+	trigrams    map[string]map[string]struct{} // триграмма -> слова, для нечеткого поиска
+	titleWords  map[string]map[int]struct{}    // слово заголовка -> ID задач
+	completions []completion                   // слова заголовков по алфавиту, для автодополнения
+}
+
+// NewSearchIndex создает пустой индекс
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		docs:     make(map[int]*indexedDoc),
+		postings: make(map[string]map[int]struct{}),
+		words:    make(map[string]map[int]struct{}),
+
+		trigrams:   make(map[string]map[string]struct{}),
+		titleWords: make(map[string]map[int]struct{}),
+	}
+}
+
+// Index добавляет задачу в индекс или переиндексирует ее
+func (si *SearchIndex) Index(task *Task) {
+	si.Remove(task.ID)
+
+	doc := &indexedDoc{task: task}
+	doc.fields[fieldTitle] = tokenize(task.Title)
+	doc.fields[fieldDescription] = tokenize(task.Description)
+
+	for field, tokens := range doc.fields {
+		si.totalLen[field] += len(tokens)
+		for _, tok := range tokens {
+			addPosting(si.postings, tok.stem, task.ID)
+			si.addWord(tok.word, task.ID, field == fieldTitle)
+		}
+	}
+
+	si.docs[task.ID] = doc
+}
+
+// Remove удаляет задачу из индекса
+func (si *SearchIndex) Remove(id int) {
+	doc, exists := si.docs[id]
+	if !exists {
+		return
+	}
+
+	for field, tokens := range doc.fields {
+		si.totalLen[field] -= len(tokens)
+		for _, tok := range tokens {
+			removePosting(si.postings, tok.stem, id)
+			si.removeWord(tok.word, id, field == fieldTitle)
+		}
+	}
+
+	delete(si.docs, id)
+}
+
+// Search выполняет запрос и возвращает результаты по убыванию релевантности.
+// Все условия запроса должны выполняться одновременно.
+func (si *SearchIndex) Search(query string, limit int) []*SearchResult {
+	clauses := parseSearchQuery(query)
+	if len(clauses) == 0 {
+		return []*SearchResult{}
+	}
+
+	// Для каждого условия находим подходящие документы и частоты совпадений
+	matches := make([]map[int]*clauseMatch, len(clauses))
+	for i, clause := range clauses {
+		matches[i] = si.matchClause(clause)
+	}
+
+	results := make([]*SearchResult, 0)
+	for id := range matches[0] {
+		doc := si.docs[id]
+		score := 0.0
+		highlights := [fieldCount]map[int]bool{}
+		matchedAll := true
+
+		for i := range clauses {
+			m, ok := matches[i][id]
+			if !ok {
+				matchedAll = false
+				break
+			}
+
+			idf := math.Log(1 + (float64(len(si.docs))-float64(len(matches[i]))+0.5)/(float64(len(matches[i]))+0.5))
+			for field := range fieldCount {
+				tf := float64(m.counts[field])
+				if tf == 0 {
+					continue
+				}
+				norm := 1 - bm25B + bm25B*float64(len(doc.fields[field]))/si.avgLen(field)
+				score += fieldBoosts[field] * idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+
+				if highlights[field] == nil {
+					highlights[field] = make(map[int]bool)
+				}
+				for _, pos := range m.positions[field] {
+					highlights[field][pos] = true
+				}
+			}
+		}
+		if !matchedAll {
+			continue
+		}
+
+		result := &SearchResult{Task: doc.task, Score: score, Snippets: make(map[string]string)}
+		for field := range fieldCount {
+			if len(highlights[field]) > 0 {
+				result.Snippets[fieldNames[field]] = snippet(fieldText(doc.task, field), doc.fields[field], highlights[field], field == fieldTitle)
+			}
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (si *SearchIndex) avgLen(field int) float64 {
+	if len(si.docs) == 0 || si.totalLen[field] == 0 {
+		return 1
+	}
+	return float64(si.totalLen[field]) / float64(len(si.docs))
+}
+
+// searchClause — одно условие запроса: слово, префикс ("прог*") или фраза ("купить молоко")
+type searchClause struct {
+	stems  []string
+	prefix string
+}
+
+// clauseMatch — совпадения условия в документе: число вхождений и позиции слов по полям
+type clauseMatch struct {
+	counts    [fieldCount]int
+	positions [fieldCount][]int
+}
+
+func (si *SearchIndex) matchClause(clause searchClause) map[int]*clauseMatch {
+	matches := make(map[int]*clauseMatch)
+
+	if clause.prefix != "" {
+		candidates := make(map[int]struct{})
+		for word, ids := range si.words {
+			if strings.HasPrefix(word, clause.prefix) {
+				for id := range ids {
+					candidates[id] = struct{}{}
+				}
+			}
+		}
+		for id := range candidates {
+			m := &clauseMatch{}
+			for field, tokens := range si.docs[id].fields {
+				for pos, tok := range tokens {
+					if strings.HasPrefix(tok.word, clause.prefix) {
+						m.counts[field]++
+						m.positions[field] = append(m.positions[field], pos)
+					}
+				}
+			}
+			matches[id] = m
+		}
+		return matches
+	}
+
+	// Кандидаты — документы, содержащие первую основу; фраза проверяется по позициям
+	for id := range si.postings[clause.stems[0]] {
+		m := &clauseMatch{}
+		for field, tokens := range si.docs[id].fields {
+			for pos := 0; pos+len(clause.stems) <= len(tokens); pos++ {
+				if phraseAt(tokens, pos, clause.stems) {
+					m.counts[field]++
+					for k := range clause.stems {
+						m.positions[field] = append(m.positions[field], pos+k)
+					}
+				}
+			}
+		}
+		if m.counts[fieldTitle]+m.counts[fieldDescription] > 0 {
+			matches[id] = m
+		}
+	}
+	return matches
+}
+
+func phraseAt(tokens []token, pos int, stems []string) bool {
+	for k, stem := range stems {
+		if tokens[pos+k].stem != stem {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSearchQuery разбирает запрос: слова в кавычках образуют фразу, слово со звездочкой — префикс
+func parseSearchQuery(query string) []searchClause {
+	var clauses []searchClause
+
+	parts := strings.Split(query, `"`)
+	for i, part := range parts {
+		if i%2 == 1 {
+			// Текст внутри кавычек; незакрытая кавычка тоже считается фразой
+			tokens := tokenize(part)
+			if len(tokens) == 0 {
+				continue
+			}
+			stems := make([]string, len(tokens))
+			for k, tok := range tokens {
+				stems[k] = tok.stem
+			}
+			clauses = append(clauses, searchClause{stems: stems})
+			continue
+		}
+
+		for _, field := range strings.Fields(part) {
+			if strings.HasSuffix(field, "*") {
+				tokens := tokenize(strings.TrimSuffix(field, "*"))
+				if len(tokens) == 1 {
+					clauses = append(clauses, searchClause{prefix: tokens[0].word})
+					continue
+				}
+			}
+			for _, tok := range tokenize(field) {
+				clauses = append(clauses, searchClause{stems: []string{tok.stem}})
+			}
+		}
+	}
+
+	return clauses
+}
+
+// tokenize разбивает текст на слова (буквы и цифры) и вычисляет их основы
+func tokenize(text string) []token {
+	var tokens []token
+
+	start := -1
+	for i, r := range text + " " {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			word := strings.ReplaceAll(strings.ToLower(text[start:i]), "ё", "е")
+			tokens = append(tokens, token{word: word, stem: Stem(word), start: start, end: i})
+			start = -1
+		}
+	}
+
+	return tokens
+}
+
+func fieldText(task *Task, field int) string {
+	if field == fieldTitle {
+		return task.Title
+	}
+	return task.Description
+}
+
+// snippet возвращает HTML-экранированный фрагмент текста с совпадениями в <mark>.
+// Заголовок выводится целиком, описание — окном вокруг первого совпадения.
+func snippet(text string, tokens []token, highlight map[int]bool, whole bool) string {
+	first := len(tokens)
+	for pos := range highlight {
+		first = min(first, pos)
+	}
+
+	from, to := 0, len(tokens)-1
+	if !whole {
+		from = max(0, first-snippetRadius)
+		to = min(len(tokens)-1, first+snippetRadius*2)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+
+	cursor := tokens[from].start
+	if from == 0 {
+		cursor = 0
+	}
+	for pos := from; pos <= to; pos++ {
+		tok := tokens[pos]
+		b.WriteString(html.EscapeString(text[cursor:tok.start]))
+		if highlight[pos] {
+			b.WriteString("<mark>" + html.EscapeString(text[tok.start:tok.end]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[tok.start:tok.end]))
+		}
+		cursor = tok.end
+	}
+
+	if to == len(tokens)-1 {
+		b.WriteString(html.EscapeString(text[cursor:]))
+	} else {
+		b.WriteString("…")
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func addPosting(postings map[string]map[int]struct{}, key string, id int) {
+	ids, exists := postings[key]
+	if !exists {
+		ids = make(map[int]struct{})
+		postings[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removePosting(postings map[string]map[int]struct{}, key string, id int) {
+	delete(postings[key], id)
+	if len(postings[key]) == 0 {
+		delete(postings, key)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	cases := map[string]string{
+		"задача":           "задач",
+		"задачами":         "задач",
+		"программирования": "программирован",
+		"купила":           "куп",
+		"красивейший":      "красив",
+		"Ёлки":             "елк",
+		"running":          "run",
+		"connections":      "connect",
+		"generalization":   "general",
+		"happily":          "happili",
+		"v2":               "v2",
+	}
+
+	for word, expected := range cases {
+		if stem := Stem(word); stem != expected {
+			t.Errorf("Stem(%q): ожидалось '%s', получено '%s'", word, expected, stem)
+		}
+	}
+}
+
+func TestTaskService_SearchTasks_Stemming(t *testing.T) {
+	service := NewTaskService()
+
+	service.CreateTask(t.Context(), "Изучить Go", "Изучить основы языка программирования Go")
+	service.CreateTask(t.Context(), "Купить молоко", "Зайти в магазин")
+	service.CreateTask(t.Context(), "Read documentation", "Reading the chi router docs")
+
+	results := service.SearchTasks(t.Context(), "программирование", 0)
+	if len(results) != 1 || results[0].Task.ID != 1 {
+		t.Fatalf("Ожидалась задача 1 по словоформе, получено %d результатов", len(results))
+	}
+
+	results = service.SearchTasks(t.Context(), "reads", 0)
+	if len(results) != 1 || results[0].Task.ID != 3 {
+		t.Fatalf("Ожидалась задача 3 по английской словоформе, получено %d результатов", len(results))
+	}
+}
+
+func TestTaskService_SearchTasks_PhraseAndPrefix(t *testing.T) {
+	service := NewTaskService()
+
+	service.CreateTask(t.Context(), "Купить свежее молоко", "")
+	service.CreateTask(t.Context(), "Молоко купить", "")
+
+	results := service.SearchTasks(t.Context(), `"купить молоко"`, 0)
+	if len(results) != 0 {
+		t.Errorf("Фраза не должна совпадать при другом порядке слов, получено %d результатов", len(results))
+	}
+
+	results = service.SearchTasks(t.Context(), `"молоко купить"`, 0)
+	if len(results) != 1 || results[0].Task.ID != 2 {
+		t.Errorf("Ожидалась задача 2 по фразе, получено %d результатов", len(results))
+	}
+
+	results = service.SearchTasks(t.Context(), "свеж*", 0)
+	if len(results) != 1 || results[0].Task.ID != 1 {
+		t.Errorf("Ожидалась задача 1 по префиксу, получено %d результатов", len(results))
+	}
+}
+
+func TestTaskService_SearchTasks_Ranking(t *testing.T) {
+	service := NewTaskService()
+
+	service.CreateTask(t.Context(), "Отчет", "Подготовить квартальный отчет по задачам")
+	service.CreateTask(t.Context(), "Задачи на неделю", "Разобрать задачи")
+
+	results := service.SearchTasks(t.Context(), "задача", 0)
+	if len(results) != 2 {
+		t.Fatalf("Ожидалось 2 результата, получено %d", len(results))
+	}
+
+	// Совпадение в заголовке весит больше, чем в описании
+	if results[0].Task.ID != 2 {
+		t.Errorf("Первой ожидалась задача 2, получена %d", results[0].Task.ID)
+	}
+
+	if results[0].Snippets["title"] != "<mark>Задачи</mark> на неделю" {
+		t.Errorf("Неожиданный фрагмент заголовка: '%s'", results[0].Snippets["title"])
+	}
+}
+
+func TestTaskService_SearchTasks_IndexUpdates(t *testing.T) {
+	service := NewTaskService()
+
+	task := service.CreateTask(t.Context(), "Позвонить врачу", "")
+	service.UpdateTask(t.Context(), task.ID, "Написать письмо", "", false)
+
+	if results := service.SearchTasks(t.Context(), "врач", 0); len(results) != 0 {
+		t.Errorf("Старый заголовок не должен находиться после обновления, получено %d результатов", len(results))
+	}
+
+	if results := service.SearchTasks(t.Context(), "письма", 0); len(results) != 1 {
+		t.Errorf("Ожидался 1 результат по новому заголовку, получено %d", len(results))
+	}
+
+	service.DeleteTask(t.Context(), task.ID)
+	if results := service.SearchTasks(t.Context(), "письма", 0); len(results) != 0 {
+		t.Errorf("Удаленная задача не должна находиться, получено %d результатов", len(results))
+	}
+}
+
+func TestTaskHandler_SearchTasks(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	service.CreateTask(t.Context(), "Починить <b>сервер</b>", strings.Repeat("слово ", 30)+"сервер упал")
+
+	req := httptest.NewRequest("GET", "/tasks/search?q=серверы", nil)
+	w := httptest.NewRecorder()
+	handler.SearchTasks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, w.Code)
+	}
+
+	var results []SearchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Ожидался 1 результат, получено %d", len(results))
+	}
+
+	if results[0].Snippets["title"] != "Починить &lt;b&gt;<mark>сервер</mark>&lt;/b&gt;" {
+		t.Errorf("Неожиданный фрагмент заголовка: '%s'", results[0].Snippets["title"])
+	}
+
+	if !strings.HasPrefix(results[0].Snippets["description"], "…") {
+		t.Errorf("Длинное описание должно обрезаться, получено '%s'", results[0].Snippets["description"])
+	}
+}
+
+func TestTaskHandler_SearchTasks_EmptyQuery(t *testing.T) {
+	service := NewTaskService()
+	handler := NewTaskHandler(service)
+
+	req := httptest.NewRequest("GET", "/tasks/search", nil)
+	w := httptest.NewRecorder()
+	handler.SearchTasks(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
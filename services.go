@@ -1,71 +1,167 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
 
-// TaskServiceInterface определяет интерфейс для работы с задачами
-type TaskServiceInterface interface {
-	CreateTask(title, description string) *Task
-	GetTask(id int) (*Task, error)
-	GetAllTasks() []*Task
-	UpdateTask(id int, title, description string, completed bool) (*Task, error)
-	DeleteTask(id int) error
+// serviceError хранит текст ошибки и сопоставляется со своей категорией (ErrNotFound, ErrInvalidInput, ErrConflict)
+type serviceError struct {
+	kind error
+	msg  string
+}
+
+func (e *serviceError) Error() string {
+	return e.msg
+}
+
+func (e *serviceError) Is(target error) bool {
+	return target == e.kind
+}
+
+// newNotFoundError создает ошибку об отсутствующем объекте
+func newNotFoundError(format string, args ...any) error {
+	return &serviceError{kind: ErrNotFound, msg: fmt.Sprintf(format, args...)}
+}
+
+// newInvalidInputError создает ошибку о неверных входных данных
+func newInvalidInputError(format string, args ...any) error {
+	return &serviceError{kind: ErrInvalidInput, msg: fmt.Sprintf(format, args...)}
+}
+
+// newConflictError создает ошибку о занятом идентификаторе
+func newConflictError(format string, args ...any) error {
+	return &serviceError{kind: ErrConflict, msg: fmt.Sprintf(format, args...)}
 }
 
 // TaskService управляет задачами в памяти
 type TaskService struct {
-	tasks  map[int]*Task
-	nextID int
-	mutex  sync.RWMutex
+	tasks   map[int]*Task
+	index   *SearchIndex
+	nextID  int
+	mutex   sync.RWMutex
+	events  *eventHub
+	pending []TaskEvent // события текущей операции; рассылаются после ее завершения
+	// deleteHooks вызываются для задач, удаленных завершенной операцией (OnDelete)
+	deleteHooks []func(ctx context.Context, taskID int)
+}
+
+// TaskFilterer отбирает задачи по условию. Его реализует только сервис сервера:
+// клиенту для этого пришлось бы загрузить все задачи, поэтому в контракт models он не входит.
+type TaskFilterer interface {
+	FilterTasks(ctx context.Context, match func(*Task) bool) []*Task
+}
+
+// ServerTaskService объединяет все возможности сервиса задач сервера. Обертки с
+// метриками и трассировкой принимают и возвращают его, чтобы не скрыть ни одной из них.
+type ServerTaskService interface {
+	TaskServiceInterface
+	TaskFilterer
+	TaskSearcher
+	TaskBatcher
+	TaskImporter
+	TaskSubscriber
 }
 
 // NewTaskService создает новый сервис задач
-func NewTaskService() TaskServiceInterface {
+func NewTaskService() *TaskService {
 	return &TaskService{
 		tasks:  make(map[int]*Task),
+		index:  NewSearchIndex(),
 		nextID: 1,
+		events: newEventHub(),
 	}
 }
 
-// CreateTask создает новую задачу
-func (ts *TaskService) CreateTask(title, description string) *Task {
+// OnDelete регистрирует hook, который вызывается для каждой удаленной задачи, кем бы
+// она ни была удалена: DeleteTask, пакетом или через CalDAV. Удаления, откаченные
+// атомарным пакетом, hook не видит. Hook выполняется под мьютексом сервиса, поэтому
+// должен быть быстрым и не обращаться к сервису задач.
+func (ts *TaskService) OnDelete(hook func(ctx context.Context, taskID int)) {
 	ts.mutex.Lock()
 	defer ts.mutex.Unlock()
+	ts.deleteHooks = append(ts.deleteHooks, hook)
+}
+
+// lock захватывает мьютекс на запись
+func (ts *TaskService) lock(ctx context.Context) {
+	traceLockWait(ctx, "TaskService.mutex.Lock", ts.mutex.Lock)
+}
+
+// rlock захватывает мьютекс на чтение
+func (ts *TaskService) rlock(ctx context.Context) {
+	traceLockWait(ctx, "TaskService.mutex.RLock", ts.mutex.RLock)
+}
+
+// HealthCheck проверяет, что сервис отвечает: мьютекс удается захватить на чтение
+// до истечения ctx. Долгое ожидание означает зависшую или слишком долгую операцию записи.
+func (ts *TaskService) HealthCheck(ctx context.Context) error {
+	acquired := make(chan struct{})
+	go func() {
+		ts.mutex.RLock()
+		ts.mutex.RUnlock()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("сервис задач занят: %w", ctx.Err())
+	}
+}
+
+// CreateTask создает новую задачу
+func (ts *TaskService) CreateTask(ctx context.Context, title, description string, opts ...TaskOption) *Task {
+	ts.lock(ctx)
+	defer ts.mutex.Unlock()
+	defer ts.publishLocked(ctx)
+
+	return ts.createLocked(title, description, opts...)
+}
 
+// createLocked создает задачу; вызывающий должен удерживать мьютекс на запись
+func (ts *TaskService) createLocked(title, description string, opts ...TaskOption) *Task {
 	task := &Task{
 		ID:          ts.nextID,
 		Title:       title,
 		Description: description,
 		Completed:   false,
+		Tags:        []string{},
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	for _, opt := range opts {
+		opt(task)
+	}
 
 	ts.tasks[ts.nextID] = task
+	ts.index.Index(task)
 	ts.nextID++
+	ts.recordLocked(TaskEventCreated, task)
 
 	return task
 }
 
 // GetTask возвращает задачу по ID
-func (ts *TaskService) GetTask(id int) (*Task, error) {
-	ts.mutex.RLock()
+func (ts *TaskService) GetTask(ctx context.Context, id int) (*Task, error) {
+	ts.rlock(ctx)
 	defer ts.mutex.RUnlock()
 
 	task, exists := ts.tasks[id]
 	if !exists {
-		return nil, fmt.Errorf("задача с ID %d не найдена", id)
+		return nil, newNotFoundError("задача с ID %d не найдена", id)
 	}
 
 	return task, nil
 }
 
 // GetAllTasks возвращает все задачи
-func (ts *TaskService) GetAllTasks() []*Task {
-	ts.mutex.RLock()
+func (ts *TaskService) GetAllTasks(ctx context.Context) []*Task {
+	ts.rlock(ctx)
 	defer ts.mutex.RUnlock()
 
 	tasks := make([]*Task, 0, len(ts.tasks))
@@ -76,34 +172,120 @@ func (ts *TaskService) GetAllTasks() []*Task {
 	return tasks
 }
 
+// FilterTasks возвращает задачи, удовлетворяющие условию, в порядке возрастания ID
+func (ts *TaskService) FilterTasks(ctx context.Context, match func(*Task) bool) []*Task {
+	ts.rlock(ctx)
+	defer ts.mutex.RUnlock()
+
+	tasks := make([]*Task, 0)
+	for _, task := range ts.tasks {
+		if match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+// filterTasks возвращает задачи service, удовлетворяющие условию, в порядке возрастания ID.
+// Если service не умеет отбирать задачи сам, отбор выполняется по всем задачам.
+func filterTasks(ctx context.Context, service TaskServiceInterface, match func(*Task) bool) []*Task {
+	if filterer, ok := service.(TaskFilterer); ok {
+		return filterer.FilterTasks(ctx, match)
+	}
+
+	tasks := make([]*Task, 0)
+	for _, task := range service.GetAllTasks(ctx) {
+		if match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+// capability возвращает service как дополнительный интерфейс T или ошибку
+// ErrNotSupported, если service не реализует operation
+func capability[T any](service TaskServiceInterface, operation string) (T, error) {
+	c, ok := service.(T)
+	if !ok {
+		return c, fmt.Errorf("%w: %s", ErrNotSupported, operation)
+	}
+	return c, nil
+}
+
 // UpdateTask обновляет существующую задачу
-func (ts *TaskService) UpdateTask(id int, title, description string, completed bool) (*Task, error) {
-	ts.mutex.Lock()
+func (ts *TaskService) UpdateTask(ctx context.Context, id int, title, description string, completed bool, opts ...TaskOption) (*Task, error) {
+	ts.lock(ctx)
 	defer ts.mutex.Unlock()
+	defer ts.publishLocked(ctx)
+
+	return ts.updateLocked(id, title, description, completed, opts...)
+}
 
+// updateLocked обновляет задачу; вызывающий должен удерживать мьютекс на запись
+func (ts *TaskService) updateLocked(id int, title, description string, completed bool, opts ...TaskOption) (*Task, error) {
 	task, exists := ts.tasks[id]
 	if !exists {
-		return nil, fmt.Errorf("задача с ID %d не найдена", id)
+		return nil, newNotFoundError("задача с ID %d не найдена", id)
 	}
 
 	task.Title = title
 	task.Description = description
 	task.Completed = completed
+	for _, opt := range opts {
+		opt(task)
+	}
 	task.UpdatedAt = time.Now()
+	ts.index.Index(task)
+	ts.recordLocked(TaskEventUpdated, task)
 
 	return task, nil
 }
 
 // DeleteTask удаляет задачу по ID
-func (ts *TaskService) DeleteTask(id int) error {
-	ts.mutex.Lock()
+func (ts *TaskService) DeleteTask(ctx context.Context, id int) error {
+	ts.lock(ctx)
 	defer ts.mutex.Unlock()
+	defer ts.publishLocked(ctx)
+
+	return ts.deleteLocked(id)
+}
 
-	_, exists := ts.tasks[id]
+// deleteLocked удаляет задачу; вызывающий должен удерживать мьютекс на запись
+func (ts *TaskService) deleteLocked(id int) error {
+	task, exists := ts.tasks[id]
 	if !exists {
-		return fmt.Errorf("задача с ID %d не найдена", id)
+		return newNotFoundError("задача с ID %d не найдена", id)
 	}
 
 	delete(ts.tasks, id)
+	ts.index.Remove(id)
+	ts.recordLocked(TaskEventDeleted, task)
 	return nil
 }
+
+// SearchTasks выполняет полнотекстовый поиск по заголовкам и описаниям задач
+func (ts *TaskService) SearchTasks(ctx context.Context, query string, limit int) []*SearchResult {
+	ts.rlock(ctx)
+	defer ts.mutex.RUnlock()
+
+	return ts.index.Search(query, limit)
+}
+
+// FuzzySearchTasks ищет задачи по заголовкам с допуском опечаток
+func (ts *TaskService) FuzzySearchTasks(ctx context.Context, query string, limit int) []*SearchResult {
+	ts.rlock(ctx)
+	defer ts.mutex.RUnlock()
+
+	return ts.index.FuzzySearch(query, limit)
+}
+
+// SuggestTasks возвращает подсказки автодополнения для вводимой строки
+func (ts *TaskService) SuggestTasks(ctx context.Context, input string, limit int) *Suggestions {
+	ts.rlock(ctx)
+	defer ts.mutex.RUnlock()
+
+	return ts.index.Suggest(input, limit)
+}
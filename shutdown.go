@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Коды завершения процесса
+const (
+	exitOK = 0
+	// exitError — сервер не запустился или остановился из-за ошибки
+	exitError = 1
+	// exitUsage — неверные флаги или конфигурация
+	exitUsage = 2
+	// exitShutdownTimeout — не все запросы завершились за http.shutdown_timeout
+	exitShutdownTimeout = 3
+)
+
+// shutdownStep — остановка одного сервера. Шаг, не успевший до конца ctx,
+// должен прервать оставшуюся работу сам и вернуть ошибку ctx.
+type shutdownStep struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// shutdownAll останавливает серверы параллельно, чтобы общий срок ctx достался каждому
+// целиком, и возвращает ошибки всех шагов
+func shutdownAll(ctx context.Context, steps []shutdownStep) error {
+	errs := make([]error, len(steps))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := step.stop(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", step.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// shutdownHTTP перестает принимать соединения и ждет завершения запросов; по истечении
+// ctx оставшиеся соединения закрываются
+func shutdownHTTP(server *http.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		err := server.Shutdown(ctx)
+		if err != nil {
+			server.Close()
+		}
+		return err
+	}
+}
+
+// shutdownGRPC ждет завершения вызовов gRPC; по истечении ctx прерывает их
+func shutdownGRPC(server *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			<-done
+			return ctx.Err()
+		}
+	}
+}
+
+// shutdownExitCode выбирает код завершения по ошибке остановки
+func shutdownExitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitShutdownTimeout
+	}
+	return exitError
+}
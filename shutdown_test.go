@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	todov1 "todo-api/proto/todo/v1"
+)
+
+// startHTTPServer запускает http.Server на свободном порту и возвращает его адрес
+func startHTTPServer(t *testing.T, handler http.Handler) (*http.Server, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Ошибка открытия порта: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return server, "http://" + listener.Addr().String()
+}
+
+// slowHandler сообщает о начале запроса в started и отвечает, когда закрыт release
+func slowHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		io.WriteString(w, "готово")
+	})
+}
+
+func TestShutdownHTTP_DrainsRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	server, url := startHTTPServer(t, slowHandler(started, release))
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{string(body), err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan error)
+	go func() { stopped <- shutdownHTTP(server)(ctx) }()
+
+	// Пока запрос выполняется, сервер не останавливается
+	select {
+	case err := <-stopped:
+		t.Fatalf("Остановка не дождалась запроса: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if got := <-results; got.err != nil || got.body != "готово" {
+		t.Errorf("Начатый запрос должен завершиться: %+v", got)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Ожидалась остановка без ошибки, получено %v", err)
+	}
+}
+
+func TestShutdownHTTP_Timeout(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	server, url := startHTTPServer(t, slowHandler(started, release))
+
+	failed := make(chan error)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		failed <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := shutdownHTTP(server)(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || shutdownExitCode(err) != exitShutdownTimeout {
+		t.Errorf("Ожидалось истечение срока остановки, получено %v", err)
+	}
+	// Незавершенные соединения закрываются принудительно
+	if err := <-failed; err == nil {
+		t.Errorf("Запрос, не успевший до срока, должен прерваться")
+	}
+}
+
+func TestShutdownGRPC(t *testing.T) {
+	// startWatch запускает сервер и открывает поток WatchTasks, который сам не заканчивается
+	startWatch := func(t *testing.T, service TaskServiceInterface) (*grpc.Server, todov1.TaskService_WatchTasksClient) {
+		t.Helper()
+		listener := bufconn.Listen(1 << 20)
+		server := NewGRPCServer(service)
+		go server.Serve(listener)
+		t.Cleanup(server.Stop)
+
+		conn, err := grpc.NewClient("passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatalf("Ошибка подключения: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		stream, err := todov1.NewTaskServiceClient(conn).WatchTasks(context.Background(), &todov1.WatchTasksRequest{})
+		if err != nil {
+			t.Fatalf("Ошибка подписки: %v", err)
+		}
+		if _, err := stream.Header(); err != nil {
+			t.Fatalf("Ошибка получения заголовков: %v", err)
+		}
+		return server, stream
+	}
+
+	t.Run("закрытие подписок", func(t *testing.T) {
+		service := NewTaskService()
+		server, stream := startWatch(t, service)
+
+		// Закрытые подписки завершают поток, и сервер останавливается без ожидания срока
+		service.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownGRPC(server)(ctx); err != nil {
+			t.Errorf("Ожидалась остановка без ошибки, получено %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+			t.Errorf("Ожидалось завершение потока с Unavailable, получено %v", err)
+		}
+	})
+
+	t.Run("истечение срока", func(t *testing.T) {
+		server, stream := startWatch(t, NewTaskService())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := shutdownGRPC(server)(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Ожидалось истечение срока остановки, получено %v", err)
+		}
+		if _, err := stream.Recv(); err == nil {
+			t.Errorf("Поток должен прерваться принудительной остановкой")
+		}
+	})
+}
+
+func TestShutdownAll(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Шаги ждут параллельно: каждому достается весь срок
+	waitDeadline := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	start := time.Now()
+	err := shutdownAll(ctx, []shutdownStep{
+		{name: "HTTP", stop: waitDeadline},
+		{name: "gRPC", stop: waitDeadline},
+		{name: "JSON-RPC", stop: func(context.Context) error { return nil }},
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Шаги должны выполняться параллельно, прошло %s", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "HTTP") || !strings.Contains(err.Error(), "gRPC") || strings.Contains(err.Error(), "JSON-RPC") {
+		t.Errorf("Неверная ошибка остановки: %v", err)
+	}
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{err, exitShutdownTimeout},
+		{errors.New("HTTP: ошибка"), exitError},
+	}
+	for _, tt := range tests {
+		if got := shutdownExitCode(tt.err); got != tt.want {
+			t.Errorf("shutdownExitCode(%v) = %d, ожидалось %d", tt.err, got, tt.want)
+		}
+	}
+}
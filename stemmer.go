@@ -0,0 +1,379 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Stem приводит слово к основе: кириллица обрабатывается русским стеммером Snowball,
+// латиница — английским Porter2. Остальные слова возвращаются без изменений.
+func Stem(word string) string {
+	word = strings.ToLower(word)
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return stemRussian(word)
+		}
+	}
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return word
+		}
+	}
+	return stemEnglish(word)
+}
+
+// --- Русский стеммер (Snowball) ---
+
+var (
+	ruPerfectiveGerund1 = []string{"в", "вши", "вшись"}
+	ruPerfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	ruAdjective         = []string{"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом", "его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	ruParticiple1       = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple2       = []string{"ивш", "ывш", "ующ"}
+	ruReflexive         = []string{"ся", "сь"}
+	ruVerb1             = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	ruVerb2             = []string{"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю"}
+	ruNoun              = []string{"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й", "иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я"}
+	ruDerivational      = []string{"ост", "ость"}
+	ruSuperlative       = []string{"ейш", "ейше"}
+)
+
+func isRuVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+func stemRussian(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ё", "е"))
+
+	// RV — часть слова после первой гласной
+	rv := len(w)
+	for i, r := range w {
+		if isRuVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r1 := snowballRegion(w, 0, isRuVowel)
+	r2 := snowballRegion(w, r1, isRuVowel)
+
+	// Шаг 1
+	if end, ok := ruEnding(w, rv, ruPerfectiveGerund1, ruPerfectiveGerund2); ok {
+		w = w[:end]
+	} else {
+		if end, ok := ruEnding(w, rv, nil, ruReflexive); ok {
+			w = w[:end]
+		}
+		if end, ok := ruEnding(w, rv, nil, ruAdjective); ok {
+			w = w[:end]
+			if end, ok := ruEnding(w, rv, ruParticiple1, ruParticiple2); ok {
+				w = w[:end]
+			}
+		} else if end, ok := ruEnding(w, rv, ruVerb1, ruVerb2); ok {
+			w = w[:end]
+		} else if end, ok := ruEnding(w, rv, nil, ruNoun); ok {
+			w = w[:end]
+		}
+	}
+
+	// Шаг 2
+	if end, ok := ruEnding(w, rv, nil, []string{"и"}); ok {
+		w = w[:end]
+	}
+
+	// Шаг 3
+	if end, ok := ruEnding(w, r2, nil, ruDerivational); ok {
+		w = w[:end]
+	}
+
+	// Шаг 4
+	if end, ok := ruEnding(w, rv, nil, ruSuperlative); ok {
+		w = w[:end]
+		if end, ok := ruEnding(w, rv, nil, []string{"нн"}); ok {
+			w = w[:end+1]
+		}
+	} else if end, ok := ruEnding(w, rv, nil, []string{"нн"}); ok {
+		w = w[:end+1]
+	} else if end, ok := ruEnding(w, rv, nil, []string{"ь"}); ok {
+		w = w[:end]
+	}
+
+	return string(w)
+}
+
+// ruEnding ищет самое длинное окончание из обеих групп, начинающееся не раньше limit.
+// Окончания первой группы допустимы только после «а» или «я».
+// Возвращает позицию, по которую нужно обрезать слово.
+func ruEnding(w []rune, limit int, group1, group2 []string) (int, bool) {
+	best, bestGroup1 := -1, false
+	check := func(endings []string, isGroup1 bool) {
+		for _, ending := range endings {
+			e := []rune(ending)
+			start := len(w) - len(e)
+			if start < limit || (best >= 0 && start >= best) {
+				continue
+			}
+			if string(w[start:]) == ending {
+				best, bestGroup1 = start, isGroup1
+			}
+		}
+	}
+	check(group1, true)
+	check(group2, false)
+
+	if best < 0 {
+		return 0, false
+	}
+	if bestGroup1 && (best-1 < limit || (w[best-1] != 'а' && w[best-1] != 'я')) {
+		return 0, false
+	}
+	return best, true
+}
+
+// snowballRegion возвращает начало области после первой согласной, следующей за гласной, начиная с from
+func snowballRegion(w []rune, from int, isVowel func(rune) bool) int {
+	for i := from + 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// --- Английский стеммер (Porter2) ---
+
+func isEnVowel(r rune) bool {
+	return strings.ContainsRune("aeiouy", r)
+}
+
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+
+	w := []rune(strings.TrimPrefix(word, "'"))
+	// Y в начале слова или после гласной считается согласной
+	for i := range w {
+		if w[i] == 'y' && (i == 0 || isEnVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+
+	s := &enStem{w: w}
+	s.r1 = snowballRegion(w, 0, isEnVowel)
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(string(w), prefix) {
+			s.r1 = len([]rune(prefix))
+		}
+	}
+	s.r2 = snowballRegion(w, s.r1, isEnVowel)
+
+	s.step0()
+	s.step1a()
+	s.step1b()
+	s.step1c()
+	s.step2()
+	s.step3()
+	s.step4()
+	s.step5()
+
+	return strings.ReplaceAll(string(s.w), "Y", "y")
+}
+
+type enStem struct {
+	w      []rune
+	r1, r2 int
+}
+
+func (s *enStem) hasSuffix(suffix string) bool {
+	return strings.HasSuffix(string(s.w), suffix)
+}
+
+// longest возвращает самый длинный суффикс из списка, которым оканчивается слово
+func (s *enStem) longest(suffixes ...string) string {
+	best := ""
+	for _, suffix := range suffixes {
+		if len(suffix) > len(best) && s.hasSuffix(suffix) {
+			best = suffix
+		}
+	}
+	return best
+}
+
+func (s *enStem) replace(suffix, with string) {
+	s.w = append(s.w[:len(s.w)-len([]rune(suffix))], []rune(with)...)
+}
+
+func (s *enStem) suffixStart(suffix string) int {
+	return len(s.w) - len([]rune(suffix))
+}
+
+func (s *enStem) hasVowelBefore(pos int) bool {
+	for _, r := range s.w[:pos] {
+		if isEnVowel(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsShortSyllable проверяет, оканчивается ли слово на короткий слог
+func (s *enStem) endsShortSyllable() bool {
+	w, n := s.w, len(s.w)
+	if n == 2 {
+		return isEnVowel(w[0]) && !isEnVowel(w[1])
+	}
+	if n >= 3 {
+		return !isEnVowel(w[n-3]) && isEnVowel(w[n-2]) && !isEnVowel(w[n-1]) && !strings.ContainsRune("wxY", w[n-1])
+	}
+	return false
+}
+
+func (s *enStem) isShort() bool {
+	return s.r1 >= len(s.w) && s.endsShortSyllable()
+}
+
+func (s *enStem) step0() {
+	if suffix := s.longest("'", "'s", "'s'"); suffix != "" {
+		s.replace(suffix, "")
+	}
+}
+
+func (s *enStem) step1a() {
+	switch suffix := s.longest("sses", "ied", "ies", "s", "us", "ss"); suffix {
+	case "sses":
+		s.replace(suffix, "ss")
+	case "ied", "ies":
+		if s.suffixStart(suffix) > 1 {
+			s.replace(suffix, "i")
+		} else {
+			s.replace(suffix, "ie")
+		}
+	case "s":
+		if s.hasVowelBefore(len(s.w) - 2) {
+			s.replace(suffix, "")
+		}
+	}
+}
+
+func (s *enStem) step1b() {
+	suffix := s.longest("eed", "eedly", "ed", "edly", "ing", "ingly")
+	switch suffix {
+	case "":
+		return
+	case "eed", "eedly":
+		if s.suffixStart(suffix) >= s.r1 {
+			s.replace(suffix, "ee")
+		}
+		return
+	}
+
+	if !s.hasVowelBefore(s.suffixStart(suffix)) {
+		return
+	}
+	s.replace(suffix, "")
+
+	switch {
+	case s.hasSuffix("at"), s.hasSuffix("bl"), s.hasSuffix("iz"):
+		s.w = append(s.w, 'e')
+	case s.longest("bb", "dd", "ff", "gg", "mm", "nn", "pp", "rr", "tt") != "":
+		s.w = s.w[:len(s.w)-1]
+	case s.isShort():
+		s.w = append(s.w, 'e')
+	}
+}
+
+func (s *enStem) step1c() {
+	n := len(s.w)
+	if n > 2 && (s.w[n-1] == 'y' || s.w[n-1] == 'Y') && !isEnVowel(s.w[n-2]) {
+		s.w[n-1] = 'i'
+	}
+}
+
+var enStep2 = map[string]string{
+	"tional": "tion", "enci": "ence", "anci": "ance", "abli": "able", "entli": "ent",
+	"izer": "ize", "ization": "ize", "ational": "ate", "ation": "ate", "ator": "ate",
+	"alism": "al", "aliti": "al", "alli": "al", "fulness": "ful", "ousli": "ous",
+	"ousness": "ous", "iveness": "ive", "iviti": "ive", "biliti": "ble", "bli": "ble",
+	"ogi": "og", "fulli": "ful", "lessli": "less", "li": "",
+}
+
+func (s *enStem) step2() {
+	suffix := s.longestOf(enStep2)
+	if suffix == "" || s.suffixStart(suffix) < s.r1 {
+		return
+	}
+
+	before := s.suffixStart(suffix) - 1
+	switch suffix {
+	case "ogi":
+		if before < 0 || s.w[before] != 'l' {
+			return
+		}
+	case "li":
+		if before < 0 || !strings.ContainsRune("cdeghkmnrt", s.w[before]) {
+			return
+		}
+	}
+	s.replace(suffix, enStep2[suffix])
+}
+
+var enStep3 = map[string]string{
+	"tional": "tion", "ational": "ate", "alize": "al", "icate": "ic", "iciti": "ic",
+	"ical": "ic", "ful": "", "ness": "", "ative": "",
+}
+
+func (s *enStem) step3() {
+	suffix := s.longestOf(enStep3)
+	if suffix == "" || s.suffixStart(suffix) < s.r1 {
+		return
+	}
+	if suffix == "ative" && s.suffixStart(suffix) < s.r2 {
+		return
+	}
+	s.replace(suffix, enStep3[suffix])
+}
+
+func (s *enStem) step4() {
+	suffix := s.longest("al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement",
+		"ment", "ent", "ism", "ate", "iti", "ous", "ive", "ize", "ion")
+	if suffix == "" || s.suffixStart(suffix) < s.r2 {
+		return
+	}
+
+	if suffix == "ion" {
+		before := s.suffixStart(suffix) - 1
+		if before < 0 || (s.w[before] != 's' && s.w[before] != 't') {
+			return
+		}
+	}
+	s.replace(suffix, "")
+}
+
+func (s *enStem) step5() {
+	n := len(s.w)
+	switch {
+	case n > 0 && s.w[n-1] == 'e':
+		if n-1 >= s.r2 {
+			s.w = s.w[:n-1]
+			return
+		}
+		if n-1 >= s.r1 {
+			s.w = s.w[:n-1]
+			if s.endsShortSyllable() {
+				s.w = append(s.w, 'e')
+			}
+		}
+	case n > 1 && s.w[n-1] == 'l' && s.w[n-2] == 'l' && n-1 >= s.r2:
+		s.w = s.w[:n-1]
+	}
+}
+
+func (s *enStem) longestOf(suffixes map[string]string) string {
+	best := ""
+	for suffix := range suffixes {
+		if len(suffix) > len(best) && s.hasSuffix(suffix) {
+			best = suffix
+		}
+	}
+	return best
+}
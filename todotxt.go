@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Формат todo.txt (https://github.com/todotxt/todo.txt): одна задача на строку
+//
+//	x (A) 2024-05-02 2024-05-01 Сдать отчет +работа @срочно due:2024-05-03 id:7
+//
+// Соответствие полям Task:
+//   - "x " в начале — Completed; дата завершения берется из UpdatedAt;
+//   - (A), (B), (C) — приоритеты high, medium, low; у выполненных задач приоритет
+//     сохраняется расширением pri:A, как это делают todo.sh и совместимые клиенты;
+//   - дата создания — CreatedAt;
+//   - +проект — Project, @контекст — Tags;
+//   - расширения due:, id: и description: — DueDate, ID и Description.
+//
+// Пробелы, переводы строк и знак % в значениях кодируются как %20, %0A и %25. Слова
+// заголовка, которые иначе разобрались бы как разметка (+проект, @контекст, известные
+// расширения, а в начале — x, приоритет или дата), кодируются по первому символу:
+// %2Bслово, %78.
+// Остальные расширения key:value остаются в заголовке, чтобы не потерять их при обратном экспорте.
+
+const todoTxtDateLayout = "2006-01-02"
+
+// todoTxtPriorities сопоставляет приоритеты API буквам todo.txt
+var todoTxtPriorities = map[Priority]string{
+	PriorityHigh:   "A",
+	PriorityMedium: "B",
+	PriorityLow:    "C",
+}
+
+// FormatTodoTxt сериализует задачу в строку todo.txt
+func FormatTodoTxt(task *Task) string {
+	var parts []string
+
+	if task.Completed {
+		parts = append(parts, "x")
+		if !task.UpdatedAt.IsZero() {
+			parts = append(parts, task.UpdatedAt.Format(todoTxtDateLayout))
+		}
+	} else if letter, ok := todoTxtPriorities[task.Priority]; ok {
+		parts = append(parts, "("+letter+")")
+	}
+	if !task.CreatedAt.IsZero() {
+		parts = append(parts, task.CreatedAt.Format(todoTxtDateLayout))
+	}
+
+	for i, word := range strings.Fields(task.Title) {
+		parts = append(parts, escapeTodoTxtWord(word, i == 0))
+	}
+	if task.Project != "" {
+		parts = append(parts, "+"+escapeTodoTxt(task.Project))
+	}
+	for _, tag := range task.Tags {
+		parts = append(parts, "@"+escapeTodoTxt(tag))
+	}
+
+	if task.Completed {
+		if letter, ok := todoTxtPriorities[task.Priority]; ok {
+			parts = append(parts, "pri:"+letter)
+		}
+	}
+	if task.DueDate != nil {
+		parts = append(parts, "due:"+formatTodoTxtDue(*task.DueDate))
+	}
+	if task.Description != "" {
+		parts = append(parts, "description:"+escapeTodoTxt(task.Description))
+	}
+	if task.ID != 0 {
+		parts = append(parts, "id:"+strconv.Itoa(task.ID))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ParseTodoTxt разбирает строку todo.txt в задачу
+func ParseTodoTxt(line string) (*Task, error) {
+	fields := strings.Fields(line)
+	task := &Task{}
+
+	// Признак выполнения и дата завершения
+	if len(fields) > 0 && fields[0] == "x" {
+		task.Completed = true
+		fields = fields[1:]
+		if len(fields) > 0 {
+			if date, ok := parseTodoTxtDate(fields[0]); ok {
+				task.UpdatedAt = date
+				fields = fields[1:]
+			}
+		}
+	}
+
+	// Приоритет (A)–(Z); буквы после C считаются низким приоритетом
+	if len(fields) > 0 && !task.Completed {
+		if priority, ok := parseTodoTxtPriority(fields[0]); ok {
+			task.Priority = priority
+			fields = fields[1:]
+		}
+	}
+
+	// Дата создания
+	if len(fields) > 0 {
+		if date, ok := parseTodoTxtDate(fields[0]); ok {
+			task.CreatedAt = date
+			fields = fields[1:]
+		}
+	}
+
+	var title []string
+	projectField, projectIndex := "", 0
+	for _, field := range fields {
+		switch {
+		case len(field) > 1 && field[0] == '+':
+			// Проектом считается последний +проект, остальные остаются в заголовке на своих местах
+			if projectField != "" {
+				title = append(title[:projectIndex], append([]string{projectField}, title[projectIndex:]...)...)
+			}
+			projectField, projectIndex = field, len(title)
+			task.Project = unescapeTodoTxt(field[1:])
+		case len(field) > 1 && field[0] == '@':
+			task.Tags = append(task.Tags, unescapeTodoTxt(field[1:]))
+		default:
+			handled, err := parseTodoTxtExtension(task, field)
+			if err != nil {
+				return nil, err
+			}
+			if !handled {
+				title = append(title, unescapeTodoTxt(field))
+			}
+		}
+	}
+
+	task.Title = strings.Join(title, " ")
+	if err := validateImportedTask(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// parseTodoTxtExtension разбирает известные расширения key:value; false означает,
+// что поле не является известным расширением и относится к заголовку
+func parseTodoTxtExtension(task *Task, field string) (bool, error) {
+	key, value, found := strings.Cut(field, ":")
+	if !found || value == "" {
+		return false, nil
+	}
+
+	switch key {
+	case "due":
+		due, err := parseImportTime("due", unescapeTodoTxt(value))
+		if err != nil {
+			return false, err
+		}
+		task.DueDate = due
+	case "id":
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return false, fmt.Errorf("неверный id '%s'", value)
+		}
+		task.ID = id
+	case "pri":
+		priority, ok := parseTodoTxtPriority("(" + value + ")")
+		if !ok {
+			return false, fmt.Errorf("неверный приоритет '%s'", value)
+		}
+		task.Priority = priority
+	case "description":
+		task.Description = unescapeTodoTxt(value)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseTodoTxtPriority разбирает приоритет вида (A)
+func parseTodoTxtPriority(field string) (Priority, bool) {
+	if len(field) != 3 || field[0] != '(' || field[2] != ')' || field[1] < 'A' || field[1] > 'Z' {
+		return PriorityNone, false
+	}
+	for priority, letter := range todoTxtPriorities {
+		if letter == field[1:2] {
+			return priority, true
+		}
+	}
+	return PriorityLow, true
+}
+
+// parseTodoTxtDate разбирает дату в формате YYYY-MM-DD
+func parseTodoTxtDate(field string) (time.Time, bool) {
+	date, err := time.ParseInLocation(todoTxtDateLayout, field, time.Local)
+	return date, err == nil
+}
+
+// formatTodoTxtDue форматирует срок: только дата, если срок приходится на полночь,
+// иначе полная отметка времени, чтобы не потерять время
+func formatTodoTxtDue(due time.Time) string {
+	local := due.In(time.Local)
+	if local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 && local.Nanosecond() == 0 {
+		return local.Format(todoTxtDateLayout)
+	}
+	return due.Format(time.RFC3339)
+}
+
+// todoTxtEscaper кодирует символы, которые нельзя записать в поле todo.txt
+var todoTxtEscaper = strings.NewReplacer("%", "%25", " ", "%20", "\t", "%09", "\r", "%0D", "\n", "%0A")
+
+func escapeTodoTxt(value string) string {
+	return todoTxtEscaper.Replace(value)
+}
+
+// escapeTodoTxtWord кодирует слово заголовка так, чтобы при разборе оно осталось
+// в заголовке; first — слово стоит в начале заголовка
+func escapeTodoTxtWord(word string, first bool) string {
+	word = escapeTodoTxt(word)
+	if todoTxtMarkup(word, first) {
+		return fmt.Sprintf("%%%02X", word[0]) + word[1:]
+	}
+	return word
+}
+
+// todoTxtMarkup сообщает, разберет ли ParseTodoTxt слово как разметку, а не заголовок
+func todoTxtMarkup(word string, first bool) bool {
+	if len(word) > 1 && (word[0] == '+' || word[0] == '@') {
+		return true
+	}
+	if key, value, found := strings.Cut(word, ":"); found && value != "" {
+		switch key {
+		case "due", "id", "pri", "description":
+			return true
+		}
+	}
+	if !first {
+		return false
+	}
+	_, priority := parseTodoTxtPriority(word)
+	_, date := parseTodoTxtDate(word)
+	return word == "x" || priority || date
+}
+
+// unescapeTodoTxt декодирует значение; некорректные последовательности остаются как есть
+func unescapeTodoTxt(value string) string {
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+// todoTxtEncoder записывает задачи по одной на строку
+type todoTxtEncoder struct {
+	writer *bufio.Writer
+}
+
+func newTodoTxtEncoder(w io.Writer) taskEncoder {
+	return &todoTxtEncoder{writer: bufio.NewWriter(w)}
+}
+
+func (e *todoTxtEncoder) Encode(task *Task) error {
+	_, err := e.writer.WriteString(FormatTodoTxt(task) + "\n")
+	return err
+}
+
+func (e *todoTxtEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+// decodeTodoTxt читает задачи из файла todo.txt; пустые строки пропускаются
+func decodeTodoTxt(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var rows []ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		task, err := ParseTodoTxt(text)
+		rows = append(rows, ImportRow{Line: line, Task: task, Err: err})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать todo.txt: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoTxt(t *testing.T) {
+	task, err := ParseTodoTxt("(A) 2024-05-01 Позвонить маме +семья @телефон rec:1w due:2024-05-03")
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if task.Priority != PriorityHigh || task.Project != "семья" || strings.Join(task.Tags, ",") != "телефон" {
+		t.Errorf("Неверно разобраны приоритет, проект или теги: %+v", task)
+	}
+	// Неизвестные расширения остаются в заголовке
+	if task.Title != "Позвонить маме rec:1w" {
+		t.Errorf("Ожидался заголовок 'Позвонить маме rec:1w', получен '%s'", task.Title)
+	}
+	if task.CreatedAt.Format(todoTxtDateLayout) != "2024-05-01" || task.DueDate == nil || task.DueDate.Format(todoTxtDateLayout) != "2024-05-03" {
+		t.Errorf("Неверно разобраны даты: %v %v", task.CreatedAt, task.DueDate)
+	}
+
+	task, err = ParseTodoTxt("x 2024-05-02 2024-05-01 Сдать отчет pri:B")
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if !task.Completed || task.Priority != PriorityMedium || task.UpdatedAt.Format(todoTxtDateLayout) != "2024-05-02" {
+		t.Errorf("Неверно разобрана выполненная задача: %+v", task)
+	}
+
+	// Проектом считается последний +проект
+	task, _ = ParseTodoTxt("Обсудить +план с +работа")
+	if task.Project != "работа" || task.Title != "Обсудить +план с" {
+		t.Errorf("Ожидались проект 'работа' и заголовок 'Обсудить +план с', получено %q и %q", task.Project, task.Title)
+	}
+
+	for _, line := range []string{"(A) +проект @тег", "Задача due:завтра", "Задача id:первая"} {
+		if _, err := ParseTodoTxt(line); err == nil {
+			t.Errorf("ParseTodoTxt(%q): ожидалась ошибка", line)
+		}
+	}
+}
+
+func TestTodoTxt_RoundTrip(t *testing.T) {
+	due := time.Date(2024, 5, 3, 18, 30, 0, 0, time.UTC)
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	tasks := []*Task{
+		{
+			ID:          3,
+			Title:       "Сдать отчет",
+			Description: "за 100% квартал\nс графиками",
+			Completed:   true,
+			Tags:        []string{"срочно", "отдел продаж"},
+			Priority:    PriorityHigh,
+			Project:     "Годовой план",
+			DueDate:     &due,
+			CreatedAt:   created,
+			UpdatedAt:   created.AddDate(0, 0, 1),
+		},
+		{ID: 4, Title: "Купить молоко", Priority: PriorityLow, CreatedAt: created, UpdatedAt: created},
+		// Слова заголовка, похожие на разметку todo.txt, остаются в заголовке
+		{ID: 5, Title: "Написать @ivan", Tags: []string{"почта"}},
+		{ID: 6, Title: "Обсудить +план"},
+		{ID: 7, Title: "x marks the spot"},
+		{ID: 8, Title: "Перенести due:soon и id:abc на pri:Z"},
+		{ID: 9, Title: "(A) 2024-05-01 description:нет"},
+		{ID: 10, Title: "Скидка 100% %20"},
+	}
+
+	format, _ := LookupTaskFormat("todotxt")
+	var buf bytes.Buffer
+	if err := format.ExportTasks(&buf, tasks); err != nil {
+		t.Fatalf("Ошибка экспорта: %v", err)
+	}
+
+	rows, err := format.DecodeTasks(&buf)
+	if err != nil {
+		t.Fatalf("Ошибка разбора: %v", err)
+	}
+
+	for i, row := range rows {
+		if row.Err != nil {
+			t.Fatalf("Строка %d: %v", row.Line, row.Err)
+		}
+		want, got := tasks[i], row.Task
+		if got.ID != want.ID || got.Title != want.Title || got.Description != want.Description ||
+			got.Completed != want.Completed || got.Priority != want.Priority || got.Project != want.Project ||
+			strings.Join(got.Tags, ",") != strings.Join(want.Tags, ",") {
+			t.Errorf("Задача %d не совпадает:\nожидалось %+v\nполучено %+v", want.ID, want, got)
+		}
+		if (want.DueDate == nil) != (got.DueDate == nil) || (want.DueDate != nil && !got.DueDate.Equal(*want.DueDate)) {
+			t.Errorf("Задача %d: срок не совпадает: %v", want.ID, got.DueDate)
+		}
+		if !got.CreatedAt.Equal(want.CreatedAt) {
+			t.Errorf("Задача %d: дата создания не совпадает: %v", want.ID, got.CreatedAt)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer возвращает трассировщик глобального провайдера. Пока трассировка не настроена
+// (SetupTracing), провайдер ничего не записывает, и spans почти ничего не стоят.
+func tracer() trace.Tracer {
+	return otel.Tracer("todo-api")
+}
+
+// tracingFlushTimeout ограничивает запись накопленных spans при остановке
+const tracingFlushTimeout = 5 * time.Second
+
+// TracingExporter — куда записываются завершенные spans
+type TracingExporter string
+
+const (
+	// TracingExporterOff отключает трассировку
+	TracingExporterOff TracingExporter = "off"
+	// TracingExporterStdout пишет spans в stdout, по JSON объекту на строку
+	TracingExporterStdout TracingExporter = "stdout"
+	// TracingExporterFile дописывает spans в файл tracing.path в том же формате
+	TracingExporterFile TracingExporter = "file"
+)
+
+// ParseTracingExporter разбирает экспортер трассировки; пустая строка означает off
+func ParseTracingExporter(s string) (TracingExporter, error) {
+	switch exporter := TracingExporter(strings.ToLower(strings.TrimSpace(s))); exporter {
+	case "", TracingExporterOff:
+		return TracingExporterOff, nil
+	case TracingExporterStdout, TracingExporterFile:
+		return exporter, nil
+	}
+	return "", fmt.Errorf("экспортер трассировки должен быть off, stdout или file, получено %q", s)
+}
+
+// SetupTracing настраивает глобальный провайдер трассировки и распространение контекста
+// в заголовках W3C traceparent и tracestate. Возвращенная функция дописывает
+// накопленные spans и закрывает файл; ее нужно вызвать при остановке сервера.
+func SetupTracing(config TracingConfig) (func(ctx context.Context) error, error) {
+	exporter, err := ParseTracingExporter(config.Exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	var out io.Writer
+	closeOut := func() error { return nil }
+	switch exporter {
+	case TracingExporterOff:
+		return func(context.Context) error { return nil }, nil
+	case TracingExporterStdout:
+		out = os.Stdout
+	case TracingExporterFile:
+		file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось открыть файл трассировки: %w", err)
+		}
+		out, closeOut = file, file.Close
+	}
+
+	spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		closeOut()
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName("todo-api"),
+			semconv.ServiceVersion("1.0.0"),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeOut())
+	}, nil
+}
+
+// TracingMiddleware возвращает middleware, которое начинает span сервера для каждого
+// запроса к маршрутизатору routes. Если клиент передал traceparent, span продолжает
+// его трассировку. Имя span — метод и шаблон маршрута, как метка route в метриках.
+func TracingMiddleware(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			name := r.Method
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			}
+			if pattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path); pattern != "" {
+				route := openAPIPath(pattern)
+				name += " " + route
+				attrs = append(attrs, semconv.HTTPRoute(route))
+			}
+
+			ctx, span := tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// startHandlerSpan начинает span обработчика и возвращает запрос с его контекстом
+func startHandlerSpan(r *http.Request, name string) (*http.Request, trace.Span) {
+	ctx, span := tracer().Start(r.Context(), name)
+	return r.WithContext(ctx), span
+}
+
+// decodeJSON разбирает JSON тело запроса в v; разбор записывается отдельным span,
+// чтобы медленный клиент или большое тело было видно в трассировке
+func decodeJSON(r *http.Request, v any) error {
+	_, span := tracer().Start(r.Context(), "json.Decode")
+	defer span.End()
+
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// TraceTaskService возвращает обертку, которая записывает span на каждый вызов service.
+// Spans создаются только для вызовов, контекст которых принадлежит трассируемому запросу.
+func TraceTaskService(service ServerTaskService) ServerTaskService {
+	return &tracedTaskService{next: service}
+}
+
+// tracedTaskService передает вызовы next внутри span "TaskService.<метод>"
+type tracedTaskService struct {
+	next ServerTaskService
+}
+
+// startServiceSpan начинает span операции сервиса задач
+func startServiceSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return startChildSpan(ctx, "TaskService."+operation)
+}
+
+// startChildSpan начинает span name, дочерний для span из ctx; вне трассируемого
+// запроса span не создается, чтобы фоновые вызовы не порождали корневых spans
+func startChildSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer().Start(ctx, name)
+}
+
+// endWithError завершает span, отмечая в нем ошибку операции
+func endWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedTaskService) CreateTask(ctx context.Context, title, description string, opts ...TaskOption) *Task {
+	ctx, span := startServiceSpan(ctx, "CreateTask")
+	defer span.End()
+	return s.next.CreateTask(ctx, title, description, opts...)
+}
+
+func (s *tracedTaskService) GetTask(ctx context.Context, id int) (*Task, error) {
+	ctx, span := startServiceSpan(ctx, "GetTask")
+	task, err := s.next.GetTask(ctx, id)
+	endWithError(span, err)
+	return task, err
+}
+
+func (s *tracedTaskService) GetAllTasks(ctx context.Context) []*Task {
+	ctx, span := startServiceSpan(ctx, "GetAllTasks")
+	defer span.End()
+	return s.next.GetAllTasks(ctx)
+}
+
+func (s *tracedTaskService) FilterTasks(ctx context.Context, match func(*Task) bool) []*Task {
+	ctx, span := startServiceSpan(ctx, "FilterTasks")
+	defer span.End()
+	return s.next.FilterTasks(ctx, match)
+}
+
+func (s *tracedTaskService) UpdateTask(ctx context.Context, id int, title, description string, completed bool, opts ...TaskOption) (*Task, error) {
+	ctx, span := startServiceSpan(ctx, "UpdateTask")
+	task, err := s.next.UpdateTask(ctx, id, title, description, completed, opts...)
+	endWithError(span, err)
+	return task, err
+}
+
+func (s *tracedTaskService) DeleteTask(ctx context.Context, id int) error {
+	ctx, span := startServiceSpan(ctx, "DeleteTask")
+	err := s.next.DeleteTask(ctx, id)
+	endWithError(span, err)
+	return err
+}
+
+func (s *tracedTaskService) ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchItemResult, error) {
+	ctx, span := startServiceSpan(ctx, "ApplyBatch")
+	results, err := s.next.ApplyBatch(ctx, ops, atomic)
+	endWithError(span, err)
+	return results, err
+}
+
+func (s *tracedTaskService) ImportTasks(ctx context.Context, tasks []*Task, opts ImportOptions) []BatchItemResult {
+	ctx, span := startServiceSpan(ctx, "ImportTasks")
+	defer span.End()
+	return s.next.ImportTasks(ctx, tasks, opts)
+}
+
+func (s *tracedTaskService) SearchTasks(ctx context.Context, query string, limit int) []*SearchResult {
+	ctx, span := startServiceSpan(ctx, "SearchTasks")
+	defer span.End()
+	return s.next.SearchTasks(ctx, query, limit)
+}
+
+func (s *tracedTaskService) FuzzySearchTasks(ctx context.Context, query string, limit int) []*SearchResult {
+	ctx, span := startServiceSpan(ctx, "FuzzySearchTasks")
+	defer span.End()
+	return s.next.FuzzySearchTasks(ctx, query, limit)
+}
+
+func (s *tracedTaskService) SuggestTasks(ctx context.Context, input string, limit int) *Suggestions {
+	ctx, span := startServiceSpan(ctx, "SuggestTasks")
+	defer span.End()
+	return s.next.SuggestTasks(ctx, input, limit)
+}
+
+// Subscribe не трассируется: подписка живет столько, сколько открыт поток
+func (s *tracedTaskService) Subscribe(ctx context.Context) (<-chan TaskEvent, func()) {
+	return s.next.Subscribe(ctx)
+}
+
+// TraceAttachmentService возвращает обертку, которая записывает span
+// "AttachmentService.<метод>" на каждый вызов service внутри трассируемого запроса
+func TraceAttachmentService(service AttachmentServiceInterface) AttachmentServiceInterface {
+	return &tracedAttachmentService{next: service}
+}
+
+// tracedAttachmentService передает вызовы next внутри span "AttachmentService.<метод>"
+type tracedAttachmentService struct {
+	next AttachmentServiceInterface
+}
+
+func (s *tracedAttachmentService) AddAttachment(ctx context.Context, taskID int, filename string, r io.Reader) (*Attachment, error) {
+	ctx, span := startChildSpan(ctx, "AttachmentService.AddAttachment")
+	attachment, err := s.next.AddAttachment(ctx, taskID, filename, r)
+	endWithError(span, err)
+	return attachment, err
+}
+
+func (s *tracedAttachmentService) GetAttachments(ctx context.Context, taskID int) ([]*Attachment, error) {
+	ctx, span := startChildSpan(ctx, "AttachmentService.GetAttachments")
+	attachments, err := s.next.GetAttachments(ctx, taskID)
+	endWithError(span, err)
+	return attachments, err
+}
+
+func (s *tracedAttachmentService) GetAttachmentsForTasks(ctx context.Context, taskIDs []int) map[int][]*Attachment {
+	ctx, span := startChildSpan(ctx, "AttachmentService.GetAttachmentsForTasks")
+	defer span.End()
+	return s.next.GetAttachmentsForTasks(ctx, taskIDs)
+}
+
+func (s *tracedAttachmentService) OpenAttachment(ctx context.Context, taskID, id int) (*Attachment, io.ReadSeekCloser, error) {
+	ctx, span := startChildSpan(ctx, "AttachmentService.OpenAttachment")
+	attachment, content, err := s.next.OpenAttachment(ctx, taskID, id)
+	endWithError(span, err)
+	return attachment, content, err
+}
+
+func (s *tracedAttachmentService) DeleteAttachment(ctx context.Context, taskID, id int) error {
+	ctx, span := startChildSpan(ctx, "AttachmentService.DeleteAttachment")
+	err := s.next.DeleteAttachment(ctx, taskID, id)
+	endWithError(span, err)
+	return err
+}
+
+func (s *tracedAttachmentService) DeleteTaskAttachments(ctx context.Context, taskID int) {
+	ctx, span := startChildSpan(ctx, "AttachmentService.DeleteTaskAttachments")
+	defer span.End()
+	s.next.DeleteTaskAttachments(ctx, taskID)
+}
+
+// traceLockWait захватывает мьютекс через acquire; если ctx трассируется, ожидание
+// записывается span name, чтобы конкуренцию за блокировку было видно отдельно
+func traceLockWait(ctx context.Context, name string, acquire func()) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		acquire()
+		return
+	}
+	_, span := tracer().Start(ctx, name)
+	acquire()
+	span.End()
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordSpans направляет spans в память до конца теста
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	return recorder
+}
+
+// spansByName индексирует завершенные spans по имени
+func spansByName(t *testing.T, recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+// spanAttribute возвращает значение атрибута span
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// newTracedRouter собирает маршрутизатор с трассировкой и сервисом задач в обертке
+func newTracedRouter() *chi.Mux {
+	handler := NewTaskHandler(TraceTaskService(NewTaskService()))
+	r := chi.NewRouter()
+	r.Use(TracingMiddleware(r))
+	r.Post("/tasks", handler.CreateTask)
+	r.Get("/tasks/{id}", handler.GetTask)
+	return r
+}
+
+func TestTracing_RequestSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	router := newTracedRouter()
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"Купить молоко"}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Неверный статус: %d %s", w.Code, w.Body.String())
+	}
+
+	spans := spansByName(t, recorder)
+	server := spans["POST /tasks"]
+	if server == nil {
+		t.Fatalf("Нет span запроса: %v", spans)
+	}
+	// Span запроса продолжает трассировку клиента
+	if server.SpanContext().TraceID().String() != traceID || server.Parent().SpanID().String() != parentID {
+		t.Errorf("Span запроса не продолжает traceparent: %v, родитель %v", server.SpanContext(), server.Parent())
+	}
+	if spanAttribute(server, "http.route").AsString() != "/tasks" || spanAttribute(server, "http.response.status_code").AsInt64() != http.StatusCreated {
+		t.Errorf("Неверные атрибуты span запроса: %v", server.Attributes())
+	}
+
+	// Каждый span вложен в предыдущий уровень: запрос → обработчик → разбор JSON и сервис → мьютекс
+	parents := map[string]string{
+		"TaskHandler.CreateTask": "POST /tasks",
+		"json.Decode":            "TaskHandler.CreateTask",
+		"TaskService.CreateTask": "TaskHandler.CreateTask",
+		"TaskService.mutex.Lock": "TaskService.CreateTask",
+	}
+	for name, parent := range parents {
+		span := spans[name]
+		if span == nil {
+			t.Errorf("Нет span %s", name)
+			continue
+		}
+		if span.Parent().SpanID() != spans[parent].SpanContext().SpanID() {
+			t.Errorf("Родитель span %s должен быть %s", name, parent)
+		}
+		if span.SpanContext().TraceID().String() != traceID {
+			t.Errorf("Span %s из другой трассировки", name)
+		}
+	}
+}
+
+func TestTracing_ErrorStatus(t *testing.T) {
+	recorder := recordSpans(t)
+	router := newTracedRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/99", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Неверный статус: %d", w.Code)
+	}
+
+	spans := spansByName(t, recorder)
+	if span := spans["TaskService.GetTask"]; span == nil || span.Status().Code != codes.Error {
+		t.Errorf("Ошибка сервиса должна отмечаться в span: %+v", span)
+	}
+	if span := spans["TaskService.mutex.RLock"]; span == nil {
+		t.Errorf("Нет span ожидания мьютекса на чтение")
+	}
+	// Ответ 4xx — ошибка клиента, а не сервера
+	if span := spans["GET /tasks/{id}"]; span == nil || span.Status().Code == codes.Error {
+		t.Errorf("Span запроса с 404 не должен быть ошибкой: %+v", span)
+	}
+}
+
+func TestTracing_TransportSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	service := TraceTaskService(NewTaskService())
+	store, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	graphQL := newTestGraphQLHandler(t, service, NewAttachmentService(service, store), DefaultGraphQLMaxDepth, DefaultGraphQLMaxComplexity)
+	router := SetupRoutes(NewTaskHandler(service), WithGraphQL(graphQL), WithJSONRPC(NewJSONRPCServer(service)))
+
+	// Резолверы GraphQL и методы JSON-RPC передают сервису контекст запроса,
+	// поэтому span сервиса — дочерний для span запроса
+	tests := []struct {
+		path, body, request, operation string
+	}{
+		{"/graphql", `{"query":"mutation { createTask(input: {title: \"Купить молоко\"}) { id } }"}`, "POST /graphql", "TaskService.CreateTask"},
+		{"/rpc", `{"jsonrpc":"2.0","method":"TaskService.GetTask","params":{"id":1},"id":1}`, "POST /rpc", "TaskService.GetTask"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"error`) {
+			t.Fatalf("%s: неверный ответ %d %s", tt.path, w.Code, w.Body.String())
+		}
+
+		spans := spansByName(t, recorder)
+		request, operation := spans[tt.request], spans[tt.operation]
+		if request == nil || operation == nil {
+			t.Fatalf("%s: нет span запроса или сервиса: %v", tt.path, spans)
+		}
+		if operation.Parent().SpanID() != request.SpanContext().SpanID() {
+			t.Errorf("Родитель span %s должен быть %s", tt.operation, tt.request)
+		}
+		if spans["TaskService.mutex.Lock"] == nil && spans["TaskService.mutex.RLock"] == nil {
+			t.Errorf("%s: нет span ожидания мьютекса", tt.path)
+		}
+	}
+}
+
+func TestTracing_AttachmentSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	tasks := NewTaskService()
+	service := TraceTaskService(tasks)
+	store, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachments := TraceAttachmentService(NewAttachmentService(service, store))
+	tasks.OnDelete(attachments.DeleteTaskAttachments)
+
+	ctx, request := tracer().Start(t.Context(), "request")
+	task := service.CreateTask(ctx, "Отчет", "")
+	attachment, err := attachments.AddAttachment(ctx, task.ID, "notes.txt", strings.NewReader("заметки"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, content, err := attachments.OpenAttachment(ctx, task.ID, attachment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content.Close()
+	service.DeleteTask(ctx, task.ID)
+	request.End()
+
+	// Удаление вложений вызывается hook сервиса задач и попадает в span удаления задачи
+	spans := spansByName(t, recorder)
+	parents := map[string]string{
+		"AttachmentService.AddAttachment":         "request",
+		"AttachmentService.OpenAttachment":        "request",
+		"AttachmentService.DeleteTaskAttachments": "TaskService.DeleteTask",
+	}
+	for name, parent := range parents {
+		span := spans[name]
+		if span == nil {
+			t.Errorf("Нет span %s", name)
+			continue
+		}
+		if span.Parent().SpanID() != spans[parent].SpanContext().SpanID() {
+			t.Errorf("Родитель span %s должен быть %s", name, parent)
+		}
+	}
+}
+
+func TestTracing_WithoutRequest(t *testing.T) {
+	recorder := recordSpans(t)
+
+	// Вызовы вне трассируемого запроса (например, с Unix сокета JSON-RPC) не создают корневых spans
+	service := TraceTaskService(NewTaskService())
+	service.CreateTask(context.Background(), "Купить молоко", "")
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("Ожидалось отсутствие spans, получено %d", len(spans))
+	}
+}
+
+func TestSetupTracing(t *testing.T) {
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	if _, err := SetupTracing(TracingConfig{Exporter: "jaeger"}); err == nil {
+		t.Errorf("Ожидалась ошибка неизвестного экспортера")
+	}
+	shutdown, err := SetupTracing(TracingConfig{Exporter: "off"})
+	if err != nil || shutdown(context.Background()) != nil {
+		t.Errorf("Выключенная трассировка должна настраиваться без ошибок: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err = SetupTracing(TracingConfig{Exporter: "file", Path: path})
+	if err != nil {
+		t.Fatalf("Ошибка настройки трассировки: %v", err)
+	}
+	_, span := tracer().Start(context.Background(), "TaskHandler.GetTasks")
+	span.End()
+	// Spans накапливаются и записываются при остановке
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Ошибка остановки трассировки: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"TaskHandler.GetTasks"`) || !strings.Contains(string(data), "todo-api") {
+		t.Errorf("В файле трассировки нет span:\n%s", data)
+	}
+}
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxImportSize ограничивает размер импортируемого файла
+const MaxImportSize = 32 << 20
+
+// taskColumns — колонки CSV и Markdown-таблицы в порядке экспорта
+var taskColumns = []string{
+	"id", "title", "description", "completed", "tags", "priority", "project", "due_date", "created_at", "updated_at",
+}
+
+// TaskFormat описывает формат экспорта и импорта задач
+type TaskFormat struct {
+	Name        string
+	ContentType string
+	Extension   string
+	newEncoder  func(w io.Writer) taskEncoder // nil для форматов, которые только импортируются
+	decode      func(r io.Reader) ([]ImportRow, error)
+	matchUID    bool // импорт обновляет задачи с тем же UID
+}
+
+// taskEncoder последовательно записывает задачи в поток
+type taskEncoder interface {
+	Encode(task *Task) error
+	Flush() error
+}
+
+// ImportRow — задача, прочитанная из одной строки файла, или ошибка разбора этой строки
+type ImportRow struct {
+	Line     int
+	Task     *Task
+	Err      error
+	Unmapped []string // поля исходной задачи, которые не удалось перенести
+}
+
+// taskFormats — поддерживаемые форматы по имени
+var taskFormats = map[string]*TaskFormat{
+	"csv": {
+		Name:        "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		newEncoder:  newCSVTaskEncoder,
+		decode:      decodeCSVTasks,
+	},
+	"ndjson": {
+		Name:        "ndjson",
+		ContentType: "application/x-ndjson",
+		Extension:   "ndjson",
+		newEncoder:  newNDJSONTaskEncoder,
+		decode:      decodeNDJSONTasks,
+	},
+	"markdown": {
+		Name:        "markdown",
+		ContentType: "text/markdown; charset=utf-8",
+		Extension:   "md",
+		newEncoder:  newMarkdownTaskEncoder,
+		decode:      decodeMarkdownTasks,
+	},
+	"todotxt": {
+		Name:        "todotxt",
+		ContentType: "text/plain; charset=utf-8",
+		Extension:   "txt",
+		newEncoder:  newTodoTxtEncoder,
+		decode:      decodeTodoTxt,
+	},
+	"ics": {
+		Name:        "ics",
+		ContentType: "text/calendar; charset=utf-8",
+		Extension:   "ics",
+		newEncoder:  newICalendarEncoder,
+		decode:      decodeICalendar,
+		matchUID:    true,
+	},
+	"todoist": {
+		Name:        "todoist",
+		ContentType: "application/json",
+		Extension:   "json",
+		decode:      decodeTodoist,
+	},
+	"todoist-csv": {
+		Name:        "todoist-csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		decode:      decodeTodoistCSV,
+	},
+	"trello": {
+		Name:        "trello",
+		ContentType: "application/json",
+		Extension:   "json",
+		decode:      decodeTrello,
+	},
+	"taskwarrior": {
+		Name:        "taskwarrior",
+		ContentType: "application/json",
+		Extension:   "json",
+		decode:      decodeTaskwarrior,
+	},
+}
+
+// LookupTaskFormat возвращает формат по имени
+func LookupTaskFormat(name string) (*TaskFormat, bool) {
+	format, ok := taskFormats[strings.ToLower(name)]
+	return format, ok
+}
+
+// TaskFormatNames возвращает имена поддерживаемых форматов для сообщений об ошибках;
+// с exportOnly — только форматы, в которые можно экспортировать
+func TaskFormatNames(exportOnly bool) string {
+	names := make([]string, 0, len(taskFormats))
+	for name, format := range taskFormats {
+		if exportOnly && !format.CanExport() {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// CanExport сообщает, поддерживает ли формат экспорт
+func (f *TaskFormat) CanExport() bool {
+	return f.newEncoder != nil
+}
+
+// ExportTasks записывает задачи в w в заданном формате
+func (f *TaskFormat) ExportTasks(w io.Writer, tasks []*Task) error {
+	encoder := f.newEncoder(w)
+	for _, task := range tasks {
+		if err := encoder.Encode(task); err != nil {
+			return err
+		}
+	}
+	return encoder.Flush()
+}
+
+// DecodeTasks читает задачи из r. Ошибки отдельных строк возвращаются в ImportRow.Err,
+// ошибка функции означает, что файл не удалось прочитать целиком.
+func (f *TaskFormat) DecodeTasks(r io.Reader) ([]ImportRow, error) {
+	return f.decode(r)
+}
+
+// ImportTasks добавляет задачи под одной блокировкой. Результаты идут в порядке задач;
+// задача с занятым ID получает ошибку ErrConflict, остальные импортируются независимо.
+// С MatchUID задача, UID которой совпадает с UID существующей, обновляет ее.
+func (ts *TaskService) ImportTasks(ctx context.Context, tasks []*Task, opts ImportOptions) []BatchItemResult {
+	ts.lock(ctx)
+	defer ts.mutex.Unlock()
+	defer ts.publishLocked(ctx)
+
+	results := make([]BatchItemResult, len(tasks))
+	nextID := ts.nextID
+	taken := make(map[int]bool)
+	now := time.Now()
+
+	var byUID map[string]*Task
+	if opts.MatchUID {
+		byUID = make(map[string]*Task, len(ts.tasks))
+		for _, task := range ts.tasks {
+			byUID[TaskUID(task)] = task
+		}
+	}
+
+	for i, source := range tasks {
+		task := *source
+		task.Tags = normalizeTags(task.Tags)
+		task.Project = strings.TrimSpace(task.Project)
+
+		if err := validateImportedTask(&task); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		if existing, ok := byUID[task.UID]; ok && task.UID != "" {
+			task.ID = existing.ID
+			task.UID = existing.UID
+			task.CreatedAt = existing.CreatedAt
+			if !opts.PreserveTimestamps || task.UpdatedAt.IsZero() {
+				task.UpdatedAt = now
+			}
+
+			snapshot := task
+			results[i] = BatchItemResult{Task: &snapshot, Updated: true}
+			if !opts.DryRun {
+				*existing = task
+				ts.index.Index(existing)
+				ts.recordLocked(TaskEventUpdated, existing)
+			}
+			continue
+		}
+
+		if opts.PreserveIDs {
+			if task.ID <= 0 {
+				results[i].Err = newInvalidInputError("поле 'id' должно быть положительным числом")
+				continue
+			}
+			if _, exists := ts.tasks[task.ID]; exists || taken[task.ID] {
+				results[i].Err = newConflictError("задача с ID %d уже существует", task.ID)
+				continue
+			}
+		} else {
+			task.ID = nextID
+		}
+		taken[task.ID] = true
+		if task.ID >= nextID {
+			nextID = task.ID + 1
+		}
+
+		if !opts.PreserveTimestamps || task.CreatedAt.IsZero() {
+			task.CreatedAt = now
+		}
+		if !opts.PreserveTimestamps || task.UpdatedAt.IsZero() {
+			task.UpdatedAt = task.CreatedAt
+		}
+
+		snapshot := task
+		results[i].Task = &snapshot
+		if !opts.DryRun {
+			stored := task
+			ts.tasks[stored.ID] = &stored
+			ts.index.Index(&stored)
+			ts.recordLocked(TaskEventCreated, &stored)
+			if byUID != nil && stored.UID != "" {
+				byUID[stored.UID] = &stored
+			}
+		}
+	}
+
+	if !opts.DryRun {
+		ts.nextID = nextID
+	}
+	return results
+}
+
+// validateImportedTask проверяет обязательные поля импортируемой задачи
+func validateImportedTask(task *Task) error {
+	if strings.TrimSpace(task.Title) == "" {
+		return newInvalidInputError("поле 'title' обязательно")
+	}
+	if !task.Priority.Valid() {
+		return newInvalidInputError("поле 'priority' должно быть 'low', 'medium' или 'high'")
+	}
+	return nil
+}
+
+// taskRecord представляет задачу в виде значений колонок taskColumns
+func taskRecord(task *Task) []string {
+	return []string{
+		strconv.Itoa(task.ID),
+		task.Title,
+		task.Description,
+		strconv.FormatBool(task.Completed),
+		strings.Join(task.Tags, ", "),
+		string(task.Priority),
+		task.Project,
+		formatOptionalTime(task.DueDate),
+		task.CreatedAt.Format(time.RFC3339),
+		task.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// recordDecoder собирает задачи из табличных записей с заголовком (CSV и Markdown)
+type recordDecoder struct {
+	columns map[string]int
+}
+
+// newRecordDecoder разбирает строку заголовка; колонка title обязательна
+func newRecordDecoder(header []string) (*recordDecoder, error) {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("в заголовке нет колонки 'title'")
+	}
+	return &recordDecoder{columns: columns}, nil
+}
+
+// field возвращает значение колонки или пустую строку, если колонки нет
+func (d *recordDecoder) field(record []string, name string) string {
+	i, ok := d.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// decode превращает запись в задачу
+func (d *recordDecoder) decode(record []string) (*Task, error) {
+	task := &Task{
+		Title:       d.field(record, "title"),
+		Description: d.field(record, "description"),
+		Priority:    Priority(strings.ToLower(d.field(record, "priority"))),
+		Project:     d.field(record, "project"),
+	}
+
+	if value := d.field(record, "id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("неверный id '%s'", value)
+		}
+		task.ID = id
+	}
+
+	completed, err := parseImportBool(d.field(record, "completed"))
+	if err != nil {
+		return nil, err
+	}
+	task.Completed = completed
+
+	if value := d.field(record, "tags"); value != "" {
+		task.Tags = strings.Split(value, ",")
+	}
+
+	if task.DueDate, err = parseImportTime("due_date", d.field(record, "due_date")); err != nil {
+		return nil, err
+	}
+	if created, err := parseImportTime("created_at", d.field(record, "created_at")); err != nil {
+		return nil, err
+	} else if created != nil {
+		task.CreatedAt = *created
+	}
+	if updated, err := parseImportTime("updated_at", d.field(record, "updated_at")); err != nil {
+		return nil, err
+	} else if updated != nil {
+		task.UpdatedAt = *updated
+	}
+
+	if err := validateImportedTask(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// parseImportBool разбирает признак выполнения задачи
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "false", "0", "no", "нет", "[ ]", "[]":
+		return false, nil
+	case "true", "1", "yes", "да", "x", "[x]":
+		return true, nil
+	}
+	return false, fmt.Errorf("неверное значение completed '%s'", value)
+}
+
+// importTimeLayouts — допустимые форматы дат при импорте
+var importTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"}
+
+// parseImportTime разбирает необязательную дату; пустая строка означает отсутствие даты
+func parseImportTime(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("неверная дата в поле '%s': '%s'", field, value)
+}
+
+// formatOptionalTime форматирует необязательную дату в RFC 3339
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// csvTaskEncoder записывает задачи в CSV с заголовком
+type csvTaskEncoder struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func newCSVTaskEncoder(w io.Writer) taskEncoder {
+	return &csvTaskEncoder{writer: csv.NewWriter(w)}
+}
+
+func (e *csvTaskEncoder) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	return e.writer.Write(taskColumns)
+}
+
+func (e *csvTaskEncoder) Encode(task *Task) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.writer.Write(taskRecord(task))
+}
+
+func (e *csvTaskEncoder) Flush() error {
+	// Пустой экспорт все равно содержит заголовок
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// decodeCSVTasks читает задачи из CSV; первая строка — заголовок с именами колонок
+func decodeCSVTasks(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать заголовок CSV: %w", err)
+	}
+	decoder, err := newRecordDecoder(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, ImportRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		task, err := decoder.decode(record)
+		rows = append(rows, ImportRow{Line: line, Task: task, Err: err})
+	}
+}
+
+// ndjsonTaskEncoder записывает по одной задаче в формате JSON на строку
+type ndjsonTaskEncoder struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONTaskEncoder(w io.Writer) taskEncoder {
+	return &ndjsonTaskEncoder{encoder: json.NewEncoder(w)}
+}
+
+func (e *ndjsonTaskEncoder) Encode(task *Task) error {
+	return e.encoder.Encode(task)
+}
+
+func (e *ndjsonTaskEncoder) Flush() error {
+	return nil
+}
+
+// decodeNDJSONTasks читает задачи из JSON Lines; пустые строки пропускаются
+func decodeNDJSONTasks(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var rows []ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var task Task
+		if err := json.Unmarshal([]byte(text), &task); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: fmt.Errorf("неверный JSON: %w", err)})
+			continue
+		}
+		if err := validateImportedTask(&task); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, ImportRow{Line: line, Task: &task})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать JSON Lines: %w", err)
+	}
+	return rows, nil
+}
+
+// markdownTaskEncoder записывает задачи в виде Markdown-таблицы
+type markdownTaskEncoder struct {
+	writer        *bufio.Writer
+	headerWritten bool
+}
+
+func newMarkdownTaskEncoder(w io.Writer) taskEncoder {
+	return &markdownTaskEncoder{writer: bufio.NewWriter(w)}
+}
+
+func (e *markdownTaskEncoder) writeHeader() {
+	if e.headerWritten {
+		return
+	}
+	e.headerWritten = true
+	e.writeRow(taskColumns)
+	separator := make([]string, len(taskColumns))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	e.writeRow(separator)
+}
+
+func (e *markdownTaskEncoder) writeRow(cells []string) {
+	e.writer.WriteString("|")
+	for _, cell := range cells {
+		e.writer.WriteString(" " + escapeMarkdownCell(cell) + " |")
+	}
+	e.writer.WriteString("\n")
+}
+
+func (e *markdownTaskEncoder) Encode(task *Task) error {
+	e.writeHeader()
+	record := taskRecord(task)
+	// Выполненность показываем как чекбокс, чтобы таблица читалась глазами
+	record[3] = "[ ]"
+	if task.Completed {
+		record[3] = "[x]"
+	}
+	e.writeRow(record)
+	return nil
+}
+
+func (e *markdownTaskEncoder) Flush() error {
+	e.writeHeader()
+	return e.writer.Flush()
+}
+
+// escapeMarkdownCell экранирует символы, ломающие ячейку таблицы
+func escapeMarkdownCell(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "|", `\|`)
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+// splitMarkdownRow разбивает строку таблицы на ячейки с учетом экранирования
+func splitMarkdownRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+
+	var cells []string
+	var cell strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			if r != '|' && r != '\\' {
+				cell.WriteRune('\\')
+			}
+			cell.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '|':
+			cells = append(cells, strings.ReplaceAll(cell.String(), "<br>", "\n"))
+			cell.Reset()
+		default:
+			cell.WriteRune(r)
+		}
+	}
+	// Завершающая черта необязательна
+	if rest := strings.TrimSpace(cell.String()); rest != "" {
+		cells = append(cells, strings.ReplaceAll(rest, "<br>", "\n"))
+	}
+	return cells
+}
+
+// isMarkdownSeparator проверяет, что строка — разделитель заголовка таблицы (|---|:--:|)
+func isMarkdownSeparator(cells []string) bool {
+	for _, cell := range cells {
+		if strings.Trim(strings.TrimSpace(cell), ":-") != "" || !strings.Contains(cell, "-") {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+// decodeMarkdownTasks читает задачи из первой Markdown-таблицы в документе;
+// текст вокруг таблицы игнорируется
+func decodeMarkdownTasks(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var (
+		rows    []ImportRow
+		decoder *recordDecoder
+		header  []string
+		inTable bool
+	)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(text, "|") {
+			if inTable {
+				break
+			}
+			header = nil
+			continue
+		}
+
+		cells := splitMarkdownRow(text)
+		switch {
+		case inTable:
+			task, err := decoder.decode(cells)
+			rows = append(rows, ImportRow{Line: line, Task: task, Err: err})
+		case header != nil && isMarkdownSeparator(cells):
+			var err error
+			if decoder, err = newRecordDecoder(header); err != nil {
+				return nil, err
+			}
+			inTable = true
+		default:
+			header = cells
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать Markdown: %w", err)
+	}
+	if !inTable {
+		return nil, fmt.Errorf("в документе нет таблицы задач")
+	}
+	return rows, nil
+}
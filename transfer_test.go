@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskFormats_RoundTrip(t *testing.T) {
+	due := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	original := &Task{
+		ID:          7,
+		Title:       "Отчет | квартал",
+		Description: "Первая строка\nвторая, с \"кавычками\" и \\ слешем",
+		Completed:   true,
+		Tags:        []string{"работа", "срочно"},
+		Priority:    PriorityHigh,
+		Project:     "Годовой план",
+		DueDate:     &due,
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Hour),
+	}
+
+	for _, name := range []string{"csv", "ndjson", "markdown"} {
+		format, _ := LookupTaskFormat(name)
+
+		var buf bytes.Buffer
+		if err := format.ExportTasks(&buf, []*Task{original}); err != nil {
+			t.Fatalf("%s: ошибка экспорта: %v", name, err)
+		}
+
+		rows, err := format.DecodeTasks(&buf)
+		if err != nil {
+			t.Fatalf("%s: ошибка разбора: %v", name, err)
+		}
+		if len(rows) != 1 || rows[0].Err != nil {
+			t.Fatalf("%s: ожидалась одна строка без ошибок, получено %+v", name, rows)
+		}
+
+		task := rows[0].Task
+		if task.ID != original.ID || task.Title != original.Title || task.Description != original.Description ||
+			task.Completed != original.Completed || task.Priority != original.Priority || task.Project != original.Project {
+			t.Errorf("%s: поля не совпадают: %+v", name, task)
+		}
+		if tags := normalizeTags(task.Tags); strings.Join(tags, ",") != "работа,срочно" {
+			t.Errorf("%s: ожидались теги [работа срочно], получено %v", name, tags)
+		}
+		if task.DueDate == nil || !task.DueDate.Equal(due) || !task.CreatedAt.Equal(original.CreatedAt) || !task.UpdatedAt.Equal(original.UpdatedAt) {
+			t.Errorf("%s: даты не совпадают: %v %v %v", name, task.DueDate, task.CreatedAt, task.UpdatedAt)
+		}
+	}
+}
+
+func TestDecodeCSVTasks_RowErrors(t *testing.T) {
+	input := "title,priority,due_date,completed\n" +
+		"Хорошая,low,2024-05-01,да\n" +
+		",high,,\n" +
+		"Плохой приоритет,urgent,,\n" +
+		"Плохая дата,,завтра,\n"
+
+	format, _ := LookupTaskFormat("csv")
+	rows, err := format.DecodeTasks(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if len(rows) != 4 {
+		t.Fatalf("Ожидалось 4 строки, получено %d", len(rows))
+	}
+	if rows[0].Err != nil || !rows[0].Task.Completed {
+		t.Errorf("Первая строка должна разобраться: %+v", rows[0])
+	}
+	for i, line := range []int{3, 4, 5} {
+		if rows[i+1].Err == nil || rows[i+1].Line != line {
+			t.Errorf("Ожидалась ошибка в строке %d, получено %+v", line, rows[i+1])
+		}
+	}
+
+	if _, err := format.DecodeTasks(strings.NewReader("name,done\n")); err == nil {
+		t.Errorf("Ожидалась ошибка для заголовка без колонки title")
+	}
+}
+
+func TestTaskService_ImportTasks(t *testing.T) {
+	service := NewTaskService()
+	service.CreateTask(t.Context(), "Существующая", "")
+
+	tasks := []*Task{
+		{ID: 1, Title: "Конфликт"},
+		{ID: 10, Title: "Десятая", Tags: []string{"#Дом"}},
+		{ID: 10, Title: "Повтор в файле"},
+	}
+
+	results := service.ImportTasks(t.Context(), tasks, ImportOptions{PreserveIDs: true, DryRun: true})
+	if !errors.Is(results[0].Err, ErrConflict) || results[1].Err != nil || !errors.Is(results[2].Err, ErrConflict) {
+		t.Fatalf("Неверные результаты проверки: %+v", results)
+	}
+	if len(service.GetAllTasks(t.Context())) != 1 {
+		t.Fatalf("Проверка без сохранения не должна добавлять задачи")
+	}
+
+	service.ImportTasks(t.Context(), tasks, ImportOptions{PreserveIDs: true})
+	task, err := service.GetTask(t.Context(), 10)
+	if err != nil {
+		t.Fatalf("Задача с сохраненным ID не найдена: %v", err)
+	}
+	if len(task.Tags) != 1 || task.Tags[0] != "дом" {
+		t.Errorf("Теги должны нормализоваться, получено %v", task.Tags)
+	}
+
+	// Новые задачи получают ID после максимального импортированного
+	if next := service.CreateTask(t.Context(), "Следующая", ""); next.ID != 11 {
+		t.Errorf("Ожидался ID 11, получен %d", next.ID)
+	}
+
+	results = service.ImportTasks(t.Context(), []*Task{{ID: 10, Title: "Переназначенная"}}, ImportOptions{})
+	if results[0].Err != nil || results[0].Task.ID != 12 {
+		t.Errorf("Ожидался новый ID 12, получено %+v", results[0])
+	}
+}
+
+func TestTaskHandler_ExportImport(t *testing.T) {
+	source := NewTaskService()
+	source.CreateTask(t.Context(), "Купить молоко", "", WithTags([]string{"дом"}))
+	source.CreateTask(t.Context(), "Сдать отчет", "", WithPriority(PriorityHigh))
+
+	req := httptest.NewRequest("GET", "/tasks/export?format=ndjson", nil)
+	w := httptest.NewRecorder()
+	NewTaskHandler(source).ExportTasks(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Неверный ответ экспорта: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	exported := w.Body.String() + "{\"title\": \"\"}\n"
+
+	target := NewTaskService()
+	handler := NewTaskHandler(target)
+
+	req = httptest.NewRequest("POST", "/tasks/import?format=ndjson&dry_run=true", strings.NewReader(exported))
+	w = httptest.NewRecorder()
+	handler.ImportTasks(w, req)
+
+	var response ImportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Ошибка при парсинге ответа: %v", err)
+	}
+	if w.Code != http.StatusOK || response.Imported != 2 || response.Failed != 1 || response.Errors[0].Line != 3 || response.Errors[0].Status != http.StatusBadRequest {
+		t.Errorf("Неверный результат проверки: %d %+v", w.Code, response)
+	}
+	if len(target.GetAllTasks(t.Context())) != 0 {
+		t.Errorf("В режиме dry_run задачи не должны импортироваться")
+	}
+
+	req = httptest.NewRequest("POST", "/tasks/import?format=ndjson", strings.NewReader(exported))
+	w = httptest.NewRecorder()
+	handler.ImportTasks(w, req)
+
+	if w.Code != http.StatusCreated || len(target.GetAllTasks(t.Context())) != 2 {
+		t.Errorf("Ожидался импорт 2 задач со статусом 201, получено %d задач со статусом %d", len(target.GetAllTasks(t.Context())), w.Code)
+	}
+
+	// С uids=match задача с известным UID обновляет существующую и в формате ndjson
+	first, _ := target.GetTask(t.Context(), 1)
+	req = httptest.NewRequest("POST", "/tasks/import?format=ndjson&uids=match", strings.NewReader(`{"title": "Купить кефир", "uid": "`+TaskUID(first)+`"}`))
+	w = httptest.NewRecorder()
+	handler.ImportTasks(w, req)
+
+	response = ImportResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Updated != 1 || response.Imported != 0 || len(target.GetAllTasks(t.Context())) != 2 {
+		t.Errorf("uids=match должен обновить задачу 1, получено %+v", response)
+	}
+
+	req = httptest.NewRequest("POST", "/tasks/import?format=xml", strings.NewReader(exported))
+	w = httptest.NewRecorder()
+	handler.ImportTasks(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %d для неизвестного формата, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ViewHandler обрабатывает HTTP запросы для сохраненных представлений
+type ViewHandler struct {
+	views ViewServiceInterface
+	tasks TaskServiceInterface
+}
+
+// NewViewHandler создает новый обработчик представлений
+func NewViewHandler(views ViewServiceInterface, tasks TaskServiceInterface) *ViewHandler {
+	return &ViewHandler{views: views, tasks: tasks}
+}
+
+// CreateView обрабатывает POST /views
+func (vh *ViewHandler) CreateView(w http.ResponseWriter, r *http.Request) {
+	var req SaveViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный JSON", http.StatusBadRequest)
+		return
+	}
+
+	view, err := vh.views.CreateView(req.Name, req.Query)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(view)
+}
+
+// GetViews обрабатывает GET /views
+func (vh *ViewHandler) GetViews(w http.ResponseWriter, r *http.Request) {
+	views := vh.views.GetAllViews()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+// GetView обрабатывает GET /views/{name}
+func (vh *ViewHandler) GetView(w http.ResponseWriter, r *http.Request) {
+	view, err := vh.views.GetView(chi.URLParam(r, "name"))
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// UpdateView обрабатывает PUT /views/{name}
+func (vh *ViewHandler) UpdateView(w http.ResponseWriter, r *http.Request) {
+	var req SaveViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный JSON", http.StatusBadRequest)
+		return
+	}
+
+	view, err := vh.views.UpdateView(chi.URLParam(r, "name"), req.Query)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// DeleteView обрабатывает DELETE /views/{name}
+func (vh *ViewHandler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	if err := vh.views.DeleteView(chi.URLParam(r, "name")); err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunView обрабатывает GET /views/{name}/tasks — выполняет сохраненный запрос
+func (vh *ViewHandler) RunView(w http.ResponseWriter, r *http.Request) {
+	view, err := vh.views.GetView(chi.URLParam(r, "name"))
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	query, err := ParseQuery(view.Query)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	tasks := filterTasks(r.Context(), vh.tasks, query.Match)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tasks)
+}
+
+// writeViewError сопоставляет ошибки сервиса представлений со статус-кодами
+func writeViewError(w http.ResponseWriter, err error) {
+	var syntaxErr *QuerySyntaxError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrViewExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrViewNameRequired), errors.As(err, &syntaxErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+	}
+}
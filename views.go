@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrViewExists возвращается при создании представления с уже занятым именем
+	ErrViewExists = errors.New("представление с таким именем уже существует")
+	// ErrViewNameRequired возвращается при создании представления без имени
+	ErrViewNameRequired = errors.New("поле 'name' обязательно")
+	// ErrViewServiceClosed возвращается при изменении представлений после Close
+	ErrViewServiceClosed = errors.New("сервис представлений закрыт")
+)
+
+// ViewServiceInterface определяет интерфейс для работы с сохраненными представлениями
+type ViewServiceInterface interface {
+	CreateView(name, query string) (*SavedView, error)
+	GetView(name string) (*SavedView, error)
+	GetAllViews() []*SavedView
+	UpdateView(name, query string) (*SavedView, error)
+	DeleteView(name string) error
+	// Close дожидается начатой записи и запрещает дальнейшие изменения
+	Close() error
+	// HealthCheck проверяет, что изменения представлений можно сохранить
+	HealthCheck(ctx context.Context) error
+}
+
+// ViewService хранит сохраненные представления в памяти и, если задан путь, в JSON-файле
+type ViewService struct {
+	views  map[string]*SavedView
+	path   string
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// NewViewService создает сервис представлений. Если path не пуст,
+// представления загружаются из файла и сохраняются в него при каждом изменении.
+func NewViewService(path string) (ViewServiceInterface, error) {
+	vs := &ViewService{
+		views: make(map[string]*SavedView),
+		path:  path,
+	}
+
+	if path == "" {
+		return vs, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return vs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать представления: %w", err)
+	}
+
+	var views []*SavedView
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать файл представлений: %w", err)
+	}
+	for _, view := range views {
+		vs.views[view.Name] = view
+	}
+
+	return vs, nil
+}
+
+// CreateView сохраняет новое представление, предварительно проверив запрос
+func (vs *ViewService) CreateView(name, query string) (*SavedView, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrViewNameRequired
+	}
+	if _, err := ParseQuery(query); err != nil {
+		return nil, err
+	}
+
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	if _, exists := vs.views[name]; exists {
+		return nil, ErrViewExists
+	}
+
+	view := &SavedView{
+		Name:      name,
+		Query:     query,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	vs.views[name] = view
+
+	if err := vs.save(); err != nil {
+		delete(vs.views, name)
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// GetView возвращает представление по имени
+func (vs *ViewService) GetView(name string) (*SavedView, error) {
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+
+	view, exists := vs.views[name]
+	if !exists {
+		return nil, newNotFoundError("представление '%s' не найдено", name)
+	}
+
+	return view, nil
+}
+
+// GetAllViews возвращает все представления, отсортированные по имени
+func (vs *ViewService) GetAllViews() []*SavedView {
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+
+	views := make([]*SavedView, 0, len(vs.views))
+	for _, view := range vs.views {
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+
+	return views
+}
+
+// UpdateView заменяет запрос существующего представления
+func (vs *ViewService) UpdateView(name, query string) (*SavedView, error) {
+	if _, err := ParseQuery(query); err != nil {
+		return nil, err
+	}
+
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	view, exists := vs.views[name]
+	if !exists {
+		return nil, newNotFoundError("представление '%s' не найдено", name)
+	}
+
+	previous := *view
+	view.Query = query
+	view.UpdatedAt = time.Now()
+
+	if err := vs.save(); err != nil {
+		*view = previous
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// DeleteView удаляет представление
+func (vs *ViewService) DeleteView(name string) error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	view, exists := vs.views[name]
+	if !exists {
+		return newNotFoundError("представление '%s' не найдено", name)
+	}
+
+	delete(vs.views, name)
+
+	if err := vs.save(); err != nil {
+		vs.views[name] = view
+		return err
+	}
+
+	return nil
+}
+
+// save атомарно записывает представления в файл; вызывающий должен удерживать мьютекс
+func (vs *ViewService) save() error {
+	if vs.closed {
+		return ErrViewServiceClosed
+	}
+	if vs.path == "" {
+		return nil
+	}
+
+	views := make([]*SavedView, 0, len(vs.views))
+	for _, view := range vs.views {
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+
+	data, err := json.MarshalIndent(views, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(vs.path), 0o755); err != nil {
+		return err
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы не оставить файл недописанным
+	tmp, err := os.CreateTemp(filepath.Dir(vs.path), ".views-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Сбрасываем данные на диск до переименования, иначе после сбоя питания
+	// на месте файла может оказаться пустой
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), vs.path)
+}
+
+// Close дожидается записи, которая уже идет, и запрещает дальнейшие изменения:
+// после остановки сервера файл представлений больше не меняется
+func (vs *ViewService) Close() error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	vs.closed = true
+	return nil
+}
+
+// HealthCheck проверяет, что сервис не закрыт и каталог файла представлений доступен
+// для записи. Файл разбирается при создании сервиса, поэтому его формат уже проверен.
+func (vs *ViewService) HealthCheck(ctx context.Context) error {
+	vs.mutex.RLock()
+	closed := vs.closed
+	vs.mutex.RUnlock()
+
+	if closed {
+		return ErrViewServiceClosed
+	}
+	if vs.path == "" {
+		return nil
+	}
+	return checkWritableDir(filepath.Dir(vs.path))
+}